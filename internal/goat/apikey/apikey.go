@@ -0,0 +1,279 @@
+// Package apikey authenticates machine clients, such as CLI tools and CI jobs, with API keys that
+// users create for themselves.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// Config controls the format of keys and how requests present them.
+type Config struct {
+	Prefix        string        // Leading part of every key, which makes leaked keys easy to spot and scan for.
+	Header        string        // Request header carrying the key; "Authorization: Bearer <key>" is accepted too.
+	AllowedScopes []string      // Scopes keys may be created with; empty allows any.
+	TouchInterval time.Duration // Minimum time between last-used updates, to limit writes to the store.
+}
+
+// DefaultConfig returns a Config for keys like "goat_<id>_<secret>" sent in the X-API-Key header.
+func DefaultConfig() Config {
+	return Config{
+		Prefix:        "goat",
+		Header:        "X-API-Key",
+		TouchInterval: time.Minute,
+	}
+}
+
+// Gin context keys under which Middleware stores the authenticated user and their key.
+const (
+	userKey   = "goat.user"
+	apiKeyKey = "goat.api_key"
+)
+
+// idLength is the number of hex characters of randomness in a key ID.
+const idLength = 16
+
+// Manager issues and validates API keys. It implements goat.Authenticator and goat.APIKeyService.
+// Requests are authenticated with the keys and users of their tenant, as resolved by
+// tenant.Middleware.
+type Manager[U models.Account] struct {
+	store  goat.APIKeyStore
+	users  goat.UserService[U]
+	config Config
+	now    func() time.Time
+}
+
+var (
+	_ goat.Authenticator[*models.User] = (*Manager[*models.User])(nil)
+	_ goat.APIKeyService               = (*Manager[*models.User])(nil)
+)
+
+// NewManager creates a Manager that keeps keys in store and loads users from users.
+func NewManager[U models.Account](store goat.APIKeyStore, users goat.UserService[U], config Config) *Manager[U] {
+	return &Manager[U]{store: store, users: users, config: config, now: time.Now}
+}
+
+// ForTenant implements goat.APIKeyService.
+func (m *Manager[U]) ForTenant(tenantID string) goat.APIKeyService {
+	return m.scoped(tenantID)
+}
+
+// scoped returns a copy of m that only sees the keys and users of tenantID.
+func (m *Manager[U]) scoped(tenantID string) *Manager[U] {
+	scoped := *m
+	scoped.store = m.store.ForTenant(tenantID)
+	scoped.users = m.users.ForTenant(tenantID)
+	return &scoped
+}
+
+// CreateAPIKey implements goat.APIKeyService.
+func (m *Manager[U]) CreateAPIKey(userID uint, name string, scopes []string, ttl time.Duration) (*models.APIKey, string, error) {
+	if len(m.config.AllowedScopes) > 0 {
+		allowed := &models.APIKey{Scopes: m.config.AllowedScopes}
+		for _, s := range scopes {
+			if !allowed.HasScope(s) {
+				return nil, "", goat.ErrInvalidScope
+			}
+		}
+	}
+	if _, err := m.users.GetUserByID(userID); err != nil {
+		return nil, "", err
+	}
+
+	id := make([]byte, idLength/2)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := m.now()
+	key := &models.APIKey{
+		ID:         m.config.Prefix + "_" + hex.EncodeToString(id),
+		UserID:     userID,
+		Name:       name,
+		SecretHash: hashSecret(encodedSecret),
+		Scopes:     scopes,
+		CreatedAt:  now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := m.store.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, key.ID + "_" + encodedSecret, nil
+}
+
+// ListAPIKeys implements goat.APIKeyService.
+func (m *Manager[U]) ListAPIKeys(userID uint) ([]*models.APIKey, error) {
+	return m.store.ListAPIKeys(userID)
+}
+
+// RevokeAPIKey implements goat.APIKeyService. Keys of other users are reported as not found.
+func (m *Manager[U]) RevokeAPIKey(userID uint, id string) error {
+	key, err := m.store.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if key.UserID != userID {
+		return goat.ErrAPIKeyNotFound
+	}
+	return m.store.DeleteAPIKey(id)
+}
+
+// Verify checks a full key and returns its record.
+func (m *Manager[U]) Verify(value string) (*models.APIKey, error) {
+	id, secret, ok := m.parse(value)
+	if !ok {
+		return nil, goat.ErrUnauthorized
+	}
+	key, err := m.store.GetAPIKey(id)
+	if err != nil {
+		if errors.Is(err, goat.ErrAPIKeyNotFound) {
+			return nil, goat.ErrUnauthorized
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, goat.ErrUnauthorized
+	}
+	if key.Expired(m.now()) {
+		return nil, goat.ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// Authenticate implements goat.Authenticator. It validates the request's API key, records its use
+// and returns the key's user.
+func (m *Manager[U]) Authenticate(c *gin.Context) (U, error) {
+	var zero U
+	m = m.scoped(tenant.ID(c))
+	key, err := m.Verify(m.keyFromRequest(c))
+	if err != nil {
+		return zero, err
+	}
+
+	now := m.now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= m.config.TouchInterval {
+		if err := m.store.TouchAPIKey(key.ID, now); err != nil {
+			return zero, err
+		}
+		key.LastUsedAt = &now
+	}
+	c.Set(apiKeyKey, key)
+
+	user, err := m.users.GetUserByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, goat.ErrUserNotFound) {
+			// The user was deleted.
+			return zero, goat.ErrUnauthorized
+		}
+		return zero, err
+	}
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		return zero, err
+	}
+	return user, nil
+}
+
+// RefreshAuthToken implements goat.Authenticator. API keys are not refreshed; this authenticates
+// the request like Authenticate.
+func (m *Manager[U]) RefreshAuthToken(c *gin.Context) (U, error) {
+	return m.Authenticate(c)
+}
+
+// SocialLogin implements goat.Authenticator. API keys do not support social login.
+func (m *Manager[U]) SocialLogin(c *gin.Context, provider string) (U, error) {
+	var zero U
+	return zero, goat.ErrUnsupportedProvider
+}
+
+// Middleware authenticates every request with Authenticate, aborting with 401 when there is no
+// valid key. The user and key are available to later handlers through User and Current.
+func (m *Manager[U]) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := m.Authenticate(c)
+		if err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+// RequireScope aborts with 403 unless the request's key, as stored by Middleware, grants scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := Current(c)
+		if !ok || !key.HasScope(scope) {
+			goat.AbortWithProblem(c, goat.ErrPermissionDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Current returns the API key of the request, as loaded by Authenticate.
+func Current(c *gin.Context) (*models.APIKey, bool) {
+	key, ok := c.Get(apiKeyKey)
+	if !ok {
+		return nil, false
+	}
+	k, ok := key.(*models.APIKey)
+	return k, ok
+}
+
+// User returns the user stored in c by Middleware.
+func User[U models.Account](c *gin.Context) (U, bool) {
+	user, ok := c.Get(userKey)
+	if !ok {
+		var zero U
+		return zero, false
+	}
+	u, ok := user.(U)
+	return u, ok
+}
+
+// keyFromRequest returns the key in the configured header, or in a bearer token with the key prefix.
+func (m *Manager[U]) keyFromRequest(c *gin.Context) string {
+	if value := c.GetHeader(m.config.Header); value != "" {
+		return value
+	}
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if strings.EqualFold(scheme, "Bearer") && strings.HasPrefix(token, m.config.Prefix+"_") {
+		return token
+	}
+	return ""
+}
+
+// parse splits a full key "<prefix>_<id>_<secret>" into its ID and secret.
+func (m *Manager[U]) parse(value string) (id, secret string, ok bool) {
+	n := len(m.config.Prefix) + 1 + idLength
+	if len(value) <= n+1 || !strings.HasPrefix(value, m.config.Prefix+"_") || value[n] != '_' {
+		return "", "", false
+	}
+	return value[:n], value[n+1:], true
+}
+
+// hashSecret returns the stored form of a key's secret. Secrets are random, so a fast hash suffices.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
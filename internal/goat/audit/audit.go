@@ -0,0 +1,158 @@
+// Package audit records authentication and account events in an append-only log, and lets
+// administrators query and export it. Events are chained by hash so that edits, deletions and
+// insertions can be detected, and signed checkpoints pin the chain down against rewrites.
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes for Query.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// maxUserAgentLength is the longest user agent stored with an event; longer ones are truncated.
+const maxUserAgentLength = 255
+
+// Page is one page of query results.
+type Page struct {
+	Events []*models.AuditEvent `json:"events"`
+	Next   string               `json:"next,omitempty"` // Cursor for the next page, set as AuditQuery.Before; empty on the last page.
+}
+
+// Config controls how the hash chain is computed and checkpointed.
+type Config struct {
+	HashKey        []byte     // When set, events are hashed with HMAC-SHA256 under this key instead of SHA-256, so that only its holders can recompute the chain.
+	CheckpointKeys jwt.KeySet // Signs checkpoints and verifies them in VerifyChain; Checkpoint fails without it.
+}
+
+// Logger records audit events in a goat.AuditStore. It implements goat.Auditor.
+type Logger struct {
+	store  goat.AuditStore
+	config Config
+	now    func() time.Time
+
+	mu   sync.Mutex // Serializes appends to the chain.
+	head *link      // Last event appended, or nil when it must be read from the store.
+}
+
+var _ goat.Auditor = (*Logger)(nil)
+
+// NewLogger creates a Logger that appends events to store.
+func NewLogger(store goat.AuditStore, config Config) *Logger {
+	return &Logger{store: store, config: config, now: time.Now}
+}
+
+// Record implements goat.Auditor. The event's ID, time and place in the hash chain are always set
+// by the Logger; its IP, user agent and tenant are taken from c unless already set.
+func (l *Logger) Record(c *gin.Context, event *models.AuditEvent) error {
+	now := l.now()
+	id, err := newEventID(now)
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	// Milliseconds are the finest precision every store keeps, and the time must read back exactly
+	// for the event's hash to verify.
+	event.CreatedAt = now.UTC().Truncate(time.Millisecond)
+	if c != nil {
+		if event.IP == "" {
+			event.IP = c.ClientIP()
+		}
+		if event.UserAgent == "" {
+			event.UserAgent = truncate(c.Request.UserAgent(), maxUserAgentLength)
+		}
+		if event.TenantID == "" {
+			event.TenantID = tenant.ID(c)
+		}
+	}
+	return l.append(event)
+}
+
+// Query returns a page of the events matching query, newest first. A zero limit means DefaultLimit,
+// and limits above MaxLimit are lowered to it.
+func (l *Logger) Query(query models.AuditQuery) (*Page, error) {
+	switch {
+	case query.Limit <= 0:
+		query.Limit = DefaultLimit
+	case query.Limit > MaxLimit:
+		query.Limit = MaxLimit
+	}
+
+	// Fetch one extra event to learn whether there is another page.
+	limit := query.Limit
+	query.Limit++
+	events, err := l.store.QueryAuditEvents(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.Next = page.Events[limit-1].ID
+	}
+	return page, nil
+}
+
+// Export writes every event matching query to w as JSON Lines, one event per line, newest first.
+// query.Limit sets the page size used to read the store, not the number of events exported.
+func (l *Logger) Export(w io.Writer, query models.AuditQuery) error {
+	enc := json.NewEncoder(w)
+	for {
+		page, err := l.Query(query)
+		if err != nil {
+			return err
+		}
+		for _, event := range page.Events {
+			if err := enc.Encode(event); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		query.Before = page.Next
+	}
+}
+
+// Event returns an event of type typ by the user actorID on the user targetID.
+func Event(typ string, actorID, targetID uint) *models.AuditEvent {
+	return &models.AuditEvent{Type: typ, ActorID: actorID, TargetID: targetID}
+}
+
+// newEventID returns a 32 hex character ID that starts with the nanosecond time, so that IDs sort
+// in the order events were recorded, followed by random bits to keep IDs unique.
+func newEventID(now time.Time) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%016x", uint64(now.UnixNano()), binary.BigEndian.Uint64(b[:])), nil
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
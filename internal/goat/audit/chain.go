@@ -0,0 +1,429 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNoCheckpointKeys is returned by Checkpoint when the Logger has no Config.CheckpointKeys.
+var ErrNoCheckpointKeys = errors.New("audit: no checkpoint signing keys")
+
+// ErrNotRedactable is returned by Redact for an event that does not match its hash.
+var ErrNotRedactable = errors.New("audit: event cannot be redacted without breaking the chain")
+
+// maxAppendAttempts is how many times append retries when another writer takes the next sequence
+// number first.
+const maxAppendAttempts = 5
+
+// Reasons a chain is broken, reported in BrokenLinkError.
+const (
+	ReasonMissing           = "event is missing"
+	ReasonModified          = "event does not match its hash"
+	ReasonPrevHash          = "previous hash does not match the previous event"
+	ReasonCheckpointInvalid = "checkpoint signature is invalid"
+	ReasonCheckpointHash    = "event does not match the checkpoint"
+	ReasonTruncated         = "chain ends before the checkpoint"
+)
+
+// link is the position and hash of an event in the chain.
+type link struct {
+	seq  uint64
+	hash string
+}
+
+// Verification is the result of a successful VerifyChain.
+type Verification struct {
+	Seq         uint64 `json:"seq"`         // Sequence number of the last event verified, which is the number of events.
+	Hash        string `json:"hash"`        // Hash of that event.
+	Checkpoints int    `json:"checkpoints"` // Number of checkpoints whose signature and hash were verified.
+}
+
+// BrokenLinkError reports the first place where the chain no longer verifies. Everything before Seq
+// is intact. It wraps goat.ErrAuditChainBroken.
+type BrokenLinkError struct {
+	Seq     uint64 `json:"seq"`                // Sequence number of the broken link.
+	EventID string `json:"event_id,omitempty"` // ID of the event found at Seq; empty if it is missing.
+	Reason  string `json:"reason"`             // One of the Reason* constants.
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("audit hash chain is broken at seq %d: %s", e.Seq, e.Reason)
+}
+
+func (e *BrokenLinkError) Unwrap() error {
+	return goat.ErrAuditChainBroken
+}
+
+// checkpointClaims are the claims signed in a checkpoint's JWS.
+type checkpointClaims struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+	gojwt.RegisteredClaims
+}
+
+// chainedFields is the canonical encoding of an event that its hash covers: every field but the
+// hash itself, in a fixed order, with metadata keys sorted by encoding/json. The IP, user agent and
+// metadata values are replaced by their digests, so that Redact can remove them and keep the hash.
+type chainedFields struct {
+	Seq       uint64            `json:"seq"`
+	PrevHash  string            `json:"prev_hash"`
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	ActorID   uint              `json:"actor_id"`
+	TargetID  uint              `json:"target_id"`
+	TenantID  string            `json:"tenant_id"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// Keys of AuditEvent.Redacted: the digest of a redacted metadata value is kept under
+// redactedMetadata and its key.
+const (
+	redactedIP        = "ip"
+	redactedUserAgent = "user_agent"
+	redactedMetadata  = "metadata."
+)
+
+// personalMetadata are the metadata keys whose values Redact removes.
+var personalMetadata = []string{"email"}
+
+// append links event to the head of the chain and saves it. When another writer to the same store
+// has moved the head, the store rejects the sequence number and append retries from the new head.
+func (l *Logger) append(event *models.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		if l.head == nil {
+			head, err := l.store.LastAuditEvent()
+			switch {
+			case err == nil:
+				l.head = &link{seq: head.Seq, hash: head.Hash}
+			case errors.Is(err, goat.ErrAuditEventNotFound):
+				l.head = &link{}
+			default:
+				return err
+			}
+		}
+
+		event.Seq = l.head.seq + 1
+		event.PrevHash = l.head.hash
+		sum, err := l.hash(event)
+		if err != nil {
+			return err
+		}
+		event.Hash = sum
+
+		err = l.store.AppendAuditEvent(event)
+		if err == nil {
+			l.head = &link{seq: event.Seq, hash: event.Hash}
+			return nil
+		}
+		l.head = nil
+		if !errors.Is(err, goat.ErrAuditSeqTaken) || attempt == maxAppendAttempts {
+			return err
+		}
+	}
+}
+
+// hash returns the hex-encoded hash of event's chained fields, with the digests of its IP, user
+// agent and metadata values, or the digests kept in event.Redacted for those it no longer holds. A
+// value put back in place of a redacted one is hashed as it is, so it must be the original.
+func (l *Logger) hash(event *models.AuditEvent) (string, error) {
+	fields := chainedFields{
+		Seq:       event.Seq,
+		PrevHash:  event.PrevHash,
+		ID:        event.ID,
+		Type:      event.Type,
+		ActorID:   event.ActorID,
+		TargetID:  event.TargetID,
+		TenantID:  event.TenantID,
+		IP:        l.digestOf(event, redactedIP, event.IP),
+		UserAgent: l.digestOf(event, redactedUserAgent, event.UserAgent),
+		CreatedAt: event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	for key, digest := range event.Redacted {
+		if name, ok := strings.CutPrefix(key, redactedMetadata); ok {
+			if fields.Metadata == nil {
+				fields.Metadata = map[string]string{}
+			}
+			fields.Metadata[name] = digest
+		}
+	}
+	for key, value := range event.Metadata {
+		if fields.Metadata == nil {
+			fields.Metadata = map[string]string{}
+		}
+		fields.Metadata[key] = l.digest(value)
+	}
+	return l.sum(fields)
+}
+
+// digestOf returns the digest of value, or the one kept under key in event.Redacted once the value
+// has been redacted.
+func (l *Logger) digestOf(event *models.AuditEvent, key, value string) string {
+	if digest, ok := event.Redacted[key]; ok && value == "" {
+		return digest
+	}
+	return l.digest(value)
+}
+
+// digest returns the hex-encoded hash of a single value; the digest of an empty value is empty.
+func (l *Logger) digest(value string) string {
+	if value == "" {
+		return ""
+	}
+	h := l.hasher()
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sum returns the hex-encoded hash of fields' JSON encoding.
+func (l *Logger) sum(fields chainedFields) (string, error) {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	h := l.hasher()
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hasher returns an HMAC-SHA256 under the HashKey, or a SHA-256 if there is none.
+func (l *Logger) hasher() hash.Hash {
+	if len(l.config.HashKey) > 0 {
+		return hmac.New(sha256.New, l.config.HashKey)
+	}
+	return sha256.New()
+}
+
+// hashes reports whether event matches its stored hash.
+func (l *Logger) hashes(event *models.AuditEvent) (bool, error) {
+	sum, err := l.hash(event)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(sum), []byte(event.Hash)), nil
+}
+
+// Redact removes the personal data of a recorded event, its IP address, user agent and the email in
+// its metadata, keeping their digests in event.Redacted so that the chain still verifies. It does
+// nothing when there is none left. An event that does not match its hash is refused with
+// ErrNotRedactable, so that redacting it cannot hide that it was tampered with.
+//
+// Without a HashKey the digests are plain SHA-256 hashes, and a value with few candidates, such as
+// an IP address, can be recovered by hashing them all; set a HashKey where that matters.
+func (l *Logger) Redact(event *models.AuditEvent) error {
+	redacted := *event
+	redacted.Redacted = map[string]string{}
+	for key, digest := range event.Redacted {
+		redacted.Redacted[key] = digest
+	}
+	redacted.Metadata = nil
+	for key, value := range event.Metadata {
+		if redacted.Metadata == nil {
+			redacted.Metadata = map[string]string{}
+		}
+		redacted.Metadata[key] = value
+	}
+
+	changed := false
+	remove := func(key string, value *string) {
+		if *value != "" {
+			redacted.Redacted[key] = l.digest(*value)
+			*value = ""
+			changed = true
+		}
+	}
+	remove(redactedIP, &redacted.IP)
+	remove(redactedUserAgent, &redacted.UserAgent)
+	for _, key := range personalMetadata {
+		if value, ok := redacted.Metadata[key]; ok {
+			remove(redactedMetadata+key, &value)
+			delete(redacted.Metadata, key)
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	ok, err := l.hashes(event)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotRedactable
+	}
+	if err := l.store.RedactAuditEvent(&redacted); err != nil {
+		return err
+	}
+	*event = redacted
+	return nil
+}
+
+// VerifyChain walks the whole chain in order, recomputing every hash, and checks it against the
+// stored checkpoints. It returns a *BrokenLinkError for the first link that does not verify, and
+// other errors only when the store cannot be read. Checkpoints are checked only when the Logger has
+// CheckpointKeys, which must still include the keys that signed them.
+//
+// Events appended after the last checkpoint are only protected by their hashes: someone who can
+// write to the store, and knows the HashKey if one is set, can rewrite or drop them undetected.
+//
+// Redacted events are verified with the digests kept in place of the values Redact removed.
+func (l *Logger) VerifyChain() (*Verification, error) {
+	var checkpoints []*models.AuditCheckpoint
+	if l.config.CheckpointKeys != nil {
+		var err error
+		checkpoints, err = l.store.ListAuditCheckpoints()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l.verify(link{}, checkpoints)
+}
+
+// verify walks the chain from the event after from, checking checkpoints, in Seq order, as it
+// reaches them.
+func (l *Logger) verify(from link, checkpoints []*models.AuditCheckpoint) (*Verification, error) {
+	result := &Verification{Seq: from.seq, Hash: from.hash}
+	for {
+		events, err := l.store.ListAuditChain(result.Seq, MaxLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.Seq != result.Seq+1 {
+				return nil, &BrokenLinkError{Seq: result.Seq + 1, Reason: ReasonMissing}
+			}
+			ok, err := l.hashes(event)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, &BrokenLinkError{Seq: event.Seq, EventID: event.ID, Reason: ReasonModified}
+			}
+			if event.PrevHash != result.Hash {
+				return nil, &BrokenLinkError{Seq: event.Seq, EventID: event.ID, Reason: ReasonPrevHash}
+			}
+			result.Seq, result.Hash = event.Seq, event.Hash
+
+			for len(checkpoints) > 0 && checkpoints[0].Seq <= result.Seq {
+				if reason := l.checkCheckpoint(checkpoints[0], result.Hash); reason != "" {
+					return nil, &BrokenLinkError{Seq: event.Seq, EventID: event.ID, Reason: reason}
+				}
+				checkpoints = checkpoints[1:]
+				result.Checkpoints++
+			}
+		}
+		if len(events) < MaxLimit {
+			break
+		}
+	}
+
+	if len(checkpoints) > 0 {
+		return nil, &BrokenLinkError{Seq: result.Seq + 1, Reason: ReasonTruncated}
+	}
+	return result, nil
+}
+
+// checkCheckpoint returns why checkpoint does not vouch for the event with hash sum, or "" if it does.
+func (l *Logger) checkCheckpoint(checkpoint *models.AuditCheckpoint, sum string) string {
+	keys := l.config.CheckpointKeys
+	claims := &checkpointClaims{}
+	_, err := gojwt.ParseWithClaims(checkpoint.Signature, claims, keys.Keyfunc, gojwt.WithValidMethods(keys.Algorithms()))
+	if err != nil || claims.Seq != checkpoint.Seq || claims.Hash != checkpoint.Hash {
+		return ReasonCheckpointInvalid
+	}
+	if checkpoint.Hash != sum {
+		return ReasonCheckpointHash
+	}
+	return ""
+}
+
+// Checkpoint signs the current head of the chain and saves it as a checkpoint. The events since the
+// last checkpoint are verified first, so that a tampered chain is never signed; if they do not
+// verify, a *BrokenLinkError is returned. When nothing was appended since the last checkpoint, that
+// checkpoint is returned and no new one is saved.
+func (l *Logger) Checkpoint() (*models.AuditCheckpoint, error) {
+	if l.config.CheckpointKeys == nil {
+		return nil, ErrNoCheckpointKeys
+	}
+
+	// Start from the last checkpoint, once its own signature checks out.
+	var from link
+	last, err := l.store.LastAuditCheckpoint()
+	switch {
+	case err == nil:
+		if reason := l.checkCheckpoint(last, last.Hash); reason != "" {
+			return nil, &BrokenLinkError{Seq: last.Seq, Reason: reason}
+		}
+		from = link{seq: last.Seq, hash: last.Hash}
+	case !errors.Is(err, goat.ErrAuditCheckpointNotFound):
+		return nil, err
+	}
+
+	head, err := l.verify(from, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.Seq == 0 {
+		return nil, goat.ErrAuditEventNotFound
+	}
+	if last != nil && head.Seq == last.Seq {
+		return last, nil
+	}
+
+	now := l.now()
+	signature, err := l.config.CheckpointKeys.Sign(&checkpointClaims{
+		Seq:              head.Seq,
+		Hash:             head.Hash,
+		RegisteredClaims: gojwt.RegisteredClaims{IssuedAt: gojwt.NewNumericDate(now)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &models.AuditCheckpoint{Seq: head.Seq, Hash: head.Hash, Signature: signature, CreatedAt: now.UTC().Truncate(time.Millisecond)}
+	if err := l.store.SaveAuditCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// RunCheckpoints writes a checkpoint every interval until ctx is cancelled; an empty log is skipped.
+// Failures, including a chain found broken, are reported to onError, if not nil, and the checkpoint
+// is retried sooner, backing off up to interval.
+func (l *Logger) RunCheckpoints(ctx context.Context, interval time.Duration, onError func(error)) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := l.Checkpoint(); err != nil && !errors.Is(err, goat.ErrAuditEventNotFound) {
+				failures++
+				if onError != nil {
+					onError(err)
+				}
+				timer.Reset(goat.RetryDelay(failures, interval))
+				continue
+			}
+			failures = 0
+			timer.Reset(interval)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package audit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+// tamperedStore hands the chain and the checkpoints of a MemoryAuditStore through edit functions,
+// as someone with write access to the store could have changed them.
+type tamperedStore struct {
+	*repository.MemoryAuditStore
+	events      func([]*models.AuditEvent) []*models.AuditEvent
+	checkpoints func([]*models.AuditCheckpoint)
+}
+
+func (s *tamperedStore) ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) {
+	events, err := s.MemoryAuditStore.ListAuditChain(afterSeq, limit)
+	if err != nil || s.events == nil {
+		return events, err
+	}
+	return s.events(events), nil
+}
+
+func (s *tamperedStore) ListAuditCheckpoints() ([]*models.AuditCheckpoint, error) {
+	checkpoints, err := s.MemoryAuditStore.ListAuditCheckpoints()
+	if err == nil && s.checkpoints != nil {
+		s.checkpoints(checkpoints)
+	}
+	return checkpoints, err
+}
+
+func (s *tamperedStore) LastAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	checkpoint, err := s.MemoryAuditStore.LastAuditCheckpoint()
+	if err == nil && s.checkpoints != nil {
+		s.checkpoints([]*models.AuditCheckpoint{checkpoint})
+	}
+	return checkpoint, err
+}
+
+// newCheckpointKeys returns an EdDSA key set for signing checkpoints.
+func newCheckpointKeys(t *testing.T) jwt.KeySet {
+	t.Helper()
+	keys, err := jwt.NewKeyManager(repository.NewMemoryKeyStore(), jwt.KeyManagerConfig{
+		Algorithm:          jwt.AlgorithmEdDSA,
+		RotationInterval:   24 * time.Hour,
+		VerificationPeriod: time.Hour,
+		EncryptionKey:      make([]byte, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// newTestChain records n events by user 1 in a new store and returns the Logger and the store.
+func newTestChain(t *testing.T, n int, config Config) (*Logger, *tamperedStore) {
+	t.Helper()
+	store := &tamperedStore{MemoryAuditStore: repository.NewMemoryAuditStore()}
+	l := NewLogger(store, config)
+	for i := 0; i < n; i++ {
+		event := Event("login_succeeded", 1, 1)
+		event.IP, event.UserAgent = "203.0.113.7", "curl/8.0"
+		event.Metadata = map[string]string{"email": "ada@example.com", "method": "password"}
+		if err := l.Record(nil, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l, store
+}
+
+// at applies edit to the event with seq, keeping the others.
+func at(seq uint64, edit func(*models.AuditEvent)) func([]*models.AuditEvent) []*models.AuditEvent {
+	return func(events []*models.AuditEvent) []*models.AuditEvent {
+		for _, event := range events {
+			if event.Seq == seq {
+				edit(event)
+			}
+		}
+		return events
+	}
+}
+
+// without drops the event with seq.
+func without(seq uint64) func([]*models.AuditEvent) []*models.AuditEvent {
+	return func(events []*models.AuditEvent) []*models.AuditEvent {
+		kept := []*models.AuditEvent{}
+		for _, event := range events {
+			if event.Seq != seq {
+				kept = append(kept, event)
+			}
+		}
+		return kept
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	for _, hashKey := range [][]byte{nil, []byte("audit hash key")} {
+		l, _ := newTestChain(t, 1, Config{HashKey: hashKey})
+		// Someone who can compute hashes can rewrite an event and its hash, but not the next
+		// event's link to it.
+		rehash := func(event *models.AuditEvent) {
+			event.Type = "login_failed"
+			event.Hash, _ = l.hash(event)
+		}
+
+		tests := []struct {
+			name   string
+			events func([]*models.AuditEvent) []*models.AuditEvent
+			want   *BrokenLinkError // nil if the chain verifies.
+		}{
+			{"intact", nil, nil},
+			{"type changed", at(3, func(e *models.AuditEvent) { e.Type = "login_failed" }), &BrokenLinkError{Seq: 3, Reason: ReasonModified}},
+			{"IP changed", at(2, func(e *models.AuditEvent) { e.IP = "198.51.100.1" }), &BrokenLinkError{Seq: 2, Reason: ReasonModified}},
+			{"metadata added", at(4, func(e *models.AuditEvent) { e.Metadata["admin"] = "true" }), &BrokenLinkError{Seq: 4, Reason: ReasonModified}},
+			{"rewritten with its hash", at(3, rehash), &BrokenLinkError{Seq: 4, Reason: ReasonPrevHash}},
+			{"deleted", without(3), &BrokenLinkError{Seq: 3, Reason: ReasonMissing}},
+			{"first deleted", without(1), &BrokenLinkError{Seq: 1, Reason: ReasonMissing}},
+		}
+		for _, tt := range tests {
+			l, store := newTestChain(t, 5, Config{HashKey: hashKey})
+			store.events = tt.events
+
+			result, err := l.VerifyChain()
+			var broken *BrokenLinkError
+			switch {
+			case tt.want == nil && err != nil:
+				t.Errorf("%s: VerifyChain error = %v", tt.name, err)
+			case tt.want == nil && (result.Seq != 5 || result.Checkpoints != 0):
+				t.Errorf("%s: VerifyChain = %+v, want seq 5", tt.name, result)
+			case tt.want != nil && !errors.As(err, &broken):
+				t.Errorf("%s: VerifyChain error = %v, want a *BrokenLinkError", tt.name, err)
+			case tt.want != nil && (broken.Seq != tt.want.Seq || broken.Reason != tt.want.Reason):
+				t.Errorf("%s: VerifyChain error = %+v, want %+v", tt.name, broken, tt.want)
+			case tt.want != nil && !errors.Is(err, goat.ErrAuditChainBroken):
+				t.Errorf("%s: VerifyChain error does not wrap goat.ErrAuditChainBroken", tt.name)
+			}
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for _, hashKey := range [][]byte{nil, []byte("audit hash key")} {
+		l, store := newTestChain(t, 3, Config{HashKey: hashKey})
+		events, err := store.ListAuditChain(0, MaxLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		event := events[1]
+		if err := l.Redact(event); err != nil {
+			t.Fatal(err)
+		}
+		if event.IP != "" || event.UserAgent != "" || event.Metadata["email"] != "" || event.Metadata["method"] != "password" {
+			t.Errorf("redacted event = %+v, want the IP, user agent and email removed", event)
+		}
+		want := map[string]string{"ip": l.digest("203.0.113.7"), "user_agent": l.digest("curl/8.0"), "metadata.email": l.digest("ada@example.com")}
+		if !reflect.DeepEqual(event.Redacted, want) {
+			t.Errorf("Redacted = %v, want %v", event.Redacted, want)
+		}
+
+		// The chain still verifies, and redacting again changes nothing.
+		if _, err := l.VerifyChain(); err != nil {
+			t.Errorf("VerifyChain after Redact: %v", err)
+		}
+		stored, err := store.ListAuditChain(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Redact(stored[0]); err != nil || !reflect.DeepEqual(stored[0], event) {
+			t.Errorf("Redact again: error %v, event %+v, want %+v", err, stored[0], event)
+		}
+
+		// Putting other values back is detected.
+		store.events = at(2, func(e *models.AuditEvent) { e.IP = "198.51.100.1" })
+		var broken *BrokenLinkError
+		if _, err := l.VerifyChain(); !errors.As(err, &broken) || broken.Seq != 2 || broken.Reason != ReasonModified {
+			t.Errorf("VerifyChain with a value restored after redaction: error = %v", err)
+		}
+
+		// An event that does not match its hash is not redacted.
+		tampered := *events[2]
+		tampered.Type = "login_failed"
+		if err := l.Redact(&tampered); !errors.Is(err, ErrNotRedactable) {
+			t.Errorf("Redact of a tampered event: error = %v, want %v", err, ErrNotRedactable)
+		}
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	keys := newCheckpointKeys(t)
+
+	if _, err := NewLogger(repository.NewMemoryAuditStore(), Config{}).Checkpoint(); !errors.Is(err, ErrNoCheckpointKeys) {
+		t.Errorf("Checkpoint without keys: error = %v, want %v", err, ErrNoCheckpointKeys)
+	}
+	if _, err := NewLogger(repository.NewMemoryAuditStore(), Config{CheckpointKeys: keys}).Checkpoint(); !errors.Is(err, goat.ErrAuditEventNotFound) {
+		t.Errorf("Checkpoint of an empty log: error = %v, want %v", err, goat.ErrAuditEventNotFound)
+	}
+
+	l, store := newTestChain(t, 3, Config{CheckpointKeys: keys})
+	first, err := l.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Seq != 3 {
+		t.Errorf("checkpoint seq = %d, want 3", first.Seq)
+	}
+	again, err := l.Checkpoint()
+	if err != nil || again.Seq != first.Seq || again.Signature != first.Signature {
+		t.Errorf("Checkpoint with nothing appended: %+v, %v, want the last checkpoint", again, err)
+	}
+	if err := l.Record(nil, Event("logout", 1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := l.VerifyChain()
+	if err != nil || result.Seq != 4 || result.Checkpoints != 2 {
+		t.Errorf("VerifyChain = %+v, %v, want seq 4 with 2 checkpoints", result, err)
+	}
+
+	// A chain tampered with since the last checkpoint is not signed.
+	if err := l.Record(nil, Event("logout", 1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	store.events = at(5, func(e *models.AuditEvent) { e.Type = "login_failed" })
+	var broken *BrokenLinkError
+	if _, err := l.Checkpoint(); !errors.As(err, &broken) || broken.Seq != 5 {
+		t.Errorf("Checkpoint of a tampered chain: error = %v, want a broken link at seq 5", err)
+	}
+}
+
+func TestVerifyChainCheckpoints(t *testing.T) {
+	keys := newCheckpointKeys(t)
+	forged := newCheckpointKeys(t)
+
+	tests := []struct {
+		name        string
+		events      func([]*models.AuditEvent) []*models.AuditEvent
+		checkpoints func(*testing.T, *Logger, []*models.AuditCheckpoint)
+		want        *BrokenLinkError
+	}{
+		{"intact", nil, nil, nil},
+		{
+			"chain rewritten before the checkpoint",
+			func(events []*models.AuditEvent) []*models.AuditEvent {
+				// Every hash recomputed from the rewritten event on, as someone holding the
+				// HashKey could; only the checkpoint catches it.
+				l := NewLogger(nil, Config{})
+				prev := ""
+				for _, event := range events {
+					if event.Seq == 2 {
+						event.Type = "login_failed"
+					}
+					if event.Seq >= 2 {
+						event.PrevHash = prev
+						event.Hash, _ = l.hash(event)
+					}
+					prev = event.Hash
+				}
+				return events
+			},
+			nil,
+			&BrokenLinkError{Seq: 3, Reason: ReasonCheckpointHash},
+		},
+		{"truncated", without(5), nil, &BrokenLinkError{Seq: 5, Reason: ReasonTruncated}},
+		{
+			"signature forged",
+			nil,
+			func(t *testing.T, _ *Logger, checkpoints []*models.AuditCheckpoint) {
+				signature, err := forged.Sign(&checkpointClaims{Seq: checkpoints[0].Seq, Hash: checkpoints[0].Hash})
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkpoints[0].Signature = signature
+			},
+			&BrokenLinkError{Seq: 3, Reason: ReasonCheckpointInvalid},
+		},
+		{
+			"hash changed",
+			nil,
+			func(_ *testing.T, _ *Logger, checkpoints []*models.AuditCheckpoint) {
+				checkpoints[0].Hash = checkpoints[1].Hash
+			},
+			&BrokenLinkError{Seq: 3, Reason: ReasonCheckpointInvalid},
+		},
+	}
+	for _, tt := range tests {
+		l, store := newTestChain(t, 3, Config{CheckpointKeys: keys})
+		if _, err := l.Checkpoint(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := l.Record(nil, Event("logout", 1, 1)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := l.Checkpoint(); err != nil {
+			t.Fatal(err)
+		}
+		store.events = tt.events
+		if tt.checkpoints != nil {
+			store.checkpoints = func(checkpoints []*models.AuditCheckpoint) { tt.checkpoints(t, l, checkpoints) }
+		}
+
+		result, err := l.VerifyChain()
+		var broken *BrokenLinkError
+		switch {
+		case tt.want == nil && (err != nil || result.Seq != 5 || result.Checkpoints != 2):
+			t.Errorf("%s: VerifyChain = %+v, %v, want seq 5 with 2 checkpoints", tt.name, result, err)
+		case tt.want != nil && !errors.As(err, &broken):
+			t.Errorf("%s: VerifyChain error = %v, want a *BrokenLinkError", tt.name, err)
+		case tt.want != nil && (broken.Seq != tt.want.Seq || broken.Reason != tt.want.Reason):
+			t.Errorf("%s: VerifyChain error = %+v, want %+v", tt.name, broken, tt.want)
+		}
+	}
+}
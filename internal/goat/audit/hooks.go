@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// Watch records the account changes made through users with auditor: deletions, password resets and
+// changes of a user's role. The changes are made below the HTTP layer, so the events carry no actor,
+// IP or user agent; handlers that know the actor record their own events. Failures to record are
+// reported to the OnError function of the service's event bus.
+func Watch[U models.Account](users goat.UserHooks[U], auditor goat.Auditor) {
+	users.AfterUserEvent(models.UserDeleted, func(event *models.UserEvent[U]) error {
+		return auditor.Record(nil, userEvent(models.EventUserDeleted, event))
+	})
+	users.AfterUserEvent(models.UserPasswordReset, func(event *models.UserEvent[U]) error {
+		return auditor.Record(nil, userEvent(models.EventPasswordChanged, event))
+	})
+	users.AfterUserEvent(models.UserUpdated, func(event *models.UserEvent[U]) error {
+		from, to := event.Previous.GetUser().Role, event.User.GetUser().Role
+		if from == to {
+			return nil
+		}
+		recorded := userEvent(models.EventRoleChanged, event)
+		recorded.Metadata = map[string]string{"previous_role": from, "role": to}
+		return auditor.Record(nil, recorded)
+	})
+}
+
+// userEvent returns an audit event of typ on the user of event, in its tenant.
+func userEvent[U models.Account](typ string, event *models.UserEvent[U]) *models.AuditEvent {
+	recorded := Event(typ, 0, event.User.GetUser().ID)
+	recorded.TenantID = event.TenantID
+	return recorded
+}
@@ -2,6 +2,8 @@ package goat
 
 import (
 	"errors"
+
+	"github.com/bontusss/goat/internal/goat/models"
 )
 
 // This code defines several custom error types specific to authentication failures.
@@ -9,14 +11,107 @@ var (
 	// common errors
 	ErrInvalidCredentials  = errors.New("invalid email or password")
 	ErrUserNotFound        = errors.New("user not found")
+	ErrEmailTaken          = errors.New("email is already registered")
 	ErrUnauthorized        = errors.New("unauthorized")
 	ErrInternalServerError = errors.New("internal server error")
 	ErrEmailNotProvided    = errors.New("email is required")
 	ErrPasswordNotProvided = errors.New("password not provided")
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrDisposableEmail     = errors.New("disposable email addresses are not allowed")
+	ErrWeakPassword        = errors.New("password does not meet the password policy")
+	ErrMalformedRequest    = errors.New("malformed request")
 
 	// Potential additional errors (you can add more as needed)
 	ErrInvalidToken     = errors.New("invalid token")
 	ErrExpiredToken     = errors.New("token expired")
 	ErrMissingToken     = errors.New("missing token")
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// session errors
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionExpired      = errors.New("session expired")
+	ErrUnsupportedProvider = errors.New("unsupported login provider")
+
+	// social login errors
+	ErrInvalidOAuthState = errors.New("invalid or expired login state")
+	ErrEmailNotVerified  = errors.New("email address is not verified by the provider")
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrIdentityTaken     = errors.New("identity is already linked to a user")
+	ErrLastLoginMethod   = errors.New("cannot remove the last way to sign in")
+	ErrAccountExists     = errors.New("an account with this email exists; sign in to it to link the provider")
+
+	// OpenID Connect provider errors
+	ErrClientNotFound  = errors.New("client not found")
+	ErrConsentNotFound = errors.New("consent not found")
+	ErrGrantNotFound   = errors.New("authorization grant not found")
+
+	// API key errors
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExpired  = errors.New("api key expired")
+	ErrInvalidScope   = errors.New("scope is not allowed")
+
+	// tenant errors
+	ErrTenantRequired = errors.New("tenant could not be determined")
+	ErrTenantMismatch = errors.New("credentials belong to another tenant")
+
+	// organization errors
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrMembershipNotFound   = errors.New("user is not a member of the organization")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrInvitationExpired    = errors.New("invitation expired")
+	ErrInvalidRole          = errors.New("invalid organization role")
+	ErrAlreadyMember        = errors.New("user is already a member of the organization")
+	ErrOwnerRequired        = errors.New("organization must keep its owner")
+
+	// account state errors
+	ErrAccountSuspended     = errors.New("account is suspended")
+	ErrAccountDeactivated   = errors.New("account is deactivated")
+	ErrInvalidAccountStatus = errors.New("invalid account status")
+	ErrUserNotDeleted       = errors.New("user is not deleted")
+	ErrRestoreWindowExpired = errors.New("deleted user can no longer be restored")
+
+	// lifecycle hook errors
+	ErrVetoed = errors.New("change was rejected")
+
+	// outbox errors
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+	// webhook errors
+	ErrWebhookNotFound         = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryExists   = errors.New("webhook delivery already exists")
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEvent     = errors.New("unknown webhook event type")
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+
+	// audit errors
+	ErrAuditEventNotFound      = errors.New("audit event not found")
+	ErrAuditCheckpointNotFound = errors.New("audit checkpoint not found")
+	ErrAuditSeqTaken           = errors.New("audit sequence number is already taken")
+	ErrAuditChainBroken        = errors.New("audit hash chain is broken")
+
+	// privacy errors
+	ErrInvalidErasureMode   = errors.New("erasure mode must be delete or anonymize")
+	ErrInvalidErasureReport = errors.New("erasure report does not match its digest")
+	ErrErasureIncomplete    = errors.New("personal data remains after erasure")
+
+	// user listing errors
+	ErrInvalidUserSort = errors.New("users can only be sorted by created_at or email")
+	ErrInvalidCursor   = errors.New("invalid page cursor")
 )
+
+// AccountError returns the error that keeps user from signing in, or nil if their account is active.
+// Soft-deleted users are reported as not found, as everywhere else.
+func AccountError(user *models.User) error {
+	switch user.Status {
+	case "", models.AccountActive:
+		return nil
+	case models.AccountSuspended:
+		return ErrAccountSuspended
+	case models.AccountDeactivated:
+		return ErrAccountDeactivated
+	case models.AccountDeleted:
+		return ErrUserNotFound
+	}
+	return ErrInvalidAccountStatus
+}
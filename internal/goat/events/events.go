@@ -0,0 +1,129 @@
+// Package events dispatches user lifecycle events to the hooks registered on a user service.
+// Before-hooks run synchronously and can veto a change; after-hooks run in their own goroutines once
+// the change is saved, so a slow hook, such as one sending a welcome email, never delays the caller.
+package events
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// Bus holds the hooks of a user service and runs them. The zero value has no hooks, and so does a
+// nil *Bus, so that services created without one emit nothing.
+type Bus[U models.Account] struct {
+	mu      sync.RWMutex
+	before  map[models.UserEventType][]models.UserHook[U]
+	after   map[models.UserEventType][]models.UserHook[U]
+	onError func(event *models.UserEvent[U], err error)
+	running sync.WaitGroup
+}
+
+// NewBus creates a Bus with no hooks.
+func NewBus[U models.Account]() *Bus[U] {
+	return &Bus[U]{}
+}
+
+// Veto returns an error for a before-hook to reject a change with. It matches goat.ErrVetoed, and
+// reason is shown to the client.
+func Veto(reason string) error {
+	return fmt.Errorf("%w: %s", goat.ErrVetoed, reason)
+}
+
+// Before registers hook to run before changes of type typ, in the order hooks were registered.
+func (b *Bus[U]) Before(typ models.UserEventType, hook models.UserHook[U]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.before == nil {
+		b.before = map[models.UserEventType][]models.UserHook[U]{}
+	}
+	b.before[typ] = append(b.before[typ], hook)
+}
+
+// After registers hook to run after changes of type typ. Hooks run concurrently with each other
+// and with the caller, and must not modify the event's users.
+func (b *Bus[U]) After(typ models.UserEventType, hook models.UserHook[U]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.after == nil {
+		b.after = map[models.UserEventType][]models.UserHook[U]{}
+	}
+	b.after[typ] = append(b.after[typ], hook)
+}
+
+// OnError sets the function told about after-hooks that fail or panic. By default failures are
+// dropped, since the change they followed is already saved.
+func (b *Bus[U]) OnError(fn func(event *models.UserEvent[U], err error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onError = fn
+}
+
+// Handles reports whether any hook is registered for typ, letting services skip the work of
+// building an event nobody listens to.
+func (b *Bus[U]) Handles(typ models.UserEventType) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.before[typ]) > 0 || len(b.after[typ]) > 0
+}
+
+// Check runs the before-hooks for event and returns the first error, which vetoes the change.
+func (b *Bus[U]) Check(event *models.UserEvent[U]) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	hooks := b.before[event.Type]
+	b.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish starts the after-hooks for event and returns without waiting for them.
+func (b *Bus[U]) Publish(event *models.UserEvent[U]) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	hooks := b.after[event.Type]
+	onError := b.onError
+	b.mu.RUnlock()
+
+	for _, hook := range hooks {
+		b.running.Add(1)
+		go func(hook models.UserHook[U]) {
+			defer b.running.Done()
+			if err := run(hook, event); err != nil && onError != nil {
+				onError(event, err)
+			}
+		}(hook)
+	}
+}
+
+// Wait blocks until every after-hook started so far has returned, e.g. before the process exits.
+func (b *Bus[U]) Wait() {
+	if b == nil {
+		return
+	}
+	b.running.Wait()
+}
+
+// run calls hook, turning a panic into an error so that one faulty hook cannot crash the process.
+func run[U models.Account](hook models.UserHook[U], event *models.UserEvent[U]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("events: %s hook panicked: %v", event.Type, r)
+		}
+	}()
+	return hook(event)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler lets users create, list and revoke their API keys. Requests are authenticated with
+// auth, typically a session.Manager or jwt.Authenticator, so an API key cannot mint more keys.
+type APIKeyHandler[U models.Account] struct {
+	keys goat.APIKeyService
+	auth goat.Authenticator[U]
+}
+
+// NewAPIKeyHandler creates an APIKeyHandler managing keys for users authenticated by auth.
+func NewAPIKeyHandler[U models.Account](keys goat.APIKeyService, auth goat.Authenticator[U]) *APIKeyHandler[U] {
+	return &APIKeyHandler[U]{keys: keys, auth: auth}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *APIKeyHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.GET("/api-keys", h.ListAPIKeys)
+	r.POST("/api-keys", h.CreateAPIKey)
+	r.DELETE("/api-keys/:id", h.RevokeAPIKey)
+}
+
+// createAPIKeyRequest is the body accepted by CreateAPIKey.
+type createAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int64    `json:"expires_in"` // Lifetime in seconds; 0 for a key that does not expire.
+}
+
+// createAPIKeyResponse is the created key, with its full value.
+type createAPIKeyResponse struct {
+	*models.APIKey
+	Key string `json:"key"` // Shown only in this response.
+}
+
+// CreateAPIKey creates a key for the current user and responds with it, including the full key.
+func (h *APIKeyHandler[U]) CreateAPIKey(c *gin.Context) {
+	user, err := h.auth.Authenticate(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ExpiresIn < 0 {
+		abortWithMalformedRequest(c, errors.New("invalid request body"))
+		return
+	}
+
+	key, value, err := tenant.APIKeys(c, h.keys).CreateAPIKey(user.GetUser().ID, req.Name, req.Scopes, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, createAPIKeyResponse{APIKey: key, Key: value})
+}
+
+// ListAPIKeys responds with the current user's keys, without their secrets.
+func (h *APIKeyHandler[U]) ListAPIKeys(c *gin.Context) {
+	user, err := h.auth.Authenticate(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	keys, err := tenant.APIKeys(c, h.keys).ListAPIKeys(user.GetUser().ID)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes one of the current user's keys.
+func (h *APIKeyHandler[U]) RevokeAPIKey(c *gin.Context) {
+	user, err := h.auth.Authenticate(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	if err := tenant.APIKeys(c, h.keys).RevokeAPIKey(user.GetUser().ID, c.Param("id")); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
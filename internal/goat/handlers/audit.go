@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// auditing is embedded in the handlers that record audit events.
+type auditing struct {
+	auditor goat.Auditor
+}
+
+// SetAuditor makes the handler record the events it handles with auditor.
+func (a *auditing) SetAuditor(auditor goat.Auditor) {
+	a.auditor = auditor
+}
+
+// record records event if an auditor is set. The request's outcome is already decided, so a
+// failure is attached to c for the application's error middleware rather than returned.
+func (a *auditing) record(c *gin.Context, event *models.AuditEvent) {
+	if a.auditor == nil {
+		return
+	}
+	if err := a.auditor.Record(c, event); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// loginSucceeded records a login by userID with method: "password", "session", "token" or the name
+// of a social login provider.
+func (a *auditing) loginSucceeded(c *gin.Context, method string, userID uint) {
+	event := audit.Event(models.EventLoginSucceeded, userID, userID)
+	event.Metadata = map[string]string{"method": method}
+	a.record(c, event)
+}
+
+// loginFailed records a failed login as email with method, and why it failed. The email is
+// normalized, so that the events can be found by the address of the account.
+func (a *auditing) loginFailed(c *gin.Context, method, email string, err error) {
+	event := audit.Event(models.EventLoginFailed, 0, 0)
+	event.Metadata = map[string]string{"method": method, "reason": goat.CodeOf(err)}
+	if email != "" {
+		event.Metadata["email"] = utils.NormalizeEmail(email)
+	}
+	a.record(c, event)
+}
+
+// AuditHandler lets administrators query and export the audit log. It does no authorization of its
+// own: mount it behind the application's admin-only middleware.
+type AuditHandler struct {
+	logger *audit.Logger
+}
+
+// NewAuditHandler creates an AuditHandler reading the events recorded by logger.
+func NewAuditHandler(logger *audit.Logger) *AuditHandler {
+	return &AuditHandler{logger: logger}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *AuditHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/audit-events", h.Query)
+	r.GET("/audit-events/export", h.Export)
+	r.GET("/audit-events/verify", h.Verify)
+}
+
+// verifyResponse is the result of Verify.
+type verifyResponse struct {
+	Intact bool                   `json:"intact"`
+	Head   *audit.Verification    `json:"head,omitempty"`   // Set when the chain is intact.
+	Broken *audit.BrokenLinkError `json:"broken,omitempty"` // Set when it is not.
+}
+
+// Query responds with a page of events, newest first. The query string can filter by type (repeated
+// for several types), actor_id, target_id, tenant_id, since and until (RFC 3339), and page with
+// limit and before, set to the next cursor of the previous page.
+func (h *AuditHandler) Query(c *gin.Context) {
+	query, ok := auditQuery(c)
+	if !ok {
+		return
+	}
+	page, err := h.logger.Query(query)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Export responds with every matching event as JSON Lines. It accepts the filters of Query.
+func (h *AuditHandler) Export(c *gin.Context) {
+	query, ok := auditQuery(c)
+	if !ok {
+		return
+	}
+	query.Limit = audit.MaxLimit
+
+	c.Header("Content-Type", "application/jsonl")
+	c.Header("Content-Disposition", `attachment; filename="audit-events.jsonl"`)
+	c.Status(http.StatusOK)
+	if err := h.logger.Export(c.Writer, query); err != nil {
+		// The status line is already sent; all that is left is to stop and report the error.
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// Verify checks the whole hash chain and responds with its head if it is intact, or with the first
+// broken link. Either way the status is 200: a broken chain is a finding, not a failed request.
+func (h *AuditHandler) Verify(c *gin.Context) {
+	head, err := h.logger.VerifyChain()
+	var broken *audit.BrokenLinkError
+	switch {
+	case errors.As(err, &broken):
+		c.JSON(http.StatusOK, verifyResponse{Broken: broken})
+	case err != nil:
+		goat.AbortWithProblem(c, err)
+	default:
+		c.JSON(http.StatusOK, verifyResponse{Intact: true, Head: head})
+	}
+}
+
+// auditQuery parses the query string of Query and Export, aborting with 400 if it is malformed.
+func auditQuery(c *gin.Context) (models.AuditQuery, bool) {
+	query := models.AuditQuery{
+		Types:    c.QueryArray("type"),
+		TenantID: c.Query("tenant_id"),
+		Before:   c.Query("before"),
+	}
+	var err error
+	parseUint := func(name string, dst *uint) {
+		if v := c.Query(name); v != "" && err == nil {
+			var n uint64
+			n, err = strconv.ParseUint(v, 10, 0)
+			*dst = uint(n)
+		}
+	}
+	parseTime := func(name string, dst *time.Time) {
+		if v := c.Query(name); v != "" && err == nil {
+			*dst, err = time.Parse(time.RFC3339, v)
+		}
+	}
+	parseUint("actor_id", &query.ActorID)
+	parseUint("target_id", &query.TargetID)
+	parseTime("since", &query.Since)
+	parseTime("until", &query.Until)
+	if v := c.Query("limit"); v != "" && err == nil {
+		query.Limit, err = strconv.Atoi(v)
+	}
+	if err != nil {
+		abortWithMalformedRequest(c, fmt.Errorf("invalid query: %v", err))
+		return query, false
+	}
+	return query, true
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithMalformedRequest reports a request that could not be read, such as a body that is not
+// valid JSON or a path parameter of the wrong type, as a 400 Bad Request problem.
+func abortWithMalformedRequest(c *gin.Context, err error) {
+	goat.AbortWithProblem(c, fmt.Errorf("%w: %v", goat.ErrMalformedRequest, err))
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public signing keys of a jwt.KeyManager.
+type JWKSHandler struct {
+	keys *jwt.KeyManager
+}
+
+// NewJWKSHandler creates a JWKSHandler for keys.
+func NewJWKSHandler(keys *jwt.KeyManager) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *JWKSHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// JWKS responds with the JSON Web Key Set. Clients may cache it briefly; they should refetch
+// when they see a kid they do not know.
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler exposes a goat.OrganizationService over HTTP. Requests are authenticated with
+// auth, and every change is made on behalf of the authenticated user.
+type OrganizationHandler[U models.Account] struct {
+	orgs goat.OrganizationService[U]
+	auth goat.Authenticator[U]
+	auditing
+}
+
+// NewOrganizationHandler creates an OrganizationHandler for orgs, authenticating users with auth.
+func NewOrganizationHandler[U models.Account](orgs goat.OrganizationService[U], auth goat.Authenticator[U]) *OrganizationHandler[U] {
+	return &OrganizationHandler[U]{orgs: orgs, auth: auth}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *OrganizationHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.POST("/organizations", h.CreateOrganization)
+	r.GET("/organizations", h.ListOrganizations)
+	r.GET("/organizations/:id", h.GetOrganization)
+	r.DELETE("/organizations/:id", h.DeleteOrganization)
+	r.GET("/organizations/:id/members", h.ListMembers)
+	r.PUT("/organizations/:id/members/:user_id", h.UpdateMemberRole)
+	r.DELETE("/organizations/:id/members/:user_id", h.RemoveMember)
+	r.POST("/organizations/:id/owner", h.TransferOwnership)
+	r.POST("/organizations/:id/invitations", h.InviteMember)
+	r.GET("/organizations/:id/invitations", h.ListInvitations)
+	r.DELETE("/organizations/:id/invitations/:invitation_id", h.RevokeInvitation)
+	r.POST("/invitations/accept", h.AcceptInvitation)
+	r.POST("/invitations/decline", h.DeclineInvitation)
+}
+
+// createOrganizationRequest is the body accepted by CreateOrganization.
+type createOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// roleRequest is the body accepted by UpdateMemberRole.
+type roleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// transferOwnershipRequest is the body accepted by TransferOwnership.
+type transferOwnershipRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// inviteRequest is the body accepted by InviteMember.
+type inviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// inviteResponse is the created invitation, with its token.
+type inviteResponse struct {
+	*models.Invitation
+	Token string `json:"token"` // Shown only in this response.
+}
+
+// invitationTokenRequest is the body accepted by AcceptInvitation and DeclineInvitation.
+type invitationTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CreateOrganization creates an organization owned by the current user.
+func (h *OrganizationHandler[U]) CreateOrganization(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	var req createOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	org, err := tenant.Organizations(c, h.orgs).CreateOrganization(user.GetUser().ID, req.Name)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// ListOrganizations responds with the organizations the current user is a member of.
+func (h *OrganizationHandler[U]) ListOrganizations(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	orgs, err := tenant.Organizations(c, h.orgs).ListOrganizations(user.GetUser().ID)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// GetOrganization responds with one of the current user's organizations.
+func (h *OrganizationHandler[U]) GetOrganization(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	org, err := tenant.Organizations(c, h.orgs).GetOrganization(user.GetUser().ID, c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization deletes an organization the current user owns.
+func (h *OrganizationHandler[U]) DeleteOrganization(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	if err := tenant.Organizations(c, h.orgs).DeleteOrganization(user.GetUser().ID, c.Param("id")); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListMembers responds with the members of one of the current user's organizations.
+func (h *OrganizationHandler[U]) ListMembers(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	members, err := tenant.Organizations(c, h.orgs).ListMembers(user.GetUser().ID, c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole changes the role of a member.
+func (h *OrganizationHandler[U]) UpdateMemberRole(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	memberID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	if err := tenant.Organizations(c, h.orgs).UpdateMemberRole(user.GetUser().ID, c.Param("id"), memberID, req.Role); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	event := audit.Event(models.EventRoleChanged, user.GetUser().ID, memberID)
+	event.Metadata = map[string]string{"organization_id": c.Param("id"), "role": req.Role}
+	h.record(c, event)
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember removes a member from the organization; members can remove themselves to leave.
+func (h *OrganizationHandler[U]) RemoveMember(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	memberID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if err := tenant.Organizations(c, h.orgs).RemoveMember(user.GetUser().ID, c.Param("id"), memberID); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TransferOwnership hands the organization over to the member in the JSON body.
+func (h *OrganizationHandler[U]) TransferOwnership(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	var req transferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	if err := tenant.Organizations(c, h.orgs).TransferOwnership(user.GetUser().ID, c.Param("id"), req.UserID); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	event := audit.Event(models.EventOwnershipTransfer, user.GetUser().ID, req.UserID)
+	event.Metadata = map[string]string{"organization_id": c.Param("id")}
+	h.record(c, event)
+	c.Status(http.StatusNoContent)
+}
+
+// InviteMember invites the email in the JSON body and responds with the invitation, including its
+// token. Delivering the token to the invitee, e.g. in an email link, is up to the application.
+func (h *OrganizationHandler[U]) InviteMember(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	var req inviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+
+	invitation, token, err := tenant.Organizations(c, h.orgs).InviteMember(user.GetUser().ID, c.Param("id"), req.Email, req.Role)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, inviteResponse{Invitation: invitation, Token: token})
+}
+
+// ListInvitations responds with the organization's pending invitations, without their tokens.
+func (h *OrganizationHandler[U]) ListInvitations(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	invitations, err := tenant.Organizations(c, h.orgs).ListInvitations(user.GetUser().ID, c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation withdraws a pending invitation.
+func (h *OrganizationHandler[U]) RevokeInvitation(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	if err := tenant.Organizations(c, h.orgs).RevokeInvitation(user.GetUser().ID, c.Param("id"), c.Param("invitation_id")); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation adds the current user to the organization of the invitation token in the JSON body,
+// and responds with the new membership.
+func (h *OrganizationHandler[U]) AcceptInvitation(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	var req invitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	membership, err := tenant.Organizations(c, h.orgs).AcceptInvitation(user, req.Token)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, membership)
+}
+
+// DeclineInvitation discards the invitation token in the JSON body.
+func (h *OrganizationHandler[U]) DeclineInvitation(c *gin.Context) {
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	var req invitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	if err := tenant.Organizations(c, h.orgs).DeclineInvitation(user, req.Token); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// authenticate returns the current user, or aborts the request if there is none.
+func (h *OrganizationHandler[U]) authenticate(c *gin.Context) (U, bool) {
+	user, err := h.auth.Authenticate(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return user, false
+	}
+	return user, true
+}
+
+// userIDParam parses the user_id path parameter, aborting with 400 if it is not a user ID.
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 0)
+	if err != nil {
+		abortWithMalformedRequest(c, errors.New("invalid user id"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/privacy"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyHandler lets administrators answer data subject requests: export a user's data and erase
+// it. It does no authorization of its own: mount it behind the application's admin-only middleware.
+// Each request manages the users of its tenant, as resolved by tenant.Middleware.
+type PrivacyHandler[U models.Account] struct {
+	manager *privacy.Manager[U]
+}
+
+// NewPrivacyHandler creates a PrivacyHandler working through manager.
+func NewPrivacyHandler[U models.Account](manager *privacy.Manager[U]) *PrivacyHandler[U] {
+	return &PrivacyHandler[U]{manager: manager}
+}
+
+// tenantManager returns the manager scoped to the request's tenant.
+func (h *PrivacyHandler[U]) tenantManager(c *gin.Context) *privacy.Manager[U] {
+	return h.manager.ForTenant(tenant.ID(c))
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *PrivacyHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.GET("/users/:user_id/export", h.Export)
+	r.POST("/users/:user_id/erasure", h.Erase)
+	r.POST("/erasure-reports/verify", h.VerifyErasure)
+}
+
+// eraseRequest is the body accepted by Erase.
+type eraseRequest struct {
+	Mode privacy.Mode `json:"mode" binding:"required"` // "delete" or "anonymize".
+}
+
+// Export responds with the user's data as a JSON attachment, or as a ZIP archive when the query
+// string sets format=zip.
+func (h *PrivacyHandler[U]) Export(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		abortWithMalformedRequest(c, errors.New("format must be json or zip"))
+		return
+	}
+	export, err := h.tenantManager(c).ExportUserData(id)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d.%s"`, id, format))
+	write := export.WriteJSON
+	c.Header("Content-Type", "application/json")
+	if format == "zip" {
+		write = export.WriteZip
+		c.Header("Content-Type", "application/zip")
+	}
+	c.Status(http.StatusOK)
+	if err := write(c.Writer); err != nil {
+		// The status line is already sent; all that is left is to stop and report the error.
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// Erase erases the user's data and responds with the erasure report.
+func (h *PrivacyHandler[U]) Erase(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req eraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+	report, err := h.tenantManager(c).Erase(id, req.Mode)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// VerifyErasure checks a report sent in the body against its digest and the stores, and responds
+// with 204 if the erasure holds.
+func (h *PrivacyHandler[U]) VerifyErasure(c *gin.Context) {
+	var report privacy.Report
+	if err := c.ShouldBindJSON(&report); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+	if err := h.tenantManager(c).VerifyErasure(&report); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
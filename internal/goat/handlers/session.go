@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/session"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler exposes cookie-based login and logout backed by a session.Manager,
+// and lets users see and revoke the sessions they are logged in with.
+type SessionHandler[U models.Account] struct {
+	sessions *session.Manager[U]
+	auditing
+}
+
+// NewSessionHandler creates a SessionHandler for sessions.
+func NewSessionHandler[U models.Account](sessions *session.Manager[U]) *SessionHandler[U] {
+	return &SessionHandler[U]{sessions: sessions}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *SessionHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.POST("/session", h.Login)
+	r.DELETE("/session", h.Logout)
+
+	sessions := r.Group("/sessions", h.sessions.Middleware())
+	sessions.GET("", h.ListSessions)
+	sessions.DELETE("", h.RevokeOtherSessions)
+	sessions.DELETE("/:id", h.RevokeSession)
+}
+
+// sessionResponse is a session as listed to its user.
+type sessionResponse struct {
+	*models.Session
+	Current bool `json:"current"` // The session used for this request.
+}
+
+// Login checks the email and password in the JSON body, starts a session and responds with the user.
+func (h *SessionHandler[U]) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	user, err := h.sessions.Login(c, req.Email, req.Password)
+	if err != nil {
+		h.loginFailed(c, "session", req.Email, err)
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.loginSucceeded(c, "session", user.GetUser().ID)
+
+	user.GetUser().Password = "" // Never send the password hash back.
+	c.JSON(http.StatusOK, user)
+}
+
+// Logout ends the current session.
+func (h *SessionHandler[U]) Logout(c *gin.Context) {
+	if err := h.sessions.Logout(c); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions responds with the current user's sessions, marking the one used for the request.
+func (h *SessionHandler[U]) ListSessions(c *gin.Context) {
+	user, _ := session.User[U](c)
+	current, _ := session.Current(c)
+	currentID := ""
+	if current != nil {
+		currentID = current.ID
+	}
+	listSessions(c, h.sessions, user.GetUser().ID, currentID)
+}
+
+// RevokeSession ends one of the current user's sessions.
+func (h *SessionHandler[U]) RevokeSession(c *gin.Context) {
+	user, _ := session.User[U](c)
+	if revokeSession(c, h.sessions, user.GetUser().ID) {
+		h.record(c, audit.Event(models.EventSessionRevoked, user.GetUser().ID, user.GetUser().ID))
+	}
+}
+
+// listSessions responds with the sessions of userID in sessions, marking the one with the ID current.
+func listSessions(c *gin.Context, sessions goat.SessionService, userID uint, current string) {
+	list, err := tenant.Sessions(c, sessions).ListSessions(userID)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	res := make([]sessionResponse, len(list))
+	for i, s := range list {
+		res[i] = sessionResponse{Session: s, Current: s.ID == current}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// revokeSession ends the session named by the id path parameter in sessions, if it belongs to
+// userID, and reports whether it did.
+func revokeSession(c *gin.Context, sessions goat.SessionService, userID uint) bool {
+	id := c.Param("id")
+
+	// Only allow revoking sessions that belong to the caller.
+	scoped := tenant.Sessions(c, sessions)
+	owned, err := scoped.ListSessions(userID)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return false
+	}
+	found := false
+	for _, s := range owned {
+		found = found || s.ID == id
+	}
+	if !found {
+		goat.AbortWithProblem(c, goat.ErrSessionNotFound)
+		return false
+	}
+
+	if err := scoped.RevokeSession(id); err != nil {
+		goat.AbortWithProblem(c, err)
+		return false
+	}
+	c.Status(http.StatusNoContent)
+	return true
+}
+
+// RevokeOtherSessions ends all of the current user's sessions except the one used for the request.
+func (h *SessionHandler[U]) RevokeOtherSessions(c *gin.Context) {
+	user, _ := session.User[U](c)
+	current, _ := session.Current(c)
+
+	if err := tenant.Sessions(c, h.sessions).RevokeAllSessions(user.GetUser().ID, current.ID); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	event := audit.Event(models.EventSessionRevoked, user.GetUser().ID, user.GetUser().ID)
+	event.Metadata = map[string]string{"scope": "others"}
+	h.record(c, event)
+	c.Status(http.StatusNoContent)
+}
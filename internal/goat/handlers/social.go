@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SocialHandler exposes sign-in through external identity providers. The redirect is started by
+// social, and the callback is completed by an authenticator such as session.Manager or
+// jwt.Authenticator, which also establishes the login.
+type SocialHandler[U models.Account] struct {
+	social goat.SocialLogin[U]
+	auth   goat.Authenticator[U]
+	auditing
+}
+
+// NewSocialHandler creates a SocialHandler that starts sign-ins with social and completes them with auth.
+func NewSocialHandler[U models.Account](social goat.SocialLogin[U], auth goat.Authenticator[U]) *SocialHandler[U] {
+	return &SocialHandler[U]{social: social, auth: auth}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r. The callback URL registered with each
+// provider must be <prefix>/auth/<provider>/callback.
+func (h *SocialHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.GET("/auth/:provider", h.Begin)
+	r.GET("/auth/:provider/callback", h.Callback)
+}
+
+// Begin redirects the user to the provider named in the path.
+func (h *SocialHandler[U]) Begin(c *gin.Context) {
+	if err := h.social.Begin(c, c.Param("provider")); err != nil {
+		goat.AbortWithProblem(c, err)
+	}
+}
+
+// Callback completes the sign-in and responds with the user.
+func (h *SocialHandler[U]) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	user, err := h.auth.SocialLogin(c, provider)
+	if err != nil {
+		h.loginFailed(c, provider, "", err)
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.loginSucceeded(c, provider, user.GetUser().ID)
+
+	user.GetUser().Password = "" // Never send the password hash back.
+	c.JSON(http.StatusOK, user)
+}
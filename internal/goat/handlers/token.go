@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHandler exposes JWT access token issuing, refresh and revocation backed by a jwt.Authenticator,
+// and, when the authenticator tracks sessions, lets users see and revoke the sessions they signed in with.
+type TokenHandler[U models.Account] struct {
+	tokens *jwt.Authenticator[U]
+	auditing
+}
+
+// NewTokenHandler creates a TokenHandler for tokens.
+func NewTokenHandler[U models.Account](tokens *jwt.Authenticator[U]) *TokenHandler[U] {
+	return &TokenHandler[U]{tokens: tokens}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *TokenHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.POST("/token", h.Login)
+	r.POST("/token/refresh", h.Refresh)
+
+	authenticated := r.Group("/token", h.tokens.Middleware())
+	authenticated.DELETE("", h.Revoke)
+	authenticated.DELETE("/all", h.RevokeAll)
+	authenticated.GET("/sessions", h.ListSessions)
+	authenticated.DELETE("/sessions", h.RevokeOtherSessions)
+	authenticated.DELETE("/sessions/:id", h.RevokeSession)
+}
+
+// tokenResponse is the body returned when a token is issued.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Login checks the email and password in the JSON body and responds with an access token.
+func (h *TokenHandler[U]) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	user, token, err := h.tokens.Login(c, req.Email, req.Password)
+	if err != nil {
+		h.loginFailed(c, "token", req.Email, err)
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.loginSucceeded(c, "token", user.GetUser().ID)
+	c.JSON(http.StatusOK, tokenResponse{AccessToken: token, TokenType: "Bearer"})
+}
+
+// Refresh exchanges the request's bearer token for a new one, revoking the old token.
+func (h *TokenHandler[U]) Refresh(c *gin.Context) {
+	_, token, err := h.tokens.Refresh(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse{AccessToken: token, TokenType: "Bearer"})
+}
+
+// Revoke revokes the request's bearer token.
+func (h *TokenHandler[U]) Revoke(c *gin.Context) {
+	user, _ := jwt.User[U](c)
+	claims, _ := jwt.CurrentClaims(c)
+	if err := h.tokens.RevokeToken(claims); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.record(c, audit.Event(models.EventTokenRevoked, user.GetUser().ID, user.GetUser().ID))
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAll revokes every token issued to the current user, including the request's.
+func (h *TokenHandler[U]) RevokeAll(c *gin.Context) {
+	user, _ := jwt.User[U](c)
+	if err := h.tokens.RevokeAllTokens(user); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	event := audit.Event(models.EventTokenRevoked, user.GetUser().ID, user.GetUser().ID)
+	event.Metadata = map[string]string{"scope": "all"}
+	h.record(c, event)
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions responds with the current user's sessions, marking the one the request's token belongs to.
+func (h *TokenHandler[U]) ListSessions(c *gin.Context) {
+	user, _ := jwt.User[U](c)
+	claims, _ := jwt.CurrentClaims(c)
+	listSessions(c, h.tokens, user.GetUser().ID, claims.SessionID)
+}
+
+// RevokeSession ends one of the current user's sessions; its tokens stop verifying at once.
+func (h *TokenHandler[U]) RevokeSession(c *gin.Context) {
+	user, _ := jwt.User[U](c)
+	if revokeSession(c, h.tokens, user.GetUser().ID) {
+		h.record(c, audit.Event(models.EventSessionRevoked, user.GetUser().ID, user.GetUser().ID))
+	}
+}
+
+// RevokeOtherSessions ends all of the current user's sessions except the one the request's token belongs to.
+func (h *TokenHandler[U]) RevokeOtherSessions(c *gin.Context) {
+	user, _ := jwt.User[U](c)
+	claims, _ := jwt.CurrentClaims(c)
+
+	if err := tenant.Sessions(c, h.tokens).RevokeAllSessions(user.GetUser().ID, claims.SessionID); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	event := audit.Event(models.EventSessionRevoked, user.GetUser().ID, user.GetUser().ID)
+	event.Metadata = map[string]string{"scope": "others"}
+	h.record(c, event)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler exposes a goat.UserService over HTTP. Each request is served from the users of its
+// tenant, as resolved by tenant.Middleware.
+type UserHandler[U models.Account] struct {
+	service goat.UserService[U]
+	auditing
+}
+
+// NewUserHandler creates a UserHandler for service.
+func NewUserHandler[U models.Account](service goat.UserService[U]) *UserHandler[U] {
+	return &UserHandler[U]{service: service}
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *UserHandler[U]) RegisterRoutes(r gin.IRouter) {
+	r.POST("/register", h.Register)
+	r.POST("/login", h.Login)
+}
+
+// loginRequest is the body accepted by Login.
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Register creates a user from the JSON body and responds with the created user.
+func (h *UserHandler[U]) Register(c *gin.Context) {
+	user := models.New[U]()
+	if err := c.ShouldBindJSON(user); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	// Roles are granted by the application, never chosen at sign-up.
+	user.GetUser().Role = ""
+
+	if err := tenant.Users(c, h.service).Register(user); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.record(c, audit.Event(models.EventUserRegistered, user.GetUser().ID, user.GetUser().ID))
+
+	user.GetUser().Password = "" // Never send the password hash back.
+	c.JSON(http.StatusCreated, user)
+}
+
+// Login checks the email and password in the JSON body and responds with the user.
+func (h *UserHandler[U]) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+
+	user, err := tenant.Users(c, h.service).Login(req.Email, req.Password)
+	if err != nil {
+		h.loginFailed(c, "password", req.Email, err)
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	h.loginSucceeded(c, "password", user.GetUser().ID)
+
+	user.GetUser().Password = "" // Never send the password hash back.
+	c.JSON(http.StatusOK, user)
+}
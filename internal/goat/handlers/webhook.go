@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/bontusss/goat/internal/goat/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDeliveryLimit is the number of deliveries listed when the request sets no limit.
+const defaultDeliveryLimit = 50
+
+// WebhookHandler lets administrators register webhook endpoints, read their delivery logs and replay
+// deliveries. It does no authorization of its own: mount it behind the application's admin-only
+// middleware. Each request manages the endpoints of its tenant, as resolved by tenant.Middleware.
+type WebhookHandler struct {
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookHandler creates a WebhookHandler managing the endpoints of dispatcher.
+func NewWebhookHandler(dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{dispatcher: dispatcher}
+}
+
+// tenantDispatcher returns the dispatcher scoped to the request's tenant.
+func (h *WebhookHandler) tenantDispatcher(c *gin.Context) *webhook.Dispatcher {
+	return h.dispatcher.ForTenant(tenant.ID(c))
+}
+
+// RegisterRoutes mounts the handler's endpoints on r.
+func (h *WebhookHandler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/webhooks", h.CreateEndpoint)
+	r.GET("/webhooks", h.ListEndpoints)
+	r.GET("/webhooks/:id", h.GetEndpoint)
+	r.PATCH("/webhooks/:id", h.UpdateEndpoint)
+	r.DELETE("/webhooks/:id", h.DeleteEndpoint)
+	r.POST("/webhooks/:id/secret", h.RotateSecret)
+	r.GET("/webhooks/:id/deliveries", h.ListDeliveries)
+	r.GET("/webhook-deliveries/:id", h.GetDelivery)
+	r.POST("/webhook-deliveries/:id/replay", h.ReplayDelivery)
+}
+
+// createEndpointRequest is the body accepted by CreateEndpoint.
+type createEndpointRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"` // Empty for every event type.
+}
+
+// updateEndpointRequest is the body accepted by UpdateEndpoint; absent fields are left unchanged.
+type updateEndpointRequest struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// endpointSecretResponse is an endpoint with its signing secret.
+type endpointSecretResponse struct {
+	*models.WebhookEndpoint
+	Secret string `json:"secret"` // Shown only in this response.
+}
+
+// CreateEndpoint registers an endpoint and responds with it and its signing secret.
+func (h *WebhookHandler) CreateEndpoint(c *gin.Context) {
+	var req createEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+	endpoint, err := h.tenantDispatcher(c).CreateEndpoint(req.URL, req.Events)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, endpointSecretResponse{WebhookEndpoint: endpoint, Secret: endpoint.Secret})
+}
+
+// ListEndpoints responds with every endpoint, oldest first.
+func (h *WebhookHandler) ListEndpoints(c *gin.Context) {
+	endpoints, err := h.tenantDispatcher(c).Endpoints()
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// GetEndpoint responds with one endpoint.
+func (h *WebhookHandler) GetEndpoint(c *gin.Context) {
+	endpoint, err := h.tenantDispatcher(c).Endpoint(c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// UpdateEndpoint changes an endpoint's URL, events or active flag and responds with the endpoint.
+func (h *WebhookHandler) UpdateEndpoint(c *gin.Context) {
+	var req updateEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithMalformedRequest(c, err)
+		return
+	}
+	endpoint, err := h.tenantDispatcher(c).Endpoint(c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	if req.URL != nil {
+		endpoint.URL = *req.URL
+	}
+	if req.Events != nil {
+		endpoint.Events = *req.Events
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+	if err := h.tenantDispatcher(c).UpdateEndpoint(endpoint); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteEndpoint removes an endpoint and its delivery log.
+func (h *WebhookHandler) DeleteEndpoint(c *gin.Context) {
+	if err := h.tenantDispatcher(c).DeleteEndpoint(c.Param("id")); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RotateSecret gives an endpoint a new signing secret and responds with the endpoint and the secret.
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	endpoint, err := h.tenantDispatcher(c).RotateSecret(c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, endpointSecretResponse{WebhookEndpoint: endpoint, Secret: endpoint.Secret})
+}
+
+// ListDeliveries responds with an endpoint's deliveries, newest first; limit sets how many.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	limit := defaultDeliveryLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			abortWithMalformedRequest(c, errors.New("invalid limit"))
+			return
+		}
+		limit = n
+	}
+	deliveries, err := h.tenantDispatcher(c).Deliveries(c.Param("id"), limit)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery responds with one delivery, including its payload and the outcome of its last attempt.
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	delivery, err := h.tenantDispatcher(c).Delivery(c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayDelivery queues a delivery's event to be sent again and responds with the new delivery.
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	delivery, err := h.tenantDispatcher(c).Replay(c.Param("id"))
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
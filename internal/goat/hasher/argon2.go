@@ -0,0 +1,106 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id hashes passwords with Argon2id and encodes them as PHC strings,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+type Argon2id struct {
+	Memory      uint32 // Memory cost in KiB.
+	Iterations  uint32 // Number of passes over the memory.
+	Parallelism uint8  // Number of lanes.
+	SaltLength  int    // Length of the random salt in bytes.
+	KeyLength   uint32 // Length of the derived key in bytes.
+}
+
+// NewArgon2id returns an Argon2id hasher with the parameters recommended by RFC 9106 for
+// memory-constrained environments.
+func NewArgon2id() *Argon2id {
+	return &Argon2id{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+}
+
+// ID implements Algorithm.
+func (a *Argon2id) ID() string {
+	return AlgorithmArgon2id
+}
+
+// Hash implements goat.PasswordHasher.
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt, err := randomSalt(a.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return phcHash{
+		id:      AlgorithmArgon2id,
+		version: argon2.Version,
+		params: map[string]string{
+			"m": strconv.FormatUint(uint64(a.Memory), 10),
+			"t": strconv.FormatUint(uint64(a.Iterations), 10),
+			"p": strconv.FormatUint(uint64(a.Parallelism), 10),
+		},
+		salt: salt,
+		hash: key,
+	}.String(), nil
+}
+
+// Verify implements goat.PasswordHasher.
+func (a *Argon2id) Verify(password, encoded string) (bool, error) {
+	h, m, t, p, err := a.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, t, m, p, uint32(len(h.hash)))
+	return subtle.ConstantTimeCompare(key, h.hash) == 1, nil
+}
+
+// NeedsRehash implements goat.PasswordHasher.
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	h, m, t, p, err := a.decode(encoded)
+	if err != nil {
+		return true
+	}
+	return h.version != argon2.Version || m != a.Memory || t != a.Iterations || p != a.Parallelism ||
+		len(h.salt) != a.SaltLength || uint32(len(h.hash)) != a.KeyLength
+}
+
+// Bounds on the parameters of stored Argon2id hashes. argon2.IDKey panics on zero passes or lanes,
+// or an empty key, and a stored hash must not make a login allocate more memory than any sane
+// configuration would.
+const (
+	maxArgon2Memory     = 4 * 1024 * 1024 // 4 GiB, in KiB.
+	maxArgon2Iterations = 1024
+	minArgon2KeyLength  = 4
+)
+
+// decode parses an encoded Argon2id hash, rejecting parameters that are out of range.
+func (a *Argon2id) decode(encoded string) (h phcHash, m, t uint32, p uint8, err error) {
+	if h, err = parsePHC(encoded); err != nil {
+		return
+	}
+	if h.id != AlgorithmArgon2id {
+		err = ErrUnknownAlgorithm
+		return
+	}
+	var v uint64
+	if v, err = h.uintParam("m", 32); err != nil {
+		return
+	}
+	m = uint32(v)
+	if v, err = h.uintParam("t", 32); err != nil {
+		return
+	}
+	t = uint32(v)
+	if v, err = h.uintParam("p", 8); err != nil {
+		return
+	}
+	p = uint8(v)
+	if m < 8*uint32(p) || m > maxArgon2Memory || t == 0 || t > maxArgon2Iterations || p == 0 || len(h.hash) < minArgon2KeyLength {
+		err = ErrMalformedHash
+	}
+	return
+}
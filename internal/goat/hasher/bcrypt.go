@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes passwords with bcrypt. Hashes keep bcrypt's own modular crypt format
+// ($2a$10$...), which is what the repositories stored before hashing became pluggable.
+type Bcrypt struct {
+	Cost int
+}
+
+// NewBcrypt returns a bcrypt hasher with the given cost, or bcrypt.DefaultCost when cost is 0.
+func NewBcrypt(cost int) *Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{Cost: cost}
+}
+
+// ID implements Algorithm.
+func (b *Bcrypt) ID() string {
+	return AlgorithmBcrypt
+}
+
+// Hash implements goat.PasswordHasher.
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify implements goat.PasswordHasher.
+func (b *Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash implements goat.PasswordHasher.
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost != b.Cost
+}
@@ -0,0 +1,188 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// Errors returned while decoding stored password hashes.
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Algorithm identifiers, as they appear in encoded hashes.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmScrypt   = "scrypt"
+)
+
+// Default returns the hasher used by the repositories when none is configured.
+// New passwords are hashed with Argon2id; bcrypt and scrypt hashes are still accepted
+// and are reported as needing a rehash so they are upgraded on the next login.
+func Default() goat.PasswordHasher {
+	return NewChain(NewArgon2id(), NewBcrypt(0), NewScrypt())
+}
+
+// Algorithm is a PasswordHasher for a single algorithm that can recognise its own hashes.
+type Algorithm interface {
+	goat.PasswordHasher
+	ID() string
+}
+
+// Chain hashes new passwords with a preferred algorithm and verifies hashes produced by any of
+// the algorithms it knows about, so that the preferred algorithm can change without locking
+// out existing users.
+type Chain struct {
+	preferred  Algorithm
+	algorithms map[string]Algorithm
+}
+
+// NewChain creates a Chain that hashes with preferred and also verifies hashes of legacy algorithms.
+func NewChain(preferred Algorithm, legacy ...Algorithm) *Chain {
+	c := &Chain{preferred: preferred, algorithms: map[string]Algorithm{preferred.ID(): preferred}}
+	for _, a := range legacy {
+		if _, ok := c.algorithms[a.ID()]; !ok {
+			c.algorithms[a.ID()] = a
+		}
+	}
+	return c
+}
+
+// Hash implements goat.PasswordHasher.
+func (c *Chain) Hash(password string) (string, error) {
+	return c.preferred.Hash(password)
+}
+
+// Verify implements goat.PasswordHasher.
+func (c *Chain) Verify(password, encoded string) (bool, error) {
+	a, ok := c.algorithms[Identify(encoded)]
+	if !ok {
+		return false, ErrUnknownAlgorithm
+	}
+	return a.Verify(password, encoded)
+}
+
+// NeedsRehash implements goat.PasswordHasher.
+// Hashes of any algorithm other than the preferred one always need a rehash.
+func (c *Chain) NeedsRehash(encoded string) bool {
+	if Identify(encoded) != c.preferred.ID() {
+		return true
+	}
+	return c.preferred.NeedsRehash(encoded)
+}
+
+// Identify returns the algorithm identifier of an encoded hash, or "" when it is not recognised.
+func Identify(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return AlgorithmScrypt
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	}
+	return ""
+}
+
+// phcHash is the decoded form of a PHC string: $id$v=version$param=value,...$salt$hash
+type phcHash struct {
+	id      string
+	version int
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+func (h phcHash) String() string {
+	var b strings.Builder
+	b.WriteString("$" + h.id)
+	if h.version != 0 {
+		fmt.Fprintf(&b, "$v=%d", h.version)
+	}
+	b.WriteString("$")
+	for i, kv := range sortedParams(h.id, h.params) {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(kv)
+	}
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(h.salt))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(h.hash))
+	return b.String()
+}
+
+// paramOrder lists the parameters of each algorithm in their conventional order.
+var paramOrder = map[string][]string{
+	AlgorithmArgon2id: {"m", "t", "p"},
+	AlgorithmScrypt:   {"ln", "r", "p"},
+}
+
+func sortedParams(id string, params map[string]string) []string {
+	kvs := make([]string, 0, len(params))
+	for _, k := range paramOrder[id] {
+		if v, ok := params[k]; ok {
+			kvs = append(kvs, k+"="+v)
+		}
+	}
+	return kvs
+}
+
+func parsePHC(encoded string) (phcHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return phcHash{}, ErrMalformedHash
+	}
+	h := phcHash{id: parts[1], params: map[string]string{}}
+	rest := parts[2:]
+	if strings.HasPrefix(rest[0], "v=") {
+		v, err := strconv.Atoi(strings.TrimPrefix(rest[0], "v="))
+		if err != nil {
+			return phcHash{}, ErrMalformedHash
+		}
+		h.version = v
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return phcHash{}, ErrMalformedHash
+	}
+	for _, kv := range strings.Split(rest[0], ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return phcHash{}, ErrMalformedHash
+		}
+		h.params[k] = v
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(rest[1]); err != nil {
+		return phcHash{}, ErrMalformedHash
+	}
+	if h.hash, err = base64.RawStdEncoding.DecodeString(rest[2]); err != nil {
+		return phcHash{}, ErrMalformedHash
+	}
+	return h, nil
+}
+
+// uintParam reads a numeric PHC parameter.
+func (h phcHash) uintParam(name string, bits int) (uint64, error) {
+	v, err := strconv.ParseUint(h.params[name], 10, bits)
+	if err != nil {
+		return 0, ErrMalformedHash
+	}
+	return v, nil
+}
+
+func randomSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,129 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fastArgon2id and fastScrypt are cheap enough to run many times in tests.
+func fastArgon2id() *Argon2id {
+	return &Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func fastScrypt() *Scrypt {
+	return &Scrypt{LogN: 4, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, a := range []Algorithm{fastArgon2id(), NewBcrypt(4), fastScrypt()} {
+		t.Run(a.ID(), func(t *testing.T) {
+			encoded, err := a.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Identify(encoded) != a.ID() {
+				t.Errorf("Identify(%q) = %q, want %q", encoded, Identify(encoded), a.ID())
+			}
+			if ok, err := a.Verify("correct horse", encoded); err != nil || !ok {
+				t.Errorf("Verify(right password) = %v, %v, want true", ok, err)
+			}
+			if ok, err := a.Verify("battery staple", encoded); err != nil || ok {
+				t.Errorf("Verify(wrong password) = %v, %v, want false", ok, err)
+			}
+			if a.NeedsRehash(encoded) {
+				t.Error("NeedsRehash of a fresh hash = true")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	a := fastArgon2id()
+	encoded, err := a.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stronger := fastArgon2id()
+	stronger.Iterations = 2
+	if !stronger.NeedsRehash(encoded) {
+		t.Error("Argon2id NeedsRehash after raising the iterations = false")
+	}
+	longer := fastArgon2id()
+	longer.KeyLength = 64
+	if !longer.NeedsRehash(encoded) {
+		t.Error("Argon2id NeedsRehash after lengthening the key = false")
+	}
+
+	b := NewBcrypt(4)
+	legacy, err := b.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NewBcrypt(5).NeedsRehash(legacy) {
+		t.Error("Bcrypt NeedsRehash after raising the cost = false")
+	}
+
+	chain := NewChain(a, b, fastScrypt())
+	if !chain.NeedsRehash(legacy) {
+		t.Error("Chain NeedsRehash of a legacy algorithm's hash = false")
+	}
+	if chain.NeedsRehash(encoded) {
+		t.Error("Chain NeedsRehash of the preferred algorithm's hash = true")
+	}
+	if ok, err := chain.Verify("password", legacy); err != nil || !ok {
+		t.Errorf("Chain Verify of a legacy hash = %v, %v, want true", ok, err)
+	}
+	if !chain.NeedsRehash("garbage") {
+		t.Error("Chain NeedsRehash of an unknown hash = false")
+	}
+}
+
+func TestArgon2idRejectsBadParameters(t *testing.T) {
+	a := fastArgon2id()
+	encoded, err := a.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := "m=64,t=1,p=1"
+	for _, bad := range []string{
+		"m=64,t=1,p=0",
+		"m=64,t=0,p=1",
+		"m=0,t=1,p=1",
+		"m=64,t=1,p=255",         // Less than 8 KiB of memory per lane.
+		"m=4294967295,t=1,p=1",   // Far more memory than any configuration.
+		"m=64,t=4294967295,p=1",  // Far more passes than any configuration.
+		"m=64,t=1",               // Missing a parameter.
+		"m=64,t=1,p=1,p=x",       // Malformed value.
+		"m=-1,t=1,p=1",           // Negative value.
+		"m=64,t=1,p=99999999999", // Overflows the lane count.
+	} {
+		tampered := strings.Replace(encoded, params, bad, 1)
+		if _, err := a.Verify("password", tampered); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify with %s = %v, want ErrMalformedHash", bad, err)
+		}
+		if !a.NeedsRehash(tampered) {
+			t.Errorf("NeedsRehash with %s = false", bad)
+		}
+	}
+
+	// An empty key would make argon2 panic as well.
+	empty := encoded[:strings.LastIndex(encoded, "$")+1]
+	if _, err := a.Verify("password", empty); !errors.Is(err, ErrMalformedHash) {
+		t.Errorf("Verify with an empty key = %v, want ErrMalformedHash", err)
+	}
+}
+
+func TestScryptRejectsBadParameters(t *testing.T) {
+	s := fastScrypt()
+	encoded, err := s.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"ln=0,r=8,p=1", "ln=4,r=0,p=1", "ln=4,r=8,p=0", "ln=40,r=8,p=1", "ln=63,r=2147483647,p=1"} {
+		tampered := strings.Replace(encoded, "ln=4,r=8,p=1", bad, 1)
+		if _, err := s.Verify("password", tampered); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify with %s = %v, want ErrMalformedHash", bad, err)
+		}
+	}
+}
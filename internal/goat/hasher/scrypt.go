@@ -0,0 +1,103 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"strconv"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt hashes passwords with scrypt and encodes them as PHC strings,
+// e.g. $scrypt$ln=15,r=8,p=1$<salt>$<hash>.
+type Scrypt struct {
+	LogN       uint8 // CPU/memory cost as a power of two.
+	R          int   // Block size.
+	P          int   // Parallelisation.
+	SaltLength int   // Length of the random salt in bytes.
+	KeyLength  int   // Length of the derived key in bytes.
+}
+
+// NewScrypt returns a scrypt hasher with N=2^15, r=8, p=1.
+func NewScrypt() *Scrypt {
+	return &Scrypt{LogN: 15, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+}
+
+// ID implements Algorithm.
+func (s *Scrypt) ID() string {
+	return AlgorithmScrypt
+}
+
+// Hash implements goat.PasswordHasher.
+func (s *Scrypt) Hash(password string) (string, error) {
+	salt, err := randomSalt(s.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, s.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	return phcHash{
+		id: AlgorithmScrypt,
+		params: map[string]string{
+			"ln": strconv.Itoa(int(s.LogN)),
+			"r":  strconv.Itoa(s.R),
+			"p":  strconv.Itoa(s.P),
+		},
+		salt: salt,
+		hash: key,
+	}.String(), nil
+}
+
+// Verify implements goat.PasswordHasher.
+func (s *Scrypt) Verify(password, encoded string) (bool, error) {
+	h, ln, r, p, err := s.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	key, err := scrypt.Key([]byte(password), h.salt, 1<<ln, r, p, len(h.hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, h.hash) == 1, nil
+}
+
+// NeedsRehash implements goat.PasswordHasher.
+func (s *Scrypt) NeedsRehash(encoded string) bool {
+	h, ln, r, p, err := s.decode(encoded)
+	if err != nil {
+		return true
+	}
+	return ln != s.LogN || r != s.R || p != s.P || len(h.salt) != s.SaltLength || len(h.hash) != s.KeyLength
+}
+
+// maxScryptMemory bounds the memory, 128·r·N bytes, a stored scrypt hash can make a login use.
+const maxScryptMemory = 4 << 30
+
+// decode parses an encoded scrypt hash, rejecting parameters that are out of range.
+func (s *Scrypt) decode(encoded string) (h phcHash, ln uint8, r, p int, err error) {
+	if h, err = parsePHC(encoded); err != nil {
+		return
+	}
+	if h.id != AlgorithmScrypt {
+		err = ErrUnknownAlgorithm
+		return
+	}
+	var v uint64
+	if v, err = h.uintParam("ln", 6); err != nil {
+		return
+	}
+	ln = uint8(v)
+	if v, err = h.uintParam("r", 31); err != nil {
+		return
+	}
+	r = int(v)
+	if v, err = h.uintParam("p", 31); err != nil {
+		return
+	}
+	p = int(v)
+	if ln == 0 || r == 0 || p == 0 || uint64(r) > maxScryptMemory/128>>ln || len(h.hash) == 0 {
+		err = ErrMalformedHash
+	}
+	return
+}
@@ -1,26 +1,249 @@
 package goat
 
 import (
+	"time"
+
 	"github.com/bontusss/goat/internal/goat/models"
 	"github.com/gin-gonic/gin"
 )
 
-// UserService defines the interface for user management
-type UserService interface {
-	Register(user *models.User) error
-	Login(email, password string) (*models.User, error)
-	GetUserByID(id uint) (*models.User, error) // Get user by ID
-	UpdateUser(user *models.User) error        // Update user information
-	DeleteUser(id uint) error                  // Delete a user (consider security implications)
-	ResetPassword(email, newPassword string) error
+// UserService defines the interface for user management. A service sees the users of one tenant:
+// the default tenant, until ForTenant scopes it to another.
+// U is the application's user type: *models.User or a pointer to a struct embedding models.User.
+type UserService[U models.Account] interface {
+	ForTenant(tenantID string) UserService[U] // A service that only sees and creates the tenant's users
+	Register(user U) error
+	Login(email, password string) (U, error)
+	GetUserByID(id uint) (U, error)                                 // Get user by ID
+	GetUserByEmail(email string) (U, error)                         // Get user by email
+	UpdateUser(user U) error                                        // Update user information; returns ErrUserNotFound if the user is missing or deleted
+	DeleteUser(id uint) error                                       // Delete a user (consider security implications)
+	ResetPassword(email, newPassword string) error                  // Set the password of the user with email; returns ErrUserNotFound if the user is missing or deleted
+	ListUsers(query *models.UserQuery) (*models.UserPage[U], error) // List the users that are not deleted a page at a time; pass the page's Next as the cursor to get the next one
 	// You can add more methods as needed (e.g., search users)
 }
 
+// AccountService is implemented by user services that manage account states. DeleteUser only
+// soft-deletes: the user is hidden from the service, and keeps their email, until purged.
+type AccountService[U models.Account] interface {
+	SuspendUser(id uint) error         // Keep an active or deactivated user from signing in
+	DeactivateUser(id uint) error      // Close a user's account at their request
+	ReactivateUser(id uint) error      // Make a suspended or deactivated user active again
+	GetDeletedUser(id uint) (U, error) // Get a soft-deleted user; returns ErrUserNotFound for users that are not deleted
+	RestoreUser(id uint) error         // Make a soft-deleted user active again; returns ErrRestoreWindowExpired once the retention period is over, and ErrEmailTaken if another user has registered the email since
+	PurgeDeletedUsers() (int, error)   // Remove the users of every tenant deleted longer ago than the retention period, with their sessions, API keys, identities and memberships, and return how many
+	AnonymizeUser(id uint) error       // Replace the email, password and custom fields of a user, deleted or not, with placeholders and deactivate them, keeping their ID
+	EraseUser(id uint) error           // Remove a user, deleted or not, at once and for good
+}
+
+// UserHooks is implemented by user services that emit user lifecycle events. Hooks registered
+// on a service also run for the services ForTenant returns from it.
+type UserHooks[U models.Account] interface {
+	BeforeUserEvent(typ models.UserEventType, hook models.UserHook[U]) // Run hook synchronously before the change is saved; an error vetoes the change and is returned to the caller
+	AfterUserEvent(typ models.UserEventType, hook models.UserHook[U])  // Run hook in its own goroutine once the change is saved
+}
+
 // Authenticator defines the interface for authentication methods
-type Authenticator interface {
-	Authenticate(c *gin.Context) (*models.User, error)
+type Authenticator[U models.Account] interface {
+	Authenticate(c *gin.Context) (U, error)
 	// Additional methods for different authentication flows (optional)
-	RefreshAuthToken(c *gin.Context) (*models.User, error)             // Refresh JWT token (if using JWT)
-	SocialLogin(c *gin.Context, provider string) (*models.User, error) // Social login using providers (optional)
+	RefreshAuthToken(c *gin.Context) (U, error)             // Refresh JWT token (if using JWT)
+	SocialLogin(c *gin.Context, provider string) (U, error) // Social login using providers (optional)
 	// You can add more methods for specific authentication flows
 }
+
+// SocialLogin defines the interface for signing in through an external identity provider
+type SocialLogin[U models.Account] interface {
+	Begin(c *gin.Context, provider string) error         // Redirect the user to the provider
+	Complete(c *gin.Context, provider string) (U, error) // Handle the provider's callback and return the user's account
+}
+
+// IdentityStore defines the interface for storing the external identities linked to users. A store
+// sees the identities of one tenant: the default tenant, until ForTenant scopes it to another.
+type IdentityStore interface {
+	ForTenant(tenantID string) IdentityStore                        // A store that only sees and links the tenant's identities
+	CreateIdentity(identity *models.Identity) error                 // Save a link; returns ErrIdentityTaken if the identity is already linked in the tenant
+	GetIdentity(provider, subject string) (*models.Identity, error) // Get a link; returns ErrIdentityNotFound if missing
+	ListIdentities(userID uint) ([]*models.Identity, error)         // List a user's links, oldest first
+	DeleteIdentity(provider, subject string) error                  // Delete a link; returns ErrIdentityNotFound if missing
+}
+
+// IdentityService defines the interface for managing the external identities a user signs in with.
+// Like UserService, it serves one tenant.
+type IdentityService[U models.Account] interface {
+	ForTenant(tenantID string) IdentityService[U] // A service that only links and finds the tenant's users
+	LinkIdentity(userID uint, identity *models.Identity) error
+	UnlinkIdentity(userID uint, provider, subject string) error // Refuses to remove the user's last way to sign in
+	ListIdentities(userID uint) ([]*models.Identity, error)
+	GetUserByIdentity(provider, subject string) (U, error) // Get the user an identity is linked to
+}
+
+// OIDCStore defines the interface for storing the clients, consents and grants of the OpenID Connect
+// provider. Clients are shared by every tenant; a store sees the consents and grants of one tenant:
+// the default tenant, until ForTenant scopes it to another.
+type OIDCStore interface {
+	ForTenant(tenantID string) OIDCStore                              // A store that only sees and saves the tenant's consents and grants
+	SaveClient(client *models.Client) error                           // Insert or update a client
+	GetClient(id string) (*models.Client, error)                      // Get a client; returns ErrClientNotFound if missing
+	DeleteClient(id string) error                                     // Delete a client; returns ErrClientNotFound if missing
+	SaveConsent(consent *models.Consent) error                        // Insert or replace a user's consent for a client
+	GetConsent(userID uint, clientID string) (*models.Consent, error) // Get a consent; returns ErrConsentNotFound if missing
+	ListConsents(userID uint) ([]*models.Consent, error)              // List a user's consents, oldest first
+	DeleteConsent(userID uint, clientID string) error                 // Withdraw a consent
+	CreateGrant(grant *models.Grant) error                            // Save a new authorization code or refresh token
+	TakeGrant(id string) (*models.Grant, error)                       // Get and delete a grant, so it is redeemed at most once; returns ErrGrantNotFound if missing
+	DeleteExpiredGrants(now time.Time) error                          // Delete the grants of every tenant that have expired
+	DeleteUserGrants(userID uint) (int, error)                        // Delete a user's codes and refresh tokens, and return how many
+}
+
+// APIKeyStore defines the interface for storing users' API keys. A store sees the keys of one
+// tenant: the default tenant, until ForTenant scopes it to another.
+type APIKeyStore interface {
+	ForTenant(tenantID string) APIKeyStore             // A store that only sees and saves the tenant's keys
+	CreateAPIKey(key *models.APIKey) error             // Save a new key
+	GetAPIKey(id string) (*models.APIKey, error)       // Get a key by ID; returns ErrAPIKeyNotFound if missing
+	ListAPIKeys(userID uint) ([]*models.APIKey, error) // List a user's keys, newest first
+	TouchAPIKey(id string, lastUsedAt time.Time) error // Record use of a key
+	DeleteAPIKey(id string) error                      // Delete a key; returns ErrAPIKeyNotFound if missing
+}
+
+// APIKeyService defines the interface for managing a user's API keys. Like UserService, it serves
+// one tenant.
+type APIKeyService interface {
+	ForTenant(tenantID string) APIKeyService                                                                   // A service that only sees and creates keys of the tenant's users
+	CreateAPIKey(userID uint, name string, scopes []string, ttl time.Duration) (*models.APIKey, string, error) // Returns the key and its full value, which is shown only once; a zero ttl never expires
+	ListAPIKeys(userID uint) ([]*models.APIKey, error)
+	RevokeAPIKey(userID uint, id string) error // Revoke one of the user's keys
+}
+
+// OrganizationStore defines the interface for storing organizations, their members and pending
+// invitations. A store sees the organizations of one tenant: the default tenant, until ForTenant
+// scopes it to another.
+type OrganizationStore interface {
+	ForTenant(tenantID string) OrganizationStore                         // A store that only sees and saves the tenant's organizations
+	CreateOrganization(org *models.Organization) error                   // Save a new organization
+	GetOrganization(id string) (*models.Organization, error)             // Get an organization; returns ErrOrganizationNotFound if missing
+	UpdateOrganization(org *models.Organization) error                   // Update an organization; returns ErrOrganizationNotFound if missing
+	DeleteOrganization(id string) error                                  // Delete an organization with its memberships and invitations, atomically
+	TransferOwnership(orgID string, from, to uint) error                 // Make member to the owner in place of from, who becomes an admin, atomically; returns ErrMembershipNotFound if to is not a member
+	ListOrganizations(userID uint) ([]*models.Organization, error)       // List the organizations a user is a member of, oldest first
+	SaveMembership(membership *models.Membership) error                  // Insert or update a membership
+	GetMembership(orgID string, userID uint) (*models.Membership, error) // Get a membership; returns ErrMembershipNotFound if missing
+	ListMembers(orgID string) ([]*models.Membership, error)              // List an organization's members, oldest first
+	DeleteMembership(orgID string, userID uint) error                    // Delete a membership; returns ErrMembershipNotFound if missing
+	CreateInvitation(invitation *models.Invitation) error                // Save a new invitation
+	GetInvitation(id string) (*models.Invitation, error)                 // Get an invitation; returns ErrInvitationNotFound if missing
+	ListInvitations(orgID string) ([]*models.Invitation, error)          // List an organization's pending invitations, oldest first
+	DeleteInvitation(id string) error                                    // Delete an invitation; returns ErrInvitationNotFound if missing
+	DeleteInvitationsByEmail(email string) (int, error)                  // Delete the invitations sent to an address, and return how many
+}
+
+// OrganizationService defines the interface for managing organizations, memberships and invitations.
+// actorID is the user making the change, whose role decides whether it is allowed. Like UserService,
+// it serves one tenant.
+type OrganizationService[U models.Account] interface {
+	ForTenant(tenantID string) OrganizationService[U] // A service that only sees the tenant's organizations and users
+	CreateOrganization(ownerID uint, name string) (*models.Organization, error)
+	GetOrganization(userID uint, orgID string) (*models.Organization, error) // Members only
+	ListOrganizations(userID uint) ([]*models.Organization, error)
+	DeleteOrganization(actorID uint, orgID string) error // Owner only
+	ListMembers(userID uint, orgID string) ([]*models.Membership, error)
+	UpdateMemberRole(actorID uint, orgID string, userID uint, role string) error
+	RemoveMember(actorID uint, orgID string, userID uint) error // Members may remove themselves, except the owner
+	TransferOwnership(actorID uint, orgID string, newOwnerID uint) error
+	InviteMember(actorID uint, orgID, email, role string) (*models.Invitation, string, error) // Returns the invitation and its token, which is shown only once
+	ListInvitations(actorID uint, orgID string) ([]*models.Invitation, error)
+	RevokeInvitation(actorID uint, orgID, invitationID string) error
+	AcceptInvitation(user U, token string) (*models.Membership, error) // Only the user the invitation was sent to can accept it
+	DeclineInvitation(user U, token string) error
+}
+
+// AuditStore defines the interface for append-only audit event storage
+type AuditStore interface {
+	AppendAuditEvent(event *models.AuditEvent) error                         // Save a new event; returns ErrAuditSeqTaken if its Seq is already used
+	QueryAuditEvents(query *models.AuditQuery) ([]*models.AuditEvent, error) // List matching events, newest first, at most query.Limit of them
+	LastAuditEvent() (*models.AuditEvent, error)                             // Get the event with the highest Seq; returns ErrAuditEventNotFound if there are none
+	ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) // List events with Seq above afterSeq, in Seq order, at most limit of them
+	RedactAuditEvent(event *models.AuditEvent) error                         // Replace the IP, user agent, metadata and redaction digests of a stored event with event's
+	SaveAuditCheckpoint(checkpoint *models.AuditCheckpoint) error            // Save a new checkpoint
+	LastAuditCheckpoint() (*models.AuditCheckpoint, error)                   // Get the checkpoint with the highest Seq; returns ErrAuditCheckpointNotFound if there are none
+	ListAuditCheckpoints() ([]*models.AuditCheckpoint, error)                // List every checkpoint, in Seq order
+}
+
+// Auditor defines the interface for recording audit events
+type Auditor interface {
+	Record(c *gin.Context, event *models.AuditEvent) error // Stamp the event with its ID, time and the request's metadata, then save it; c may be nil
+}
+
+// OutboxStore defines the interface for reading the outbox that user repositories write lifecycle
+// events to
+type OutboxStore interface {
+	AppendOutboxMessage(message *models.OutboxMessage) error                                            // Save a new message outside of any user change
+	ClaimOutboxMessages(now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) // Take up to limit pending messages due at now, pushing their NextAttemptAt to now+lease so no other relay takes them meanwhile
+	GetOutboxMessage(id string) (*models.OutboxMessage, error)                                          // Get a message; returns ErrOutboxMessageNotFound if missing
+	UpdateOutboxMessage(message *models.OutboxMessage) error                                            // Save a message's Status, Attempts, NextAttemptAt and LastError; returns ErrOutboxMessageNotFound if missing
+	DeleteOutboxMessage(id string) error                                                                // Remove a delivered message; deleting a missing one is not an error
+	ListDeadOutboxMessages(limit int) ([]*models.OutboxMessage, error)                                  // List dead messages, oldest first, at most limit of them
+	DeleteUserOutboxMessages(userID uint) (int, error)                                                  // Remove every message about a user, and return how many
+}
+
+// WebhookStore defines the interface for webhook endpoint and delivery storage
+type WebhookStore interface {
+	CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error                                            // Save a new endpoint
+	GetWebhookEndpoint(id string) (*models.WebhookEndpoint, error)                                           // Get an endpoint; returns ErrWebhookNotFound if missing
+	ListWebhookEndpoints(tenantID string) ([]*models.WebhookEndpoint, error)                                 // List a tenant's endpoints, oldest first
+	UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error                                            // Update an endpoint, but not its tenant; returns ErrWebhookNotFound if missing
+	DeleteWebhookEndpoint(id string) error                                                                   // Delete an endpoint and its deliveries; returns ErrWebhookNotFound if missing
+	CreateWebhookDelivery(delivery *models.WebhookDelivery) error                                            // Save a new delivery; returns ErrWebhookDeliveryExists if its ID is taken
+	GetWebhookDelivery(id string) (*models.WebhookDelivery, error)                                           // Get a delivery; returns ErrWebhookDeliveryNotFound if missing
+	UpdateWebhookDelivery(delivery *models.WebhookDelivery) error                                            // Save a delivery's outcome; returns ErrWebhookDeliveryNotFound if missing
+	ListWebhookDeliveries(endpointID string, limit int) ([]*models.WebhookDelivery, error)                   // List an endpoint's deliveries, newest first, at most limit of them
+	ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) // Take up to limit pending deliveries due at now, pushing their NextAttemptAt to now+lease
+	DeleteUserWebhookDeliveries(userID uint) (int, error)                                                    // Delete every delivery of an event about a user, and return how many
+}
+
+// PasswordHasher defines the interface for password hashing algorithms
+type PasswordHasher interface {
+	Hash(password string) (string, error)          // Encode a new hash of password
+	Verify(password, encoded string) (bool, error) // Report whether password matches the encoded hash
+	NeedsRehash(encoded string) bool               // Report whether encoded uses an outdated algorithm or parameters
+}
+
+// SessionStore defines the interface for server-side session storage. A store sees the sessions of
+// one tenant: the default tenant, until ForTenant scopes it to another.
+type SessionStore interface {
+	ForTenant(tenantID string) SessionStore            // A store that only sees and saves the tenant's sessions
+	Create(session *models.Session) error              // Save a new session
+	Get(id string) (*models.Session, error)            // Get a session by ID; returns ErrSessionNotFound if missing
+	Touch(id string, lastSeenAt time.Time) error       // Record activity on a session
+	Delete(id string) error                            // Delete a session
+	DeleteExpired(now time.Time) error                 // Delete the sessions of every tenant whose absolute expiry has passed
+	ListByUser(userID uint) ([]*models.Session, error) // List a user's sessions, most recently used first
+	DeleteByUser(userID uint, except string) error     // Delete a user's sessions, keeping the one with ID except
+}
+
+// SessionService defines the interface for managing a user's active sessions. Like UserService, it
+// serves one tenant.
+type SessionService interface {
+	ForTenant(tenantID string) SessionService // A service that only sees the sessions of the tenant's users
+	ListSessions(userID uint) ([]*models.Session, error)
+	RevokeSession(id string) error
+	RevokeAllSessions(userID uint, exceptCurrent string) error // Keep the session with ID exceptCurrent, if any
+}
+
+// RevocationStore defines the interface for revoking access tokens before they expire. A store sees
+// the revocations of one tenant: the default tenant, until ForTenant scopes it to another.
+type RevocationStore interface {
+	ForTenant(tenantID string) RevocationStore            // A store that only sees and records the tenant's revocations
+	RevokeToken(jti string, expiresAt time.Time) error    // Deny a token until it would have expired anyway
+	IsRevoked(jti string) (bool, error)                   // Report whether a token has been revoked
+	RevokeUserTokens(userID uint, before time.Time) error // Invalidate all of a user's tokens issued before a time
+	TokensValidAfter(userID uint) (time.Time, error)      // Get a user's watermark; zero if tokens were never revoked
+	DeleteExpired(now time.Time) error                    // Delete the denylist entries of every tenant for tokens that have expired
+}
+
+// KeyStore defines the interface for persisting token signing keys
+type KeyStore interface {
+	SaveKey(key *models.SigningKey) error    // Insert or update a key
+	ListKeys() ([]*models.SigningKey, error) // List all keys, oldest first
+}
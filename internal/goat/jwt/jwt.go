@@ -1 +1,512 @@
-package jwt
\ No newline at end of file
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// Config controls how access tokens are signed and validated.
+type Config struct {
+	Keys     KeySet        // Signs and verifies tokens, e.g. a KeyManager; when nil, Secret is used with HS256.
+	Secret   []byte        // HMAC key for HS256 signatures; at least 32 random bytes.
+	Issuer   string        // Value of the iss claim, checked on every token.
+	Audience string        // Value of the aud claim; empty disables the audience check.
+	TokenTTL time.Duration // Lifetime of an access token.
+	Leeway   time.Duration // Allowed clock skew when checking exp, nbf and iat.
+
+	// MaxSessionAge is how long after signing in a user can keep refreshing their token; they then
+	// have to sign in again. Zero means DefaultMaxSessionAge.
+	MaxSessionAge time.Duration
+}
+
+// DefaultMaxSessionAge is the MaxSessionAge used when Config leaves it zero.
+const DefaultMaxSessionAge = 7 * 24 * time.Hour
+
+// RefreshedTokenHeader is the response header in which RefreshAuthToken and SocialLogin return the new token.
+const RefreshedTokenHeader = "X-Refreshed-Token"
+
+// Gin context keys under which Middleware stores the authenticated user and the token's claims.
+const (
+	userKey   = "goat.user"
+	claimsKey = "goat.claims"
+)
+
+// maxUserAgentLength bounds the user agent recorded for a session.
+const maxUserAgentLength = 512
+
+// Claims are the claims of a goat access token. The subject is the user ID and the
+// token ID (jti) is what the revocation store denylists.
+type Claims struct {
+	gojwt.RegisteredClaims
+	Tenant    string             `json:"tid,omitempty"`       // Tenant of the user; empty for the default tenant.
+	AuthTime  *gojwt.NumericDate `json:"auth_time,omitempty"` // When the user signed in; kept when the token is refreshed.
+	SessionID string             `json:"sid,omitempty"`       // Session the user signed in with, when sessions are tracked; kept when the token is refreshed.
+}
+
+// UserID parses the subject claim.
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 0)
+	if err != nil {
+		return 0, goat.ErrInvalidToken
+	}
+	return uint(id), nil
+}
+
+// Authenticator issues and validates JWT access tokens. It implements goat.Authenticator.
+// Tokens can be revoked individually by ID, or all at once for a user (after a password change,
+// for example) by moving the user's watermark forward with RevokeAllTokens. Revocations are kept
+// for the tenant named in the token.
+//
+// With SetSessions, every sign-in is also recorded as a session. The Authenticator implements
+// goat.SessionService over them, so token users can list the devices they are signed in on and revoke them.
+type Authenticator[U models.Account] struct {
+	users       goat.UserService[U]
+	revocations goat.RevocationStore
+	sessions    goat.SessionStore // Sessions sign-ins are recorded in; nil unless SetSessions was called.
+	keys        KeySet
+	config      Config
+	social      goat.SocialLogin[U]
+	now         func() time.Time
+}
+
+var (
+	_ goat.Authenticator[*models.User] = (*Authenticator[*models.User])(nil)
+	_ goat.SessionService              = (*Authenticator[*models.User])(nil)
+)
+
+// NewAuthenticator creates an Authenticator that loads users from users and checks every token
+// against revocations.
+func NewAuthenticator[U models.Account](users goat.UserService[U], revocations goat.RevocationStore, config Config) *Authenticator[U] {
+	keys := config.Keys
+	if keys == nil {
+		keys = hmacKeySet(config.Secret)
+	}
+	return &Authenticator[U]{users: users, revocations: revocations, keys: keys, config: config, now: time.Now}
+}
+
+// IssueToken signs a new access token for user, who has just signed in. The token belongs to no
+// session; Login and SocialLogin record one for the client when sessions are tracked.
+func (a *Authenticator[U]) IssueToken(user U) (string, error) {
+	return a.issue(user, nil, "")
+}
+
+// SetSessions makes Login and SocialLogin record a session in sessions for every sign-in, with the
+// client's user agent and IP address, and name it in the sid claim of the tokens they issue. Refresh
+// records activity on the session, and once it is revoked its tokens no longer verify. A session
+// ends MaxSessionAge after sign-in, when its tokens can no longer be refreshed.
+func (a *Authenticator[U]) SetSessions(sessions goat.SessionStore) {
+	a.sessions = sessions
+}
+
+// signIn issues the first access token of user, who has just signed in with the client of c,
+// recording a session for it when sessions are tracked.
+func (a *Authenticator[U]) signIn(c *gin.Context, user U) (string, error) {
+	if a.sessions == nil {
+		return a.IssueToken(user)
+	}
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := a.now()
+	err = a.sessions.ForTenant(user.GetUser().TenantID).Create(&models.Session{
+		ID:         id,
+		UserID:     user.GetUser().ID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(a.maxSessionAge()),
+		UserAgent:  truncate(c.Request.UserAgent(), maxUserAgentLength),
+		IP:         c.ClientIP(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return a.issue(user, gojwt.NewNumericDate(now), id)
+}
+
+// issue signs a new access token for user, who signed in at authTime, or now if it is nil, with
+// the session sessionID, if any.
+func (a *Authenticator[U]) issue(user U, authTime *gojwt.NumericDate, sessionID string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now, err := a.issuedAt(user.GetUser())
+	if err != nil {
+		return "", err
+	}
+	if authTime == nil {
+		authTime = gojwt.NewNumericDate(now)
+	}
+	claims := Claims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(user.GetUser().ID), 10),
+			Issuer:    a.config.Issuer,
+			IssuedAt:  gojwt.NewNumericDate(now),
+			NotBefore: gojwt.NewNumericDate(now),
+			ExpiresAt: gojwt.NewNumericDate(now.Add(a.config.TokenTTL)),
+		},
+		Tenant:    user.GetUser().TenantID,
+		AuthTime:  authTime,
+		SessionID: sessionID,
+	}
+	if a.config.Audience != "" {
+		claims.Audience = gojwt.ClaimStrings{a.config.Audience}
+	}
+	return a.keys.Sign(claims)
+}
+
+// issuedAt returns the time a token issued now for user is issued at. iat has one second
+// resolution, and Verify rejects tokens issued in the second of a revocation, before or after it;
+// so once the user's tokens are revoked, issuing waits for the next second.
+func (a *Authenticator[U]) issuedAt(user *models.User) (time.Time, error) {
+	now := a.now()
+	validAfter, err := a.revocations.ForTenant(user.TenantID).TokensValidAfter(user.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if second := now.Truncate(time.Second); second.Before(validAfter) && !now.Before(validAfter) {
+		next := second.Add(time.Second)
+		time.Sleep(next.Sub(now))
+		return next, nil
+	}
+	return now, nil
+}
+
+// Login checks the credentials against the users of the request's tenant and issues an access token.
+func (a *Authenticator[U]) Login(c *gin.Context, email, password string) (U, string, error) {
+	user, err := tenant.Users(c, a.users).Login(email, password)
+	if err != nil {
+		var zero U
+		return zero, "", err
+	}
+	token, err := a.signIn(c, user)
+	if err != nil {
+		var zero U
+		return zero, "", err
+	}
+	return user, token, nil
+}
+
+// Verify parses and validates a token: signature, issuer, audience and lifetime, then the
+// denylist, the user's watermark and, for tokens of a tracked session, that it was not revoked.
+func (a *Authenticator[U]) Verify(token string) (*Claims, error) {
+	opts := []gojwt.ParserOption{
+		gojwt.WithValidMethods(a.keys.Algorithms()),
+		gojwt.WithIssuer(a.config.Issuer),
+		gojwt.WithIssuedAt(),
+		gojwt.WithLeeway(a.config.Leeway),
+		gojwt.WithTimeFunc(a.now),
+		gojwt.WithExpirationRequired(),
+	}
+	if a.config.Audience != "" {
+		opts = append(opts, gojwt.WithAudience(a.config.Audience))
+	}
+
+	claims := &Claims{}
+	_, err := gojwt.ParseWithClaims(token, claims, a.keys.Keyfunc, opts...)
+	if err != nil {
+		if errors.Is(err, gojwt.ErrTokenExpired) {
+			return nil, goat.ErrExpiredToken
+		}
+		return nil, goat.ErrInvalidToken
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, goat.ErrInvalidToken
+	}
+
+	revocations := a.revocations.ForTenant(claims.Tenant)
+	revoked, err := revocations.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, goat.ErrInvalidToken
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	validAfter, err := revocations.TokensValidAfter(userID)
+	if err != nil {
+		return nil, err
+	}
+	// iat has one second resolution, so a token issued in the second of the revocation, even just
+	// after it, is rejected: it cannot be told apart from one issued just before.
+	if claims.IssuedAt.Time.Before(validAfter) {
+		return nil, goat.ErrInvalidToken
+	}
+
+	if claims.SessionID != "" && a.sessions != nil {
+		_, err := a.sessions.ForTenant(claims.Tenant).Get(claims.SessionID)
+		if errors.Is(err, goat.ErrSessionNotFound) {
+			return nil, goat.ErrInvalidToken
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// Authenticate implements goat.Authenticator. It validates the bearer token of the request and
+// returns its user.
+func (a *Authenticator[U]) Authenticate(c *gin.Context) (U, error) {
+	user, _, err := a.authenticate(c)
+	return user, err
+}
+
+// Refresh validates the request's token, revokes it and issues a replacement. The replacement keeps
+// the time the user signed in and their session, and once that is longer ago than the maximum
+// session age, Refresh returns ErrExpiredToken: a stolen token cannot be renewed forever.
+func (a *Authenticator[U]) Refresh(c *gin.Context) (U, string, error) {
+	var zero U
+	user, claims, err := a.authenticate(c)
+	if err != nil {
+		return zero, "", err
+	}
+	authTime := claims.AuthTime
+	if authTime == nil {
+		authTime = claims.IssuedAt // Issued before auth_time was recorded.
+	}
+	if a.now().Sub(authTime.Time) > a.maxSessionAge() {
+		return zero, "", goat.ErrExpiredToken
+	}
+	if claims.SessionID != "" && a.sessions != nil {
+		if err := a.sessions.ForTenant(claims.Tenant).Touch(claims.SessionID, a.now()); err != nil {
+			return zero, "", err
+		}
+	}
+	if err := a.RevokeToken(claims); err != nil {
+		return zero, "", err
+	}
+	token, err := a.issue(user, authTime, claims.SessionID)
+	if err != nil {
+		return zero, "", err
+	}
+	return user, token, nil
+}
+
+// maxSessionAge returns the configured MaxSessionAge, or DefaultMaxSessionAge.
+func (a *Authenticator[U]) maxSessionAge() time.Duration {
+	if a.config.MaxSessionAge == 0 {
+		return DefaultMaxSessionAge
+	}
+	return a.config.MaxSessionAge
+}
+
+// RefreshAuthToken implements goat.Authenticator. The new token is returned in the
+// RefreshedTokenHeader response header.
+func (a *Authenticator[U]) RefreshAuthToken(c *gin.Context) (U, error) {
+	user, token, err := a.Refresh(c)
+	if err != nil {
+		return user, err
+	}
+	c.Header(RefreshedTokenHeader, token)
+	return user, nil
+}
+
+// SetSocialLogin enables SocialLogin, completing provider sign-ins with social.
+func (a *Authenticator[U]) SetSocialLogin(social goat.SocialLogin[U]) {
+	a.social = social
+}
+
+// SocialLogin implements goat.Authenticator. It completes a provider sign-in started with the
+// SocialLogin set by SetSocialLogin and returns a token for the user in the RefreshedTokenHeader
+// response header.
+func (a *Authenticator[U]) SocialLogin(c *gin.Context, provider string) (U, error) {
+	var zero U
+	if a.social == nil {
+		return zero, goat.ErrUnsupportedProvider
+	}
+	user, err := a.social.Complete(c, provider)
+	if err != nil {
+		return zero, err
+	}
+	token, err := a.signIn(c, user)
+	if err != nil {
+		return zero, err
+	}
+	c.Header(RefreshedTokenHeader, token)
+	return user, nil
+}
+
+// RevokeToken denylists the token with the given claims until it expires.
+func (a *Authenticator[U]) RevokeToken(claims *Claims) error {
+	return a.revocations.ForTenant(claims.Tenant).RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeAllTokens invalidates every token issued to user so far, and any issued in the rest of the
+// current second, and ends their sessions. Call it after a password change or when an account is
+// compromised; the user services do so themselves on a password reset once given the revocation
+// store with SetRevocations.
+func (a *Authenticator[U]) RevokeAllTokens(user U) error {
+	u := user.GetUser()
+	if err := a.revocations.ForTenant(u.TenantID).RevokeUserTokens(u.ID, a.now()); err != nil {
+		return err
+	}
+	if a.sessions != nil {
+		return a.sessions.ForTenant(u.TenantID).DeleteByUser(u.ID, "")
+	}
+	return nil
+}
+
+// ForTenant implements goat.SessionService.
+func (a *Authenticator[U]) ForTenant(tenantID string) goat.SessionService {
+	scoped := *a
+	if a.sessions != nil {
+		scoped.sessions = a.sessions.ForTenant(tenantID)
+	}
+	return &scoped
+}
+
+// ListSessions implements goat.SessionService. Without SetSessions there are none.
+func (a *Authenticator[U]) ListSessions(userID uint) ([]*models.Session, error) {
+	if a.sessions == nil {
+		return []*models.Session{}, nil
+	}
+	return a.sessions.ListByUser(userID)
+}
+
+// RevokeSession implements goat.SessionService. The session's tokens stop verifying at once.
+func (a *Authenticator[U]) RevokeSession(id string) error {
+	if a.sessions == nil {
+		return goat.ErrSessionNotFound
+	}
+	return a.sessions.Delete(id)
+}
+
+// RevokeAllSessions implements goat.SessionService.
+func (a *Authenticator[U]) RevokeAllSessions(userID uint, exceptCurrent string) error {
+	if a.sessions == nil {
+		return nil
+	}
+	return a.sessions.DeleteByUser(userID, exceptCurrent)
+}
+
+// TenantResolver returns a tenant.Resolver that reads the tenant claim of the request's bearer token.
+// Requests without a valid token name no tenant.
+func (a *Authenticator[U]) TenantResolver() tenant.Resolver {
+	return func(c *gin.Context) (string, error) {
+		token, err := bearerToken(c.Request)
+		if err != nil {
+			return "", nil
+		}
+		claims, err := a.Verify(token)
+		if err != nil {
+			return "", nil
+		}
+		return claims.Tenant, nil
+	}
+}
+
+// Middleware authenticates every request with Authenticate, aborting with 401 when the bearer token
+// is missing or invalid. The user and claims are available to later handlers through User and CurrentClaims.
+func (a *Authenticator[U]) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, claims, err := a.authenticate(c)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		c.Set(userKey, user)
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// User returns the user stored in c by Middleware.
+func User[U models.Account](c *gin.Context) (U, bool) {
+	user, ok := c.Get(userKey)
+	if !ok {
+		var zero U
+		return zero, false
+	}
+	u, ok := user.(U)
+	return u, ok
+}
+
+// CurrentClaims returns the claims of the token stored in c by Middleware.
+func CurrentClaims(c *gin.Context) (*Claims, bool) {
+	claims, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	cl, ok := claims.(*Claims)
+	return cl, ok
+}
+
+func (a *Authenticator[U]) authenticate(c *gin.Context) (U, *Claims, error) {
+	var zero U
+	token, err := bearerToken(c.Request)
+	if err != nil {
+		return zero, nil, err
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		return zero, nil, err
+	}
+	if claims.Tenant != tenant.ID(c) {
+		return zero, nil, goat.ErrTenantMismatch
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return zero, nil, err
+	}
+	user, err := tenant.Users(c, a.users).GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, goat.ErrUserNotFound) {
+			// Tokens of deleted users are no longer valid.
+			return zero, nil, goat.ErrInvalidToken
+		}
+		return zero, nil, err
+	}
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		return zero, nil, err
+	}
+	return user, claims, nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", goat.ErrMissingToken
+	}
+	return token, nil
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// newTokenID returns 16 random bytes, base64url encoded, for use as a jti or session ID.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,144 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// newTestAuthenticator returns an HS256 authenticator whose clock is at *now.
+func newTestAuthenticator(revocations goat.RevocationStore, now *time.Time) *Authenticator[*models.User] {
+	a := NewAuthenticator[*models.User](nil, revocations, Config{
+		Secret:   []byte("0123456789abcdef0123456789abcdef"),
+		Issuer:   "goat",
+		TokenTTL: 15 * time.Minute,
+	})
+	a.now = func() time.Time { return *now }
+	return a
+}
+
+// tokenIssuedAt signs a token for user with the given iat, as if issued then.
+func tokenIssuedAt(t *testing.T, a *Authenticator[*models.User], user *models.User, iat time.Time) string {
+	t.Helper()
+	token, err := a.keys.Sign(Claims{RegisteredClaims: gojwt.RegisteredClaims{
+		ID:        "jti-" + iat.Format(time.RFC3339Nano),
+		Subject:   "1",
+		Issuer:    a.config.Issuer,
+		IssuedAt:  gojwt.NewNumericDate(iat),
+		ExpiresAt: gojwt.NewNumericDate(iat.Add(a.config.TokenTTL)),
+	}, Tenant: user.TenantID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1}
+	revocations := repository.NewMemoryRevocationStore()
+	a := newTestAuthenticator(revocations, &now)
+	other := NewAuthenticator[*models.User](nil, revocations, Config{Secret: []byte("another-secret-another-secret-!!"), Issuer: "goat", TokenTTL: time.Minute})
+	other.now = a.now
+
+	valid, err := a.IssueToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := a.IssueToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.Verify(revoked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RevokeToken(claims); err != nil {
+		t.Fatal(err)
+	}
+	forged, err := other.IssueToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		at      time.Duration // Time since issue at which the token is verified.
+		wantErr error
+	}{
+		{"valid", valid, time.Minute, nil},
+		{"expired", valid, time.Hour, goat.ErrExpiredToken},
+		{"denylisted", revoked, time.Minute, goat.ErrInvalidToken},
+		{"signed with another key", forged, 0, goat.ErrInvalidToken},
+		{"malformed", "not-a-token", 0, goat.ErrInvalidToken},
+	}
+	issued := now
+	for _, tt := range tests {
+		now = issued.Add(tt.at)
+		_, err := a.Verify(tt.token)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Verify error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerifyWatermark(t *testing.T) {
+	second := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1}
+	revocations := repository.NewMemoryRevocationStore()
+	now := second.Add(100 * time.Millisecond)
+	a := newTestAuthenticator(revocations, &now)
+
+	before, err := a.IssueToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = second.Add(500 * time.Millisecond)
+	if err := a.RevokeAllTokens(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token issued later in the second of the revocation carries the same iat as one issued
+	// before it, so it is rejected too.
+	sameSecond := tokenIssuedAt(t, a, user, second.Add(900*time.Millisecond))
+	// Issuing waits for the next second, so a token issued right after the revocation is valid.
+	now = second.Add(900 * time.Millisecond)
+	after, err := a.IssueToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextSecond := tokenIssuedAt(t, a, user, second.Add(time.Second))
+
+	now = second.Add(2 * time.Second)
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issued before the revocation", before, false},
+		{"issued in the second of the revocation", sameSecond, false},
+		{"issued after the revocation", after, true},
+		{"issued in the next second", nextSecond, true},
+	}
+	for _, tt := range tests {
+		_, err := a.Verify(tt.token)
+		if tt.valid && err != nil {
+			t.Errorf("%s: Verify error = %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, goat.ErrInvalidToken) {
+			t.Errorf("%s: Verify error = %v, want %v", tt.name, err, goat.ErrInvalidToken)
+		}
+	}
+
+	// The watermark is kept per tenant.
+	elsewhere := &models.User{ID: 1, TenantID: "acme"}
+	if _, err := a.Verify(tokenIssuedAt(t, a, elsewhere, second.Add(100*time.Millisecond))); err != nil {
+		t.Errorf("token of the same user ID in another tenant: Verify error = %v", err)
+	}
+}
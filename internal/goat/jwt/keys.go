@@ -0,0 +1,470 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms for managed keys.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// ErrNoSigningKey is returned when a KeyManager has no active key to sign with.
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// minMissReloadInterval is how often, at most, a token signed by an unknown kid makes a KeyManager
+// reload its keys, so that forged kids cannot turn into a store query per request.
+const minMissReloadInterval = 10 * time.Second
+
+// KeySet signs tokens and resolves the keys that verify them.
+type KeySet interface {
+	Sign(claims gojwt.Claims) (string, error)
+	Keyfunc(token *gojwt.Token) (interface{}, error)
+	Algorithms() []string // Algorithms accepted when verifying.
+}
+
+// hmacKeySet signs and verifies HS256 tokens with a shared secret.
+type hmacKeySet []byte
+
+func (k hmacKeySet) Sign(claims gojwt.Claims) (string, error) {
+	return gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims).SignedString([]byte(k))
+}
+
+func (k hmacKeySet) Keyfunc(*gojwt.Token) (interface{}, error) {
+	return []byte(k), nil
+}
+
+func (k hmacKeySet) Algorithms() []string {
+	return []string{gojwt.SigningMethodHS256.Alg()}
+}
+
+// KeyManagerConfig controls key generation, rotation and storage.
+type KeyManagerConfig struct {
+	Algorithm          string        // AlgorithmRS256 or AlgorithmEdDSA.
+	RotationInterval   time.Duration // How long a key signs tokens before it is replaced.
+	VerificationPeriod time.Duration // How long a rotated key keeps verifying tokens; at least the token TTL.
+	EncryptionKey      []byte        // 32-byte AES-256 key that encrypts private keys at rest.
+	RSABits            int           // RSA modulus size; defaults to 2048.
+}
+
+// managedKey is a decrypted signing key.
+type managedKey struct {
+	record  *models.SigningKey
+	private crypto.Signer
+}
+
+// KeyManager holds a set of asymmetric signing keys identified by kid. One key is active and
+// signs new tokens; keys rotated out keep verifying tokens for the verification period, after which
+// they are retired. Keys are persisted, encrypted, in a goat.KeyStore, so every instance sharing the
+// store signs and verifies with the same keys. KeyManager implements KeySet.
+type KeyManager struct {
+	store  goat.KeyStore
+	config KeyManagerConfig
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys []*managedKey // Non-retired keys, oldest first.
+
+	missMu     sync.Mutex
+	lastMissAt time.Time // When an unknown kid last made the manager reload its keys.
+}
+
+var _ KeySet = (*KeyManager)(nil)
+
+// NewKeyManager loads the keys from store, generating the first key when there is no active one.
+func NewKeyManager(store goat.KeyStore, config KeyManagerConfig) (*KeyManager, error) {
+	if config.Algorithm != AlgorithmRS256 && config.Algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", config.Algorithm)
+	}
+	if len(config.EncryptionKey) != 32 {
+		return nil, errors.New("key encryption key must be 32 bytes")
+	}
+	if config.RSABits == 0 {
+		config.RSABits = 2048
+	}
+
+	m := &KeyManager{store: store, config: config, now: time.Now}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	if m.active() == nil {
+		if err := m.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Reload reads the keys from the store, picking up rotations made by other instances.
+func (m *KeyManager) Reload() error {
+	records, err := m.store.ListKeys()
+	if err != nil {
+		return err
+	}
+	keys := make([]*managedKey, 0, len(records))
+	for _, record := range records {
+		if record.Status == models.KeyStatusRetired {
+			continue
+		}
+		private, err := m.decrypt(record)
+		if err != nil {
+			return fmt.Errorf("decrypt signing key %s: %w", record.ID, err)
+		}
+		keys = append(keys, &managedKey{record: record, private: private})
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return nil
+}
+
+// Rotate generates a new active key. The previous active key keeps verifying tokens until the
+// verification period has passed; keys past it are retired.
+func (m *KeyManager) Rotate() error {
+	private, err := m.generate()
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+	encrypted, err := m.encrypt(der)
+	if err != nil {
+		return err
+	}
+	now := m.now().UTC()
+	record := &models.SigningKey{
+		ID:         keyID(private.Public()),
+		Algorithm:  m.config.Algorithm,
+		Status:     models.KeyStatusActive,
+		PrivateKey: encrypted,
+		CreatedAt:  now,
+	}
+	if err := m.store.SaveKey(record); err != nil {
+		return err
+	}
+
+	// The keys in memory change only once the store has every change, so a failed write leaves the
+	// previous active key signing.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]*managedKey, len(m.keys), len(m.keys)+1)
+	for i, k := range m.keys {
+		keys[i] = k
+		if k.record.Status == models.KeyStatusActive {
+			rotated := *k.record
+			rotated.Status = models.KeyStatusRotated
+			rotated.RotatedAt = &now
+			if err := m.store.SaveKey(&rotated); err != nil {
+				return err
+			}
+			keys[i] = &managedKey{record: &rotated, private: k.private}
+		}
+	}
+	m.keys = append(keys, &managedKey{record: record, private: private})
+	return m.retire(now)
+}
+
+// Run rotates keys on schedule until ctx is cancelled. Every interval it reloads the keys from the
+// store, rotates the active key once it is older than the rotation interval and retires expired keys.
+// Failures are reported to onError, if not nil, and the work is retried sooner, backing off up to
+// interval; the keys already loaded keep signing and verifying meanwhile.
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if err := m.maintain(); err != nil {
+				failures++
+				if onError != nil {
+					onError(err)
+				}
+				timer.Reset(goat.RetryDelay(failures, interval))
+				continue
+			}
+			failures = 0
+			timer.Reset(interval)
+		}
+	}
+}
+
+// maintain does one round of Run's work.
+func (m *KeyManager) maintain() error {
+	if err := m.Reload(); err != nil {
+		return err
+	}
+	active := m.active()
+	if active == nil || m.now().Sub(active.record.CreatedAt) >= m.config.RotationInterval {
+		return m.Rotate()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retire(m.now().UTC())
+}
+
+// Sign implements KeySet. The token header carries the kid of the active key.
+func (m *KeyManager) Sign(claims gojwt.Claims) (string, error) {
+	k := m.active()
+	if k == nil {
+		return "", ErrNoSigningKey
+	}
+	token := gojwt.NewWithClaims(signingMethod(k.record.Algorithm), claims)
+	token.Header["kid"] = k.record.ID
+	return token.SignedString(k.private)
+}
+
+// Keyfunc implements KeySet. It accepts tokens signed by any key that is not retired. A kid it does
+// not know may belong to a key another instance has just rotated in, so the keys are reloaded once,
+// at most every minMissReloadInterval, before the token is rejected.
+func (m *KeyManager) Keyfunc(token *gojwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key := m.verificationKey(kid, token.Method.Alg()); key != nil {
+		return key, nil
+	}
+	if kid == "" || !m.reloadOnMiss() {
+		return nil, goat.ErrInvalidToken
+	}
+	if key := m.verificationKey(kid, token.Method.Alg()); key != nil {
+		return key, nil
+	}
+	return nil, goat.ErrInvalidToken
+}
+
+// verificationKey returns the public key of the non-retired key kid, or nil.
+func (m *KeyManager) verificationKey(kid, alg string) crypto.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.keys {
+		if k.record.ID == kid && alg == k.record.Algorithm {
+			return k.private.Public()
+		}
+	}
+	return nil
+}
+
+// reloadOnMiss reloads the keys unless an unknown kid already did within minMissReloadInterval. It
+// reports whether the keys were reloaded.
+func (m *KeyManager) reloadOnMiss() bool {
+	m.missMu.Lock()
+	defer m.missMu.Unlock()
+	now := m.now()
+	if !m.lastMissAt.IsZero() && now.Sub(m.lastMissAt) < minMissReloadInterval {
+		return false
+	}
+	m.lastMissAt = now
+	return m.Reload() == nil
+}
+
+// Algorithms implements KeySet.
+func (m *KeyManager) Algorithms() []string {
+	return []string{AlgorithmRS256, AlgorithmEdDSA}
+}
+
+// JWK is a public JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"` // EC and OKP keys.
+	X         string `json:"x,omitempty"`   // EC x coordinate or OKP public key.
+	Y         string `json:"y,omitempty"`   // EC y coordinate.
+	N         string `json:"n,omitempty"`   // RSA modulus.
+	E         string `json:"e,omitempty"`   // RSA exponent.
+}
+
+// PublicKey decodes the key. RSA, EC (P-256, P-384, P-521) and Ed25519 keys are supported.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// JWKS is a JSON Web Key Set document, as served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of every key that is not retired.
+func (m *KeyManager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range m.keys {
+		jwk := JWK{KeyID: k.record.ID, Use: "sig", Algorithm: k.record.Algorithm}
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// active returns the newest active key.
+func (m *KeyManager) active() *managedKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if m.keys[i].record.Status == models.KeyStatusActive {
+			return m.keys[i]
+		}
+	}
+	return nil
+}
+
+// retire marks rotated keys past the verification period as retired. m.mu must be held.
+func (m *KeyManager) retire(now time.Time) error {
+	kept := make([]*managedKey, 0, len(m.keys))
+	for _, k := range m.keys {
+		if k.record.Status == models.KeyStatusRotated && now.Sub(*k.record.RotatedAt) >= m.config.VerificationPeriod {
+			retired := *k.record
+			retired.Status = models.KeyStatusRetired
+			if err := m.store.SaveKey(&retired); err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, k)
+	}
+	m.keys = kept
+	return nil
+}
+
+func (m *KeyManager) generate() (crypto.Signer, error) {
+	if m.config.Algorithm == AlgorithmEdDSA {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+	return rsa.GenerateKey(rand.Reader, m.config.RSABits)
+}
+
+// encrypt seals plaintext with AES-256-GCM, prefixing the random nonce.
+func (m *KeyManager) encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := m.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (m *KeyManager) decrypt(record *models.SigningKey) (crypto.Signer, error) {
+	gcm, err := m.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(record.PrivateKey) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := record.PrivateKey[:gcm.NonceSize()], record.PrivateKey[gcm.NonceSize():]
+	der, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+func (m *KeyManager) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func signingMethod(alg string) gojwt.SigningMethod {
+	if alg == AlgorithmEdDSA {
+		return gojwt.SigningMethodEdDSA
+	}
+	return gojwt.SigningMethodRS256
+}
+
+// keyID derives a kid from the public key, so every instance names a key the same way.
+func keyID(public crypto.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
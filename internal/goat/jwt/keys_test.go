@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// failingKeyStore fails SaveKey once saves have succeeded, counting down; a negative saves never
+// fails.
+type failingKeyStore struct {
+	*repository.MemoryKeyStore
+	saves int
+}
+
+func (s *failingKeyStore) SaveKey(key *models.SigningKey) error {
+	if s.saves == 0 {
+		return errors.New("store unavailable")
+	}
+	s.saves--
+	return s.MemoryKeyStore.SaveKey(key)
+}
+
+// newTestKeyManager returns a manager over store whose clock is at *now.
+func newTestKeyManager(t *testing.T, store goat.KeyStore, algorithm string, now *time.Time) *KeyManager {
+	t.Helper()
+	m, err := NewKeyManager(store, KeyManagerConfig{
+		Algorithm:          algorithm,
+		RotationInterval:   24 * time.Hour,
+		VerificationPeriod: time.Hour,
+		EncryptionKey:      make([]byte, 32),
+		RSABits:            1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+// kidOf returns the kid in the header of token.
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := gojwt.NewParser().ParseUnverified(token, &gojwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header["kid"].(string)
+}
+
+func verifies(m *KeyManager, token string) bool {
+	_, err := gojwt.ParseWithClaims(token, &gojwt.RegisteredClaims{}, m.Keyfunc, gojwt.WithValidMethods(m.Algorithms()))
+	return err == nil
+}
+
+func TestKeyRotationAndRetirement(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			m := newTestKeyManager(t, repository.NewMemoryKeyStore(), algorithm, &now)
+
+			old, err := m.Sign(&gojwt.RegisteredClaims{Subject: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			fresh, err := m.Sign(&gojwt.RegisteredClaims{Subject: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kidOf(t, old) == kidOf(t, fresh) {
+				t.Fatal("Rotate kept signing with the same key")
+			}
+			if !verifies(m, old) || !verifies(m, fresh) {
+				t.Fatal("tokens of the rotated and the active key do not verify")
+			}
+			if n := len(m.JWKS().Keys); n != 2 {
+				t.Fatalf("JWKS has %d keys during the verification period, want 2", n)
+			}
+
+			// Past the verification period the rotated key is retired, here and in the store.
+			now = now.Add(time.Hour)
+			if err := m.maintain(); err != nil {
+				t.Fatal(err)
+			}
+			if verifies(m, old) {
+				t.Error("token of a retired key verifies")
+			}
+			if !verifies(m, fresh) {
+				t.Error("token of the active key does not verify")
+			}
+			if err := m.Reload(); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(m.JWKS().Keys); n != 1 {
+				t.Errorf("JWKS has %d keys after retirement, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRotateKeepsSigningWhenTheStoreFails(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &failingKeyStore{MemoryKeyStore: repository.NewMemoryKeyStore(), saves: -1}
+	m := newTestKeyManager(t, store, AlgorithmEdDSA, &now)
+	before, err := m.Sign(&gojwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key is saved, but marking the active key rotated fails.
+	store.saves = 1
+	if err := m.Rotate(); err == nil {
+		t.Fatal("Rotate succeeded with a failing store")
+	}
+	after, err := m.Sign(&gojwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatalf("Sign after a failed rotation: %v", err)
+	}
+	if kidOf(t, before) != kidOf(t, after) {
+		t.Error("a failed rotation changed the signing key")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		keyType   string
+		check     func(JWK) bool
+	}{
+		{AlgorithmEdDSA, "OKP", func(k JWK) bool { return k.Curve == "Ed25519" && k.X != "" && k.N == "" }},
+		{AlgorithmRS256, "RSA", func(k JWK) bool { return k.N != "" && k.E == "AQAB" && k.X == "" }},
+	}
+	for _, tt := range tests {
+		now := time.Now()
+		m := newTestKeyManager(t, repository.NewMemoryKeyStore(), tt.algorithm, &now)
+		token, err := m.Sign(&gojwt.RegisteredClaims{Subject: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys := m.JWKS().Keys
+		if len(keys) != 1 {
+			t.Fatalf("%s: JWKS has %d keys, want 1", tt.algorithm, len(keys))
+		}
+		k := keys[0]
+		if k.KeyType != tt.keyType || k.Algorithm != tt.algorithm || k.Use != "sig" || k.KeyID != kidOf(t, token) || !tt.check(k) {
+			t.Errorf("%s: JWKS key = %+v", tt.algorithm, k)
+		}
+
+		// The published key verifies the manager's tokens.
+		public, err := k.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = gojwt.Parse(token, func(*gojwt.Token) (interface{}, error) { return public, nil })
+		if err != nil {
+			t.Errorf("%s: token does not verify with the published key: %v", tt.algorithm, err)
+		}
+	}
+}
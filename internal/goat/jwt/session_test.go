@@ -0,0 +1,204 @@
+package jwt
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// testUsers is a goat.UserService holding a single user, whose password is "secret".
+type testUsers struct {
+	goat.UserService[*models.User]
+	user *models.User
+}
+
+func (u testUsers) ForTenant(string) goat.UserService[*models.User] { return u }
+
+func (u testUsers) Login(email, password string) (*models.User, error) {
+	if email != u.user.Email || password != "secret" {
+		return nil, goat.ErrInvalidCredentials
+	}
+	return u.user, nil
+}
+
+func (u testUsers) GetUserByID(id uint) (*models.User, error) {
+	if id != u.user.ID {
+		return nil, goat.ErrUserNotFound
+	}
+	return u.user, nil
+}
+
+// testContext returns a context for a request from a client with the user agent and, if token is
+// set, the bearer token.
+func testContext(userAgent, token string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/token", nil)
+	c.Request.RemoteAddr = "192.0.2.1:4242"
+	c.Request.Header.Set("User-Agent", userAgent)
+	if token != "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c
+}
+
+// newSessionAuthenticator returns an authenticator for user that records sessions in a memory
+// store, and whose clock is at *now.
+func newSessionAuthenticator(user *models.User, now *time.Time) *Authenticator[*models.User] {
+	a := newTestAuthenticator(repository.NewMemoryRevocationStore(), now)
+	a.users = testUsers{user: user}
+	a.SetSessions(repository.NewMemorySessionStore())
+	return a
+}
+
+func TestLoginRecordsSession(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1, Email: "ada@example.com"}
+	a := newSessionAuthenticator(user, &now)
+
+	_, token, err := a.Login(testContext("laptop", ""), user.Email, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := a.ListSessions(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("ListSessions returned %d sessions, want 1", len(sessions))
+	}
+	s := sessions[0]
+	if s.ID != claims.SessionID || s.UserAgent != "laptop" || s.IP != "192.0.2.1" || !s.ExpiresAt.Equal(now.Add(DefaultMaxSessionAge)) {
+		t.Errorf("session = %+v, want ID %q, user agent laptop, IP 192.0.2.1 and expiry %v", s, claims.SessionID, now.Add(DefaultMaxSessionAge))
+	}
+
+	// Refreshing keeps the session and records the activity.
+	now = now.Add(time.Minute)
+	_, refreshed, err := a.Refresh(testContext("laptop", token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshedClaims, err := a.Verify(refreshed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshedClaims.SessionID != claims.SessionID {
+		t.Errorf("refreshed token has session %q, want %q", refreshedClaims.SessionID, claims.SessionID)
+	}
+	if s, err := a.sessions.Get(claims.SessionID); err != nil || !s.LastSeenAt.Equal(now) {
+		t.Errorf("session after refresh = %+v, %v, want last seen at %v", s, err, now)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1, Email: "ada@example.com"}
+
+	tests := []struct {
+		name       string
+		revoke     func(a *Authenticator[*models.User], first, second *Claims) error
+		wantFirst  bool // Whether the token of the first session still verifies.
+		wantSecond bool
+	}{
+		{
+			"one session",
+			func(a *Authenticator[*models.User], first, _ *Claims) error { return a.RevokeSession(first.SessionID) },
+			false, true,
+		},
+		{
+			"all but the current session",
+			func(a *Authenticator[*models.User], _, second *Claims) error {
+				return a.RevokeAllSessions(user.ID, second.SessionID)
+			},
+			false, true,
+		},
+		{
+			"all tokens",
+			func(a *Authenticator[*models.User], _, _ *Claims) error { return a.RevokeAllTokens(user) },
+			false, false,
+		},
+		{
+			"in another tenant",
+			func(a *Authenticator[*models.User], first, _ *Claims) error {
+				return a.ForTenant("acme").RevokeSession(first.SessionID)
+			},
+			true, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := now
+			a := newSessionAuthenticator(user, &now)
+			_, first, err := a.Login(testContext("laptop", ""), user.Email, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, second, err := a.Login(testContext("phone", ""), user.Email, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			firstClaims, err := a.Verify(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secondClaims, err := a.Verify(second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.revoke(a, firstClaims, secondClaims)
+			if err != nil && !errors.Is(err, goat.ErrSessionNotFound) {
+				t.Fatal(err)
+			}
+			now = now.Add(2 * time.Second)
+			for _, token := range []struct {
+				name  string
+				token string
+				want  bool
+			}{{"first", first, tt.wantFirst}, {"second", second, tt.wantSecond}} {
+				_, err := a.Verify(token.token)
+				if token.want && err != nil {
+					t.Errorf("%s token: Verify error = %v", token.name, err)
+				}
+				if !token.want && !errors.Is(err, goat.ErrInvalidToken) {
+					t.Errorf("%s token: Verify error = %v, want %v", token.name, err, goat.ErrInvalidToken)
+				}
+			}
+		})
+	}
+}
+
+func TestUntrackedSessions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1, Email: "ada@example.com"}
+	a := newTestAuthenticator(repository.NewMemoryRevocationStore(), &now)
+	a.users = testUsers{user: user}
+
+	_, token, err := a.Login(testContext("laptop", ""), user.Email, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != "" {
+		t.Errorf("token has session %q without SetSessions", claims.SessionID)
+	}
+	if sessions, err := a.ListSessions(user.ID); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions = %v, %v, want none", sessions, err)
+	}
+	if err := a.RevokeSession("any"); !errors.Is(err, goat.ErrSessionNotFound) {
+		t.Errorf("RevokeSession error = %v, want %v", err, goat.ErrSessionNotFound)
+	}
+}
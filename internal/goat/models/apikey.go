@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential a user creates for scripts and CI jobs. The full key is
+// "<ID>_<secret>"; the ID is shown in listings so users can tell their keys apart, and only a hash
+// of the secret is stored.
+type APIKey struct {
+	ID         string     `json:"id" bson:"id"`                         // Public prefix of the key, e.g. "goat_3kTq9xWb2LmA".
+	TenantID   string     `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the user; set by the store.
+	UserID     uint       `json:"user_id" bson:"user_id"`
+	Name       string     `json:"name" bson:"name"`
+	SecretHash string     `json:"-" bson:"secret_hash"` // SHA-256 of the secret, hex encoded.
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // Nil for keys that do not expire.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+}
+
+// Expired reports whether the key can no longer be used at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key grants scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return contains(k.Scopes, scope)
+}
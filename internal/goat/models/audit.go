@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Types of audit events.
+const (
+	EventLoginSucceeded    = "login.succeeded"
+	EventLoginFailed       = "login.failed"
+	EventUserRegistered    = "user.registered"
+	EventUserDeleted       = "user.deleted"
+	EventPasswordChanged   = "password.changed"
+	EventRoleChanged       = "role.changed"
+	EventOwnershipTransfer = "organization.ownership_transferred"
+	EventSessionRevoked    = "session.revoked"
+	EventTokenRevoked      = "token.revoked"
+	EventUserErased        = "user.erased" // The user's data was erased; the metadata holds the digest of the erasure report.
+)
+
+// AuditEvent records something that happened to an account. Events are only ever appended.
+type AuditEvent struct {
+	ID        string            `json:"id" bson:"id"`                         // Ordered by time, so sorting by ID sorts events by when they were recorded.
+	Type      string            `json:"type" bson:"type"`                     // One of the Event* constants, or an application-defined type.
+	ActorID   uint              `json:"actor_id,omitempty" bson:"actor_id"`   // User who acted; zero for anonymous requests, such as failed logins.
+	TargetID  uint              `json:"target_id,omitempty" bson:"target_id"` // User acted upon; the actor itself for most account events.
+	TenantID  string            `json:"tenant_id,omitempty" bson:"tenant_id"`
+	IP        string            `json:"ip,omitempty" bson:"ip"`
+	UserAgent string            `json:"user_agent,omitempty" bson:"user_agent"`
+	Metadata  map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"` // Event-specific details, e.g. the email of a failed login.
+	Redacted  map[string]string `json:"redacted,omitempty" bson:"redacted,omitempty"` // Digests of the personal fields removed by redaction, keyed by "ip", "user_agent" or "metadata." and the key.
+	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
+	Seq       uint64            `json:"seq" bson:"seq"`             // Position in the hash chain, counting from 1.
+	PrevHash  string            `json:"prev_hash" bson:"prev_hash"` // Hash of the previous event; empty for the first.
+	Hash      string            `json:"hash" bson:"hash"`           // Hash of this event's fields and PrevHash.
+}
+
+// AuditCheckpoint is a signed statement of the head of the audit hash chain. Events up to Seq can no
+// longer be rewritten without invalidating the checkpoint, even by someone able to recompute hashes.
+type AuditCheckpoint struct {
+	Seq       uint64    `json:"seq" bson:"seq"`             // Sequence number of the last event covered.
+	Hash      string    `json:"hash" bson:"hash"`           // Hash of that event.
+	Signature string    `json:"signature" bson:"signature"` // JWS over Seq and Hash.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AuditQuery selects audit events. Zero fields match everything; results are newest first.
+type AuditQuery struct {
+	Types    []string  // Match any of these types.
+	ActorID  uint      // Match events by this user.
+	TargetID uint      // Match events on this user.
+	TenantID string    // Match events in this tenant.
+	Email    string    // Match events whose metadata names this email, such as failed logins.
+	Since    time.Time // Match events recorded at or after this time.
+	Until    time.Time // Match events recorded before this time.
+	Before   string    // Match events recorded before the event with this ID; used to fetch the next page.
+	Limit    int       // Return at most this many events.
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// UserEventType identifies a change in a user's lifecycle.
+type UserEventType string
+
+// Types of user lifecycle events.
+const (
+	UserRegistered UserEventType = "user.registered"
+	UserUpdated    UserEventType = "user.updated"
+	UserDeleted    UserEventType = "user.deleted" // Soft-deleted; the user can be restored until purged.
+
+	UserPasswordReset UserEventType = "user.password_reset"
+
+	UserSuspended   UserEventType = "user.suspended"
+	UserDeactivated UserEventType = "user.deactivated"
+	UserReactivated UserEventType = "user.reactivated" // A suspended or deactivated user made active again.
+	UserRestored    UserEventType = "user.restored"    // A soft-deleted user made active again.
+
+	UserAnonymized UserEventType = "user.anonymized" // Personal data replaced with placeholders; the deactivated user is kept.
+	UserErased     UserEventType = "user.erased"     // Removed at once and for good, soft-deleted or not.
+)
+
+// UserEvent describes a change to a user, as seen by the hooks of a user service. Before-hooks see
+// the event before the change is saved, so a new user has no ID yet.
+type UserEvent[U Account] struct {
+	Type       UserEventType
+	User       U         // The user as registered or updated; for other types, as it was stored before the change.
+	Previous   U         // For UserUpdated, the user as it was stored before the update; zero otherwise.
+	TenantID   string    // Tenant of the service the change was made through.
+	OccurredAt time.Time // When the change was requested.
+}
+
+// UserHook handles a user lifecycle event. A before-hook vetoes the change by returning an error.
+type UserHook[U Account] func(event *UserEvent[U]) error
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Identity links a user to an account at an external identity provider. The provider and subject
+// together identify the external account; each can be linked to at most one user in each tenant.
+type Identity struct {
+	TenantID string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the user; set by the store.
+	UserID   uint      `json:"user_id" bson:"user_id"`
+	Provider string    `json:"provider" bson:"provider"` // Name of the provider, e.g. "google".
+	Subject  string    `json:"subject" bson:"subject"`   // The provider's stable identifier for the account.
+	Email    string    `json:"email" bson:"email"`       // Email reported by the provider when the identity was linked.
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+}
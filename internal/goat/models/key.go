@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Signing key statuses.
+const (
+	KeyStatusActive  = "active"  // Signs new tokens and verifies existing ones.
+	KeyStatusRotated = "rotated" // Verifies tokens signed before the last rotation.
+	KeyStatusRetired = "retired" // No longer trusted; kept only for the record.
+)
+
+// SigningKey is a token signing key as persisted by a goat.KeyStore. The private key is stored
+// PKCS #8 encoded and encrypted, never in the clear.
+type SigningKey struct {
+	ID         string     `json:"kid" bson:"kid"`
+	Algorithm  string     `json:"alg" bson:"alg"`
+	Status     string     `json:"status" bson:"status"`
+	PrivateKey []byte     `json:"-" bson:"private_key"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty" bson:"rotated_at,omitempty"` // When the key stopped signing.
+}
@@ -1,49 +1,178 @@
 package models
 
-// CustomFields defines an interface for user-specific data
-type CustomFields interface {
-	// Get and set the user fields (optional)
-	GetName() string
-	SetName(name string)
-	GetBio() string
-	SetBio(bio string)
-	// define methods for accessing or manipulating custom data here (optional)
-}
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
 
-type UserCustomFields struct {
-	Name *string `json:"name,omitempty"`
-	Bio  *string `json:"bio,omitempty"`
+// Account is the constraint for user types handled by goat's repositories and services.
+// It is satisfied by *User and by a pointer to any application struct that embeds User,
+// so applications can add their own profile fields:
+//
+//	type Member struct {
+//		models.User `bson:",inline"`
+//		Company     string `json:"company" bson:"company"`
+//	}
+//
+// The bson inline tag keeps the User fields at the top level of the MongoDB document.
+type Account interface {
+	GetUser() *User
 }
 
+// Account states. Users stored before states existed have no status and are active.
+const (
+	AccountActive      = "active"
+	AccountSuspended   = "suspended"   // Locked by an administrator.
+	AccountDeactivated = "deactivated" // Closed by the user, who can have it reactivated.
+	AccountDeleted     = "deleted"     // Soft-deleted: hidden from everything but restore and purge.
+)
+
 type User struct {
-	ID           uint         `json:"id"`
-	Email        string       `json:"email"`
-	Password     string       `json:"password"`
-	CustomFields CustomFields `json:"custom_fields,omitempty"`
+	ID        uint       `json:"id" bson:"id"`
+	TenantID  string     `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant the user belongs to; empty in single-tenant applications.
+	Email     string     `json:"email" bson:"email"`
+	Password  string     `json:"password,omitempty" bson:"password"`
+	Role      string     `json:"role,omitempty" bson:"role"`                       // Application-defined role; goat stores it and filters by it, nothing more.
+	Status    string     `json:"status,omitempty" bson:"status"`                   // One of the account states; changed only through goat.AccountService.
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`                     // When the user registered; set by Register.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // When the user was soft-deleted.
+
+	unusablePassword bool // Set by SetUnusablePassword; never read from input or storage.
+}
+
+// EmailConflict reports a stored email that NormalizeStoredEmails could not normalize, because
+// another user of the tenant already has the normalized address. The application resolves it, for
+// example by merging the accounts or giving one of them another address with UpdateUser.
+type EmailConflict struct {
+	TenantID   string `json:"tenant_id,omitempty"`
+	UserID     uint   `json:"user_id"` // The user left with the stored email.
+	Email      string `json:"email"`   // The stored email, as it was.
+	Normalized string `json:"normalized"`
+	HeldBy     uint   `json:"held_by"` // The user who has the normalized address.
+}
+
+// Orders ListUsers can return users in. Prefixed with "-", they are descending.
+const (
+	SortByCreatedAt = "created_at" // The default.
+	SortByEmail     = "email"
+)
+
+// UserQuery selects users for ListUsers. Zero fields match everything; soft-deleted users are never
+// listed.
+type UserQuery struct {
+	EmailPrefix  string    // Match users whose email starts with this; normalized like an email.
+	Status       string    // Match users in this account state: active, suspended or deactivated.
+	Role         string    // Match users with this role.
+	CreatedSince time.Time // Match users created at or after this time.
+	CreatedUntil time.Time // Match users created before this time.
+	Sort         string    // One of the SortBy constants, optionally prefixed with "-"; ties are broken by ID.
+	Cursor       string    // The Next of the previous page; the other fields must be the same as for that page.
+	Limit        int       // Return at most this many users; defaults to 50, and is capped at 500.
+}
+
+// UserPage is one page of users returned by ListUsers.
+type UserPage[U Account] struct {
+	Users []U    `json:"users"`
+	Next  string `json:"next,omitempty"` // Cursor for the next page; empty on the last page.
+}
+
+// ErasedEmail returns the placeholder address an anonymized user with id is given. It is under the
+// reserved .invalid domain, so mail is never sent to it.
+func ErasedEmail(id uint) string {
+	return fmt.Sprintf("erased-%d@erased.invalid", id)
+}
+
+// GetUser implements the Account interface
+func (u *User) GetUser() *User {
+	return u
+}
+
+// IsActive reports whether u may sign in.
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == AccountActive
+}
+
+// unusablePasswordPrefix starts a stored password that no input matches. Encoded hashes never
+// start with it.
+const unusablePasswordPrefix = "!"
+
+// SetUnusablePassword gives u a password that no input matches, for accounts that only sign in
+// through linked identities. Repositories store it as is instead of hashing it; resetting the
+// password makes it usable again.
+func (u *User) SetUnusablePassword() error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	u.Password = unusablePasswordPrefix + base64.RawURLEncoding.EncodeToString(b)
+	u.unusablePassword = true
+	return nil
+}
+
+// WantsUnusablePassword reports whether SetUnusablePassword was called on u. Only then do
+// repositories store Password unhashed: a password that came from a client is always hashed, even
+// one that looks like an unusable password.
+func (u *User) WantsUnusablePassword() bool {
+	return u.unusablePassword
 }
 
-// GetName implements the CustomFields interface
-func (f *UserCustomFields) GetName() string {
-	if f.Name == nil {
-	  return ""
+// HasUsablePassword reports whether u, as read from a repository, can sign in with a password. It
+// looks at the stored password, so it says nothing about a user that has not been stored.
+func (u *User) HasUsablePassword() bool {
+	return !strings.HasPrefix(u.Password, unusablePasswordPrefix)
+}
+
+// New allocates a zero value of the account type U, which must be a pointer type.
+func New[U Account]() U {
+	t := reflect.TypeOf((*U)(nil)).Elem()
+	if t.Kind() != reflect.Pointer {
+		panic("models: account type " + t.String() + " must be a pointer")
 	}
-	return *f.Name
-  }
+	return reflect.New(t.Elem()).Interface().(U)
+}
 
-  // SetName implements the CustomFields interface
-  func (f *UserCustomFields) SetName(name string) {
-	f.Name = &name
-  }
+// MarshalCustomFields encodes the application-defined fields of an account as a JSON object,
+// leaving out the fields of the embedded User. It returns nil when there are no such fields,
+// so that plain *User values are stored with a NULL custom_fields column.
+func MarshalCustomFields(account Account) ([]byte, error) {
+	fields, err := toJSONObject(account)
+	if err != nil {
+		return nil, err
+	}
+	base, err := toJSONObject(account.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	for key := range base {
+		delete(fields, key)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
 
-  // GetBio implements the CustomFields interface
-  func (f *UserCustomFields) GetBio() string {
-	if f.Bio == nil {
-	  return ""
+// UnmarshalCustomFields decodes a JSON object produced by MarshalCustomFields into account.
+func UnmarshalCustomFields(data []byte, account Account) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
 	}
-	return *f.Bio
-  }
+	return json.Unmarshal(data, account)
+}
 
-  // SetBio implements the CustomFields interface
-  func (f *UserCustomFields) SetBio(bio string) {
-	f.Bio = &bio
-  }
\ No newline at end of file
+func toJSONObject(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// OAuth 2.0 grant types.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Client is an application registered to sign users in through goat's OpenID Connect provider.
+type Client struct {
+	ID           string    `json:"client_id" bson:"client_id"`
+	SecretHash   string    `json:"-" bson:"secret_hash"` // SHA-256 of the client secret, hex encoded; empty for public clients.
+	Name         string    `json:"client_name" bson:"name"`
+	Public       bool      `json:"public" bson:"public"` // Cannot keep a secret, e.g. a CLI or single-page app; must use PKCE.
+	RedirectURIs []string  `json:"redirect_uris" bson:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types" bson:"grant_types"`
+	Scopes       []string  `json:"scopes" bson:"scopes"`             // Scopes the client may request.
+	SkipConsent  bool      `json:"skip_consent" bson:"skip_consent"` // First-party client; users are not asked for consent.
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AllowsGrant reports whether the client may use grant type t.
+func (c *Client) AllowsGrant(t string) bool {
+	return contains(c.GrantTypes, t)
+}
+
+// Consent records the scopes a user has allowed a client to access.
+type Consent struct {
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the user; set by the store.
+	UserID    uint      `json:"user_id" bson:"user_id"`
+	ClientID  string    `json:"client_id" bson:"client_id"`
+	Scopes    []string  `json:"scopes" bson:"scopes"`
+	GrantedAt time.Time `json:"granted_at" bson:"granted_at"`
+}
+
+// Covers reports whether the consent includes all of scopes.
+func (c *Consent) Covers(scopes []string) bool {
+	for _, s := range scopes {
+		if !contains(c.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Grant is an authorization code or refresh token issued by the OpenID Connect provider. Only a hash
+// of the code or token is stored, and a grant is deleted when it is redeemed.
+type Grant struct {
+	ID            string    `json:"id" bson:"id"`                         // SHA-256 of the code or token, hex encoded.
+	Type          string    `json:"type" bson:"type"`                     // GrantAuthorizationCode or GrantRefreshToken.
+	TenantID      string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the user; set by the store.
+	ClientID      string    `json:"client_id" bson:"client_id"`
+	UserID        uint      `json:"user_id" bson:"user_id"`
+	Scopes        []string  `json:"scopes" bson:"scopes"`
+	RedirectURI   string    `json:"redirect_uri,omitempty" bson:"redirect_uri"`     // Authorization codes only.
+	Nonce         string    `json:"nonce,omitempty" bson:"nonce"`                   // Authorization codes only.
+	CodeChallenge string    `json:"code_challenge,omitempty" bson:"code_challenge"` // S256 PKCE challenge; authorization codes only.
+	AuthTime      time.Time `json:"auth_time" bson:"auth_time"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Expired reports whether the grant can no longer be redeemed at now.
+func (g *Grant) Expired(now time.Time) bool {
+	return !now.Before(g.ExpiresAt)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
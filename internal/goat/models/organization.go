@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Roles a user can have in an organization, from most to least privileged.
+const (
+	RoleOwner  = "owner"  // Manages the organization and every member; each organization has exactly one.
+	RoleAdmin  = "admin"  // Invites and removes members and changes their roles, except the owner's.
+	RoleMember = "member" // Belongs to the organization without managing it.
+)
+
+// roleRanks orders the roles by privilege.
+var roleRanks = map[string]int{RoleMember: 1, RoleAdmin: 2, RoleOwner: 3}
+
+// ValidRole reports whether role is one of the organization roles.
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// Organization is a team of users of one tenant. Its owner is also recorded as a member with RoleOwner.
+type Organization struct {
+	ID        string    `json:"id" bson:"id"`
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the members; set by the store.
+	Name      string    `json:"name" bson:"name"`
+	OwnerID   uint      `json:"owner_id" bson:"owner_id"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Membership records that a user belongs to an organization, and with which role.
+type Membership struct {
+	TenantID       string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the organization; set by the store.
+	OrganizationID string    `json:"organization_id" bson:"organization_id"`
+	UserID         uint      `json:"user_id" bson:"user_id"`
+	Role           string    `json:"role" bson:"role"`
+	JoinedAt       time.Time `json:"joined_at" bson:"joined_at"`
+}
+
+// Manages reports whether the member may invite others with role, and change or remove members who
+// have it: admins manage admins and members, and only the owner manages the owner role.
+func (m *Membership) Manages(role string) bool {
+	rank := roleRanks[m.Role]
+	return rank >= roleRanks[RoleAdmin] && rank >= roleRanks[role]
+}
+
+// Invitation asks the holder of a token to join an organization. Only a SHA-256 hash of the token
+// is stored, and it doubles as the invitation's ID.
+type Invitation struct {
+	ID             string    `json:"id" bson:"id"`                         // SHA-256 of the invitation token, hex encoded.
+	TenantID       string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the organization; set by the store.
+	OrganizationID string    `json:"organization_id" bson:"organization_id"`
+	Email          string    `json:"email" bson:"email"` // Address the invitation was sent to; only its owner can accept.
+	Role           string    `json:"role" bson:"role"`
+	InvitedBy      uint      `json:"invited_by" bson:"invited_by"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Expired reports whether the invitation can no longer be accepted at now.
+func (i *Invitation) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of outbox messages.
+const (
+	OutboxPending = "pending" // Waiting to be delivered, or retried.
+	OutboxDead    = "dead"    // Gave up after too many failed deliveries.
+)
+
+// OutboxMessage is a user lifecycle event written to the outbox in the same transaction as the change
+// it describes, and relayed to handlers afterwards. Delivered messages are removed from the outbox.
+type OutboxMessage struct {
+	ID            string          `json:"id" bson:"id"` // Ordered by time, like audit event IDs.
+	Type          UserEventType   `json:"type" bson:"type"`
+	TenantID      string          `json:"tenant_id,omitempty" bson:"tenant_id"`
+	UserID        uint            `json:"user_id" bson:"user_id"`
+	Payload       json.RawMessage `json:"payload" bson:"payload"` // The user as JSON, without its password; for UserDeleted, as it was before deletion.
+	CreatedAt     time.Time       `json:"created_at" bson:"created_at"`
+	Status        string          `json:"status" bson:"status"` // OutboxPending or OutboxDead.
+	Attempts      int             `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" bson:"next_attempt_at"` // When the message is next due; pushed back while a relay holds it.
+	LastError     string          `json:"last_error,omitempty" bson:"last_error"`
+}
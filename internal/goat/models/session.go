@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session is a server-side login session. For cookie sessions the ID is the SHA-256 digest of the
+// token held in the client's cookie, so a leaked sessions table cannot be used to hijack sessions;
+// sessions of access tokens are named by the tokens' sid claim, which grants nothing without a
+// signed token.
+type Session struct {
+	ID         string    `json:"id" bson:"id"`
+	TenantID   string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant of the user; set by the store.
+	UserID     uint      `json:"user_id" bson:"user_id"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" bson:"expires_at"` // Absolute expiry, regardless of activity.
+	UserAgent  string    `json:"user_agent" bson:"user_agent"` // User agent of the client that logged in.
+	IP         string    `json:"ip" bson:"ip"`                 // IP address the client logged in from.
+}
+
+// IdleExpired reports whether the session has been inactive for longer than idle at now.
+func (s *Session) IdleExpired(now time.Time, idle time.Duration) bool {
+	return idle > 0 && now.Sub(s.LastSeenAt) > idle
+}
+
+// Expired reports whether the session's absolute expiry has passed at now.
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of webhook deliveries.
+const (
+	WebhookPending   = "pending"   // Waiting to be sent, or retried.
+	WebhookSucceeded = "succeeded" // The endpoint answered with a 2xx status.
+	WebhookFailed    = "failed"    // Gave up after too many failed attempts.
+)
+
+// WebhookEndpoint is a URL that is sent the events it subscribes to.
+type WebhookEndpoint struct {
+	ID        string    `json:"id" bson:"id"`
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id"` // Tenant whose events the endpoint is sent; set when it is created.
+	URL       string    `json:"url" bson:"url"`
+	Secret    string    `json:"-" bson:"secret"`      // Signs the payloads sent to the endpoint; shown only when the endpoint is created.
+	Events    []string  `json:"events" bson:"events"` // Event types sent to the endpoint; empty for all of them.
+	Active    bool      `json:"active" bson:"active"` // Inactive endpoints are sent no new events.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Subscribed reports whether events of type typ are sent to the endpoint.
+func (e *WebhookEndpoint) Subscribed(typ string) bool {
+	if !e.Active {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, event := range e.Events {
+		if event == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one endpoint, with the outcome of its last attempt. Together,
+// an endpoint's deliveries are its delivery log.
+type WebhookDelivery struct {
+	ID             string          `json:"id" bson:"id"`
+	EndpointID     string          `json:"endpoint_id" bson:"endpoint_id"`
+	EventID        string          `json:"event_id" bson:"event_id"` // The same for every endpoint and replay of an event, so receivers can drop duplicates.
+	EventType      string          `json:"event_type" bson:"event_type"`
+	UserID         uint            `json:"user_id,omitempty" bson:"user_id"` // User the event is about; zero if none.
+	Payload        json.RawMessage `json:"payload" bson:"payload"`           // The request body.
+	Status         string          `json:"status" bson:"status"`             // WebhookPending, WebhookSucceeded or WebhookFailed.
+	Attempts       int             `json:"attempts" bson:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" bson:"next_attempt_at"`           // When the delivery is next due; pushed back while a dispatcher holds it.
+	ResponseStatus int             `json:"response_status,omitempty" bson:"response_status"` // HTTP status of the last attempt; zero if there was no response.
+	ResponseBody   string          `json:"response_body,omitempty" bson:"response_body"`     // Start of the last response body.
+	LastError      string          `json:"last_error,omitempty" bson:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" bson:"updated_at"` // When the last attempt finished.
+}
@@ -0,0 +1,270 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// FlowConfig controls the cookie that carries an authorization request between the redirect
+// to the provider and the callback.
+type FlowConfig struct {
+	CookieName string        // Name of the flow cookie.
+	CookiePath string        // Path attribute of the cookie; must cover the callback URL.
+	Secure     bool          // Only send the cookie over HTTPS.
+	Key        []byte        // AES-256 key sealing the cookie; when empty, a random key is generated per process.
+	MaxAge     time.Duration // How long the user has to complete the login at the provider.
+}
+
+// DefaultFlowConfig returns a FlowConfig with a secure cookie and a 10 minute limit.
+func DefaultFlowConfig() FlowConfig {
+	return FlowConfig{
+		CookieName: "goat_oauth",
+		CookiePath: "/",
+		Secure:     true,
+		MaxAge:     10 * time.Minute,
+	}
+}
+
+// flowState is what the flow cookie holds. It is encrypted and authenticated, so the client can
+// neither read the PKCE verifier nor swap in values of its own.
+type flowState struct {
+	Provider  string    `json:"p"`
+	State     string    `json:"s"`
+	Nonce     string    `json:"n"`
+	Verifier  string    `json:"v"`
+	ExpiresAt time.Time `json:"e"`
+}
+
+// Flow runs the authorization-code flow with PKCE against the providers in a Registry, and signs the
+// user in to the matching goat account. It implements goat.SocialLogin.
+//
+// A provider profile is matched first to the account its identity is linked to. Failing that, it is
+// matched by email and the identity is linked, so only emails the provider has verified are accepted;
+// otherwise anyone able to create a provider account with a victim's address could take over the
+// victim's goat account. Only accounts without a usable password are matched this way: goat does not
+// verify the emails of password sign-ups, so an account with a password may have been registered by
+// someone else ahead of the address's owner, who would then share it. For those, ErrAccountExists is
+// returned, and the identity has to be linked with IdentityService.LinkIdentity once the user has
+// signed in. When no account has the email, one is registered with an unusable password and the
+// identity linked to it.
+type Flow[U models.Account] struct {
+	providers  *Registry
+	users      goat.UserService[U]
+	identities goat.IdentityService[U]
+	config     FlowConfig
+	aead       cipher.AEAD
+	now        func() time.Time
+}
+
+var _ goat.SocialLogin[*models.User] = (*Flow[*models.User])(nil)
+
+// NewFlow creates a Flow for the providers in providers, finding and registering accounts with users
+// and linking identities with identities. When identities is nil, profiles are matched by email only.
+func NewFlow[U models.Account](providers *Registry, users goat.UserService[U], identities goat.IdentityService[U], config FlowConfig) (*Flow[U], error) {
+	key := config.Key
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	if len(key) != 32 {
+		return nil, errors.New("oauth: flow key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Flow[U]{providers: providers, users: users, identities: identities, config: config, aead: aead, now: time.Now}, nil
+}
+
+// Begin implements goat.SocialLogin. It stores a fresh state, nonce and PKCE verifier in the flow
+// cookie and redirects the user to the provider.
+func (f *Flow[U]) Begin(c *gin.Context, provider string) error {
+	p, err := f.providers.Get(provider)
+	if err != nil {
+		return err
+	}
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return err
+	}
+	req := AuthRequest{State: state, Nonce: nonce, CodeVerifier: oauth2.GenerateVerifier()}
+
+	cookie, err := f.seal(flowState{
+		Provider:  provider,
+		State:     req.State,
+		Nonce:     req.Nonce,
+		Verifier:  req.CodeVerifier,
+		ExpiresAt: f.now().Add(f.config.MaxAge),
+	})
+	if err != nil {
+		return err
+	}
+	// Lax, not Strict: the callback is a top-level navigation from the provider's site.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(f.config.CookieName, cookie, int(f.config.MaxAge.Seconds()), f.config.CookiePath, "", f.config.Secure, true)
+	c.Redirect(http.StatusFound, p.AuthCodeURL(req))
+	return nil
+}
+
+// Complete implements goat.SocialLogin. It checks the callback against the flow cookie, redeems the
+// code and returns the account for the provider's profile, registering one if needed.
+func (f *Flow[U]) Complete(c *gin.Context, provider string) (U, error) {
+	var zero U
+	p, err := f.providers.Get(provider)
+	if err != nil {
+		return zero, err
+	}
+
+	// The cookie is single use, whatever the outcome.
+	raw, _ := c.Cookie(f.config.CookieName)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(f.config.CookieName, "", -1, f.config.CookiePath, "", f.config.Secure, true)
+
+	st, err := f.open(raw)
+	if err != nil || st.Provider != provider || f.now().After(st.ExpiresAt) {
+		return zero, goat.ErrInvalidOAuthState
+	}
+	if subtle.ConstantTimeCompare([]byte(st.State), []byte(c.Query("state"))) != 1 {
+		return zero, goat.ErrInvalidOAuthState
+	}
+	if e := c.Query("error"); e != "" {
+		// The user declined, or the provider refused the request.
+		return zero, fmt.Errorf("%w: %s: %s", goat.ErrUnauthorized, provider, e)
+	}
+
+	profile, err := p.Exchange(c.Request.Context(), c.Query("code"), AuthRequest{State: st.State, Nonce: st.Nonce, CodeVerifier: st.Verifier})
+	if err != nil {
+		return zero, err
+	}
+	user, err := f.account(c, profile)
+	if err != nil {
+		return zero, err
+	}
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		return zero, err
+	}
+	return user, nil
+}
+
+// account finds the account profile's identity is linked to, or the account without a usable
+// password whose email matches profile, or registers a new one, among the users of the request's
+// tenant.
+func (f *Flow[U]) account(c *gin.Context, profile *Profile) (U, error) {
+	var zero U
+	users := tenant.Users(c, f.users)
+	var identities goat.IdentityService[U]
+	if f.identities != nil {
+		identities = tenant.Identities(c, f.identities)
+	}
+
+	if identities != nil {
+		user, err := identities.GetUserByIdentity(profile.Provider, profile.Subject)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, goat.ErrIdentityNotFound) {
+			return zero, err
+		}
+	}
+	if profile.Email == "" || !profile.EmailVerified {
+		return zero, goat.ErrEmailNotVerified
+	}
+
+	user, err := users.GetUserByEmail(profile.Email)
+	switch {
+	case err == nil:
+		if user.GetUser().HasUsablePassword() {
+			return zero, goat.ErrAccountExists
+		}
+	case !errors.Is(err, goat.ErrUserNotFound):
+		return zero, err
+	default:
+		user = models.New[U]()
+		u := user.GetUser()
+		u.Email = profile.Email
+		if err := u.SetUnusablePassword(); err != nil {
+			return zero, err
+		}
+		if err := users.Register(user); err != nil {
+			return zero, err
+		}
+	}
+
+	if identities != nil {
+		err := identities.LinkIdentity(user.GetUser().ID, &models.Identity{
+			Provider: profile.Provider,
+			Subject:  profile.Subject,
+			Email:    profile.Email,
+			LinkedAt: f.now(),
+		})
+		if err != nil {
+			return zero, err
+		}
+	}
+	return user, nil
+}
+
+func (f *Flow[U]) seal(st flowState) (string, error) {
+	plaintext, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(f.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+func (f *Flow[U]) open(cookie string) (*flowState, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < f.aead.NonceSize() {
+		return nil, errors.New("oauth: flow cookie too short")
+	}
+	nonce, ciphertext := sealed[:f.aead.NonceSize()], sealed[f.aead.NonceSize():]
+	plaintext, err := f.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	var st flowState
+	if err := json.Unmarshal(plaintext, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// randomString returns 32 bytes from crypto/rand, base64url encoded.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
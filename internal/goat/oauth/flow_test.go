@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/gin-gonic/gin"
+)
+
+// flowUsers keeps users by email. Methods the flow does not call panic through the nil embedded
+// interface.
+type flowUsers struct {
+	goat.UserService[*models.User]
+	byEmail map[string]*models.User
+}
+
+func (u *flowUsers) ForTenant(string) goat.UserService[*models.User] {
+	return u
+}
+
+func (u *flowUsers) GetUserByEmail(email string) (*models.User, error) {
+	user, ok := u.byEmail[email]
+	if !ok {
+		return nil, goat.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *flowUsers) Register(user *models.User) error {
+	user.ID = uint(len(u.byEmail) + 1)
+	u.byEmail[user.Email] = user
+	return nil
+}
+
+func TestFlowAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	social := &models.User{ID: 7, Email: "ada@example.com"}
+	if err := social.SetUnusablePassword(); err != nil {
+		t.Fatal(err)
+	}
+	withPassword := &models.User{ID: 8, Email: "ada@example.com", Password: "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$aGFzaA"}
+
+	tests := []struct {
+		name     string
+		existing *models.User
+		profile  Profile
+		wantID   uint
+		wantErr  error
+	}{
+		{"registers a new account", nil, Profile{Email: "ada@example.com", EmailVerified: true}, 1, nil},
+		{"links an account without a password", social, Profile{Email: "ada@example.com", EmailVerified: true}, 7, nil},
+		{"refuses an account with a password", withPassword, Profile{Email: "ada@example.com", EmailVerified: true}, 0, goat.ErrAccountExists},
+		{"refuses an unverified email", nil, Profile{Email: "ada@example.com"}, 0, goat.ErrEmailNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &flowUsers{byEmail: map[string]*models.User{}}
+			if tt.existing != nil {
+				users.byEmail[tt.existing.Email] = tt.existing
+			}
+			f, err := NewFlow[*models.User](NewRegistry(), users, nil, DefaultFlowConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+			profile := tt.profile
+			profile.Provider, profile.Subject = "stub", "subject-1"
+			user, err := f.account(c, &profile)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("account error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.ID != tt.wantID {
+				t.Errorf("account ID = %d, want %d", user.ID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+// GitHubProvider is GitHub's OAuth 2.0 login. GitHub does not implement OpenID Connect, so the
+// profile is read from the REST API with the access token instead of an ID token.
+type GitHubProvider struct {
+	oauth   *oauth2.Config
+	client  *http.Client
+	apiBase string
+}
+
+// NewGitHub configures Sign in with GitHub.
+func NewGitHub(config ProviderConfig) *GitHubProvider {
+	return &GitHubProvider{
+		oauth: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       append([]string{"read:user", "user:email"}, config.Scopes...),
+			Endpoint:     github.Endpoint,
+		},
+		client:  config.httpClient(),
+		apiBase: "https://api.github.com",
+	}
+}
+
+// Name implements Provider.
+func (p *GitHubProvider) Name() string {
+	return "github"
+}
+
+// AuthCodeURL implements Provider.
+func (p *GitHubProvider) AuthCodeURL(req AuthRequest) string {
+	return p.oauth.AuthCodeURL(req.State, oauth2.S256ChallengeOption(req.CodeVerifier))
+}
+
+// Exchange implements Provider.
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, req AuthRequest) (*Profile, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	// The public profile email may be unverified or hidden, so use the primary verified address.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	profile := &Profile{Provider: p.Name(), Subject: strconv.FormatInt(user.ID, 10), Name: user.Name}
+	if profile.Name == "" {
+		profile.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			profile.Email, profile.EmailVerified = e.Email, e.Verified
+		}
+	}
+	return profile, nil
+}
+
+func (p *GitHubProvider) get(ctx context.Context, client *http.Client, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("github %s: unexpected status %s", path, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
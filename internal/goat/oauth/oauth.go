@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// Profile is what a provider tells us about the user who signed in.
+type Profile struct {
+	Provider      string // Name of the provider, e.g. "google".
+	Subject       string // The provider's stable identifier for the user.
+	Email         string
+	EmailVerified bool // Whether the provider vouches that the user controls Email.
+	Name          string
+}
+
+// AuthRequest carries the per-login values bound to an authorization request.
+type AuthRequest struct {
+	State        string // Opaque value echoed back to the callback, checked against CSRF.
+	Nonce        string // Bound into the ID token by OIDC providers, checked against replay.
+	CodeVerifier string // PKCE verifier; its S256 challenge is sent with the authorization request.
+}
+
+// Provider is an OAuth 2.0 or OpenID Connect identity provider.
+type Provider interface {
+	Name() string
+	// AuthCodeURL returns the URL to redirect the user to, to start the authorization-code flow.
+	AuthCodeURL(req AuthRequest) string
+	// Exchange redeems an authorization code and returns the user's verified profile.
+	Exchange(ctx context.Context, code string, req AuthRequest) (*Profile, error)
+}
+
+// Registry holds the providers an application accepts, by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewRegistry creates a Registry containing providers.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: map[string]Provider{}}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds p, replacing any provider with the same name.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider called name, or goat.ErrUnsupportedProvider.
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, goat.ErrUnsupportedProvider
+	}
+	return p, nil
+}
+
+// Names returns the names of the registered providers, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
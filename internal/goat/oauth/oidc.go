@@ -0,0 +1,266 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// ProviderConfig holds the client registration of an application with a provider.
+type ProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string       // The application's callback URL, as registered with the provider.
+	Scopes       []string     // Extra scopes; "openid", "email" and "profile" are always requested from OIDC providers.
+	HTTPClient   *http.Client // Client for discovery, token and key requests; defaults to http.DefaultClient.
+}
+
+func (c ProviderConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Discovery is the subset of an OpenID Provider Configuration document (OpenID Connect Discovery 1.0) goat uses.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider is an OpenID Connect provider configured through discovery. Identity comes from the
+// ID token, which is verified against the provider's published keys, issuer, audience and nonce.
+type OIDCProvider struct {
+	name      string
+	discovery Discovery
+	oauth     *oauth2.Config
+	client    *http.Client
+	keys      *remoteKeySet
+	// validIssuer checks the iss claim; multi-tenant providers issue tokens from per-tenant issuers.
+	validIssuer func(iss string, claims *idTokenClaims) bool
+}
+
+// Discover fetches issuer's discovery document and returns a provider called name. The document
+// must name issuer as its issuer, exactly (OpenID Connect Discovery 1.0, section 4.3).
+func Discover(ctx context.Context, name, issuer string, config ProviderConfig) (*OIDCProvider, error) {
+	return discover(ctx, name, issuer, config, func(discovered string) bool { return discovered == issuer })
+}
+
+// discover is Discover with matchIssuer checking the issuer the document names.
+func discover(ctx context.Context, name, issuer string, config ProviderConfig, matchIssuer func(string) bool) (*OIDCProvider, error) {
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := config.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery for %s: unexpected status %s", issuer, res.Status)
+	}
+	var d Discovery
+	if err := json.NewDecoder(res.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s: incomplete provider configuration", issuer)
+	}
+	if d.Issuer == "" || !matchIssuer(d.Issuer) {
+		return nil, fmt.Errorf("oidc discovery for %s: provider configuration names issuer %q", issuer, d.Issuer)
+	}
+
+	scopes := append([]string{"openid", "email", "profile"}, config.Scopes...)
+	return &OIDCProvider{
+		name:      name,
+		discovery: d,
+		oauth: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       scopes,
+			Endpoint:     oauth2.Endpoint{AuthURL: d.AuthorizationEndpoint, TokenURL: d.TokenEndpoint},
+		},
+		client:      config.httpClient(),
+		keys:        &remoteKeySet{url: d.JWKSURI, client: config.httpClient()},
+		validIssuer: func(iss string, _ *idTokenClaims) bool { return iss == d.Issuer },
+	}, nil
+}
+
+// NewGoogle configures Sign in with Google.
+func NewGoogle(ctx context.Context, config ProviderConfig) (*OIDCProvider, error) {
+	return Discover(ctx, "google", "https://accounts.google.com", config)
+}
+
+// NewMicrosoft configures the Microsoft identity platform for tenant, which may be a tenant ID or
+// one of "common", "organizations" and "consumers". For the multi-tenant values the issuer is checked
+// against the tenant named in the token's tid claim.
+func NewMicrosoft(ctx context.Context, tenant string, config ProviderConfig) (*OIDCProvider, error) {
+	issuer := "https://login.microsoftonline.com/" + tenant + "/v2.0"
+	// The multi-tenant documents name the issuer as a template with a {tenantid} placeholder.
+	p, err := discover(ctx, "microsoft", issuer, config, func(discovered string) bool {
+		return discovered == issuer || strings.Replace(discovered, "{tenantid}", tenant, 1) == issuer
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(p.discovery.Issuer, "{tenantid}") {
+		template := p.discovery.Issuer
+		p.validIssuer = func(iss string, claims *idTokenClaims) bool {
+			return claims.TenantID != "" && iss == strings.Replace(template, "{tenantid}", claims.TenantID, 1)
+		}
+	}
+	return p, nil
+}
+
+// Name implements Provider.
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL implements Provider.
+func (p *OIDCProvider) AuthCodeURL(req AuthRequest) string {
+	return p.oauth.AuthCodeURL(req.State, oauth2.S256ChallengeOption(req.CodeVerifier), oauth2.SetAuthURLParam("nonce", req.Nonce))
+}
+
+// idTokenClaims are the ID token claims goat reads.
+type idTokenClaims struct {
+	gojwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // Some providers send "true" as a string.
+	Name          string `json:"name"`
+	TenantID      string `json:"tid"`
+}
+
+// Exchange implements Provider.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req AuthRequest) (*Profile, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	claims, err := p.verifyIDToken(ctx, rawIDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// verifyIDToken checks the ID token's signature, audience, lifetime, issuer and nonce.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := gojwt.ParseWithClaims(raw, claims, func(t *gojwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		gojwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		gojwt.WithAudience(p.oauth.ClientID),
+		gojwt.WithExpirationRequired(),
+		gojwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %w: %v", goat.ErrInvalidToken, err)
+	}
+	if !p.validIssuer(claims.Issuer, claims) {
+		return nil, fmt.Errorf("oidc: %w: unexpected issuer %q", goat.ErrInvalidToken, claims.Issuer)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("oidc: %w: nonce mismatch", goat.ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("oidc: %w: missing subject", goat.ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// remoteKeySet caches a provider's JWKS, refetching it when a token names an unknown kid,
+// which is how providers announce rotated keys.
+type remoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// minRefetchInterval limits how often an unknown kid can trigger a JWKS fetch.
+const minRefetchInterval = time.Minute
+
+func (s *remoteKeySet) key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(s.fetchedAt) < minRefetchInterval && s.keys != nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *remoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: unexpected status %s", res.Status)
+	}
+	var set jwt.JWKS
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		public, err := k.PublicKey()
+		if err != nil {
+			continue // Skip key types we do not support rather than failing the whole set.
+		}
+		keys[k.KeyID] = public
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
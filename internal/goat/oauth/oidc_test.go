@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// stubOIDC is a local OpenID provider. Its token endpoint answers every code with an ID token built
+// from claims, signed by key.
+type stubOIDC struct {
+	*httptest.Server
+	key    ed25519.PrivateKey
+	issuer string // Issuer named by the discovery document; the server URL by default.
+	claims func() gojwt.MapClaims
+}
+
+func newStubOIDC(t *testing.T) *stubOIDC {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubOIDC{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                s.issuer,
+			AuthorizationEndpoint: s.URL + "/authorize",
+			TokenEndpoint:         s.URL + "/token",
+			JWKSURI:               s.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		public := key.Public().(ed25519.PublicKey)
+		json.NewEncoder(w).Encode(jwt.JWKS{Keys: []jwt.JWK{{
+			KeyType: "OKP", KeyID: "stub", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(public),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		token := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, s.claims())
+		token.Header["kid"] = "stub"
+		idToken, err := token.SignedString(s.key)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+	s.Server = httptest.NewServer(mux)
+	s.issuer = s.URL
+	t.Cleanup(s.Close)
+	return s
+}
+
+// idClaims returns valid ID token claims for the stub's client and nonce.
+func (s *stubOIDC) idClaims() gojwt.MapClaims {
+	return gojwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            "subject-1",
+		"aud":            "client",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          "nonce",
+		"email":          "Ada@Example.com",
+		"email_verified": "true",
+	}
+}
+
+var stubRequest = AuthRequest{State: "state", Nonce: "nonce", CodeVerifier: "verifier-verifier-verifier-verifier-verifier"}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	s := newStubOIDC(t)
+	for _, issuer := range []string{"https://evil.example", s.URL + "/", ""} {
+		s.issuer = issuer
+		if _, err := Discover(context.Background(), "stub", s.URL, ProviderConfig{ClientID: "client"}); err == nil {
+			t.Errorf("Discover with the document naming issuer %q succeeded", issuer)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	s := newStubOIDC(t)
+	p, err := Discover(context.Background(), "stub", s.URL, ProviderConfig{ClientID: "client", RedirectURL: "http://app/callback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.claims = s.idClaims
+	profile, err := p.Exchange(context.Background(), "code", stubRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Provider != "stub" || profile.Subject != "subject-1" || profile.Email != "Ada@Example.com" || !profile.EmailVerified {
+		t.Errorf("Exchange profile = %+v", profile)
+	}
+
+	for name, tamper := range map[string]func(gojwt.MapClaims){
+		"wrong issuer":   func(c gojwt.MapClaims) { c["iss"] = "https://evil.example" },
+		"wrong audience": func(c gojwt.MapClaims) { c["aud"] = "someone-else" },
+		"wrong nonce":    func(c gojwt.MapClaims) { c["nonce"] = "replayed" },
+		"expired":        func(c gojwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no subject":     func(c gojwt.MapClaims) { delete(c, "sub") },
+	} {
+		s.claims = func() gojwt.MapClaims {
+			c := s.idClaims()
+			tamper(c)
+			return c
+		}
+		if _, err := p.Exchange(context.Background(), "code", stubRequest); !errors.Is(err, goat.ErrInvalidToken) {
+			t.Errorf("Exchange with %s = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestExchangeRejectsForeignSigningKey(t *testing.T) {
+	s := newStubOIDC(t)
+	p, err := Discover(context.Background(), "stub", s.URL, ProviderConfig{ClientID: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, s.key, _ = ed25519.GenerateKey(rand.Reader) // The JWKS still publishes the original key.
+	s.claims = s.idClaims
+	if _, err := p.Exchange(context.Background(), "code", stubRequest); !errors.Is(err, goat.ErrInvalidToken) {
+		t.Errorf("Exchange of a token signed by an unpublished key = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,303 @@
+package oidc
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// authRequest is a validated authorization request.
+type authRequest struct {
+	ClientID    string `json:"client_id"`
+	RedirectURI string `json:"redirect_uri"` // Where to send the user back; always set.
+	// ExplicitRedirectURI records whether the client sent redirect_uri, in which case it must send the
+	// same value to the token endpoint (RFC 6749 section 4.1.3).
+	ExplicitRedirectURI bool     `json:"explicit_redirect_uri,omitempty"`
+	Scopes              []string `json:"scopes"`
+	State               string   `json:"state,omitempty"`
+	Nonce               string   `json:"nonce,omitempty"`
+	CodeChallenge       string   `json:"code_challenge,omitempty"`
+}
+
+// consentClaims carry an authorization request through the consent page. They are signed, so the
+// page cannot alter the request, and bound to the user who was asked.
+type consentClaims struct {
+	gojwt.RegisteredClaims
+	Request authRequest `json:"req"`
+}
+
+// ConsentPage is what the consent page shows and submits.
+type ConsentPage struct {
+	Client  *models.Client
+	Scopes  []string
+	Action  string // URL the form must be posted to.
+	Request string // Value of the consent_request form field.
+}
+
+// Authorize handles the authorization endpoint. It validates the request, makes sure the user is
+// signed in and has consented, and redirects back to the client with an authorization code.
+func (p *Provider[U]) Authorize(c *gin.Context) {
+	query := c.Request.URL.Query()
+
+	// Until the client and redirect URI are known to be valid, errors are shown here rather than
+	// redirected, or the endpoint would be an open redirector.
+	client, err := p.store.GetClient(query.Get("client_id"))
+	if err != nil {
+		if errors.Is(err, goat.ErrClientNotFound) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "unknown client_id"})
+			return
+		}
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	req := &authRequest{
+		ClientID:      client.ID,
+		RedirectURI:   query.Get("redirect_uri"),
+		State:         query.Get("state"),
+		Nonce:         query.Get("nonce"),
+		CodeChallenge: query.Get("code_challenge"),
+	}
+	switch {
+	case req.RedirectURI != "":
+		req.ExplicitRedirectURI = true
+		if !contains(client.RedirectURIs, req.RedirectURI) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "redirect_uri is not registered for the client"})
+			return
+		}
+	case len(client.RedirectURIs) == 1:
+		req.RedirectURI = client.RedirectURIs[0]
+	default:
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "redirect_uri is required"})
+		return
+	}
+
+	req.Scopes = strings.Fields(query.Get("scope"))
+	if len(req.Scopes) == 0 {
+		req.Scopes = client.Scopes
+	}
+	switch {
+	case query.Get("response_type") != "code":
+		p.redirectError(c, req, "unsupported_response_type", "only the code response type is supported")
+		return
+	case !client.AllowsGrant(models.GrantAuthorizationCode):
+		p.redirectError(c, req, "unauthorized_client", "the client may not use the authorization code grant")
+		return
+	case !subset(req.Scopes, client.Scopes):
+		p.redirectError(c, req, "invalid_scope", "the client may not request these scopes")
+		return
+	case req.CodeChallenge == "" && client.Public:
+		p.redirectError(c, req, "invalid_request", "public clients must use PKCE")
+		return
+	case req.CodeChallenge != "" && (query.Get("code_challenge_method") != "S256" || len(req.CodeChallenge) != 43):
+		p.redirectError(c, req, "invalid_request", "code_challenge must be an S256 challenge")
+		return
+	}
+
+	prompt := strings.Fields(query.Get("prompt"))
+	user, err := p.auth.Authenticate(c)
+	if err != nil {
+		switch {
+		case goat.KindOf(err) != goat.KindUnauthenticated:
+			goat.AbortWithProblem(c, err)
+		case contains(prompt, "none"):
+			p.redirectError(c, req, "login_required", "")
+		case p.config.LoginURL != "":
+			p.redirectToLogin(c)
+		default:
+			goat.AbortWithProblem(c, err)
+		}
+		return
+	}
+	userID := user.GetUser().ID
+
+	if !client.SkipConsent || contains(prompt, "consent") {
+		consent, err := p.store.ForTenant(tenant.ID(c)).GetConsent(userID, client.ID)
+		if err != nil && !errors.Is(err, goat.ErrConsentNotFound) {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		if err != nil || !consent.Covers(req.Scopes) || contains(prompt, "consent") {
+			if contains(prompt, "none") {
+				p.redirectError(c, req, "consent_required", "")
+				return
+			}
+			p.askConsent(c, client, userID, req)
+			return
+		}
+	}
+
+	p.issueCode(c, userID, req)
+}
+
+// Consent handles the submission of the consent page.
+func (p *Provider[U]) Consent(c *gin.Context) {
+	user, err := p.auth.Authenticate(c)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	userID := user.GetUser().ID
+
+	claims := &consentClaims{}
+	_, err = gojwt.ParseWithClaims(c.PostForm("consent_request"), claims, p.keys.Keyfunc,
+		gojwt.WithValidMethods(p.keys.Algorithms()),
+		gojwt.WithIssuer(p.config.Issuer),
+		gojwt.WithAudience(p.config.Issuer+ConsentPath),
+		gojwt.WithSubject(strconv.FormatUint(uint64(userID), 10)),
+		gojwt.WithTimeFunc(p.now),
+		gojwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "invalid or expired consent request"})
+		return
+	}
+	req := &claims.Request
+
+	if c.PostForm("decision") != "allow" {
+		p.redirectError(c, req, "access_denied", "the user denied the request")
+		return
+	}
+
+	// Remember the scopes alongside any granted earlier, so the user is not asked again.
+	store := p.store.ForTenant(tenant.ID(c))
+	scopes := req.Scopes
+	if consent, err := store.GetConsent(userID, req.ClientID); err == nil {
+		for _, s := range consent.Scopes {
+			if !contains(scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	err = store.SaveConsent(&models.Consent{UserID: userID, ClientID: req.ClientID, Scopes: scopes, GrantedAt: p.now()})
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	p.issueCode(c, userID, req)
+}
+
+// askConsent renders the consent page for req.
+func (p *Provider[U]) askConsent(c *gin.Context, client *models.Client, userID uint, req *authRequest) {
+	now := p.now()
+	token, err := p.keys.Sign(consentClaims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Issuer:    p.config.Issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  gojwt.ClaimStrings{p.config.Issuer + ConsentPath},
+			IssuedAt:  gojwt.NewNumericDate(now),
+			ExpiresAt: gojwt.NewNumericDate(now.Add(p.config.ConsentTTL)),
+		},
+		Request: *req,
+	})
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	page := &ConsentPage{Client: client, Scopes: req.Scopes, Action: p.config.Issuer + ConsentPath, Request: token}
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY") // Keep other sites from tricking users into clicking Allow.
+	if p.config.ConsentPage != nil {
+		p.config.ConsentPage(c, page)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := consentTemplate.Execute(c.Writer, page); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Allow access</title></head><body>
+<form method="post" action="{{.Action}}">
+<p>{{.Client.Name}} would like to access your account:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+<input type="hidden" name="consent_request" value="{{.Request}}">
+<button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny">Deny</button>
+</form>
+</body></html>
+`))
+
+// issueCode creates an authorization code for req and redirects back to the client with it.
+func (p *Provider[U]) issueCode(c *gin.Context, userID uint, req *authRequest) {
+	code, err := randomToken(32)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	now := p.now()
+	grant := &models.Grant{
+		ID:            hashToken(code),
+		Type:          models.GrantAuthorizationCode,
+		ClientID:      req.ClientID,
+		UserID:        userID,
+		Scopes:        req.Scopes,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      now,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(p.config.CodeTTL),
+	}
+	if req.ExplicitRedirectURI {
+		grant.RedirectURI = req.RedirectURI
+	}
+	if err := p.store.ForTenant(tenant.ID(c)).CreateGrant(grant); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	p.redirect(c, req, url.Values{"code": {code}})
+}
+
+// redirectError sends an error response for req back to the client.
+func (p *Provider[U]) redirectError(c *gin.Context, req *authRequest, code, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+	p.redirect(c, req, params)
+}
+
+// redirect sends the user back to the client with params, the request's state and the issuer
+// (RFC 9207), which lets clients that use several providers detect mix-up attacks.
+func (p *Provider[U]) redirect(c *gin.Context, req *authRequest, params url.Values) {
+	target, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	query := target.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	query.Set("iss", p.config.Issuer)
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
+
+// redirectToLogin sends the user to sign in, to return to this authorization request afterwards.
+func (p *Provider[U]) redirectToLogin(c *gin.Context) {
+	login, err := url.Parse(p.config.LoginURL)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	query := login.Query()
+	query.Set("return_to", p.config.Issuer+AuthorizePath+"?"+c.Request.URL.RawQuery)
+	login.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, login.String())
+}
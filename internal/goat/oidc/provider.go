@@ -0,0 +1,202 @@
+// Package oidc turns goat into an OpenID Connect provider, so other applications can sign users
+// in with their goat accounts. It implements the authorization code flow with PKCE, refresh tokens
+// and the client credentials grant, and signs tokens with a jwt.KeyManager.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes understood by the provider. Clients may be allowed other scopes; they appear in access
+// tokens for the client's own APIs to check.
+const (
+	ScopeOpenID = "openid" // Issue an ID token.
+	ScopeEmail  = "email"  // Include the user's email in the ID token and userinfo.
+)
+
+// Config controls the provider's endpoints and token lifetimes.
+type Config struct {
+	// Issuer is the URL the provider's routes are mounted at, e.g. https://auth.example.com, and the iss of
+	// every token. When the provider shares a KeyManager with a jwt.Authenticator, the two must use
+	// different issuers, or access tokens issued to clients would be accepted as goat access tokens.
+	Issuer          string
+	LoginURL        string        // Where users without a session are sent to sign in, with the authorization URL in return_to.
+	CodeTTL         time.Duration // Lifetime of an authorization code.
+	AccessTokenTTL  time.Duration // Lifetime of access and ID tokens.
+	RefreshTokenTTL time.Duration // Lifetime of a refresh token; each use replaces it with a new one.
+	ConsentTTL      time.Duration // How long a user has to answer the consent page.
+	// ConsentPage renders the page asking the user to allow a client access. It must submit the
+	// page's fields to ConsentPath. When nil, a minimal HTML form is rendered.
+	ConsentPage func(c *gin.Context, page *ConsentPage)
+}
+
+// DefaultConfig returns a Config for issuer with one minute codes, one hour tokens and 30 day refresh tokens.
+func DefaultConfig(issuer string) Config {
+	return Config{
+		Issuer:          strings.TrimSuffix(issuer, "/"),
+		CodeTTL:         time.Minute,
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		ConsentTTL:      10 * time.Minute,
+	}
+}
+
+// Paths of the provider's endpoints, relative to the issuer.
+const (
+	DiscoveryPath = "/.well-known/openid-configuration"
+	AuthorizePath = "/authorize"
+	ConsentPath   = "/authorize/consent"
+	TokenPath     = "/token"
+	UserinfoPath  = "/userinfo"
+	JWKSPath      = "/jwks"
+)
+
+// Provider is an OpenID Connect provider backed by a goat user base. Users sign in to goat with
+// auth, typically a session.Manager, and are then redirected back to the client with a code.
+type Provider[U models.Account] struct {
+	store  goat.OIDCStore
+	users  goat.UserService[U]
+	auth   goat.Authenticator[U]
+	keys   *jwt.KeyManager
+	config Config
+	now    func() time.Time
+}
+
+// NewProvider creates a Provider that keeps clients, consents and grants in store, loads users from
+// users, recognizes signed-in users with auth and signs tokens with keys.
+func NewProvider[U models.Account](store goat.OIDCStore, users goat.UserService[U], auth goat.Authenticator[U], keys *jwt.KeyManager, config Config) *Provider[U] {
+	return &Provider[U]{store: store, users: users, auth: auth, keys: keys, config: config, now: time.Now}
+}
+
+// RegisterRoutes mounts the provider's endpoints on r, which must be served at the issuer URL.
+func (p *Provider[U]) RegisterRoutes(r gin.IRouter) {
+	r.GET(DiscoveryPath, p.Discovery)
+	r.GET(JWKSPath, p.JWKS)
+	r.GET(AuthorizePath, p.Authorize)
+	r.POST(ConsentPath, p.Consent)
+	r.POST(TokenPath, p.Token)
+	r.GET(UserinfoPath, p.Userinfo)
+	r.POST(UserinfoPath, p.Userinfo)
+}
+
+// RegisterClient saves client with a new ID, and for confidential clients a new secret, which is
+// returned; only its hash is stored, so it cannot be shown again.
+func (p *Provider[U]) RegisterClient(client *models.Client) (string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	client.ID = id
+	client.CreatedAt = p.now()
+
+	var secret string
+	if !client.Public {
+		if secret, err = randomToken(32); err != nil {
+			return "", err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+	if err := p.store.SaveClient(client); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RevokeConsent withdraws the consent of the user of tenantID for a client, so the consent page is
+// shown again on the client's next authorization request. Tokens already issued remain valid until
+// they expire.
+func (p *Provider[U]) RevokeConsent(tenantID string, userID uint, clientID string) error {
+	return p.store.ForTenant(tenantID).DeleteConsent(userID, clientID)
+}
+
+// discoveryDocument is the OpenID Provider Configuration (OpenID Connect Discovery 1.0).
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	AuthorizationResponseIssParameter bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+// Discovery responds with the provider's configuration document.
+func (p *Provider[U]) Discovery(c *gin.Context) {
+	issuer := p.config.Issuer
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, discoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + AuthorizePath,
+		TokenEndpoint:                     issuer + TokenPath,
+		UserinfoEndpoint:                  issuer + UserinfoPath,
+		JWKSURI:                           issuer + JWKSPath,
+		ScopesSupported:                   []string{ScopeOpenID, ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{models.GrantAuthorizationCode, models.GrantRefreshToken, models.GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  p.keys.Algorithms(),
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email"},
+		AuthorizationResponseIssParameter: true,
+	})
+}
+
+// JWKS responds with the public keys that verify the provider's tokens.
+func (p *Provider[U]) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, p.keys.JWKS())
+}
+
+// subset reports whether every element of list is in allowed.
+func subset(list, allowed []string) bool {
+	for _, s := range list {
+		if !contains(allowed, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// randomToken returns n bytes from crypto/rand, base64url encoded.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the stored form of a code, token or client secret.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,373 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/jwt"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testRedirect = "https://app.example.com/callback"
+	testVerifier = "a-pkce-code-verifier-that-is-long-enough-to-be-valid"
+)
+
+// testUsers serves users 1 and 2, active unless statuses says otherwise. Methods the provider does
+// not call panic through the nil embedded interface.
+type testUsers struct {
+	goat.UserService[*models.User]
+	statuses map[uint]string
+}
+
+func (u testUsers) ForTenant(string) goat.UserService[*models.User] {
+	return u
+}
+
+func (u testUsers) GetUserByID(id uint) (*models.User, error) {
+	if id != 1 && id != 2 {
+		return nil, goat.ErrUserNotFound
+	}
+	status, ok := u.statuses[id]
+	if !ok {
+		status = models.AccountActive
+	}
+	return &models.User{ID: id, Email: "user" + strconv.Itoa(int(id)) + "@example.com", Status: status}, nil
+}
+
+// testAuth signs in the user whose ID is in the X-User header.
+type testAuth struct{}
+
+func (testAuth) Authenticate(c *gin.Context) (*models.User, error) {
+	id, err := strconv.Atoi(c.GetHeader("X-User"))
+	if err != nil {
+		return nil, goat.ErrUnauthorized
+	}
+	return testUsers{}.GetUserByID(uint(id))
+}
+
+func (testAuth) RefreshAuthToken(*gin.Context) (*models.User, error) {
+	return nil, goat.ErrUnauthorized
+}
+func (testAuth) SocialLogin(*gin.Context, string) (*models.User, error) {
+	return nil, goat.ErrUnauthorized
+}
+
+type testProvider struct {
+	*Provider[*models.User]
+	accounts testUsers
+	router   *gin.Engine
+	clientID string
+}
+
+// newTestProvider returns a provider with a registered public client that asks for consent.
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	keys, err := jwt.NewKeyManager(repository.NewMemoryKeyStore(), jwt.KeyManagerConfig{
+		Algorithm:          jwt.AlgorithmEdDSA,
+		RotationInterval:   time.Hour,
+		VerificationPeriod: time.Hour,
+		EncryptionKey:      make([]byte, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := testUsers{statuses: map[uint]string{}}
+	p := NewProvider[*models.User](repository.NewMemoryOIDCStore(), accounts, testAuth{}, keys, DefaultConfig(testIssuer))
+	client := &models.Client{
+		Name:         "App",
+		Public:       true,
+		RedirectURIs: []string{testRedirect, testRedirect + "/other"},
+		GrantTypes:   []string{models.GrantAuthorizationCode, models.GrantRefreshToken},
+		Scopes:       []string{ScopeOpenID, ScopeEmail},
+	}
+	if _, err := p.RegisterClient(client); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	p.RegisterRoutes(router)
+	return &testProvider{Provider: p, accounts: accounts, router: router, clientID: client.ID}
+}
+
+func (tp *testProvider) do(req *http.Request, user uint) *httptest.ResponseRecorder {
+	if user != 0 {
+		req.Header.Set("X-User", strconv.Itoa(int(user)))
+	}
+	w := httptest.NewRecorder()
+	tp.router.ServeHTTP(w, req)
+	return w
+}
+
+// authorize sends an authorization request for user with params on top of the client's defaults.
+func (tp *testProvider) authorize(user uint, params url.Values) *httptest.ResponseRecorder {
+	query := url.Values{
+		"client_id":             {tp.clientID},
+		"redirect_uri":          {testRedirect},
+		"response_type":         {"code"},
+		"scope":                 {"openid email"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {challenge(testVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	return tp.do(httptest.NewRequest(http.MethodGet, AuthorizePath+"?"+query.Encode(), nil), user)
+}
+
+var consentField = regexp.MustCompile(`name="consent_request" value="([^"]+)"`)
+
+// consentRequest returns the signed consent request of a consent page.
+func consentRequest(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	m := consentField.FindStringSubmatch(w.Body.String())
+	if w.Code != http.StatusOK || m == nil {
+		t.Fatalf("authorize = %d %s, want the consent page", w.Code, w.Body)
+	}
+	return html.UnescapeString(m[1])
+}
+
+func (tp *testProvider) consent(user uint, request, decision string) *httptest.ResponseRecorder {
+	form := url.Values{"consent_request": {request}, "decision": {decision}}
+	req := httptest.NewRequest(http.MethodPost, ConsentPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return tp.do(req, user)
+}
+
+// code returns the code a redirect to the client carries.
+func code(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	location, err := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("response = %d %s, want a redirect with a code", w.Code, w.Header().Get("Location"))
+	}
+	if location.Query().Get("state") != "state" || location.Query().Get("iss") != testIssuer {
+		t.Errorf("redirect %s does not carry the state and issuer", location)
+	}
+	return location.Query().Get("code")
+}
+
+func (tp *testProvider) token(form url.Values) (*httptest.ResponseRecorder, tokenResponse, oauthError) {
+	form.Set("client_id", tp.clientID)
+	req := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := tp.do(req, 0)
+	var res tokenResponse
+	var oerr oauthError
+	json.Unmarshal(w.Body.Bytes(), &res)
+	json.Unmarshal(w.Body.Bytes(), &oerr)
+	return w, res, oerr
+}
+
+func (tp *testProvider) exchange(code, verifier, redirectURI string) (*httptest.ResponseRecorder, tokenResponse, oauthError) {
+	return tp.token(url.Values{"grant_type": {models.GrantAuthorizationCode}, "code": {code}, "code_verifier": {verifier}, "redirect_uri": {redirectURI}})
+}
+
+// grantCode runs the authorization request for user, consenting if asked, and returns the code.
+func (tp *testProvider) grantCode(t *testing.T, user uint, params url.Values) string {
+	t.Helper()
+	w := tp.authorize(user, params)
+	if w.Code == http.StatusOK {
+		w = tp.consent(user, consentRequest(t, w), "allow")
+	}
+	return code(t, w)
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	tp := newTestProvider(t)
+	w, res, _ := tp.exchange(tp.grantCode(t, 1, nil), testVerifier, testRedirect)
+	if w.Code != http.StatusOK {
+		t.Fatalf("token = %d %s", w.Code, w.Body)
+	}
+	if res.AccessToken == "" || res.RefreshToken == "" || res.Scope != "openid email" {
+		t.Errorf("token response = %+v", res)
+	}
+
+	claims := &idTokenClaims{}
+	_, err := gojwt.ParseWithClaims(res.IDToken, claims, tp.keys.Keyfunc,
+		gojwt.WithIssuer(testIssuer), gojwt.WithAudience(tp.clientID), gojwt.WithSubject("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Nonce != "nonce" || claims.Email != "user1@example.com" || claims.AuthorizedParty != tp.clientID {
+		t.Errorf("ID token claims = %+v", claims)
+	}
+
+	// The consent is remembered, so the next request is answered with a code right away.
+	code(t, tp.authorize(1, nil))
+}
+
+func TestCodeCannotBeReused(t *testing.T) {
+	tp := newTestProvider(t)
+	c := tp.grantCode(t, 1, nil)
+	if w, _, _ := tp.exchange(c, testVerifier, testRedirect); w.Code != http.StatusOK {
+		t.Fatalf("first exchange = %d %s", w.Code, w.Body)
+	}
+	if w, _, oerr := tp.exchange(c, testVerifier, testRedirect); w.Code != http.StatusBadRequest || oerr.Error != "invalid_grant" {
+		t.Errorf("second exchange = %d %s, want invalid_grant", w.Code, w.Body)
+	}
+}
+
+func TestPKCE(t *testing.T) {
+	tp := newTestProvider(t)
+
+	for name, verifier := range map[string]string{"wrong verifier": testVerifier + "x", "no verifier": ""} {
+		c := tp.grantCode(t, 1, nil)
+		if w, _, oerr := tp.exchange(c, verifier, testRedirect); w.Code != http.StatusBadRequest || oerr.Error != "invalid_grant" {
+			t.Errorf("exchange with %s = %d %s, want invalid_grant", name, w.Code, w.Body)
+		}
+		// A failed exchange still uses up the code.
+		if w, _, _ := tp.exchange(c, testVerifier, testRedirect); w.Code != http.StatusBadRequest {
+			t.Errorf("exchange after one with %s = %d, want 400", name, w.Code)
+		}
+	}
+
+	// Public clients must send a challenge, and only S256 challenges are accepted.
+	for name, params := range map[string]url.Values{
+		"no challenge":    {"code_challenge": {""}, "code_challenge_method": {""}},
+		"plain challenge": {"code_challenge_method": {"plain"}},
+	} {
+		w := tp.authorize(1, params)
+		location, _ := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || location.Query().Get("error") != "invalid_request" {
+			t.Errorf("authorize with %s = %d %s, want an invalid_request redirect", name, w.Code, location)
+		}
+	}
+}
+
+func TestRedirectURIMatching(t *testing.T) {
+	tp := newTestProvider(t)
+
+	// An unregistered redirect URI is reported here, never redirected to.
+	for _, uri := range []string{"https://evil.example/callback", testRedirect + "/", testRedirect + "?x=1"} {
+		w := tp.authorize(1, url.Values{"redirect_uri": {uri}})
+		if w.Code != http.StatusBadRequest || w.Header().Get("Location") != "" {
+			t.Errorf("authorize with redirect_uri %s = %d %s, want 400", uri, w.Code, w.Header().Get("Location"))
+		}
+	}
+	// The client registers two URIs, so it has to name one.
+	if w := tp.authorize(1, url.Values{"redirect_uri": {""}}); w.Code != http.StatusBadRequest {
+		t.Errorf("authorize without redirect_uri = %d, want 400", w.Code)
+	}
+
+	// The token request must name the redirect URI the authorization request did.
+	for _, uri := range []string{testRedirect + "/other", ""} {
+		c := tp.grantCode(t, 1, nil)
+		if w, _, oerr := tp.exchange(c, testVerifier, uri); w.Code != http.StatusBadRequest || oerr.Error != "invalid_grant" {
+			t.Errorf("exchange with redirect_uri %q = %d %s, want invalid_grant", uri, w.Code, w.Body)
+		}
+	}
+}
+
+func TestConsentRequest(t *testing.T) {
+	tp := newTestProvider(t)
+	request := consentRequest(t, tp.authorize(1, nil))
+
+	// Another user cannot answer the consent page, and the request cannot be altered.
+	if w := tp.consent(2, request, "allow"); w.Code != http.StatusBadRequest {
+		t.Errorf("consent by another user = %d, want 400", w.Code)
+	}
+	parts := strings.Split(request, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), testRedirect, "https://evil.example/callback", 1))
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if w := tp.consent(1, tampered, "allow"); w.Code != http.StatusBadRequest {
+		t.Errorf("consent with an altered request = %d, want 400", w.Code)
+	}
+	if w := tp.consent(0, request, "allow"); w.Code == http.StatusFound {
+		t.Error("consent without a signed-in user redirected")
+	}
+
+	// Denying sends the user back with access_denied.
+	w := tp.consent(1, request, "deny")
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || location.Query().Get("error") != "access_denied" || location.Query().Get("code") != "" {
+		t.Errorf("denied consent = %d %s, want an access_denied redirect", w.Code, location)
+	}
+
+	// The request expires.
+	tp.now = func() time.Time { return time.Now().Add(tp.config.ConsentTTL + time.Minute) }
+	if w := tp.consent(1, request, "allow"); w.Code != http.StatusBadRequest {
+		t.Errorf("consent after the request expired = %d, want 400", w.Code)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tp := newTestProvider(t)
+	_, res, _ := tp.exchange(tp.grantCode(t, 1, nil), testVerifier, testRedirect)
+
+	refresh := url.Values{"grant_type": {models.GrantRefreshToken}, "refresh_token": {res.RefreshToken}}
+	w, next, _ := tp.token(refresh)
+	if w.Code != http.StatusOK || next.RefreshToken == "" || next.RefreshToken == res.RefreshToken {
+		t.Fatalf("refresh = %d %s", w.Code, w.Body)
+	}
+	refresh.Set("refresh_token", res.RefreshToken)
+	if w, _, oerr := tp.token(refresh); oerr.Error != "invalid_grant" {
+		t.Errorf("second use of a refresh token = %d %s, want invalid_grant", w.Code, w.Body)
+	}
+}
+
+func TestRefreshTokenOfInactiveUser(t *testing.T) {
+	for _, status := range []string{models.AccountSuspended, models.AccountDeactivated, models.AccountDeleted} {
+		tp := newTestProvider(t)
+		_, res, _ := tp.exchange(tp.grantCode(t, 1, nil), testVerifier, testRedirect)
+
+		tp.accounts.statuses[1] = status
+		refresh := url.Values{"grant_type": {models.GrantRefreshToken}, "refresh_token": {res.RefreshToken}}
+		if w, next, oerr := tp.token(refresh); oerr.Error != "invalid_grant" || next.AccessToken != "" {
+			t.Errorf("refresh of a %s user = %d %s, want invalid_grant", status, w.Code, w.Body)
+		}
+	}
+}
+
+func TestUserinfo(t *testing.T) {
+	tests := []struct {
+		status   string
+		wantCode int
+	}{
+		{models.AccountActive, http.StatusOK},
+		{models.AccountSuspended, http.StatusUnauthorized},
+		{models.AccountDeactivated, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		tp := newTestProvider(t)
+		_, res, _ := tp.exchange(tp.grantCode(t, 1, nil), testVerifier, testRedirect)
+
+		tp.accounts.statuses[1] = tt.status
+		req := httptest.NewRequest(http.MethodGet, UserinfoPath, nil)
+		req.Header.Set("Authorization", "Bearer "+res.AccessToken)
+		w := tp.do(req, 0)
+		if w.Code != tt.wantCode {
+			t.Errorf("userinfo of a %s user = %d %s, want %d", tt.status, w.Code, w.Body, tt.wantCode)
+			continue
+		}
+		if tt.wantCode == http.StatusOK && !strings.Contains(w.Body.String(), `"email":"user1@example.com"`) {
+			t.Errorf("userinfo of a %s user = %s", tt.status, w.Body)
+		}
+		if tt.wantCode != http.StatusOK && !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+			t.Errorf("userinfo of a %s user: WWW-Authenticate = %q", tt.status, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// oauthError is an OAuth 2.0 error response (RFC 6749 section 5.2).
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// tokenResponse is a successful token endpoint response.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// AccessTokenClaims are the claims of an access token issued by the provider. The audience is the
+// issuer; the subject is the user ID, or the client ID for the client credentials grant.
+type AccessTokenClaims struct {
+	gojwt.RegisteredClaims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"` // Space separated.
+}
+
+// idTokenClaims are the claims of an ID token.
+type idTokenClaims struct {
+	gojwt.RegisteredClaims
+	AuthorizedParty string             `json:"azp"`
+	AuthTime        *gojwt.NumericDate `json:"auth_time,omitempty"`
+	Nonce           string             `json:"nonce,omitempty"`
+	Email           string             `json:"email,omitempty"`
+}
+
+// Token handles the token endpoint for the authorization code, refresh token and client credentials grants.
+func (p *Provider[U]) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, err := p.authenticateClient(c)
+	if err != nil {
+		if errors.Is(err, goat.ErrClientNotFound) || errors.Is(err, goat.ErrInvalidCredentials) {
+			if _, _, basic := c.Request.BasicAuth(); basic {
+				c.Header("WWW-Authenticate", `Basic realm="token"`)
+			}
+			c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_client"})
+			return
+		}
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	switch grantType {
+	case models.GrantAuthorizationCode, models.GrantRefreshToken, models.GrantClientCredentials:
+		if !client.AllowsGrant(grantType) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "unauthorized_client", Description: "the client may not use this grant type"})
+			return
+		}
+	}
+	switch grantType {
+	case models.GrantAuthorizationCode:
+		p.exchangeCode(c, client)
+	case models.GrantRefreshToken:
+		p.refresh(c, client)
+	case models.GrantClientCredentials:
+		p.clientCredentials(c, client)
+	default:
+		c.JSON(http.StatusBadRequest, oauthError{Error: "unsupported_grant_type"})
+	}
+}
+
+// authenticateClient identifies the client with HTTP Basic authentication or the client_id and
+// client_secret form fields. Public clients only send their ID.
+func (p *Provider[U]) authenticateClient(c *gin.Context) (*models.Client, error) {
+	id, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before being put in the header.
+		var err1, err2 error
+		id, err1 = url.QueryUnescape(id)
+		secret, err2 = url.QueryUnescape(secret)
+		if err1 != nil || err2 != nil {
+			return nil, goat.ErrInvalidCredentials
+		}
+	} else {
+		id, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	client, err := p.store.GetClient(id)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		if secret != "" {
+			return nil, goat.ErrInvalidCredentials
+		}
+		return client, nil
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, goat.ErrInvalidCredentials
+	}
+	return client, nil
+}
+
+// exchangeCode redeems an authorization code.
+func (p *Provider[U]) exchangeCode(c *gin.Context, client *models.Client) {
+	grant, ok := p.takeGrant(c, client, c.PostForm("code"), models.GrantAuthorizationCode)
+	if !ok {
+		return
+	}
+	if c.PostForm("redirect_uri") != grant.RedirectURI {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "redirect_uri does not match the authorization request"})
+		return
+	}
+	verifier := c.PostForm("code_verifier")
+	if grant.CodeChallenge != "" || verifier != "" {
+		sum := sha256.Sum256([]byte(verifier))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(challenge), []byte(grant.CodeChallenge)) != 1 {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "code_verifier does not match the code challenge"})
+			return
+		}
+	}
+	p.issueTokens(c, client, grant, grant.Scopes)
+}
+
+// refresh redeems a refresh token, which is replaced by a new one. The scope parameter can narrow
+// the scopes of the new access token; the new refresh token keeps the original scopes.
+func (p *Provider[U]) refresh(c *gin.Context, client *models.Client) {
+	grant, ok := p.takeGrant(c, client, c.PostForm("refresh_token"), models.GrantRefreshToken)
+	if !ok {
+		return
+	}
+	scopes := grant.Scopes
+	if scope := c.PostForm("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+		if !subset(scopes, grant.Scopes) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_scope", Description: "scope exceeds the original grant"})
+			return
+		}
+	}
+	grant.Nonce = "" // The nonce belongs to the original authentication only.
+	p.issueTokens(c, client, grant, scopes)
+}
+
+// clientCredentials issues an access token to a confidential client acting on its own behalf.
+func (p *Provider[U]) clientCredentials(c *gin.Context, client *models.Client) {
+	if client.Public {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "unauthorized_client", Description: "public clients cannot use the client credentials grant"})
+		return
+	}
+	scopes := strings.Fields(c.PostForm("scope"))
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	// There is no user, so OpenID Connect scopes do not apply.
+	if !subset(scopes, client.Scopes) || contains(scopes, ScopeOpenID) {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_scope"})
+		return
+	}
+
+	now := p.now()
+	accessToken, err := p.accessToken(client.ID, client.ID, scopes, now)
+	if err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// takeGrant redeems the code or refresh token, checking that it is of type t, was issued to client
+// and has not expired. It writes the error response and reports false when the grant is unusable.
+func (p *Provider[U]) takeGrant(c *gin.Context, client *models.Client, token, t string) (*models.Grant, bool) {
+	if token == "" {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "missing " + strings.TrimPrefix(t, "authorization_")})
+		return nil, false
+	}
+	grant, err := p.store.ForTenant(tenant.ID(c)).TakeGrant(hashToken(token))
+	if err != nil {
+		if errors.Is(err, goat.ErrGrantNotFound) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant"})
+			return nil, false
+		}
+		goat.AbortWithProblem(c, err)
+		return nil, false
+	}
+	if grant.Type != t || grant.ClientID != client.ID || grant.Expired(p.now()) {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant"})
+		return nil, false
+	}
+	return grant, true
+}
+
+// issueTokens responds with an access token for scopes, an ID token when the openid scope is
+// included, and a new refresh token if the client may use them. Users whose account is not active
+// get none.
+func (p *Provider[U]) issueTokens(c *gin.Context, client *models.Client, grant *models.Grant, scopes []string) {
+	user, err := tenant.Users(c, p.users).GetUserByID(grant.UserID)
+	if err != nil {
+		if errors.Is(err, goat.ErrUserNotFound) {
+			c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "the user no longer exists"})
+			return
+		}
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	// Codes and refresh tokens issued before the user was suspended, deactivated or deleted are dead.
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: err.Error()})
+		return
+	}
+	subject := strconv.FormatUint(uint64(grant.UserID), 10)
+	now := p.now()
+
+	res := tokenResponse{TokenType: "Bearer", ExpiresIn: int64(p.config.AccessTokenTTL.Seconds()), Scope: strings.Join(scopes, " ")}
+	if res.AccessToken, err = p.accessToken(subject, client.ID, scopes, now); err != nil {
+		goat.AbortWithProblem(c, err)
+		return
+	}
+
+	if contains(scopes, ScopeOpenID) {
+		claims := idTokenClaims{
+			RegisteredClaims: gojwt.RegisteredClaims{
+				Issuer:    p.config.Issuer,
+				Subject:   subject,
+				Audience:  gojwt.ClaimStrings{client.ID},
+				IssuedAt:  gojwt.NewNumericDate(now),
+				ExpiresAt: gojwt.NewNumericDate(now.Add(p.config.AccessTokenTTL)),
+			},
+			AuthorizedParty: client.ID,
+			AuthTime:        gojwt.NewNumericDate(grant.AuthTime),
+			Nonce:           grant.Nonce,
+		}
+		if contains(scopes, ScopeEmail) {
+			claims.Email = user.GetUser().Email
+		}
+		if res.IDToken, err = p.keys.Sign(claims); err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+	}
+
+	if client.AllowsGrant(models.GrantRefreshToken) {
+		refreshToken, err := randomToken(32)
+		if err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		err = p.store.ForTenant(tenant.ID(c)).CreateGrant(&models.Grant{
+			ID:        hashToken(refreshToken),
+			Type:      models.GrantRefreshToken,
+			ClientID:  client.ID,
+			UserID:    grant.UserID,
+			Scopes:    grant.Scopes,
+			AuthTime:  grant.AuthTime,
+			CreatedAt: now,
+			ExpiresAt: now.Add(p.config.RefreshTokenTTL),
+		})
+		if err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		res.RefreshToken = refreshToken
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// accessToken signs an access token for subject, issued to clientID.
+func (p *Provider[U]) accessToken(subject, clientID string, scopes []string, now time.Time) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return p.keys.Sign(AccessTokenClaims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    p.config.Issuer,
+			Subject:   subject,
+			Audience:  gojwt.ClaimStrings{p.config.Issuer},
+			IssuedAt:  gojwt.NewNumericDate(now),
+			ExpiresAt: gojwt.NewNumericDate(now.Add(p.config.AccessTokenTTL)),
+		},
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+	})
+}
@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// userinfoResponse holds the claims about the user returned by the userinfo endpoint.
+type userinfoResponse struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+// Userinfo handles the userinfo endpoint, returning claims about the user an access token was issued for.
+// Tokens of users who are suspended, deactivated or deleted since are rejected as invalid.
+func (p *Provider[U]) Userinfo(c *gin.Context) {
+	token := c.PostForm("access_token")
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, credentials, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			p.bearerError(c, http.StatusUnauthorized, "invalid_request")
+			return
+		}
+		token = credentials
+	}
+	if token == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="userinfo"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := p.VerifyAccessToken(token)
+	if err != nil {
+		p.bearerError(c, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+	if !contains(strings.Fields(claims.Scope), ScopeOpenID) {
+		p.bearerError(c, http.StatusForbidden, "insufficient_scope")
+		return
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		p.bearerError(c, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+	user, err := tenant.Users(c, p.users).GetUserByID(uint(userID))
+	if err != nil {
+		if errors.Is(err, goat.ErrUserNotFound) {
+			p.bearerError(c, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+		goat.AbortWithProblem(c, err)
+		return
+	}
+	if goat.AccountError(user.GetUser()) != nil {
+		p.bearerError(c, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+
+	res := userinfoResponse{Subject: claims.Subject}
+	if contains(strings.Fields(claims.Scope), ScopeEmail) {
+		res.Email = user.GetUser().Email
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, res)
+}
+
+// VerifyAccessToken validates an access token issued by the provider and returns its claims. APIs
+// that accept the provider's tokens can use it, checking the scope and client themselves.
+func (p *Provider[U]) VerifyAccessToken(token string) (*AccessTokenClaims, error) {
+	claims := &AccessTokenClaims{}
+	_, err := gojwt.ParseWithClaims(token, claims, p.keys.Keyfunc,
+		gojwt.WithValidMethods(p.keys.Algorithms()),
+		gojwt.WithIssuer(p.config.Issuer),
+		gojwt.WithAudience(p.config.Issuer),
+		gojwt.WithTimeFunc(p.now),
+		gojwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, gojwt.ErrTokenExpired) {
+			return nil, goat.ErrExpiredToken
+		}
+		return nil, goat.ErrInvalidToken
+	}
+	// Tokens without a client, such as goat's own access tokens, were not issued by the provider.
+	if claims.ClientID == "" {
+		return nil, goat.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// bearerError responds with an RFC 6750 error.
+func (p *Provider[U]) bearerError(c *gin.Context, status int, code string) {
+	c.Header("WWW-Authenticate", `Bearer realm="userinfo", error="`+code+`"`)
+	c.AbortWithStatusJSON(status, oauthError{Error: code})
+}
@@ -0,0 +1,209 @@
+// Package outbox relays the user lifecycle events that user repositories created with
+// repository.WithOutbox record in the same transaction as each change. Because the event is saved
+// with the change, it survives a crash that would lose an in-process hook; the price is that
+// delivery is at least once, so handlers must be idempotent, e.g. by remembering message IDs.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// maxErrorLength is the longest handler error stored with a message; longer ones are truncated.
+const maxErrorLength = 1024
+
+// maxRetryDelay is the longest Run waits before looking again after the store failed.
+const maxRetryDelay = time.Minute
+
+// Handler handles a message. Returning an error, or panicking, schedules the message for another
+// delivery to every handler of its type.
+type Handler func(ctx context.Context, message *models.OutboxMessage) error
+
+// Config controls how the Relay polls, retries and gives up.
+type Config struct {
+	BatchSize    int           // Messages claimed at once.
+	PollInterval time.Duration // Time between polls once the outbox is drained.
+	Lease        time.Duration // How long a claimed message is hidden from other relays; longer than handlers take.
+	MaxAttempts  int           // Deliveries tried before a message is dead-lettered.
+	MinBackoff   time.Duration // Delay before the first retry; doubled for each further one.
+	MaxBackoff   time.Duration // Longest delay between retries.
+}
+
+// DefaultConfig returns a Config that polls every second and retries for about a day before
+// dead-lettering.
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:    100,
+		PollInterval: time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  15,
+		MinBackoff:   time.Second,
+		MaxBackoff:   4 * time.Hour,
+	}
+}
+
+// Relay delivers outbox messages to the handlers registered for their type, and removes them once
+// every handler has succeeded. Messages of a type no handler is registered for are removed as
+// delivered. Any number of relays can share a store.
+type Relay struct {
+	store  goat.OutboxStore
+	config Config
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[models.UserEventType][]Handler
+}
+
+// NewRelay creates a Relay for the messages in store.
+func NewRelay(store goat.OutboxStore, config Config) *Relay {
+	return &Relay{store: store, config: config, now: time.Now, handlers: map[models.UserEventType][]Handler{}}
+}
+
+// Handle registers handler for messages of type typ.
+func (r *Relay) Handle(typ models.UserEventType, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[typ] = append(r.handlers[typ], handler)
+}
+
+// RelayOnce claims one batch of due messages and delivers them. It returns the number of messages
+// claimed; an error means the store failed, and the messages left undone are delivered again once
+// their lease runs out.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.ClaimOutboxMessages(r.now(), r.config.Lease, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, message := range messages {
+		if err := r.deliver(ctx, message); err != nil {
+			return len(messages), err
+		}
+	}
+	return len(messages), nil
+}
+
+// Run relays messages until ctx is cancelled. A full batch is followed by the next one straight
+// away; otherwise Run waits PollInterval before looking again. Store failures are reported to
+// onError, if not nil, and Run looks again after a delay that backs off up to maxRetryDelay.
+func (r *Relay) Run(ctx context.Context, onError func(error)) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			n, err := r.RelayOnce(ctx)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				failures++
+				if onError != nil {
+					onError(err)
+				}
+				timer.Reset(goat.RetryDelay(failures, maxRetryDelay))
+			case n == r.config.BatchSize:
+				failures = 0
+				timer.Reset(0)
+			default:
+				failures = 0
+				timer.Reset(r.config.PollInterval)
+			}
+		}
+	}
+}
+
+// DeadLetters returns up to limit dead-lettered messages, oldest first.
+func (r *Relay) DeadLetters(limit int) ([]*models.OutboxMessage, error) {
+	return r.store.ListDeadOutboxMessages(limit)
+}
+
+// Requeue gives a dead-lettered message a fresh set of attempts, starting now.
+func (r *Relay) Requeue(id string) error {
+	message, err := r.store.GetOutboxMessage(id)
+	if err != nil {
+		return err
+	}
+	message.Status = models.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = r.now()
+	message.LastError = ""
+	return r.store.UpdateOutboxMessage(message)
+}
+
+// User decodes the user carried by message. Its password is always empty.
+func User[U models.Account](message *models.OutboxMessage) (U, error) {
+	user := models.New[U]()
+	if err := json.Unmarshal(message.Payload, user); err != nil {
+		var zero U
+		return zero, err
+	}
+	return user, nil
+}
+
+// deliver runs the handlers for message, then removes it, or schedules a retry if a handler failed.
+func (r *Relay) deliver(ctx context.Context, message *models.OutboxMessage) error {
+	r.mu.RLock()
+	handlers := r.handlers[message.Type]
+	r.mu.RUnlock()
+
+	var failure error
+	for _, handler := range handlers {
+		if err := call(ctx, handler, message); err != nil {
+			failure = err
+			break
+		}
+	}
+	if failure == nil {
+		return r.store.DeleteOutboxMessage(message.ID)
+	}
+
+	message.Attempts++
+	message.LastError = truncate(failure.Error(), maxErrorLength)
+	if message.Attempts >= r.config.MaxAttempts {
+		message.Status = models.OutboxDead
+	} else {
+		message.NextAttemptAt = r.now().Add(r.backoff(message.Attempts))
+	}
+	return r.store.UpdateOutboxMessage(message)
+}
+
+// backoff returns the delay before retrying a message that has failed attempts times.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.MinBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+	return delay
+}
+
+// call runs handler, turning a panic into an error so that one faulty handler cannot stop the relay.
+func call(ctx context.Context, handler Handler, message *models.OutboxMessage) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("outbox: %s handler panicked: %v", message.Type, v)
+		}
+	}()
+	return handler(ctx, message)
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+// newTestRelay returns a relay over a memory store holding one pending user.registered message,
+// and the relay's clock.
+func newTestRelay(t *testing.T, config Config) (*Relay, *repository.MemoryOutboxStore, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryOutboxStore()
+	r := NewRelay(store, config)
+	r.now = func() time.Time { return now }
+	err := store.AppendOutboxMessage(&models.OutboxMessage{
+		ID: "m1", Type: models.UserRegistered, UserID: 7, Payload: []byte(`{"id":7}`),
+		CreatedAt: now, Status: models.OutboxPending, NextAttemptAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, store, &now
+}
+
+func TestRelayDelivers(t *testing.T) {
+	ok := func(context.Context, *models.OutboxMessage) error { return nil }
+	failing := func(context.Context, *models.OutboxMessage) error { return errors.New("webhook down") }
+	panicking := func(context.Context, *models.OutboxMessage) error { panic("nil map") }
+	long := func(context.Context, *models.OutboxMessage) error {
+		return errors.New(strings.Repeat("é", maxErrorLength))
+	}
+
+	tests := []struct {
+		name      string
+		handlers  []Handler
+		wantCalls int    // Handlers run before delivery stopped.
+		wantError string // Stored error of a message left for a retry; empty if it was delivered.
+	}{
+		{"no handlers", nil, 0, ""},
+		{"every handler succeeds", []Handler{ok, ok}, 2, ""},
+		{"a handler fails", []Handler{ok, failing, ok}, 2, "webhook down"},
+		{"a handler panics", []Handler{panicking, ok}, 1, "outbox: user.registered handler panicked: nil map"},
+		{"long error", []Handler{long}, 1, strings.Repeat("é", maxErrorLength/2)},
+	}
+	for _, tt := range tests {
+		r, store, _ := newTestRelay(t, DefaultConfig())
+		calls := 0
+		for _, h := range tt.handlers {
+			h := h
+			r.Handle(models.UserRegistered, func(ctx context.Context, m *models.OutboxMessage) error {
+				calls++
+				return h(ctx, m)
+			})
+		}
+		// Handlers of other types are not run.
+		r.Handle(models.UserDeleted, func(context.Context, *models.OutboxMessage) error {
+			t.Errorf("%s: user.deleted handler run for a user.registered message", tt.name)
+			return nil
+		})
+
+		n, err := r.RelayOnce(context.Background())
+		if err != nil || n != 1 {
+			t.Fatalf("%s: RelayOnce = %d, %v, want 1 message", tt.name, n, err)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%s: %d handlers run, want %d", tt.name, calls, tt.wantCalls)
+		}
+		m, err := store.GetOutboxMessage("m1")
+		if tt.wantError == "" {
+			if !errors.Is(err, goat.ErrOutboxMessageNotFound) {
+				t.Errorf("%s: delivered message still stored: %+v, %v", tt.name, m, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: failed message removed: %v", tt.name, err)
+		}
+		if m.Attempts != 1 || m.Status != models.OutboxPending || m.LastError != tt.wantError {
+			t.Errorf("%s: message = %d attempts, status %q, error %q, want 1, pending and %q", tt.name, m.Attempts, m.Status, m.LastError, tt.wantError)
+		}
+	}
+}
+
+func TestRelayRetriesThenDeadLetters(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxAttempts = 4
+	config.MinBackoff = time.Second
+	config.MaxBackoff = 3 * time.Second
+	r, store, now := newTestRelay(t, config)
+	fail := true
+	r.Handle(models.UserRegistered, func(context.Context, *models.OutboxMessage) error {
+		if fail {
+			return errors.New("webhook down")
+		}
+		return nil
+	})
+
+	// Each failure pushes the next attempt back, doubling up to MaxBackoff; the last one dead-letters.
+	for i, wantDelay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 0} {
+		attempt := i + 1
+		if n, err := r.RelayOnce(context.Background()); err != nil || n != 1 {
+			t.Fatalf("attempt %d: RelayOnce = %d, %v, want 1 message", attempt, n, err)
+		}
+		m, err := store.GetOutboxMessage("m1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Attempts != attempt {
+			t.Errorf("attempt %d: Attempts = %d", attempt, m.Attempts)
+		}
+		if wantDelay == 0 {
+			if m.Status != models.OutboxDead {
+				t.Errorf("attempt %d: status = %q, want %q", attempt, m.Status, models.OutboxDead)
+			}
+			break
+		}
+		if m.Status != models.OutboxPending || !m.NextAttemptAt.Equal(now.Add(wantDelay)) {
+			t.Errorf("attempt %d: status %q, next attempt at %v, want pending at %v", attempt, m.Status, m.NextAttemptAt, now.Add(wantDelay))
+		}
+
+		// The message is not claimed again before it is due.
+		*now = now.Add(wantDelay - time.Millisecond)
+		if n, err := r.RelayOnce(context.Background()); err != nil || n != 0 {
+			t.Errorf("attempt %d: RelayOnce before the retry is due = %d, %v, want none", attempt, n, err)
+		}
+		*now = now.Add(time.Millisecond)
+	}
+
+	// Dead messages are listed and never claimed, until they are requeued.
+	*now = now.Add(24 * time.Hour)
+	if n, err := r.RelayOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("RelayOnce with a dead message = %d, %v, want none", n, err)
+	}
+	dead, err := r.DeadLetters(10)
+	if err != nil || len(dead) != 1 || dead[0].ID != "m1" || dead[0].LastError != "webhook down" {
+		t.Fatalf("DeadLetters = %v, %v, want m1 with its last error", dead, err)
+	}
+	if err := r.Requeue("m1"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := store.GetOutboxMessage("m1")
+	if err != nil || m.Status != models.OutboxPending || m.Attempts != 0 || m.LastError != "" || !m.NextAttemptAt.Equal(*now) {
+		t.Errorf("requeued message = %+v, %v, want pending with no attempts, due now", m, err)
+	}
+	fail = false
+	if n, err := r.RelayOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("RelayOnce after Requeue = %d, %v, want 1 message", n, err)
+	}
+	if _, err := store.GetOutboxMessage("m1"); !errors.Is(err, goat.ErrOutboxMessageNotFound) {
+		t.Errorf("requeued message not removed once delivered: %v", err)
+	}
+	if err := r.Requeue("m1"); !errors.Is(err, goat.ErrOutboxMessageNotFound) {
+		t.Errorf("Requeue of a delivered message error = %v, want %v", err, goat.ErrOutboxMessageNotFound)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRelay(nil, Config{MinBackoff: time.Second, MaxBackoff: time.Minute})
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{6, 32 * time.Second},
+		{7, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
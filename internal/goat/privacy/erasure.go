@@ -0,0 +1,416 @@
+package privacy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+)
+
+// Mode says what Erase does with the user's account.
+type Mode string
+
+// Erasure modes.
+const (
+	Delete    Mode = "delete"    // Remove the user and everything that refers to them.
+	Anonymize Mode = "anonymize" // Keep the user's ID, and their organization memberships, behind placeholder account data.
+)
+
+// Actions taken on the data of a store, as recorded in a Step.
+const (
+	ActionDeleted    = "deleted"
+	ActionAnonymized = "anonymized"
+	ActionRevoked    = "revoked"
+	ActionRedacted   = "redacted"
+	ActionRetained   = "retained"
+)
+
+// Step records what an erasure did to the data of one store.
+type Step struct {
+	Store  string `json:"store"`
+	Action string `json:"action"` // One of the Action* constants.
+	Count  int    `json:"count"`  // Records the action applied to.
+	Note   string `json:"note,omitempty"`
+}
+
+// Report records an erasure. Its digest covers every other field, so a report kept by the
+// application, or handed to the user, can be checked against the digest in the audit log.
+type Report struct {
+	UserID      uint      `json:"user_id"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	Mode        Mode      `json:"mode"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Steps       []Step    `json:"steps"`
+	Digest      string    `json:"digest"` // SHA-256 of the report's JSON with an empty digest, hex encoded.
+}
+
+// Verify checks that the report matches its digest. It returns ErrInvalidErasureReport if not.
+func (r *Report) Verify() error {
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(digest), []byte(r.Digest)) != 1 {
+		return goat.ErrInvalidErasureReport
+	}
+	return nil
+}
+
+// digest returns the SHA-256 of the report's JSON with an empty digest.
+func (r *Report) digest() (string, error) {
+	unsigned := *r
+	unsigned.Digest = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Erase erases the data of the user with id, soft-deleted or not, from every store, and reports
+// what it did. The audit log is append-only and chained by hash, so audit events by or on the user,
+// and failed logins as them, are kept: with a redactor set, their IP addresses, user agents and
+// emails are removed; without one, they are retained as they are. In Delete mode, a user who owns an
+// organization with other members is refused with ErrOwnerRequired before anything changes;
+// organizations they are alone in are deleted.
+//
+// The user is erased last, so Erase is safe to retry after an error. Once it succeeds, the erasure
+// is recorded in the audit log if an auditor is set.
+func (m *Manager[U]) Erase(id uint, mode Mode) (*Report, error) {
+	if mode != Delete && mode != Anonymize {
+		return nil, goat.ErrInvalidErasureMode
+	}
+	user, err := m.user(id)
+	if err != nil {
+		return nil, err
+	}
+	u := user.GetUser()
+	report := &Report{UserID: id, TenantID: u.TenantID, Mode: mode, StartedAt: m.now().UTC(), Steps: []Step{}}
+	step := func(store, action string, count int, note string) {
+		report.Steps = append(report.Steps, Step{Store: store, Action: action, Count: count, Note: note})
+	}
+
+	var owned, joined []*models.Organization
+	if m.stores.Organizations != nil && mode == Delete {
+		if owned, joined, err = m.organizations(id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Queued events carry the user's data, so they go first, before any more are queued.
+	if s := m.stores.Outbox; s != nil {
+		n, err := s.DeleteUserOutboxMessages(id)
+		if err != nil {
+			return nil, err
+		}
+		step("outbox", ActionDeleted, n, "")
+	}
+	if s := m.stores.Webhooks; s != nil {
+		n, err := s.DeleteUserWebhookDeliveries(id)
+		if err != nil {
+			return nil, err
+		}
+		step("webhook_deliveries", ActionDeleted, n, "copies already delivered are held by the endpoints' owners")
+	}
+	if s := m.stores.Sessions; s != nil {
+		sessions, err := s.ListByUser(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.DeleteByUser(id, ""); err != nil {
+			return nil, err
+		}
+		step("sessions", ActionDeleted, len(sessions), "")
+	}
+	if s := m.stores.APIKeys; s != nil {
+		keys, err := s.ListAPIKeys(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if err := s.DeleteAPIKey(key.ID); err != nil && !errors.Is(err, goat.ErrAPIKeyNotFound) {
+				return nil, err
+			}
+		}
+		step("api_keys", ActionDeleted, len(keys), "")
+	}
+	if s := m.stores.Identities; s != nil {
+		identities, err := s.ListIdentities(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, identity := range identities {
+			if err := s.DeleteIdentity(identity.Provider, identity.Subject); err != nil && !errors.Is(err, goat.ErrIdentityNotFound) {
+				return nil, err
+			}
+		}
+		step("identities", ActionDeleted, len(identities), "")
+	}
+	if s := m.stores.OIDC; s != nil {
+		consents, err := s.ListConsents(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, consent := range consents {
+			if err := s.DeleteConsent(id, consent.ClientID); err != nil {
+				return nil, err
+			}
+		}
+		step("oidc_consents", ActionDeleted, len(consents), "")
+		n, err := s.DeleteUserGrants(id)
+		if err != nil {
+			return nil, err
+		}
+		step("oidc_grants", ActionDeleted, n, "")
+	}
+	if s := m.stores.Organizations; s != nil {
+		if mode == Delete {
+			for _, org := range owned {
+				if err := s.DeleteOrganization(org.ID); err != nil && !errors.Is(err, goat.ErrOrganizationNotFound) {
+					return nil, err
+				}
+			}
+			step("organizations", ActionDeleted, len(owned), "organizations the user owned alone")
+			for _, org := range joined {
+				if err := s.DeleteMembership(org.ID, id); err != nil && !errors.Is(err, goat.ErrMembershipNotFound) {
+					return nil, err
+				}
+			}
+			step("memberships", ActionDeleted, len(joined), "")
+		} else {
+			orgs, err := s.ListOrganizations(id)
+			if err != nil {
+				return nil, err
+			}
+			step("memberships", ActionRetained, len(orgs), "they refer to the anonymized user")
+		}
+		n, err := s.DeleteInvitationsByEmail(utils.DefaultEmailPolicy.NormalizeEmail(u.Email))
+		if err != nil {
+			return nil, err
+		}
+		step("invitations", ActionDeleted, n, "invitations sent to the user's address")
+	}
+	if s := m.stores.Revocations; s != nil {
+		if err := s.RevokeUserTokens(id, m.now()); err != nil {
+			return nil, err
+		}
+		step("access_tokens", ActionRevoked, 1, "tokens issued before the erasure are rejected")
+	}
+
+	// Failed logins are found by the user's email, so the audit log goes before the user.
+	if m.stores.Audit != nil {
+		events, err := m.auditEvents(id, u.Email)
+		if err != nil {
+			return nil, err
+		}
+		if m.redactor == nil {
+			step("audit_events", ActionRetained, len(events), "the audit log is append-only and chained by hash")
+		} else {
+			redacted, retained := 0, 0
+			for _, event := range events {
+				err := m.redactor.Redact(event)
+				switch {
+				case err == nil:
+					redacted++
+				case errors.Is(err, audit.ErrNotRedactable):
+					retained++
+				default:
+					return nil, err
+				}
+			}
+			step("audit_events", ActionRedacted, redacted, "IP addresses, user agents and emails removed; their digests keep the chain intact")
+			if retained > 0 {
+				step("audit_events", ActionRetained, retained, "do not match their hash; VerifyChain reports where the chain was broken")
+			}
+		}
+	}
+
+	if mode == Delete {
+		if err := m.users.EraseUser(id); err != nil {
+			return nil, err
+		}
+		step("users", ActionDeleted, 1, "")
+	} else {
+		if err := m.users.AnonymizeUser(id); err != nil {
+			return nil, err
+		}
+		step("users", ActionAnonymized, 1, "email, password and custom fields replaced with placeholders")
+	}
+
+	report.CompletedAt = m.now().UTC()
+	if report.Digest, err = report.digest(); err != nil {
+		return nil, err
+	}
+	if m.auditor != nil {
+		event := audit.Event(models.EventUserErased, 0, id)
+		event.TenantID = u.TenantID
+		event.Metadata = map[string]string{"mode": string(mode), "digest": report.Digest}
+		if err := m.auditor.Record(nil, event); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// VerifyErasure checks that report matches its digest, that it was made in the manager's tenant, and
+// that the stores hold no personal data of its user any more. It returns ErrErasureIncomplete,
+// naming the store, for data that is left. What the report records as retained is not checked, and
+// audit events are checked only when the report records them redacted; failed logins, found by an
+// email that is gone, are not checked.
+func (m *Manager[U]) VerifyErasure(report *Report) error {
+	if err := report.Verify(); err != nil {
+		return err
+	}
+	if report.TenantID != m.tenant {
+		return goat.ErrInvalidErasureReport
+	}
+	id := report.UserID
+
+	user, err := m.user(id)
+	switch {
+	case errors.Is(err, goat.ErrUserNotFound):
+		if report.Mode == Anonymize {
+			return err
+		}
+	case err != nil:
+		return err
+	case report.Mode == Delete:
+		return remains("users", 1)
+	default:
+		if err := anonymized(user); err != nil {
+			return err
+		}
+	}
+
+	if s := m.stores.Sessions; s != nil {
+		sessions, err := s.ListByUser(id)
+		if err != nil {
+			return err
+		}
+		if len(sessions) > 0 {
+			return remains("sessions", len(sessions))
+		}
+	}
+	if s := m.stores.APIKeys; s != nil {
+		keys, err := s.ListAPIKeys(id)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			return remains("api_keys", len(keys))
+		}
+	}
+	if s := m.stores.Identities; s != nil {
+		identities, err := s.ListIdentities(id)
+		if err != nil {
+			return err
+		}
+		if len(identities) > 0 {
+			return remains("identities", len(identities))
+		}
+	}
+	if s := m.stores.OIDC; s != nil {
+		consents, err := s.ListConsents(id)
+		if err != nil {
+			return err
+		}
+		if len(consents) > 0 {
+			return remains("oidc_consents", len(consents))
+		}
+	}
+	if s := m.stores.Organizations; s != nil && report.Mode == Delete {
+		orgs, err := s.ListOrganizations(id)
+		if err != nil {
+			return err
+		}
+		if len(orgs) > 0 {
+			return remains("memberships", len(orgs))
+		}
+	}
+	if s := m.stores.Audit; s != nil && report.records("audit_events", ActionRedacted) && !report.records("audit_events", ActionRetained) {
+		events, err := m.auditEvents(id, "")
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, event := range events {
+			if event.IP != "" || event.UserAgent != "" || event.Metadata["email"] != "" {
+				n++
+			}
+		}
+		if n > 0 {
+			return remains("audit_events", n)
+		}
+	}
+	return nil
+}
+
+// records reports whether the report has a step applying action to store.
+func (r *Report) records(store, action string) bool {
+	for _, step := range r.Steps {
+		if step.Store == store && step.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// organizations splits the organizations the user with id belongs to into those they own alone,
+// which are deleted with them, and those they only belong to. It returns ErrOwnerRequired if they
+// own one with other members.
+func (m *Manager[U]) organizations(id uint) (owned, joined []*models.Organization, err error) {
+	orgs, err := m.stores.Organizations.ListOrganizations(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, org := range orgs {
+		if org.OwnerID != id {
+			joined = append(joined, org)
+			continue
+		}
+		members, err := m.stores.Organizations.ListMembers(org.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(members) > 1 {
+			return nil, nil, fmt.Errorf("%w: transfer organization %s first", goat.ErrOwnerRequired, org.ID)
+		}
+		owned = append(owned, org)
+	}
+	return owned, joined, nil
+}
+
+// anonymized checks that user holds only the placeholders AnonymizeUser stores.
+func anonymized[U models.Account](user U) error {
+	u := user.GetUser()
+	if u.Email != models.ErasedEmail(u.ID) || u.HasUsablePassword() {
+		return remains("users", 1)
+	}
+	customFields, err := models.MarshalCustomFields(user)
+	if err != nil {
+		return err
+	}
+	blank, err := models.MarshalCustomFields(models.New[U]())
+	if err != nil {
+		return err
+	}
+	if string(customFields) != string(blank) {
+		return remains("users", 1)
+	}
+	return nil
+}
+
+// remains returns the ErrErasureIncomplete for n records left in store.
+func remains(store string, n int) error {
+	return fmt.Errorf("%w: %d left in %s", goat.ErrErasureIncomplete, n, store)
+}
@@ -0,0 +1,296 @@
+package privacy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/audit"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+// testUsers is a Users holding the users of every tenant, keyed by ID.
+type testUsers struct {
+	Users[*models.User]
+	users  map[uint]*models.User
+	tenant string
+}
+
+func (u *testUsers) ForTenant(tenantID string) goat.UserService[*models.User] {
+	return &testUsers{users: u.users, tenant: tenantID}
+}
+
+func (u *testUsers) GetUserByID(id uint) (*models.User, error) {
+	user, ok := u.users[id]
+	if !ok || user.TenantID != u.tenant || user.Status == models.AccountDeleted {
+		return nil, goat.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *testUsers) GetDeletedUser(id uint) (*models.User, error) {
+	user, ok := u.users[id]
+	if !ok || user.TenantID != u.tenant || user.Status != models.AccountDeleted {
+		return nil, goat.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *testUsers) EraseUser(id uint) error {
+	if _, err := u.user(id); err != nil {
+		return err
+	}
+	delete(u.users, id)
+	return nil
+}
+
+func (u *testUsers) AnonymizeUser(id uint) error {
+	user, err := u.user(id)
+	if err != nil {
+		return err
+	}
+	anonymous := &models.User{ID: id, TenantID: user.TenantID, Email: models.ErasedEmail(id), Status: models.AccountDeactivated}
+	if err := anonymous.SetUnusablePassword(); err != nil {
+		return err
+	}
+	u.users[id] = anonymous
+	return nil
+}
+
+// user returns the user with id, soft-deleted or not.
+func (u *testUsers) user(id uint) (*models.User, error) {
+	user, err := u.GetUserByID(id)
+	if errors.Is(err, goat.ErrUserNotFound) {
+		return u.GetDeletedUser(id)
+	}
+	return user, err
+}
+
+// Users of the tenant acme that newTestManager creates.
+const (
+	adaID uint = 1 // The user erased by the tests.
+	bobID uint = 2
+)
+
+// newTestManager returns a manager for the tenant acme holding the records of users adaID and
+// bobID, with adaID owning an organization alone and belonging to one owned by bobID, and the
+// audit logger recording their events.
+func newTestManager(t *testing.T) (*Manager[*models.User], *audit.Logger) {
+	t.Helper()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	users := &testUsers{users: map[uint]*models.User{}}
+	auditStore := repository.NewMemoryAuditStore()
+	logger := audit.NewLogger(auditStore, audit.Config{})
+	m := NewManager[*models.User](users, Stores{
+		Identities:    repository.NewMemoryIdentityStore(),
+		Sessions:      repository.NewMemorySessionStore(),
+		APIKeys:       repository.NewMemoryAPIKeyStore(),
+		Organizations: repository.NewMemoryOrganizationStore(),
+		OIDC:          repository.NewMemoryOIDCStore(),
+		Revocations:   repository.NewMemoryRevocationStore(),
+		Audit:         auditStore,
+		Outbox:        repository.NewMemoryOutboxStore(),
+	}).ForTenant("acme")
+	m.now = func() time.Time { return now }
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []uint{adaID, bobID} {
+		user := &models.User{ID: id, TenantID: "acme", Email: []string{"", "ada@example.com", "bob@example.com"}[id], Password: "$argon2id$hash", Status: models.AccountActive}
+		users.users[id] = user
+		must(m.stores.Sessions.Create(&models.Session{ID: user.Email, UserID: id, ExpiresAt: now.Add(time.Hour)}))
+		must(m.stores.APIKeys.CreateAPIKey(&models.APIKey{ID: "goat_" + user.Email, UserID: id}))
+		must(m.stores.Identities.CreateIdentity(&models.Identity{UserID: id, Provider: "google", Subject: user.Email}))
+		must(m.stores.OIDC.SaveConsent(&models.Consent{UserID: id, ClientID: "app", Scopes: []string{"openid"}}))
+		must(m.stores.Outbox.AppendOutboxMessage(&models.OutboxMessage{ID: user.Email, Type: models.UserRegistered, TenantID: "acme", UserID: id, Status: models.OutboxPending}))
+
+		event := audit.Event(models.EventLoginSucceeded, id, id)
+		event.TenantID, event.IP, event.UserAgent = "acme", "203.0.113.7", "curl/8.0"
+		must(logger.Record(nil, event))
+		failed := audit.Event(models.EventLoginFailed, 0, 0)
+		failed.TenantID, failed.IP = "acme", "203.0.113.7"
+		failed.Metadata = map[string]string{"email": user.Email}
+		must(logger.Record(nil, failed))
+	}
+
+	orgs := m.stores.Organizations
+	must(orgs.CreateOrganization(&models.Organization{ID: "owned", OwnerID: adaID}))
+	must(orgs.CreateOrganization(&models.Organization{ID: "joined", OwnerID: bobID}))
+	for _, membership := range []*models.Membership{
+		{OrganizationID: "owned", UserID: adaID, Role: models.RoleOwner},
+		{OrganizationID: "joined", UserID: bobID, Role: models.RoleOwner},
+		{OrganizationID: "joined", UserID: adaID, Role: models.RoleMember},
+	} {
+		must(orgs.SaveMembership(membership))
+	}
+	must(orgs.CreateInvitation(&models.Invitation{ID: "i1", OrganizationID: "joined", Email: "ada@example.com"}))
+	return m, logger
+}
+
+func TestErase(t *testing.T) {
+	type step struct {
+		action string
+		count  int
+	}
+	tests := []struct {
+		name      string
+		mode      Mode
+		redact    bool
+		wantSteps map[string][]step
+	}{
+		{"delete", Delete, false, map[string][]step{
+			"outbox": {{ActionDeleted, 1}}, "sessions": {{ActionDeleted, 1}}, "api_keys": {{ActionDeleted, 1}},
+			"identities": {{ActionDeleted, 1}}, "oidc_consents": {{ActionDeleted, 1}}, "oidc_grants": {{ActionDeleted, 0}},
+			"organizations": {{ActionDeleted, 1}}, "memberships": {{ActionDeleted, 1}}, "invitations": {{ActionDeleted, 1}},
+			"access_tokens": {{ActionRevoked, 1}}, "audit_events": {{ActionRetained, 2}}, "users": {{ActionDeleted, 1}},
+		}},
+		{"anonymize and redact", Anonymize, true, map[string][]step{
+			"outbox": {{ActionDeleted, 1}}, "sessions": {{ActionDeleted, 1}}, "api_keys": {{ActionDeleted, 1}},
+			"identities": {{ActionDeleted, 1}}, "oidc_consents": {{ActionDeleted, 1}}, "oidc_grants": {{ActionDeleted, 0}},
+			"memberships": {{ActionRetained, 2}}, "invitations": {{ActionDeleted, 1}},
+			"access_tokens": {{ActionRevoked, 1}}, "audit_events": {{ActionRedacted, 2}}, "users": {{ActionAnonymized, 1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, logger := newTestManager(t)
+			if tt.redact {
+				m.SetRedactor(logger)
+			}
+			m.SetAuditor(logger)
+
+			report, err := m.Erase(adaID, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.UserID != adaID || report.TenantID != "acme" || report.Mode != tt.mode {
+				t.Errorf("report of user %d of %q in mode %q, want user %d of acme in mode %q", report.UserID, report.TenantID, report.Mode, adaID, tt.mode)
+			}
+			got := map[string][]step{}
+			for _, st := range report.Steps {
+				got[st.Store] = append(got[st.Store], step{st.Action, st.Count})
+			}
+			for store, want := range tt.wantSteps {
+				if len(got[store]) != len(want) || got[store][0] != want[0] {
+					t.Errorf("steps of %s = %v, want %v", store, got[store], want)
+				}
+			}
+			if len(got) != len(tt.wantSteps) {
+				t.Errorf("report has steps for %d stores, want %d: %+v", len(got), len(tt.wantSteps), report.Steps)
+			}
+
+			if err := report.Verify(); err != nil {
+				t.Errorf("Verify error = %v", err)
+			}
+			if err := m.VerifyErasure(report); err != nil {
+				t.Errorf("VerifyErasure error = %v", err)
+			}
+			if err := m.ForTenant("globex").VerifyErasure(report); !errors.Is(err, goat.ErrInvalidErasureReport) {
+				t.Errorf("VerifyErasure in another tenant error = %v, want %v", err, goat.ErrInvalidErasureReport)
+			}
+
+			// The other user keeps everything.
+			if sessions, err := m.stores.Sessions.ListByUser(bobID); err != nil || len(sessions) != 1 {
+				t.Errorf("sessions of another user = %d, %v, want 1", len(sessions), err)
+			}
+			if _, err := m.users.GetUserByID(bobID); err != nil {
+				t.Errorf("another user: %v", err)
+			}
+
+			// The erasure is audited with the report's digest, and the chain still verifies.
+			page, err := logger.Query(models.AuditQuery{TenantID: "acme", Types: []string{models.EventUserErased}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Events) != 1 || page.Events[0].Metadata["digest"] != report.Digest {
+				t.Errorf("erasure events = %+v, want one with digest %s", page.Events, report.Digest)
+			}
+			if v, err := logger.VerifyChain(); err != nil || v == nil {
+				t.Errorf("VerifyChain = %+v, %v", v, err)
+			}
+		})
+	}
+}
+
+func TestEraseRefusesOwnerOfSharedOrganization(t *testing.T) {
+	m, _ := newTestManager(t)
+	if err := m.stores.Organizations.SaveMembership(&models.Membership{OrganizationID: "owned", UserID: bobID, Role: models.RoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Erase(adaID, Delete); !errors.Is(err, goat.ErrOwnerRequired) {
+		t.Fatalf("Erase error = %v, want %v", err, goat.ErrOwnerRequired)
+	}
+	// Nothing was erased.
+	if sessions, err := m.stores.Sessions.ListByUser(adaID); err != nil || len(sessions) != 1 {
+		t.Errorf("sessions after a refused erasure = %d, %v, want 1", len(sessions), err)
+	}
+	// Anonymizing keeps the user, and so their organizations.
+	if _, err := m.Erase(adaID, Anonymize); err != nil {
+		t.Errorf("Erase in anonymize mode error = %v", err)
+	}
+}
+
+func TestVerifyErasure(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    Mode
+		edit    func(t *testing.T, m *Manager[*models.User], report *Report)
+		wantErr error
+	}{
+		{"tampered count", Delete, func(t *testing.T, _ *Manager[*models.User], report *Report) {
+			report.Steps[0].Count++
+		}, goat.ErrInvalidErasureReport},
+		{"tampered user", Delete, func(t *testing.T, _ *Manager[*models.User], report *Report) {
+			report.UserID = bobID
+		}, goat.ErrInvalidErasureReport},
+		{"tampered mode", Anonymize, func(t *testing.T, _ *Manager[*models.User], report *Report) {
+			report.Mode = Delete
+		}, goat.ErrInvalidErasureReport},
+		{"dropped step", Delete, func(t *testing.T, _ *Manager[*models.User], report *Report) {
+			report.Steps = report.Steps[1:]
+		}, goat.ErrInvalidErasureReport},
+		{"session left", Delete, func(t *testing.T, m *Manager[*models.User], _ *Report) {
+			if err := m.stores.Sessions.Create(&models.Session{ID: "new", UserID: adaID, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+		}, goat.ErrErasureIncomplete},
+		{"deleted user still stored", Delete, func(t *testing.T, m *Manager[*models.User], _ *Report) {
+			m.users.(*testUsers).users[adaID] = &models.User{ID: adaID, TenantID: "acme", Email: "ada@example.com"}
+		}, goat.ErrErasureIncomplete},
+		{"anonymized user with an email", Anonymize, func(t *testing.T, m *Manager[*models.User], _ *Report) {
+			m.users.(*testUsers).users[adaID].Email = "ada@example.com"
+		}, goat.ErrErasureIncomplete},
+		{"anonymized user removed", Anonymize, func(t *testing.T, m *Manager[*models.User], _ *Report) {
+			delete(m.users.(*testUsers).users, adaID)
+		}, goat.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t)
+			report, err := m.Erase(adaID, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(t, m, report)
+			if err := m.VerifyErasure(report); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyErasure error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEraseInvalidMode(t *testing.T) {
+	m, _ := newTestManager(t)
+	if _, err := m.Erase(adaID, "shred"); !errors.Is(err, goat.ErrInvalidErasureMode) {
+		t.Errorf("Erase error = %v, want %v", err, goat.ErrInvalidErasureMode)
+	}
+	if _, err := m.Erase(9, Delete); !errors.Is(err, goat.ErrUserNotFound) {
+		t.Errorf("Erase of an unknown user error = %v, want %v", err, goat.ErrUserNotFound)
+	}
+}
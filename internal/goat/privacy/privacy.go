@@ -0,0 +1,266 @@
+// Package privacy answers data subject requests: it exports everything goat stores about a user in a
+// portable archive, and erases it, anonymizing what has to be kept, with a report that can be
+// verified later. A Manager works in one tenant; see ForTenant.
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+)
+
+// auditPageSize is the number of audit events read at once.
+const auditPageSize = 500
+
+// Users is the user service a Manager works through. The services in package service implement it.
+// ForTenant must return a Users too, as theirs do.
+type Users[U models.Account] interface {
+	goat.UserService[U]
+	goat.AccountService[U]
+}
+
+// Stores are the stores that hold data about users besides the user service. Any of them can be
+// nil, for features the application does not use; they are then left out of exports and erasures.
+type Stores struct {
+	Identities    goat.IdentityStore
+	Sessions      goat.SessionStore
+	APIKeys       goat.APIKeyStore
+	Organizations goat.OrganizationStore
+	OIDC          goat.OIDCStore
+	Revocations   goat.RevocationStore
+	Audit         goat.AuditStore
+	Outbox        goat.OutboxStore
+	Webhooks      goat.WebhookStore
+}
+
+// Redactor removes the personal data of recorded audit events. *audit.Logger implements it.
+type Redactor interface {
+	Redact(event *models.AuditEvent) error
+}
+
+// Manager exports and erases the data of the users of one tenant.
+type Manager[U models.Account] struct {
+	users    Users[U]
+	stores   Stores
+	auditor  goat.Auditor
+	redactor Redactor
+	tenant   string // Tenant whose users the manager sees; empty for the default tenant.
+	now      func() time.Time
+}
+
+// NewManager creates a Manager for the users of users and their data in stores.
+func NewManager[U models.Account](users Users[U], stores Stores) *Manager[U] {
+	return &Manager[U]{users: users, stores: stores, now: time.Now}
+}
+
+// SetAuditor makes the manager record each erasure, with the digest of its report, with auditor.
+func (m *Manager[U]) SetAuditor(auditor goat.Auditor) {
+	m.auditor = auditor
+}
+
+// SetRedactor makes Erase redact the user's audit events with redactor, instead of retaining them
+// as they are.
+func (m *Manager[U]) SetRedactor(redactor Redactor) {
+	m.redactor = redactor
+}
+
+// ForTenant returns a copy of m that sees the users of tenantID, and their data, only. The stores
+// without tenant scoping are reached through the user's ID, which is unique across tenants, and
+// audit events are queried by tenant.
+func (m *Manager[U]) ForTenant(tenantID string) *Manager[U] {
+	scoped := *m
+	scoped.tenant = tenantID
+	scoped.users = m.users.ForTenant(tenantID).(Users[U])
+	if s := m.stores.Identities; s != nil {
+		scoped.stores.Identities = s.ForTenant(tenantID)
+	}
+	if s := m.stores.Sessions; s != nil {
+		scoped.stores.Sessions = s.ForTenant(tenantID)
+	}
+	if s := m.stores.APIKeys; s != nil {
+		scoped.stores.APIKeys = s.ForTenant(tenantID)
+	}
+	if s := m.stores.Organizations; s != nil {
+		scoped.stores.Organizations = s.ForTenant(tenantID)
+	}
+	if s := m.stores.OIDC; s != nil {
+		scoped.stores.OIDC = s.ForTenant(tenantID)
+	}
+	if s := m.stores.Revocations; s != nil {
+		scoped.stores.Revocations = s.ForTenant(tenantID)
+	}
+	return &scoped
+}
+
+// Export is everything goat stores about a user. Secrets, such as password and key hashes, are left
+// out.
+type Export struct {
+	ExportedAt    time.Time              `json:"exported_at"`
+	User          *models.User           `json:"user"`
+	CustomFields  json.RawMessage        `json:"custom_fields,omitempty"` // The application's own profile fields.
+	Identities    []*models.Identity     `json:"identities"`
+	Sessions      []*models.Session      `json:"sessions"`
+	APIKeys       []*models.APIKey       `json:"api_keys"`
+	Organizations []*models.Organization `json:"organizations"`
+	Memberships   []*models.Membership   `json:"memberships"`
+	Consents      []*models.Consent      `json:"consents"`
+	AuditEvents   []*models.AuditEvent   `json:"audit_events"` // Events by or on the user, and failed logins as them, newest first.
+}
+
+// ExportUserData collects the data of the user with id, soft-deleted or not.
+func (m *Manager[U]) ExportUserData(id uint) (*Export, error) {
+	user, err := m.user(id)
+	if err != nil {
+		return nil, err
+	}
+	customFields, err := models.MarshalCustomFields(user)
+	if err != nil {
+		return nil, err
+	}
+	profile := *user.GetUser()
+	profile.Password = ""
+
+	export := &Export{
+		ExportedAt:    m.now().UTC(),
+		User:          &profile,
+		CustomFields:  customFields,
+		Identities:    []*models.Identity{},
+		Sessions:      []*models.Session{},
+		APIKeys:       []*models.APIKey{},
+		Organizations: []*models.Organization{},
+		Memberships:   []*models.Membership{},
+		Consents:      []*models.Consent{},
+		AuditEvents:   []*models.AuditEvent{},
+	}
+	if s := m.stores.Identities; s != nil {
+		if export.Identities, err = s.ListIdentities(id); err != nil {
+			return nil, err
+		}
+	}
+	if s := m.stores.Sessions; s != nil {
+		if export.Sessions, err = s.ListByUser(id); err != nil {
+			return nil, err
+		}
+	}
+	if s := m.stores.APIKeys; s != nil {
+		if export.APIKeys, err = s.ListAPIKeys(id); err != nil {
+			return nil, err
+		}
+	}
+	if s := m.stores.Organizations; s != nil {
+		if export.Organizations, err = s.ListOrganizations(id); err != nil {
+			return nil, err
+		}
+		for _, org := range export.Organizations {
+			membership, err := s.GetMembership(org.ID, id)
+			if err != nil {
+				return nil, err
+			}
+			export.Memberships = append(export.Memberships, membership)
+		}
+	}
+	if s := m.stores.OIDC; s != nil {
+		if export.Consents, err = s.ListConsents(id); err != nil {
+			return nil, err
+		}
+	}
+	if m.stores.Audit != nil {
+		if export.AuditEvents, err = m.auditEvents(id, profile.Email); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// WriteJSON writes the export to w as a single JSON document.
+func (e *Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// WriteZip writes the export to w as a ZIP archive with one JSON file for each kind of data.
+func (e *Export) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"export.json", map[string]interface{}{"exported_at": e.ExportedAt, "user_id": e.User.ID}},
+		{"user.json", e.User},
+		{"custom_fields.json", e.CustomFields},
+		{"identities.json", e.Identities},
+		{"sessions.json", e.Sessions},
+		{"api_keys.json", e.APIKeys},
+		{"organizations.json", e.Organizations},
+		{"memberships.json", e.Memberships},
+		{"consents.json", e.Consents},
+		{"audit_events.json", e.AuditEvents},
+	}
+	for _, file := range files {
+		if raw, ok := file.data.(json.RawMessage); ok && raw == nil {
+			continue // A user type without custom fields.
+		}
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// user loads the user with id, soft-deleted or not.
+func (m *Manager[U]) user(id uint) (U, error) {
+	user, err := m.users.GetUserByID(id)
+	if errors.Is(err, goat.ErrUserNotFound) {
+		return m.users.GetDeletedUser(id)
+	}
+	return user, err
+}
+
+// auditEvents returns every audit event in the manager's tenant by or on the user with id, or whose
+// metadata names email, such as failed logins, newest first.
+func (m *Manager[U]) auditEvents(id uint, email string) ([]*models.AuditEvent, error) {
+	queries := []models.AuditQuery{{ActorID: id}, {TargetID: id}}
+	if email != "" {
+		queries = append(queries, models.AuditQuery{Email: utils.NormalizeEmail(email)})
+	}
+	byID := map[string]*models.AuditEvent{}
+	for _, query := range queries {
+		query.TenantID = m.tenant
+		query.Limit = auditPageSize
+		for {
+			events, err := m.stores.Audit.QueryAuditEvents(&query)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				byID[event.ID] = event
+			}
+			if len(events) < query.Limit {
+				break
+			}
+			query.Before = events[len(events)-1].ID
+		}
+	}
+	events := make([]*models.AuditEvent, 0, len(byID))
+	for _, event := range byID {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID > events[j].ID
+	})
+	return events, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+	"github.com/google/uuid"
+)
+
+// errUserIDTaken is returned by the stores when a new user's random ID is taken by another user.
+var errUserIDTaken = errors.New("user id is already taken")
+
+// maxIDAttempts is the number of random IDs Register tries before giving up.
+const maxIDAttempts = 5
+
+// withNewID gives u a random ID and runs insert, trying another ID while insert reports that the
+// ID is taken. IDs are 32 bits, so collisions become likely once there are tens of thousands of users.
+func withNewID(u *models.User, insert func() error) error {
+	var err error
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		u.ID = uint(uuid.New().ID())
+		if err = insert(); !errors.Is(err, errUserIDTaken) {
+			return err
+		}
+	}
+	return err
+}
+
+// statusEvent returns the lifecycle event recorded when a user is given status with SetUserStatus.
+func statusEvent(status string) (models.UserEventType, error) {
+	switch status {
+	case models.AccountActive:
+		return models.UserReactivated, nil
+	case models.AccountSuspended:
+		return models.UserSuspended, nil
+	case models.AccountDeactivated:
+		return models.UserDeactivated, nil
+	}
+	return "", goat.ErrInvalidAccountStatus
+}
+
+// restorable checks that u, as stored, is a soft-deleted user deleted less than retention before now.
+func restorable(u *models.User, now time.Time, retention time.Duration) error {
+	if u.Status != models.AccountDeleted || u.DeletedAt == nil {
+		return goat.ErrUserNotDeleted
+	}
+	if now.Sub(*u.DeletedAt) >= retention {
+		return goat.ErrRestoreWindowExpired
+	}
+	return nil
+}
+
+// purgeBatch is the number of users PurgeDeletedUsers reads at once.
+const purgeBatch = 500
+
+// emailMigrationBatch is the number of users NormalizeStoredEmails reads at once.
+const emailMigrationBatch = 500
+
+// normalizeStoredEmail normalizes the email c.Email stored for the user c.UserID, storing the
+// normalized address with update. When update reports goat.ErrEmailTaken, because another user of
+// the tenant already has the address, the email is left as it was and the conflict is returned,
+// naming the user holder finds with it. It returns nil for an email that was already normalized.
+func normalizeStoredEmail(c models.EmailConflict, update func(c models.EmailConflict) error, holder func(c models.EmailConflict) (uint, error)) (*models.EmailConflict, error) {
+	if c.Normalized = utils.NormalizeEmail(c.Email); c.Normalized == c.Email {
+		return nil, nil
+	}
+	err := update(c)
+	if !errors.Is(err, goat.ErrEmailTaken) {
+		return nil, err
+	}
+	if c.HeldBy, err = holder(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// storedNow returns the current time at the precision of every store, so that users read back
+// carry the same creation and deletion times they were written with.
+func storedNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// anonymous returns the placeholder AnonymizeUser stores in place of the user with id: deactivated,
+// with models.ErasedEmail, an unusable password and no custom fields.
+func anonymous[U models.Account](tenantID string, id uint) (U, error) {
+	user := models.New[U]()
+	u := user.GetUser()
+	u.ID = id
+	u.TenantID = tenantID
+	u.Email = models.ErasedEmail(id)
+	u.Status = models.AccountDeactivated
+	if err := u.SetUnusablePassword(); err != nil {
+		var zero U
+		return zero, err
+	}
+	return user, nil
+}
+
+// erased returns what EraseUser records of the user with id in the UserErased outbox message: their
+// ID and tenant, and nothing else.
+func erased[U models.Account](tenantID string, id uint) U {
+	user := models.New[U]()
+	u := user.GetUser()
+	u.ID = id
+	u.TenantID = tenantID
+	return user
+}
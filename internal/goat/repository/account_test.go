@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+func TestNormalizeStoredEmail(t *testing.T) {
+	storeErr := errors.New("store unavailable")
+	tests := []struct {
+		name        string
+		email       string
+		update      error // Returned when the normalized email is stored.
+		wantUpdated bool
+		wantErr     error
+		want        *models.EmailConflict
+	}{
+		{"already normalized", "ada@example.com", nil, false, nil, nil},
+		{"normalized", " Ada@Example.com", nil, true, nil, nil},
+		{"address taken", "Ada@Example.com", goat.ErrEmailTaken, true, nil, &models.EmailConflict{
+			TenantID: "acme", UserID: 3, Email: "Ada@Example.com", Normalized: "ada@example.com", HeldBy: 7,
+		}},
+		{"store error", "Ada@Example.com", storeErr, true, storeErr, nil},
+	}
+	for _, tt := range tests {
+		updated := false
+		update := func(c models.EmailConflict) error {
+			updated = true
+			if c.UserID != 3 || c.TenantID != "acme" || c.Normalized != "ada@example.com" {
+				t.Errorf("%s: update of %+v, want user 3 of acme to get ada@example.com", tt.name, c)
+			}
+			return tt.update
+		}
+		holder := func(c models.EmailConflict) (uint, error) { return 7, nil }
+
+		got, err := normalizeStoredEmail(models.EmailConflict{TenantID: "acme", UserID: 3, Email: tt.email}, update, holder)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if updated != tt.wantUpdated {
+			t.Errorf("%s: updated = %t, want %t", tt.name, updated, tt.wantUpdated)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: conflict = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// A failure to find the holder of the address is returned.
+	taken := func(models.EmailConflict) error { return goat.ErrEmailTaken }
+	missing := func(models.EmailConflict) (uint, error) { return 0, storeErr }
+	if _, err := normalizeStoredEmail(models.EmailConflict{Email: "Ada@Example.com"}, taken, missing); !errors.Is(err, storeErr) {
+		t.Errorf("holder lookup failed: error = %v, want %v", err, storeErr)
+	}
+}
+
+func TestRestorable(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+	deletedAt := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+	tests := []struct {
+		name      string
+		status    string
+		deletedAt *time.Time
+		wantErr   error
+	}{
+		{"deleted just now", models.AccountDeleted, deletedAt(0), nil},
+		{"deleted within the window", models.AccountDeleted, deletedAt(retention - time.Millisecond), nil},
+		{"window just over", models.AccountDeleted, deletedAt(retention), goat.ErrRestoreWindowExpired},
+		{"window long over", models.AccountDeleted, deletedAt(2 * retention), goat.ErrRestoreWindowExpired},
+		{"active", models.AccountActive, nil, goat.ErrUserNotDeleted},
+		{"suspended", models.AccountSuspended, nil, goat.ErrUserNotDeleted},
+		{"deleted without a deletion time", models.AccountDeleted, nil, goat.ErrUserNotDeleted},
+	}
+	for _, tt := range tests {
+		u := &models.User{ID: 3, Status: tt.status, DeletedAt: tt.deletedAt}
+		if err := restorable(u, now, retention); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: restorable error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// memoryAPIKeys are the keys shared by a MemoryAPIKeyStore and the stores ForTenant returns.
+type memoryAPIKeys struct {
+	mu   sync.RWMutex
+	keys map[string]models.APIKey
+}
+
+// MemoryAPIKeyStore keeps API keys in process memory. It is meant for tests; keys are lost when the
+// process exits.
+type MemoryAPIKeyStore struct {
+	*memoryAPIKeys
+	tenant string // Tenant whose keys the store sees; empty for the default tenant.
+}
+
+// NewMemoryAPIKeyStore creates an empty MemoryAPIKeyStore.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{memoryAPIKeys: &memoryAPIKeys{keys: map[string]models.APIKey{}}}
+}
+
+// ForTenant implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) ForTenant(tenantID string) goat.APIKeyStore {
+	return &MemoryAPIKeyStore{memoryAPIKeys: s.memoryAPIKeys, tenant: tenantID}
+}
+
+// CreateAPIKey implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) CreateAPIKey(key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key.TenantID = s.tenant
+	s.keys[key.ID] = *key
+	return nil
+}
+
+// GetAPIKey implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) GetAPIKey(id string) (*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok || key.TenantID != s.tenant {
+		return nil, goat.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+// ListAPIKeys implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) ListAPIKeys(userID uint) ([]*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := []*models.APIKey{}
+	for _, key := range s.keys {
+		if key.TenantID == s.tenant && key.UserID == userID {
+			key := key
+			keys = append(keys, &key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// TouchAPIKey implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) TouchAPIKey(id string, lastUsedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok || key.TenantID != s.tenant {
+		return goat.ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = &lastUsedAt
+	s.keys[id] = key
+	return nil
+}
+
+// DeleteAPIKey implements goat.APIKeyStore.
+func (s *MemoryAPIKeyStore) DeleteAPIKey(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[id]; !ok || key.TenantID != s.tenant {
+		return goat.ErrAPIKeyNotFound
+	}
+	delete(s.keys, id)
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBAPIKeyStore stores API keys in a MongoDB collection.
+// Every query is limited to the store's tenant; see ForTenant.
+type MongoDBAPIKeyStore struct {
+	collection *mongo.Collection // MongoDB collection for API key documents.
+	tenant     string            // Tenant whose keys the store sees; empty for the default tenant.
+}
+
+// NewMongoDBAPIKeyStore initializes a new MongoDBAPIKeyStore with a given MongoDB client, database name, and collection name.
+func NewMongoDBAPIKeyStore(client *mongo.Client, dbName, collectionName string) (*MongoDBAPIKeyStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBAPIKeyStore{collection: collection}, nil
+}
+
+// ForTenant implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) ForTenant(tenantID string) goat.APIKeyStore {
+	return &MongoDBAPIKeyStore{collection: s.collection, tenant: tenantID}
+}
+
+// CreateAPIKey implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) CreateAPIKey(key *models.APIKey) error {
+	ctx := context.Background()
+	key.TenantID = s.tenant
+	_, err := s.collection.InsertOne(ctx, key)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAPIKey implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) GetAPIKey(id string) (*models.APIKey, error) {
+	ctx := context.Background()
+	key := &models.APIKey{}
+	err := s.collection.FindOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}).Decode(key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) ListAPIKeys(userID uint) ([]*models.APIKey, error) {
+	ctx := context.Background()
+	cursor, err := s.collection.Find(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	keys := []*models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// TouchAPIKey implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) TouchAPIKey(id string, lastUsedAt time.Time) error {
+	ctx := context.Background()
+	res, err := s.collection.UpdateOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}, bson.M{"$set": bson.M{"last_used_at": lastUsedAt}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// DeleteAPIKey implements goat.APIKeyStore.
+func (s *MongoDBAPIKeyStore) DeleteAPIKey(id string) error {
+	ctx := context.Background()
+	res, err := s.collection.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrAPIKeyNotFound
+	}
+	return nil
+}
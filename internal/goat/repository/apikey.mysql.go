@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MySQLAPIKeyStore stores API keys in a MySQL table; scopes are stored as a JSON array.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Every query is limited to the store's tenant; see ForTenant.
+type MySQLAPIKeyStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose keys the store sees; empty for the default tenant.
+}
+
+// NewMySQLAPIKeyStore initializes a new MySQLAPIKeyStore with a given DSN (Data Source Name) and creates the api_keys table.
+func NewMySQLAPIKeyStore(dsn string) (*MySQLAPIKeyStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+		id VARCHAR(64) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		user_id BIGINT UNSIGNED NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		secret_hash CHAR(64) NOT NULL,
+		scopes JSON NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		expires_at DATETIME(6) NULL,
+		last_used_at DATETIME(6) NULL,
+		INDEX idx_api_keys_user_id (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLAPIKeyStore{db: db}, nil
+}
+
+// ForTenant implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) ForTenant(tenantID string) goat.APIKeyStore {
+	return &MySQLAPIKeyStore{db: s.db, tenant: tenantID}
+}
+
+// CreateAPIKey implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) CreateAPIKey(key *models.APIKey) error {
+	ctx := context.Background()
+	key.TenantID = s.tenant
+	scopes, err := json.Marshal(nonNil(key.Scopes))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO api_keys (id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		key.ID, key.TenantID, key.UserID, key.Name, key.SecretHash, scopes, key.CreatedAt.UTC(), key.ExpiresAt, key.LastUsedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAPIKey implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) GetAPIKey(id string) (*models.APIKey, error) {
+	ctx := context.Background()
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, "SELECT id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at FROM api_keys WHERE tenant_id = ? AND id = ?", s.tenant, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) ListAPIKeys(userID uint) ([]*models.APIKey, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at FROM api_keys WHERE tenant_id = ? AND user_id = ? ORDER BY created_at DESC", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// TouchAPIKey implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) TouchAPIKey(id string, lastUsedAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE tenant_id = ? AND id = ?", lastUsedAt.UTC(), s.tenant, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteAPIKey implements goat.APIKeyStore.
+func (s *MySQLAPIKeyStore) DeleteAPIKey(id string) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM api_keys WHERE tenant_id = ? AND id = ?", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// scanAPIKey reads an API key row selected in the column order used by MySQLAPIKeyStore.
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var scopes []byte
+	if err := row.Scan(&key.ID, &key.TenantID, &key.UserID, &key.Name, &key.SecretHash, &scopes, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
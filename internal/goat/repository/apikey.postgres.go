@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLAPIKeyStore stores API keys in a PostgreSQL table.
+// Every query is limited to the store's tenant; see ForTenant.
+type PostgreSQLAPIKeyStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose keys the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLAPIKeyStore initializes a new PostgreSQLAPIKeyStore with a given connection string and creates the api_keys table.
+func NewPostgreSQLAPIKeyStore(connString string) (*PostgreSQLAPIKeyStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		user_id BIGINT NOT NULL,
+		name TEXT NOT NULL,
+		secret_hash TEXT NOT NULL,
+		scopes TEXT[] NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ,
+		last_used_at TIMESTAMPTZ
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS api_keys_tenant_user_id_idx ON api_keys (tenant_id, user_id)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLAPIKeyStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) ForTenant(tenantID string) goat.APIKeyStore {
+	return &PostgreSQLAPIKeyStore{conn: s.conn, tenant: tenantID}
+}
+
+// CreateAPIKey implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) CreateAPIKey(key *models.APIKey) error {
+	ctx := context.Background()
+	key.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, "INSERT INTO api_keys (id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		key.ID, key.TenantID, key.UserID, key.Name, key.SecretHash, nonNil(key.Scopes), key.CreatedAt, key.ExpiresAt, key.LastUsedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAPIKey implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) GetAPIKey(id string) (*models.APIKey, error) {
+	ctx := context.Background()
+	key := &models.APIKey{}
+	err := s.conn.QueryRow(ctx, "SELECT id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at FROM api_keys WHERE tenant_id = $1 AND id = $2", s.tenant, id).
+		Scan(&key.ID, &key.TenantID, &key.UserID, &key.Name, &key.SecretHash, &key.Scopes, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) ListAPIKeys(userID uint) ([]*models.APIKey, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT id, tenant_id, user_id, name, secret_hash, scopes, created_at, expires_at, last_used_at FROM api_keys WHERE tenant_id = $1 AND user_id = $2 ORDER BY created_at DESC", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key := &models.APIKey{}
+		if err := rows.Scan(&key.ID, &key.TenantID, &key.UserID, &key.Name, &key.SecretHash, &key.Scopes, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// TouchAPIKey implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) TouchAPIKey(id string, lastUsedAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE tenant_id = $2 AND id = $3", lastUsedAt, s.tenant, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteAPIKey implements goat.APIKeyStore.
+func (s *PostgreSQLAPIKeyStore) DeleteAPIKey(id string) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM api_keys WHERE tenant_id = $1 AND id = $2", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrAPIKeyNotFound
+	}
+	return nil
+}
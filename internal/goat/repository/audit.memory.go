@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MemoryAuditStore keeps audit events in process memory. It is meant for tests; events are lost
+// when the process exits.
+type MemoryAuditStore struct {
+	mu          sync.RWMutex
+	events      []models.AuditEvent // In Seq order.
+	checkpoints []models.AuditCheckpoint
+}
+
+// NewMemoryAuditStore creates an empty MemoryAuditStore.
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{}
+}
+
+// AppendAuditEvent implements goat.AuditStore.
+func (s *MemoryAuditStore) AppendAuditEvent(event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seqTaken(event.Seq) {
+		return goat.ErrAuditSeqTaken
+	}
+	s.events = append(s.events, *event)
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].Seq < s.events[j].Seq
+	})
+	return nil
+}
+
+// seqTaken reports whether an event with seq is stored.
+func (s *MemoryAuditStore) seqTaken(seq uint64) bool {
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Seq >= seq })
+	return i < len(s.events) && s.events[i].Seq == seq
+}
+
+// QueryAuditEvents implements goat.AuditStore.
+func (s *MemoryAuditStore) QueryAuditEvents(query *models.AuditQuery) ([]*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := []*models.AuditEvent{}
+	for _, event := range s.events {
+		if matchesAuditQuery(&event, query) {
+			event := event
+			events = append(events, &event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID > events[j].ID
+	})
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[:query.Limit]
+	}
+	return events, nil
+}
+
+// LastAuditEvent implements goat.AuditStore.
+func (s *MemoryAuditStore) LastAuditEvent() (*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.events) == 0 {
+		return nil, goat.ErrAuditEventNotFound
+	}
+	event := s.events[len(s.events)-1]
+	return &event, nil
+}
+
+// ListAuditChain implements goat.AuditStore.
+func (s *MemoryAuditStore) ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := []*models.AuditEvent{}
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Seq > afterSeq })
+	for ; i < len(s.events) && len(events) < limit; i++ {
+		event := s.events[i]
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// RedactAuditEvent implements goat.AuditStore.
+func (s *MemoryAuditStore) RedactAuditEvent(event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.events {
+		if s.events[i].ID == event.ID {
+			s.events[i].IP = event.IP
+			s.events[i].UserAgent = event.UserAgent
+			s.events[i].Metadata = event.Metadata
+			s.events[i].Redacted = event.Redacted
+			return nil
+		}
+	}
+	return goat.ErrAuditEventNotFound
+}
+
+// SaveAuditCheckpoint implements goat.AuditStore.
+func (s *MemoryAuditStore) SaveAuditCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints = append(s.checkpoints, *checkpoint)
+	sort.SliceStable(s.checkpoints, func(i, j int) bool {
+		return s.checkpoints[i].Seq < s.checkpoints[j].Seq
+	})
+	return nil
+}
+
+// LastAuditCheckpoint implements goat.AuditStore.
+func (s *MemoryAuditStore) LastAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.checkpoints) == 0 {
+		return nil, goat.ErrAuditCheckpointNotFound
+	}
+	checkpoint := s.checkpoints[len(s.checkpoints)-1]
+	return &checkpoint, nil
+}
+
+// ListAuditCheckpoints implements goat.AuditStore.
+func (s *MemoryAuditStore) ListAuditCheckpoints() ([]*models.AuditCheckpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	checkpoints := make([]*models.AuditCheckpoint, len(s.checkpoints))
+	for i := range s.checkpoints {
+		checkpoint := s.checkpoints[i]
+		checkpoints[i] = &checkpoint
+	}
+	return checkpoints, nil
+}
+
+// matchesAuditQuery reports whether event is selected by query.
+func matchesAuditQuery(event *models.AuditEvent, query *models.AuditQuery) bool {
+	switch {
+	case len(query.Types) > 0 && !containsString(query.Types, event.Type):
+		return false
+	case query.ActorID != 0 && event.ActorID != query.ActorID:
+		return false
+	case query.TargetID != 0 && event.TargetID != query.TargetID:
+		return false
+	case query.TenantID != "" && event.TenantID != query.TenantID:
+		return false
+	case query.Email != "" && event.Metadata["email"] != query.Email:
+		return false
+	case !query.Since.IsZero() && event.CreatedAt.Before(query.Since):
+		return false
+	case !query.Until.IsZero() && !event.CreatedAt.Before(query.Until):
+		return false
+	case query.Before != "" && event.ID >= query.Before:
+		return false
+	}
+	return true
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
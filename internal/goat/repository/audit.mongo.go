@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBAuditStore stores audit events in a MongoDB collection, and checkpoints in a second one.
+type MongoDBAuditStore struct {
+	collection  *mongo.Collection // MongoDB collection for audit event documents.
+	checkpoints *mongo.Collection // MongoDB collection for checkpoint documents.
+}
+
+// NewMongoDBAuditStore initializes a new MongoDBAuditStore with a given MongoDB client, database name, and collection name;
+// checkpoints are kept in the collection named collectionName + "_checkpoints". It ensures unique indexes on the event ID,
+// sequence number and checkpoint sequence number, and indexes for the common query filters.
+func NewMongoDBAuditStore(client *mongo.Client, dbName, collectionName string) (*MongoDBAuditStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": -1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"seq": -1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "id", Value: -1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := client.Database(dbName).Collection(collectionName + "_checkpoints")
+	_, err = checkpoints.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"seq": -1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBAuditStore{collection: collection, checkpoints: checkpoints}, nil
+}
+
+// AppendAuditEvent implements goat.AuditStore.
+func (s *MongoDBAuditStore) AppendAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	_, err := s.collection.InsertOne(ctx, event)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return goat.ErrAuditSeqTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// QueryAuditEvents implements goat.AuditStore.
+func (s *MongoDBAuditStore) QueryAuditEvents(query *models.AuditQuery) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	filter := bson.M{}
+	if len(query.Types) > 0 {
+		filter["type"] = bson.M{"$in": query.Types}
+	}
+	if query.ActorID != 0 {
+		filter["actor_id"] = query.ActorID
+	}
+	if query.TargetID != 0 {
+		filter["target_id"] = query.TargetID
+	}
+	if query.TenantID != "" {
+		filter["tenant_id"] = query.TenantID
+	}
+	if query.Email != "" {
+		filter["metadata.email"] = query.Email
+	}
+	createdAt := bson.M{}
+	if !query.Since.IsZero() {
+		createdAt["$gte"] = query.Since
+	}
+	if !query.Until.IsZero() {
+		createdAt["$lt"] = query.Until
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	if query.Before != "" {
+		filter["id"] = bson.M{"$lt": query.Before}
+	}
+
+	opts := options.Find().SetSort(bson.M{"id": -1})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	events := []*models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastAuditEvent implements goat.AuditStore.
+func (s *MongoDBAuditStore) LastAuditEvent() (*models.AuditEvent, error) {
+	ctx := context.Background()
+	event := &models.AuditEvent{}
+	err := s.collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"seq": -1})).Decode(event)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrAuditEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListAuditChain implements goat.AuditStore.
+func (s *MongoDBAuditStore) ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"seq": 1}).SetLimit(int64(limit))
+	cursor, err := s.collection.Find(ctx, bson.M{"seq": bson.M{"$gt": afterSeq}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	events := []*models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// RedactAuditEvent implements goat.AuditStore.
+func (s *MongoDBAuditStore) RedactAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	update := bson.M{"$set": bson.M{
+		"ip":         event.IP,
+		"user_agent": event.UserAgent,
+		"metadata":   event.Metadata,
+		"redacted":   event.Redacted,
+	}}
+	result, err := s.collection.UpdateOne(ctx, bson.M{"id": event.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return goat.ErrAuditEventNotFound
+	}
+	return nil
+}
+
+// SaveAuditCheckpoint implements goat.AuditStore.
+func (s *MongoDBAuditStore) SaveAuditCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	ctx := context.Background()
+	_, err := s.checkpoints.InsertOne(ctx, checkpoint)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LastAuditCheckpoint implements goat.AuditStore.
+func (s *MongoDBAuditStore) LastAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	checkpoint := &models.AuditCheckpoint{}
+	err := s.checkpoints.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"seq": -1})).Decode(checkpoint)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrAuditCheckpointNotFound
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ListAuditCheckpoints implements goat.AuditStore.
+func (s *MongoDBAuditStore) ListAuditCheckpoints() ([]*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	cursor, err := s.checkpoints.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"seq": 1}))
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := []*models.AuditCheckpoint{}
+	if err := cursor.All(ctx, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQLAuditStore stores audit events and checkpoints in MySQL tables; metadata is stored as a JSON document.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+type MySQLAuditStore struct {
+	db *sql.DB // MySQL DB connection.
+}
+
+// NewMySQLAuditStore initializes a new MySQLAuditStore with a given DSN (Data Source Name) and creates the
+// audit_events and audit_checkpoints tables.
+func NewMySQLAuditStore(dsn string) (*MySQLAuditStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS audit_events (
+		id CHAR(32) PRIMARY KEY,
+		type VARCHAR(64) NOT NULL,
+		actor_id BIGINT UNSIGNED NOT NULL,
+		target_id BIGINT UNSIGNED NOT NULL,
+		tenant_id VARCHAR(64) NOT NULL,
+		ip VARCHAR(45) NOT NULL,
+		user_agent VARCHAR(255) NOT NULL,
+		metadata JSON NULL,
+		created_at DATETIME(6) NOT NULL,
+		seq BIGINT UNSIGNED NOT NULL,
+		prev_hash CHAR(64) NOT NULL,
+		hash CHAR(64) NOT NULL,
+		redacted JSON NULL,
+		UNIQUE KEY idx_audit_events_seq (seq),
+		INDEX idx_audit_events_actor_id (actor_id, id),
+		INDEX idx_audit_events_target_id (target_id, id),
+		INDEX idx_audit_events_type (type, id),
+		INDEX idx_audit_events_created_at (created_at)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS audit_checkpoints (
+		seq BIGINT UNSIGNED PRIMARY KEY,
+		hash CHAR(64) NOT NULL,
+		signature TEXT NOT NULL,
+		created_at DATETIME(6) NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLAuditStore{db: db}, nil
+}
+
+// AppendAuditEvent implements goat.AuditStore.
+func (s *MySQLAuditStore) AppendAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+	redacted, err := json.Marshal(event.Redacted)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO audit_events ("+auditColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		event.ID, event.Type, event.ActorID, event.TargetID, event.TenantID, event.IP, event.UserAgent, metadata, event.CreatedAt.UTC(),
+		event.Seq, event.PrevHash, event.Hash, redacted)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return goat.ErrAuditSeqTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// QueryAuditEvents implements goat.AuditStore.
+func (s *MySQLAuditStore) QueryAuditEvents(query *models.AuditQuery) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	stmt, args := auditQuerySQL(query, func(int) string { return "?" }, "metadata->>'$.email'")
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// LastAuditEvent implements goat.AuditStore.
+func (s *MySQLAuditStore) LastAuditEvent() (*models.AuditEvent, error) {
+	ctx := context.Background()
+	row := s.db.QueryRowContext(ctx, "SELECT "+auditColumns+" FROM audit_events ORDER BY seq DESC LIMIT 1")
+	event, err := scanAuditEvent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrAuditEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListAuditChain implements goat.AuditStore.
+func (s *MySQLAuditStore) ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT "+auditColumns+" FROM audit_events WHERE seq > ? ORDER BY seq LIMIT ?", afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// RedactAuditEvent implements goat.AuditStore.
+func (s *MySQLAuditStore) RedactAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+	redacted, err := json.Marshal(event.Redacted)
+	if err != nil {
+		return err
+	}
+	// RowsAffected counts changed rows, which is zero when an event is redacted again, so check
+	// that the event exists separately.
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events WHERE id = ?", event.ID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return goat.ErrAuditEventNotFound
+	}
+	_, err = s.db.ExecContext(ctx, "UPDATE audit_events SET ip = ?, user_agent = ?, metadata = ?, redacted = ? WHERE id = ?",
+		event.IP, event.UserAgent, metadata, redacted, event.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveAuditCheckpoint implements goat.AuditStore.
+func (s *MySQLAuditStore) SaveAuditCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO audit_checkpoints ("+auditCheckpointColumns+") VALUES (?, ?, ?, ?)",
+		checkpoint.Seq, checkpoint.Hash, checkpoint.Signature, checkpoint.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LastAuditCheckpoint implements goat.AuditStore.
+func (s *MySQLAuditStore) LastAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	row := s.db.QueryRowContext(ctx, "SELECT "+auditCheckpointColumns+" FROM audit_checkpoints ORDER BY seq DESC LIMIT 1")
+	checkpoint, err := scanAuditCheckpoint(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrAuditCheckpointNotFound
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ListAuditCheckpoints implements goat.AuditStore.
+func (s *MySQLAuditStore) ListAuditCheckpoints() ([]*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT "+auditCheckpointColumns+" FROM audit_checkpoints ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []*models.AuditCheckpoint{}
+	for rows.Next() {
+		checkpoint, err := scanAuditCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
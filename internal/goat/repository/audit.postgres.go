@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLAuditStore stores audit events and checkpoints in PostgreSQL tables; metadata is stored as a JSONB document.
+type PostgreSQLAuditStore struct {
+	conn *pgx.Conn // PostgreSQL connection for database access.
+}
+
+// NewPostgreSQLAuditStore initializes a new PostgreSQLAuditStore with a given connection string and creates the
+// audit_events and audit_checkpoints tables.
+func NewPostgreSQLAuditStore(connString string) (*PostgreSQLAuditStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs are compared as byte strings when paging, whatever the database's default collation.
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS audit_events (
+		id TEXT COLLATE "C" PRIMARY KEY,
+		type TEXT NOT NULL,
+		actor_id BIGINT NOT NULL,
+		target_id BIGINT NOT NULL,
+		tenant_id TEXT NOT NULL,
+		ip TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		metadata JSONB,
+		created_at TIMESTAMPTZ NOT NULL,
+		seq BIGINT NOT NULL UNIQUE,
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		redacted JSONB
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{
+		"CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id, id)",
+		"CREATE INDEX IF NOT EXISTS audit_events_target_id_idx ON audit_events (target_id, id)",
+		"CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (type, id)",
+		"CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at)",
+		`CREATE TABLE IF NOT EXISTS audit_checkpoints (
+			seq BIGINT PRIMARY KEY,
+			hash TEXT NOT NULL,
+			signature TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+	} {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PostgreSQLAuditStore{conn: conn}, nil
+}
+
+// AppendAuditEvent implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) AppendAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+	redacted, err := json.Marshal(event.Redacted)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Exec(ctx, "INSERT INTO audit_events ("+auditColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+		event.ID, event.Type, event.ActorID, event.TargetID, event.TenantID, event.IP, event.UserAgent, metadata, event.CreatedAt,
+		event.Seq, event.PrevHash, event.Hash, redacted)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation {
+			return goat.ErrAuditSeqTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// QueryAuditEvents implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) QueryAuditEvents(query *models.AuditQuery) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	stmt, args := auditQuerySQL(query, func(n int) string { return "$" + strconv.Itoa(n) }, "metadata->>'email'")
+	rows, err := s.conn.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// LastAuditEvent implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) LastAuditEvent() (*models.AuditEvent, error) {
+	ctx := context.Background()
+	row := s.conn.QueryRow(ctx, "SELECT "+auditColumns+" FROM audit_events ORDER BY seq DESC LIMIT 1")
+	event, err := scanAuditEvent(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrAuditEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListAuditChain implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) ListAuditChain(afterSeq uint64, limit int) ([]*models.AuditEvent, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT "+auditColumns+" FROM audit_events WHERE seq > $1 ORDER BY seq LIMIT $2", afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// RedactAuditEvent implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) RedactAuditEvent(event *models.AuditEvent) error {
+	ctx := context.Background()
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+	redacted, err := json.Marshal(event.Redacted)
+	if err != nil {
+		return err
+	}
+	tag, err := s.conn.Exec(ctx, "UPDATE audit_events SET ip = $1, user_agent = $2, metadata = $3, redacted = $4 WHERE id = $5",
+		event.IP, event.UserAgent, metadata, redacted, event.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrAuditEventNotFound
+	}
+	return nil
+}
+
+// SaveAuditCheckpoint implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) SaveAuditCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "INSERT INTO audit_checkpoints ("+auditCheckpointColumns+") VALUES ($1, $2, $3, $4)",
+		checkpoint.Seq, checkpoint.Hash, checkpoint.Signature, checkpoint.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LastAuditCheckpoint implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) LastAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	row := s.conn.QueryRow(ctx, "SELECT "+auditCheckpointColumns+" FROM audit_checkpoints ORDER BY seq DESC LIMIT 1")
+	checkpoint, err := scanAuditCheckpoint(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrAuditCheckpointNotFound
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ListAuditCheckpoints implements goat.AuditStore.
+func (s *PostgreSQLAuditStore) ListAuditCheckpoints() ([]*models.AuditCheckpoint, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT "+auditCheckpointColumns+" FROM audit_checkpoints ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []*models.AuditCheckpoint{}
+	for rows.Next() {
+		checkpoint, err := scanAuditCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// identityKey identifies a linked identity in a MemoryIdentityStore.
+type identityKey struct {
+	tenant, provider, subject string
+}
+
+// memoryIdentities are the links shared by a MemoryIdentityStore and the stores ForTenant returns.
+type memoryIdentities struct {
+	mu         sync.RWMutex
+	identities map[identityKey]models.Identity
+}
+
+// MemoryIdentityStore keeps linked identities in process memory. It is meant for tests; links are
+// lost when the process exits.
+type MemoryIdentityStore struct {
+	*memoryIdentities
+	tenant string // Tenant whose identities the store sees; empty for the default tenant.
+}
+
+// NewMemoryIdentityStore creates an empty MemoryIdentityStore.
+func NewMemoryIdentityStore() *MemoryIdentityStore {
+	return &MemoryIdentityStore{memoryIdentities: &memoryIdentities{identities: map[identityKey]models.Identity{}}}
+}
+
+// ForTenant implements goat.IdentityStore.
+func (s *MemoryIdentityStore) ForTenant(tenantID string) goat.IdentityStore {
+	return &MemoryIdentityStore{memoryIdentities: s.memoryIdentities, tenant: tenantID}
+}
+
+// CreateIdentity implements goat.IdentityStore.
+func (s *MemoryIdentityStore) CreateIdentity(identity *models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identity.TenantID = s.tenant
+	key := identityKey{s.tenant, identity.Provider, identity.Subject}
+	if _, ok := s.identities[key]; ok {
+		return goat.ErrIdentityTaken
+	}
+	s.identities[key] = *identity
+	return nil
+}
+
+// GetIdentity implements goat.IdentityStore.
+func (s *MemoryIdentityStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	identity, ok := s.identities[identityKey{s.tenant, provider, subject}]
+	if !ok {
+		return nil, goat.ErrIdentityNotFound
+	}
+	return &identity, nil
+}
+
+// ListIdentities implements goat.IdentityStore.
+func (s *MemoryIdentityStore) ListIdentities(userID uint) ([]*models.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	identities := []*models.Identity{}
+	for _, identity := range s.identities {
+		if identity.TenantID == s.tenant && identity.UserID == userID {
+			identity := identity
+			identities = append(identities, &identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].LinkedAt.Before(identities[j].LinkedAt)
+	})
+	return identities, nil
+}
+
+// DeleteIdentity implements goat.IdentityStore.
+func (s *MemoryIdentityStore) DeleteIdentity(provider, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := identityKey{s.tenant, provider, subject}
+	if _, ok := s.identities[key]; !ok {
+		return goat.ErrIdentityNotFound
+	}
+	delete(s.identities, key)
+	return nil
+}
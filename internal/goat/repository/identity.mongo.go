@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBIdentityStore stores linked identities in a MongoDB collection.
+// Every query is limited to the store's tenant; see ForTenant.
+type MongoDBIdentityStore struct {
+	collection *mongo.Collection // MongoDB collection for identity documents.
+	tenant     string            // Tenant whose identities the store sees; empty for the default tenant.
+}
+
+// NewMongoDBIdentityStore initializes a new MongoDBIdentityStore with a given MongoDB client, database name, and collection name.
+// It ensures a unique index on tenant, provider and subject, so an external account is linked to at most one
+// user in each tenant.
+func NewMongoDBIdentityStore(client *mongo.Client, dbName, collectionName string) (*MongoDBIdentityStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBIdentityStore{collection: collection}, nil
+}
+
+// ForTenant implements goat.IdentityStore.
+func (s *MongoDBIdentityStore) ForTenant(tenantID string) goat.IdentityStore {
+	return &MongoDBIdentityStore{collection: s.collection, tenant: tenantID}
+}
+
+// CreateIdentity implements goat.IdentityStore.
+func (s *MongoDBIdentityStore) CreateIdentity(identity *models.Identity) error {
+	ctx := context.Background()
+	identity.TenantID = s.tenant
+	_, err := s.collection.InsertOne(ctx, identity)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return goat.ErrIdentityTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// GetIdentity implements goat.IdentityStore.
+func (s *MongoDBIdentityStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	ctx := context.Background()
+	identity := &models.Identity{}
+	err := s.collection.FindOne(ctx, bson.M{"tenant_id": s.tenant, "provider": provider, "subject": subject}).Decode(identity)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListIdentities implements goat.IdentityStore.
+func (s *MongoDBIdentityStore) ListIdentities(userID uint) ([]*models.Identity, error) {
+	ctx := context.Background()
+	cursor, err := s.collection.Find(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}, options.Find().SetSort(bson.M{"linked_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	identities := []*models.Identity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// DeleteIdentity implements goat.IdentityStore.
+func (s *MongoDBIdentityStore) DeleteIdentity(provider, subject string) error {
+	ctx := context.Background()
+	res, err := s.collection.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "provider": provider, "subject": subject})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrIdentityNotFound
+	}
+	return nil
+}
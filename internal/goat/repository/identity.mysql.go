@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQLIdentityStore stores linked identities in a MySQL table.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Every query is limited to the store's tenant; see ForTenant.
+type MySQLIdentityStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose identities the store sees; empty for the default tenant.
+}
+
+// NewMySQLIdentityStore initializes a new MySQLIdentityStore with a given DSN (Data Source Name) and creates the identities table.
+func NewMySQLIdentityStore(dsn string) (*MySQLIdentityStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// An external account can be linked to a user in each tenant.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS identities (
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		provider VARCHAR(64) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		user_id BIGINT UNSIGNED NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		linked_at DATETIME(6) NOT NULL,
+		PRIMARY KEY (tenant_id, provider, subject),
+		INDEX idx_identities_user_id (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLIdentityStore{db: db}, nil
+}
+
+// ForTenant implements goat.IdentityStore.
+func (s *MySQLIdentityStore) ForTenant(tenantID string) goat.IdentityStore {
+	return &MySQLIdentityStore{db: s.db, tenant: tenantID}
+}
+
+// CreateIdentity implements goat.IdentityStore.
+func (s *MySQLIdentityStore) CreateIdentity(identity *models.Identity) error {
+	ctx := context.Background()
+	identity.TenantID = s.tenant
+	_, err := s.db.ExecContext(ctx, "INSERT INTO identities (tenant_id, provider, subject, user_id, email, linked_at) VALUES (?, ?, ?, ?, ?, ?)",
+		identity.TenantID, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.LinkedAt.UTC())
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return goat.ErrIdentityTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// GetIdentity implements goat.IdentityStore.
+func (s *MySQLIdentityStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	ctx := context.Background()
+	identity := &models.Identity{TenantID: s.tenant}
+	err := s.db.QueryRowContext(ctx, "SELECT provider, subject, user_id, email, linked_at FROM identities WHERE tenant_id = ? AND provider = ? AND subject = ?", s.tenant, provider, subject).
+		Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.LinkedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListIdentities implements goat.IdentityStore.
+func (s *MySQLIdentityStore) ListIdentities(userID uint) ([]*models.Identity, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT provider, subject, user_id, email, linked_at FROM identities WHERE tenant_id = ? AND user_id = ? ORDER BY linked_at", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*models.Identity{}
+	for rows.Next() {
+		identity := &models.Identity{TenantID: s.tenant}
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity implements goat.IdentityStore.
+func (s *MySQLIdentityStore) DeleteIdentity(provider, subject string) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM identities WHERE tenant_id = ? AND provider = ? AND subject = ?", s.tenant, provider, subject)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrIdentityNotFound
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLIdentityStore stores linked identities in a PostgreSQL table.
+// Every query is limited to the store's tenant; see ForTenant.
+type PostgreSQLIdentityStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose identities the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLIdentityStore initializes a new PostgreSQLIdentityStore with a given connection string and creates the identities table.
+func NewPostgreSQLIdentityStore(connString string) (*PostgreSQLIdentityStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	// An external account can be linked to a user in each tenant.
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS identities (
+		tenant_id TEXT NOT NULL DEFAULT '',
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id BIGINT NOT NULL,
+		email TEXT NOT NULL,
+		linked_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (tenant_id, provider, subject)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS identities_tenant_user_id_idx ON identities (tenant_id, user_id)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLIdentityStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.IdentityStore.
+func (s *PostgreSQLIdentityStore) ForTenant(tenantID string) goat.IdentityStore {
+	return &PostgreSQLIdentityStore{conn: s.conn, tenant: tenantID}
+}
+
+// CreateIdentity implements goat.IdentityStore.
+func (s *PostgreSQLIdentityStore) CreateIdentity(identity *models.Identity) error {
+	ctx := context.Background()
+	identity.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, "INSERT INTO identities (tenant_id, provider, subject, user_id, email, linked_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		identity.TenantID, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.LinkedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation {
+			return goat.ErrIdentityTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// GetIdentity implements goat.IdentityStore.
+func (s *PostgreSQLIdentityStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	ctx := context.Background()
+	identity := &models.Identity{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT provider, subject, user_id, email, linked_at FROM identities WHERE tenant_id = $1 AND provider = $2 AND subject = $3", s.tenant, provider, subject).
+		Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.LinkedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListIdentities implements goat.IdentityStore.
+func (s *PostgreSQLIdentityStore) ListIdentities(userID uint) ([]*models.Identity, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT provider, subject, user_id, email, linked_at FROM identities WHERE tenant_id = $1 AND user_id = $2 ORDER BY linked_at", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*models.Identity{}
+	for rows.Next() {
+		identity := &models.Identity{TenantID: s.tenant}
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity implements goat.IdentityStore.
+func (s *PostgreSQLIdentityStore) DeleteIdentity(provider, subject string) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM identities WHERE tenant_id = $1 AND provider = $2 AND subject = $3", s.tenant, provider, subject)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrIdentityNotFound
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MemoryKeyStore keeps signing keys in process memory. It is meant for tests; keys are lost when the
+// process exits, which invalidates every token signed with them.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]models.SigningKey
+}
+
+// NewMemoryKeyStore creates an empty MemoryKeyStore.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: map[string]models.SigningKey{}}
+}
+
+// SaveKey implements goat.KeyStore.
+func (s *MemoryKeyStore) SaveKey(key *models.SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = *key
+	return nil
+}
+
+// ListKeys implements goat.KeyStore.
+func (s *MemoryKeyStore) ListKeys() ([]*models.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*models.SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		key := key
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBKeyStore stores signing keys in a MongoDB collection.
+type MongoDBKeyStore struct {
+	collection *mongo.Collection // MongoDB collection for key documents.
+}
+
+// NewMongoDBKeyStore initializes a new MongoDBKeyStore with a given MongoDB client, database name, and collection name.
+func NewMongoDBKeyStore(client *mongo.Client, dbName, collectionName string) (*MongoDBKeyStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"kid": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBKeyStore{collection: collection}, nil
+}
+
+// SaveKey implements goat.KeyStore.
+func (s *MongoDBKeyStore) SaveKey(key *models.SigningKey) error {
+	ctx := context.Background()
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"kid": key.ID}, key, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListKeys implements goat.KeyStore.
+func (s *MongoDBKeyStore) ListKeys() ([]*models.SigningKey, error) {
+	ctx := context.Background()
+	cursor, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	keys := []*models.SigningKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MySQLKeyStore stores signing keys in a MySQL table.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+type MySQLKeyStore struct {
+	db *sql.DB // MySQL DB connection.
+}
+
+// NewMySQLKeyStore initializes a new MySQLKeyStore with a given DSN (Data Source Name) and creates the signing_keys table.
+func NewMySQLKeyStore(dsn string) (*MySQLKeyStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS signing_keys (
+		kid VARCHAR(64) PRIMARY KEY,
+		alg VARCHAR(16) NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		private_key BLOB NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		rotated_at DATETIME(6) NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLKeyStore{db: db}, nil
+}
+
+// SaveKey implements goat.KeyStore.
+func (s *MySQLKeyStore) SaveKey(key *models.SigningKey) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO signing_keys (kid, alg, status, private_key, created_at, rotated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE status = VALUES(status), rotated_at = VALUES(rotated_at)`,
+		key.ID, key.Algorithm, key.Status, key.PrivateKey, key.CreatedAt.UTC(), key.RotatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListKeys implements goat.KeyStore.
+func (s *MySQLKeyStore) ListKeys() ([]*models.SigningKey, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT kid, alg, status, private_key, created_at, rotated_at FROM signing_keys ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.SigningKey{}
+	for rows.Next() {
+		key := &models.SigningKey{}
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.Status, &key.PrivateKey, &key.CreatedAt, &key.RotatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
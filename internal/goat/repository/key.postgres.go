@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLKeyStore stores signing keys in a PostgreSQL table.
+type PostgreSQLKeyStore struct {
+	conn *pgx.Conn // PostgreSQL connection for database access.
+}
+
+// NewPostgreSQLKeyStore initializes a new PostgreSQLKeyStore with a given connection string and creates the signing_keys table.
+func NewPostgreSQLKeyStore(connString string) (*PostgreSQLKeyStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS signing_keys (
+		kid TEXT PRIMARY KEY,
+		alg TEXT NOT NULL,
+		status TEXT NOT NULL,
+		private_key BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		rotated_at TIMESTAMPTZ
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLKeyStore{conn: conn}, nil
+}
+
+// SaveKey implements goat.KeyStore.
+func (s *PostgreSQLKeyStore) SaveKey(key *models.SigningKey) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, `INSERT INTO signing_keys (kid, alg, status, private_key, created_at, rotated_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (kid) DO UPDATE SET status = EXCLUDED.status, rotated_at = EXCLUDED.rotated_at`,
+		key.ID, key.Algorithm, key.Status, key.PrivateKey, key.CreatedAt, key.RotatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListKeys implements goat.KeyStore.
+func (s *PostgreSQLKeyStore) ListKeys() ([]*models.SigningKey, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT kid, alg, status, private_key, created_at, rotated_at FROM signing_keys ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.SigningKey{}
+	for rows.Next() {
+		key := &models.SigningKey{}
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.Status, &key.PrivateKey, &key.CreatedAt, &key.RotatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+)
+
+// Page sizes of ListUsers.
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 500
+)
+
+// userList is a models.UserQuery checked and normalized for the stores.
+type userList struct {
+	emailPrefix  string
+	status       string // Empty for every state but deleted.
+	role         string
+	createdSince time.Time
+	createdUntil time.Time
+	sort         string      // The query's sort, for the cursor.
+	field        string      // Field sorted by: created_at or email.
+	desc         bool        // Whether the sort is descending.
+	after        *userCursor // Last user of the previous page; nil on the first page.
+	limit        int
+}
+
+// userCursor is the position a page of users ends at. It is sent to clients base64url encoded, as
+// an opaque cursor.
+type userCursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Email     string    `json:"e,omitempty"`
+	ID        uint      `json:"i"`
+}
+
+// newUserList checks query and fills in its defaults. It returns ErrInvalidUserSort,
+// ErrInvalidAccountStatus or ErrInvalidCursor for a query that cannot be run.
+func newUserList(query *models.UserQuery) (*userList, error) {
+	if query == nil {
+		query = &models.UserQuery{}
+	}
+	l := &userList{
+		emailPrefix:  utils.NormalizeEmail(query.EmailPrefix),
+		status:       query.Status,
+		role:         query.Role,
+		createdSince: query.CreatedSince,
+		createdUntil: query.CreatedUntil,
+		sort:         query.Sort,
+		limit:        query.Limit,
+	}
+	if l.sort == "" {
+		l.sort = models.SortByCreatedAt
+	}
+	l.field = strings.TrimPrefix(l.sort, "-")
+	l.desc = l.field != l.sort
+	if l.field != models.SortByCreatedAt && l.field != models.SortByEmail {
+		return nil, goat.ErrInvalidUserSort
+	}
+	switch l.status {
+	case "", models.AccountActive, models.AccountSuspended, models.AccountDeactivated:
+	default:
+		return nil, goat.ErrInvalidAccountStatus
+	}
+	if l.limit <= 0 {
+		l.limit = defaultUserPageSize
+	}
+	if l.limit > maxUserPageSize {
+		l.limit = maxUserPageSize
+	}
+
+	if query.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil {
+			return nil, goat.ErrInvalidCursor
+		}
+		var after userCursor
+		if err := json.Unmarshal(data, &after); err != nil || after.Sort != l.sort || after.ID == 0 {
+			return nil, goat.ErrInvalidCursor
+		}
+		l.after = &after
+	}
+	return l, nil
+}
+
+// afterValue returns the value of the sorted field the previous page ended at.
+func (l *userList) afterValue() interface{} {
+	if l.field == models.SortByEmail {
+		return l.after.Email
+	}
+	return l.after.CreatedAt.UTC()
+}
+
+// userPage returns the page of users l selects, given up to l.limit+1 of them in order: the one
+// past the limit only tells that there is a next page.
+func userPage[U models.Account](l *userList, users []U) *models.UserPage[U] {
+	page := &models.UserPage[U]{Users: users}
+	if len(users) <= l.limit {
+		return page
+	}
+	page.Users = users[:l.limit]
+	last := page.Users[l.limit-1].GetUser()
+	cursor := userCursor{Sort: l.sort, ID: last.ID}
+	if l.field == models.SortByEmail {
+		cursor.Email = last.Email
+	} else {
+		cursor.CreatedAt = last.CreatedAt
+	}
+	data, _ := json.Marshal(cursor) // Cannot fail: the cursor has no values json cannot encode.
+	page.Next = base64.RawURLEncoding.EncodeToString(data)
+	return page
+}
+
+// userListSQL builds the SELECT statement for list on the users of tenant, reading one user past
+// the limit. email is the expression the email column is matched and sorted by; placeholder returns
+// the driver's placeholder for the nth argument, counting from 1.
+func userListSQL(tenant string, list *userList, email string, placeholder func(n int) string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return placeholder(len(args))
+	}
+	cond := func(format string, v interface{}) {
+		conds = append(conds, fmt.Sprintf(format, arg(v)))
+	}
+
+	cond("tenant_id = %s", tenant)
+	if list.status == "" {
+		cond("status <> %s", models.AccountDeleted)
+	} else {
+		cond("status = %s", list.status)
+	}
+	if list.emailPrefix != "" {
+		cond(email+" LIKE %s ESCAPE '!'", likePrefix(list.emailPrefix))
+	}
+	if list.role != "" {
+		cond("role = %s", list.role)
+	}
+	if !list.createdSince.IsZero() {
+		cond("created_at >= %s", list.createdSince.UTC())
+	}
+	if !list.createdUntil.IsZero() {
+		cond("created_at < %s", list.createdUntil.UTC())
+	}
+
+	column, dir, op := "created_at", "ASC", ">"
+	if list.field == models.SortByEmail {
+		column = email
+	}
+	if list.desc {
+		dir, op = "DESC", "<"
+	}
+	if list.after != nil {
+		// Written so that both MySQL and PostgreSQL turn the first condition into an index range.
+		v := list.afterValue()
+		conds = append(conds, fmt.Sprintf("%[1]s %[2]s= %[3]s AND (%[1]s %[2]s %[4]s OR id %[2]s %[5]s)", column, op, arg(v), arg(v), arg(list.after.ID)))
+	}
+
+	stmt := "SELECT " + userColumns + " FROM users WHERE " + strings.Join(conds, " AND ")
+	stmt += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s LIMIT %[3]d", column, dir, list.limit+1)
+	return stmt, args
+}
+
+// likePrefix returns a LIKE pattern, with ! as the escape character, matching strings that start with prefix.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
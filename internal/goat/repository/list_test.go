@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// listUsers returns n users created at the same time, with IDs from 1.
+func listUsers(n int, created time.Time) []*models.User {
+	users := make([]*models.User, n)
+	for i := range users {
+		users[i] = &models.User{ID: uint(i + 1), Email: fmt.Sprintf("user%d@example.com", i+1), CreatedAt: created}
+	}
+	return users
+}
+
+func TestUserCursor(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sort  string
+		after userCursor
+	}{
+		{models.SortByCreatedAt, userCursor{Sort: models.SortByCreatedAt, CreatedAt: created, ID: 2}},
+		{"-" + models.SortByCreatedAt, userCursor{Sort: "-" + models.SortByCreatedAt, CreatedAt: created, ID: 2}},
+		{models.SortByEmail, userCursor{Sort: models.SortByEmail, Email: "user2@example.com", ID: 2}},
+		{"-" + models.SortByEmail, userCursor{Sort: "-" + models.SortByEmail, Email: "user2@example.com", ID: 2}},
+	}
+	for _, tt := range tests {
+		list, err := newUserList(&models.UserQuery{Sort: tt.sort, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := userPage(list, listUsers(3, created))
+		if len(page.Users) != 2 || page.Next == "" {
+			t.Fatalf("%s: page has %d users and next %q, want 2 and a cursor", tt.sort, len(page.Users), page.Next)
+		}
+
+		// The cursor decodes to the last user of the page, under the same sort only.
+		next, err := newUserList(&models.UserQuery{Sort: tt.sort, Limit: 2, Cursor: page.Next})
+		if err != nil {
+			t.Fatalf("%s: newUserList with the page's cursor: %v", tt.sort, err)
+		}
+		if !reflect.DeepEqual(*next.after, tt.after) {
+			t.Errorf("%s: cursor = %+v, want %+v", tt.sort, *next.after, tt.after)
+		}
+		other := models.SortByEmail
+		if strings.TrimPrefix(tt.sort, "-") == models.SortByEmail {
+			other = models.SortByCreatedAt
+		}
+		if _, err := newUserList(&models.UserQuery{Sort: other, Cursor: page.Next}); !errors.Is(err, goat.ErrInvalidCursor) {
+			t.Errorf("%s: cursor used with sort %s: error = %v, want %v", tt.sort, other, err, goat.ErrInvalidCursor)
+		}
+	}
+
+	// The last page has no cursor.
+	list, err := newUserList(&models.UserQuery{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := userPage(list, listUsers(3, created)); page.Next != "" || len(page.Users) != 3 {
+		t.Errorf("last page has %d users and next %q, want 3 and none", len(page.Users), page.Next)
+	}
+}
+
+func TestInvalidUserCursor(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64url", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"created_at","i":12}`))},
+		{"not JSON", encode("created_at:1")},
+		{"wrong field types", encode(`{"s":"created_at","i":"1"}`)},
+		{"another sort", encode(`{"s":"email","e":"a@example.com","i":1}`)},
+		{"no ID", encode(`{"s":"created_at","c":"2024-01-01T12:00:00Z"}`)},
+	}
+	for _, tt := range tests {
+		_, err := newUserList(&models.UserQuery{Sort: models.SortByCreatedAt, Cursor: tt.cursor})
+		if !errors.Is(err, goat.ErrInvalidCursor) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, goat.ErrInvalidCursor)
+		}
+	}
+}
+
+func TestUserListSQLBreaksTiesOnID(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	placeholder := func(n int) string { return fmt.Sprintf("$%d", n) }
+	tests := []struct {
+		sort      string
+		after     userCursor
+		wantCond  string
+		wantOrder string
+		wantAfter []interface{}
+	}{
+		{
+			models.SortByCreatedAt, userCursor{Sort: models.SortByCreatedAt, CreatedAt: created, ID: 2},
+			"created_at >= $3 AND (created_at > $4 OR id > $5)", "ORDER BY created_at ASC, id ASC LIMIT 3",
+			[]interface{}{created, created, uint(2)},
+		},
+		{
+			"-" + models.SortByCreatedAt, userCursor{Sort: "-" + models.SortByCreatedAt, CreatedAt: created, ID: 2},
+			"created_at <= $3 AND (created_at < $4 OR id < $5)", "ORDER BY created_at DESC, id DESC LIMIT 3",
+			[]interface{}{created, created, uint(2)},
+		},
+		{
+			models.SortByEmail, userCursor{Sort: models.SortByEmail, Email: "user2@example.com", ID: 2},
+			"email >= $3 AND (email > $4 OR id > $5)", "ORDER BY email ASC, id ASC LIMIT 3",
+			[]interface{}{"user2@example.com", "user2@example.com", uint(2)},
+		},
+	}
+	for _, tt := range tests {
+		list, err := newUserList(&models.UserQuery{Sort: tt.sort, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := tt.after
+		list.after = &after
+
+		stmt, args := userListSQL("acme", list, "email", placeholder)
+		if !strings.Contains(stmt, tt.wantCond) || !strings.HasSuffix(stmt, tt.wantOrder) {
+			t.Errorf("%s: statement = %q, want the condition %q and to end with %q", tt.sort, stmt, tt.wantCond, tt.wantOrder)
+		}
+		want := append([]interface{}{"acme", models.AccountDeleted}, tt.wantAfter...)
+		if !reflect.DeepEqual(args, want) {
+			t.Errorf("%s: arguments = %v, want %v", tt.sort, args, want)
+		}
+	}
+}
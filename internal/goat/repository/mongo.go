@@ -2,123 +2,612 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/bontusss/goat/internal/goat"
 	"github.com/bontusss/goat/internal/goat/models"
-	"github.com/google/uuid"
+	"github.com/bontusss/goat/internal/goat/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // MongoDBUserRepository is a struct for MongoDB operations, encapsulating client and collection information.
-type MongoDBUserRepository struct {
+// Application fields of U are stored alongside the User fields in the same document.
+// Every query is limited to the repository's tenant; see ForTenant.
+type MongoDBUserRepository[U models.Account] struct {
 	Client     *mongo.Client     // MongoDB client for database access.
 	collection *mongo.Collection // MongoDB collection for user documents.
+	outboxes   *mongo.Collection // Collection lifecycle events are recorded in; nil without WithOutbox.
+	tenant     string            // Tenant whose users the repository sees; empty for the default tenant.
+	config                       // Password hashing and other shared settings.
 }
 
 // NewMongoDBUserRepository initializes a new MongoDBUserRepository with a given MongoDB client, database name, and collection name.
-// It also ensures that an index on the email field is created to enforce uniqueness.
-func NewMongoDBUserRepository(client *mongo.Client, dbName, collectionName string) (*MongoDBUserRepository, error) {
+// It also ensures that an index on the tenant and email fields is created to enforce uniqueness.
+// The repository serves the default tenant; use ForTenant for the others. Databases with users
+// stored before emails were normalized need NormalizeStoredEmails run once.
+func NewMongoDBUserRepository[U models.Account](client *mongo.Client, dbName, collectionName string, opts ...Option) (*MongoDBUserRepository[U], error) {
 	ctx := context.Background()
 	db := client.Database(dbName)
 	collection := db.Collection(collectionName)
 
-	// Create a unique index on the email field to ensure no duplicate emails are registered.
-	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.M{"email": 1},              // Index key
-		Options: options.Index().SetUnique(true), // Enforce uniqueness
+	// Documents written before account states existed have no status; they are active, and the
+	// email index below must see them.
+	_, err := collection.UpdateMany(ctx, bson.M{"status": bson.M{"$in": bson.A{nil, ""}}}, bson.M{"$set": bson.M{"status": models.AccountActive}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a unique index on tenant and email to ensure no duplicate emails are registered within
+	// a tenant. It leaves out soft-deleted users, whose email can be registered again; partial
+	// indexes with $in need MongoDB 6.0 or later.
+	live := bson.M{"status": bson.M{"$in": bson.A{models.AccountActive, models.AccountSuspended, models.AccountDeactivated}}}
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetName(mongoLiveEmailIndex).SetUnique(true).SetPartialFilterExpression(live),
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	// User IDs are random, so they are unique only if the index says so; Register retries on a collision.
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &MongoDBUserRepository{Client: client, collection: collection}, nil
-}
 
-// Register adds a new user to the MongoDB collection. It hashes the user's password before saving.
-func (r *MongoDBUserRepository) Register(user *models.User) error {
-	ctx := context.Background()
-	user.ID = uint(uuid.New().ID()) // Generate a unique ID for the user.
+	// Collections created before tenants existed have a unique index on email alone, which would
+	// keep the same address from registering with a second tenant. It is fine if there is none.
+	_, _ = collection.Indexes().DropOne(ctx, "email_1")
 
-	// Hash the user's password for secure storage.
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	// Index the account states for PurgeDeletedUsers.
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "deleted_at", Value: 1}},
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	user.Password = string(hashedPassword)
 
-	// Insert the new user document into the MongoDB collection.
-	_, err = r.collection.InsertOne(ctx, user)
+	// Index the creation time for ListUsers. Users stored before it was recorded are taken to be
+	// created now.
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}},
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	_, err = collection.UpdateMany(ctx, bson.M{"created_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"created_at": storedNow()}})
+	if err != nil {
+		return nil, err
+	}
+	// And the email, for ListUsers sorted by it. The live email index cannot serve it: a partial
+	// index is only used for filters it is known to cover, which a listing by status is not.
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "email", Value: 1}, {Key: "id", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &MongoDBUserRepository[U]{Client: client, collection: collection, config: newConfig(opts)}
+	if r.config.outbox {
+		r.outboxes = db.Collection(mongoOutboxCollection)
+	}
+	return r, nil
+}
+
+// ForTenant returns a repository that sees only the users of tenant. Users registered through it
+// belong to tenant, whatever their TenantID field says.
+func (r *MongoDBUserRepository[U]) ForTenant(tenant string) *MongoDBUserRepository[U] {
+	scoped := *r
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// Tenant returns the tenant whose users the repository sees; empty for the default tenant.
+func (r *MongoDBUserRepository[U]) Tenant() string {
+	return r.tenant
+}
+
+// scope limits filter to the repository's tenant. Documents written before tenants existed have
+// no tenant_id and belong to the default tenant.
+func (r *MongoDBUserRepository[U]) scope(filter bson.M) bson.M {
+	filter["tenant_id"] = mongoTenant(r.tenant)
+	return filter
+}
+
+// live limits filter to the repository's tenant and to users that are not soft-deleted. Documents
+// written before account states existed have no status and are active.
+func (r *MongoDBUserRepository[U]) live(filter bson.M) bson.M {
+	filter["status"] = bson.M{"$ne": models.AccountDeleted}
+	return r.scope(filter)
+}
+
+// mongoTenant returns the tenant_id value that selects the documents of tenant. Documents written
+// before tenants existed have no tenant_id and belong to the default tenant.
+func mongoTenant(tenant string) interface{} {
+	if tenant == "" {
+		return bson.M{"$in": bson.A{"", nil}}
+	}
+	return tenant
+}
+
+// Register adds a new user to the MongoDB collection. It hashes the user's password before saving.
+func (r *MongoDBUserRepository[U]) Register(user U) error {
+	ctx := context.Background()
+	u := user.GetUser()
+	u.TenantID = r.tenant
+	u.Email = utils.NormalizeEmail(u.Email)
+	u.Status = models.AccountActive
+	u.CreatedAt = storedNow()
+	u.DeletedAt = nil
+
+	// Hash the user's password for secure storage. An unusable password set by SetUnusablePassword
+	// matches nothing and is stored as is; any other value is hashed, whatever it looks like.
+	if !u.WantsUnusablePassword() {
+		hashedPassword, err := r.hasher.Hash(u.Password)
+		if err != nil {
+			return err
+		}
+		u.Password = hashedPassword
+	}
+
+	// Insert the new user document into the MongoDB collection, under a random ID that no other user has.
+	return withNewID(u, func() error {
+		return r.change(ctx, models.UserRegistered, func(ctx context.Context) (U, error) {
+			_, err := r.collection.InsertOne(ctx, user)
+			if err != nil {
+				return user, mongoUserError(err)
+			}
+			return user, nil
+		})
+	})
 }
 
 // Login checks a user's credentials against the stored values in the MongoDB collection.
 // If the credentials are valid, it returns the user object; otherwise, it returns an error.
-func (r *MongoDBUserRepository) Login(email, password string) (*models.User, error) {
+func (r *MongoDBUserRepository[U]) Login(email, password string) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
+	email = utils.NormalizeEmail(email)
+	var zero U
+	user := models.New[U]()
 
 	// Attempt to find the user by email.
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(user)
+	err := r.collection.FindOne(ctx, r.live(bson.M{"email": email})).Decode(user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// If no document is found, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+			return zero, goat.ErrInvalidCredentials
 		}
-		return nil, err
+		return zero, err
 	}
 
 	// Verify the password against the hashed password stored in the database.
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	u := user.GetUser()
+	if !u.HasUsablePassword() {
+		// The account only signs in through linked identities.
+		return zero, goat.ErrInvalidCredentials
+	}
+	ok, err := r.hasher.Verify(password, u.Password)
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			// If the password does not match, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+		return zero, err
+	}
+	if !ok {
+		// If the password does not match, return an invalid credentials error.
+		return zero, goat.ErrInvalidCredentials
+	}
+	// Only tell the owner of the password why an inactive account cannot sign in.
+	if err := goat.AccountError(u); err != nil {
+		return zero, err
+	}
+
+	// Upgrade the stored hash if it uses an outdated algorithm or cost. This is best effort:
+	// the credentials are already verified, and a failed rehash is retried on the next login.
+	if r.hasher.NeedsRehash(u.Password) {
+		if hashed, err := r.hasher.Hash(password); err == nil {
+			if _, err := r.collection.UpdateOne(ctx, r.scope(bson.M{"id": u.ID}), bson.M{"$set": bson.M{"password": hashed}}); err == nil {
+				u.Password = hashed
+			}
 		}
-		return nil, err
 	}
 	return user, nil
 }
 
-func (r *MongoDBUserRepository) DeleteUser(id uint) error {
+// DeleteUser soft-deletes a user: the document is kept, hidden from everything but GetDeletedUser,
+// RestoreUser and PurgeDeletedUsers.
+func (r *MongoDBUserRepository[U]) DeleteUser(id uint) error {
+	ctx := context.Background()
+	update := bson.M{"$set": bson.M{"status": models.AccountDeleted, "deleted_at": storedNow()}}
+	return r.change(ctx, models.UserDeleted, func(ctx context.Context) (U, error) {
+		return r.findAndUpdate(ctx, r.live(bson.M{"id": id}), update)
+	})
+}
+
+// SetUserStatus makes a user that is not deleted active, suspended or deactivated.
+func (r *MongoDBUserRepository[U]) SetUserStatus(id uint, status string) error {
 	ctx := context.Background()
-	_, err := r.collection.DeleteOne(ctx, bson.M{"id": id})
+	typ, err := statusEvent(status)
 	if err != nil {
 		return err
 	}
-	return nil
+	update := bson.M{"$set": bson.M{"status": status}}
+	return r.change(ctx, typ, func(ctx context.Context) (U, error) {
+		return r.findAndUpdate(ctx, r.live(bson.M{"id": id}), update)
+	})
+}
+
+// GetDeletedUser returns a soft-deleted user.
+func (r *MongoDBUserRepository[U]) GetDeletedUser(id uint) (U, error) {
+	ctx := context.Background()
+	user := models.New[U]()
+	err := r.collection.FindOne(ctx, r.scope(bson.M{"id": id, "status": models.AccountDeleted})).Decode(user)
+	if err != nil {
+		var zero U
+		return zero, mongoUserError(err)
+	}
+	return user, nil
+}
+
+// RestoreUser makes a soft-deleted user active again, if they were deleted within the retention period.
+func (r *MongoDBUserRepository[U]) RestoreUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserRestored, func(ctx context.Context) (U, error) {
+		user := models.New[U]()
+		if err := r.collection.FindOne(ctx, r.scope(bson.M{"id": id})).Decode(user); err != nil {
+			var zero U
+			return zero, mongoUserError(err)
+		}
+		u := user.GetUser()
+		if err := restorable(u, time.Now(), r.retention); err != nil {
+			return user, err
+		}
+		// Matching the deletion time as read keeps a concurrent restore and delete from both winning.
+		filter := r.scope(bson.M{"id": id, "status": models.AccountDeleted, "deleted_at": u.DeletedAt})
+		return r.findAndUpdate(ctx, filter, bson.M{"$set": bson.M{"status": models.AccountActive}, "$unset": bson.M{"deleted_at": ""}})
+	})
+}
+
+// PurgeDeletedUsers removes the users of every tenant that were soft-deleted longer ago than the
+// retention period, and returns how many it removed. Unless purge is nil, it is called for each user
+// first, to remove the records other stores hold about them; the first error it returns stops the
+// purge, leaving that user and the ones after them for the next run.
+func (r *MongoDBUserRepository[U]) PurgeDeletedUsers(purge func(tenantID string, id uint) error) (int, error) {
+	ctx := context.Background()
+	expired := bson.M{"status": models.AccountDeleted, "deleted_at": bson.M{"$lt": time.Now().Add(-r.retention)}}
+	opts := options.Find().SetProjection(bson.M{"id": 1, "tenant_id": 1}).SetSort(bson.M{"id": 1})
+	cursor, err := r.collection.Find(ctx, expired, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	purged := 0
+	for cursor.Next(ctx) {
+		var u models.User
+		if err := cursor.Decode(&u); err != nil {
+			return purged, err
+		}
+		if purge != nil {
+			if err := purge(u.TenantID, u.ID); err != nil {
+				return purged, err
+			}
+		}
+		// The user may have been restored meanwhile.
+		filter := bson.M{"id": u.ID, "tenant_id": mongoTenant(u.TenantID)}
+		for k, v := range expired {
+			filter[k] = v
+		}
+		res, err := r.collection.DeleteOne(ctx, filter)
+		if err != nil {
+			return purged, err
+		}
+		purged += int(res.DeletedCount)
+	}
+	return purged, cursor.Err()
+}
+
+// AnonymizeUser replaces the email, password and custom fields of a user, soft-deleted or not, with
+// placeholders and deactivates them. The document, and the ID other records refer to, are kept.
+func (r *MongoDBUserRepository[U]) AnonymizeUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserAnonymized, func(ctx context.Context) (U, error) {
+		user, err := anonymous[U](r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		// The document is replaced whole: carry over the role and creation time, which anonymizing keeps.
+		var stored models.User
+		opts := options.FindOne().SetProjection(bson.M{"role": 1, "created_at": 1})
+		if err := r.collection.FindOne(ctx, r.scope(bson.M{"id": id}), opts).Decode(&stored); err != nil {
+			return user, mongoUserError(err)
+		}
+		u := user.GetUser()
+		u.Role = stored.Role
+		u.CreatedAt = stored.CreatedAt
+		res, err := r.collection.ReplaceOne(ctx, r.scope(bson.M{"id": id}), user)
+		if err != nil {
+			return user, mongoUserError(err)
+		}
+		if res.MatchedCount == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
+}
+
+// EraseUser removes a user, soft-deleted or not, at once.
+func (r *MongoDBUserRepository[U]) EraseUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserErased, func(ctx context.Context) (U, error) {
+		user := erased[U](r.tenant, id)
+		res, err := r.collection.DeleteOne(ctx, r.scope(bson.M{"id": id}))
+		if err != nil {
+			return user, err
+		}
+		if res.DeletedCount == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
+}
+
+// findAndUpdate applies update to the user matching filter and returns the user as updated.
+func (r *MongoDBUserRepository[U]) findAndUpdate(ctx context.Context, filter, update bson.M) (U, error) {
+	user := models.New[U]()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(user); err != nil {
+		var zero U
+		return zero, mongoUserError(err)
+	}
+	return user, nil
 }
 
-func (r *MongoDBUserRepository) ResetPassword(email string, newPassword string) error {
+func (r *MongoDBUserRepository[U]) ResetPassword(email string, newPassword string) error {
 	ctx := context.Background()
-	_, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{"password": newPassword}})
+	email = utils.NormalizeEmail(email)
+	hashedPassword, err := r.hasher.Hash(newPassword)
 	if err != nil {
 		return err
 	}
+	res, err := r.collection.UpdateOne(ctx, r.live(bson.M{"email": email}), bson.M{"$set": bson.M{"password": hashedPassword}})
+	if err != nil {
+		return mongoUserError(err)
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrUserNotFound
+	}
 	return nil
 }
 
-func (r *MongoDBUserRepository) UpdateUser(user *models.User) error {
+func (r *MongoDBUserRepository[U]) UpdateUser(user U) error {
 	ctx := context.Background()
-	_, err := r.collection.UpdateOne(ctx, bson.M{"id": user.ID}, bson.M{"$set": user})
+	u := user.GetUser()
+	u.TenantID = r.tenant // Users cannot be moved to another tenant.
+	u.Email = utils.NormalizeEmail(u.Email)
+	fields, err := mongoUserFields(user)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.change(ctx, models.UserUpdated, func(ctx context.Context) (U, error) {
+		res, err := r.collection.UpdateOne(ctx, r.live(bson.M{"id": u.ID}), bson.M{"$set": fields})
+		if err != nil {
+			return user, mongoUserError(err)
+		}
+		if res.MatchedCount == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
 }
 
-func (r *MongoDBUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *MongoDBUserRepository[U]) GetUserByID(id uint) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
-	err := r.collection.FindOne(ctx, bson.M{"id": id}).Decode(user)
+	user := models.New[U]()
+	err := r.collection.FindOne(ctx, r.live(bson.M{"id": id})).Decode(user)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, mongoUserError(err)
 	}
 	return user, nil
 }
 
+func (r *MongoDBUserRepository[U]) GetUserByEmail(email string) (U, error) {
+	ctx := context.Background()
+	email = utils.NormalizeEmail(email)
+	user := models.New[U]()
+	err := r.collection.FindOne(ctx, r.live(bson.M{"email": email})).Decode(user)
+	if err != nil {
+		var zero U
+		return zero, mongoUserError(err)
+	}
+	return user, nil
+}
+
+// ListUsers returns a page of the users that are not deleted matching query. Pages are read with
+// an index range from where the cursor left off, so each costs the same however deep it is.
+func (r *MongoDBUserRepository[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	ctx := context.Background()
+	list, err := newUserList(query)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := r.live(bson.M{})
+	switch list.status {
+	case "":
+	case models.AccountActive:
+		// Documents written before account states existed have no status and are active.
+		filter["status"] = bson.M{"$in": bson.A{models.AccountActive, "", nil}}
+	default:
+		filter["status"] = list.status
+	}
+	if list.emailPrefix != "" {
+		// An anchored, case-sensitive expression is matched with an index range.
+		filter["email"] = bson.M{"$regex": "^" + regexp.QuoteMeta(list.emailPrefix)}
+	}
+	if list.role != "" {
+		filter["role"] = list.role
+	}
+	created := bson.M{}
+	if !list.createdSince.IsZero() {
+		created["$gte"] = list.createdSince
+	}
+	if !list.createdUntil.IsZero() {
+		created["$lt"] = list.createdUntil
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	dir, op := 1, "$gt"
+	if list.desc {
+		dir, op = -1, "$lt"
+	}
+	if list.after != nil {
+		v := list.afterValue()
+		filter["$or"] = bson.A{
+			bson.M{list.field: bson.M{op: v}},
+			bson.M{list.field: v, "id": bson.M{op: list.after.ID}},
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: list.field, Value: dir}, {Key: "id", Value: dir}}).SetLimit(int64(list.limit + 1))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []U{}
+	for cursor.Next(ctx) {
+		user := models.New[U]()
+		if err := cursor.Decode(user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return userPage(list, users), nil
+}
+
+// NormalizeStoredEmails normalizes the emails of users stored before emails were normalized, in every
+// tenant, so that lookups, which normalize their input, find them again. A user whose normalized
+// address another user of the tenant already has keeps the stored email and is reported instead.
+// It is safe to run again, and to stop and resume.
+func (r *MongoDBUserRepository[U]) NormalizeStoredEmails() ([]models.EmailConflict, error) {
+	ctx := context.Background()
+	opts := options.Find().SetProjection(bson.M{"id": 1, "tenant_id": 1, "email": 1}).SetSort(bson.M{"id": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	conflicts := []models.EmailConflict{}
+	for cursor.Next(ctx) {
+		var stored models.User
+		if err := cursor.Decode(&stored); err != nil {
+			return nil, err
+		}
+		tenant := mongoTenant(stored.TenantID)
+		conflict, err := normalizeStoredEmail(models.EmailConflict{TenantID: stored.TenantID, UserID: stored.ID, Email: stored.Email}, func(c models.EmailConflict) error {
+			_, err := r.collection.UpdateOne(ctx, bson.M{"id": c.UserID, "tenant_id": tenant}, bson.M{"$set": bson.M{"email": c.Normalized}})
+			return mongoUserError(err)
+		}, func(c models.EmailConflict) (uint, error) {
+			var holder models.User
+			filter := bson.M{"tenant_id": tenant, "email": c.Normalized, "id": bson.M{"$ne": c.UserID}, "status": bson.M{"$ne": models.AccountDeleted}}
+			err := r.collection.FindOne(ctx, filter).Decode(&holder)
+			return holder.ID, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// change makes a change to the users collection with fn. When the repository has an outbox, fn runs
+// in a transaction that also records an event of type typ on the user fn returns. The driver retries
+// the transaction on transient errors, so fn may run more than once.
+func (r *MongoDBUserRepository[U]) change(ctx context.Context, typ models.UserEventType, fn func(ctx context.Context) (U, error)) error {
+	if r.outboxes == nil {
+		_, err := fn(ctx)
+		return err
+	}
+
+	session, err := r.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		user, err := fn(sc)
+		if err != nil {
+			return nil, err
+		}
+		message, err := newOutboxMessage(typ, user)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.outboxes.InsertOne(sc, message); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// mongoUserFields returns the fields of user that UpdateUser sets. The account state is left out: it
+// changes only through SetUserStatus, DeleteUser and RestoreUser. So is the creation time, which
+// Register sets once.
+func mongoUserFields[U models.Account](user U) (bson.M, error) {
+	data, err := bson.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	fields := bson.M{}
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "status")
+	delete(fields, "deleted_at")
+	delete(fields, "created_at")
+	return fields, nil
+}
+
+// mongoLiveEmailIndex is the name of the index that keeps the emails of the users of a tenant that
+// are not soft-deleted unique.
+const mongoLiveEmailIndex = "tenant_id_1_live_email"
+
+// mongoUserError translates MongoDB driver errors from the users collection into goat errors.
+func mongoUserError(err error) error {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return goat.ErrUserNotFound
+	case mongoDuplicateIndex(err, "id_1"):
+		return errUserIDTaken
+	case mongoDuplicateIndex(err, mongoLiveEmailIndex):
+		return goat.ErrEmailTaken
+	}
+	return err
+}
+
+// mongoDuplicateIndex reports whether err is a duplicate key error on the index named index.
+func mongoDuplicateIndex(err error, index string) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), " index: "+index+" ")
+}
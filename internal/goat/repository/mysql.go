@@ -3,141 +3,544 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/bontusss/goat/internal/goat"
 	"github.com/bontusss/goat/internal/goat/models"
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/bontusss/goat/internal/goat/utils"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQLUserRepository is a struct for MySQL operations, encapsulating the DB connection.
-type MySQLUserRepository struct {
-	db *sql.DB // MySQL DB connection.
+// Every query is limited to the repository's tenant; see ForTenant.
+type MySQLUserRepository[U models.Account] struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose users the repository sees; empty for the default tenant.
+	config         // Password hashing and other shared settings.
 }
 
-// NewMySQLUserRepository initializes a new MySQLUserRepository with a given DSN (Data Source Name).
-func NewMySQLUserRepository(dsn string) (*MySQLUserRepository, error) {
-	db, err := sql.Open("mysql", dsn)
+// NewMySQLUserRepository initializes a new MySQLUserRepository with a given DSN (Data Source Name),
+// which must set parseTime=true so that timestamps scan into time.Time. The repository serves the
+// default tenant; use ForTenant for the others. Databases with users stored before emails were
+// normalized need NormalizeStoredEmails run once.
+func NewMySQLUserRepository[U models.Account](dsn string, opts ...Option) (*MySQLUserRepository[U], error) {
+	db, err := openMySQL(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	// Ensure that an index on the email field is created to enforce uniqueness.
-	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_email ON users(email)")
+	// Create the users table; custom fields are stored as a JSON document.
+	// Emails are unique among the users of a tenant that are not soft-deleted, so the same address
+	// can be registered with several tenants, and again once its user is deleted. live_email is the
+	// email of such users and NULL for deleted ones, which the unique key does not compare.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		id BIGINT UNSIGNED PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		email VARCHAR(255) NOT NULL,
+		password VARCHAR(255) NOT NULL,
+		custom_fields JSON NULL,
+		role VARCHAR(64) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL DEFAULT 'active',
+		created_at DATETIME(6) NOT NULL,
+		deleted_at DATETIME(6) NULL,
+		live_email VARCHAR(255) AS (IF(status = 'deleted', NULL, email)) STORED,
+		UNIQUE KEY idx_tenant_live_email (tenant_id, live_email),
+		INDEX idx_tenant_email (tenant_id, email),
+		INDEX idx_tenant_created_at (tenant_id, created_at, id),
+		INDEX idx_status_deleted_at (status, deleted_at)
+	)`)
 	if err != nil {
 		return nil, err
 	}
+	if err := migrateMySQLCustomFields(db); err != nil {
+		return nil, err
+	}
+	if err := migrateMySQLUsers(db); err != nil {
+		return nil, err
+	}
+	if err := migrateMySQLUserStatus(db); err != nil {
+		return nil, err
+	}
+	if err := migrateMySQLUserListing(db); err != nil {
+		return nil, err
+	}
+
+	cfg := newConfig(opts)
+	if cfg.outbox {
+		if _, err := db.Exec(mysqlOutboxTable); err != nil {
+			return nil, err
+		}
+	}
 
-	return &MySQLUserRepository{db: db}, nil
+	return &MySQLUserRepository[U]{db: db, config: cfg}, nil
 }
 
-// Register adds a new user to the MySQL database. It hashes the user's password before saving.
-func (r *MySQLUserRepository) Register(user *models.User) error {
-	ctx := context.Background()
-	user.ID = uint(uuid.New().ID()) // Generate a unique ID for the user.
+// errMySQLParseTime is returned by the MySQL stores for a DSN without parseTime=true.
+var errMySQLParseTime = errors.New("repository: the MySQL DSN must set parseTime=true")
 
-	// Hash the user's password for secure storage.
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+// openMySQL opens the MySQL database of dsn, which must set parseTime=true: without it, timestamps
+// are read as bytes and every query that returns one fails.
+func openMySQL(dsn string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
 	if err != nil {
+		return nil, err
+	}
+	if !cfg.ParseTime {
+		return nil, errMySQLParseTime
+	}
+	return sql.Open("mysql", dsn)
+}
+
+// migrateMySQLCustomFields upgrades a users table created before custom fields existed: it adds the
+// custom_fields column, which is NULL for the users already stored.
+func migrateMySQLCustomFields(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'custom_fields'").Scan(&n)
+	if err != nil || n > 0 {
 		return err
 	}
-	user.Password = string(hashedPassword)
+	_, err = db.Exec("ALTER TABLE users ADD COLUMN custom_fields JSON NULL AFTER password")
+	return err
+}
 
-	// Insert the new user into the database.
-	_, err = r.db.ExecContext(ctx, "INSERT INTO users (id, email, password) VALUES (?, ?, ?)", user.ID, user.Email, user.Password)
+// migrateMySQLUsers upgrades a users table created before tenants existed: it adds the tenant_id
+// column and replaces the unique email index with one on tenant and email.
+func migrateMySQLUsers(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'tenant_id'").Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT '' AFTER id, ADD UNIQUE KEY idx_tenant_email (tenant_id, email)")
 	if err != nil {
 		return err
 	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'idx_email'").Scan(&n)
+	if err != nil || n == 0 {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE users DROP INDEX idx_email")
+	return err
+}
+
+// migrateMySQLUserStatus upgrades a users table created before account states existed: it adds the
+// status, deleted_at and live_email columns, and moves the unique key on tenant and email onto
+// live_email, so that the email of a soft-deleted user can be registered again. Existing users
+// become active.
+func migrateMySQLUserStatus(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'status'").Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active', ADD COLUMN deleted_at DATETIME(6) NULL,
+		ADD COLUMN live_email VARCHAR(255) AS (IF(status = 'deleted', NULL, email)) STORED,
+		ADD UNIQUE KEY idx_tenant_live_email (tenant_id, live_email), DROP INDEX idx_tenant_email, ADD INDEX idx_tenant_email (tenant_id, email),
+		ADD INDEX idx_status_deleted_at (status, deleted_at)`)
+	return err
+}
+
+// migrateMySQLUserListing upgrades a users table created before users could be listed: it adds the
+// role and created_at columns, and the index ListUsers pages by creation time with. Existing users
+// are taken to be created at the time of the upgrade.
+func migrateMySQLUserListing(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'created_at'").Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	for _, stmt := range []string{
+		"ALTER TABLE users ADD COLUMN role VARCHAR(64) NOT NULL DEFAULT '' AFTER custom_fields, ADD COLUMN created_at DATETIME(6) NULL AFTER status",
+		"UPDATE users SET created_at = UTC_TIMESTAMP(6)",
+		"ALTER TABLE users MODIFY created_at DATETIME(6) NOT NULL, ADD INDEX idx_tenant_created_at (tenant_id, created_at, id)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ForTenant returns a repository that sees only the users of tenant. Users registered through it
+// belong to tenant, whatever their TenantID field says.
+func (r *MySQLUserRepository[U]) ForTenant(tenant string) *MySQLUserRepository[U] {
+	scoped := *r
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// Tenant returns the tenant whose users the repository sees; empty for the default tenant.
+func (r *MySQLUserRepository[U]) Tenant() string {
+	return r.tenant
+}
+
+// Register adds a new user to the MySQL database. It hashes the user's password before saving.
+func (r *MySQLUserRepository[U]) Register(user U) error {
+	ctx := context.Background()
+	u := user.GetUser()
+	u.TenantID = r.tenant
+	u.Email = utils.NormalizeEmail(u.Email)
+	u.Status = models.AccountActive
+	u.CreatedAt = storedNow()
+	u.DeletedAt = nil
+
+	// Hash the user's password for secure storage. An unusable password set by SetUnusablePassword
+	// matches nothing and is stored as is; any other value is hashed, whatever it looks like.
+	if !u.WantsUnusablePassword() {
+		hashedPassword, err := r.hasher.Hash(u.Password)
+		if err != nil {
+			return err
+		}
+		u.Password = hashedPassword
+	}
+
+	customFields, err := models.MarshalCustomFields(user)
+	if err != nil {
+		return err
+	}
+
+	// Insert the new user into the database, under a random ID that no other user has.
+	return withNewID(u, func() error {
+		return r.change(ctx, models.UserRegistered, func(db mysqlExecer) (U, error) {
+			_, err := db.ExecContext(ctx, "INSERT INTO users (id, tenant_id, email, password, custom_fields, role, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				u.ID, u.TenantID, u.Email, u.Password, customFields, u.Role, u.Status, u.CreatedAt)
+			if err != nil {
+				return user, mysqlUserError(err)
+			}
+			return user, nil
+		})
+	})
+}
+
 // Login checks a user's credentials against the stored values in the MySQL database.
 // If the credentials are valid, it returns the user object; otherwise, it returns an error.
-func (r *MySQLUserRepository) Login(email, password string) (*models.User, error) {
+func (r *MySQLUserRepository[U]) Login(email, password string) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
+	email = utils.NormalizeEmail(email)
+	var zero U
 
 	// Attempt to find the user by email.
-	err := r.db.QueryRowContext(ctx, "SELECT id, email, password FROM users WHERE email = ?", email).Scan(&user.ID, &user.Email, &user.Password)
+	user, err := scanUser[U](r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND email = ? AND status <> ?", r.tenant, email, models.AccountDeleted))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// If no row is found, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+			return zero, goat.ErrInvalidCredentials
 		}
-		return nil, err
+		return zero, err
 	}
 
 	// Verify the password against the hashed password stored in the database.
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	u := user.GetUser()
+	if !u.HasUsablePassword() {
+		// The account only signs in through linked identities.
+		return zero, goat.ErrInvalidCredentials
+	}
+	ok, err := r.hasher.Verify(password, u.Password)
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			// If the password does not match, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+		return zero, err
+	}
+	if !ok {
+		// If the password does not match, return an invalid credentials error.
+		return zero, goat.ErrInvalidCredentials
+	}
+	// Only tell the owner of the password why an inactive account cannot sign in.
+	if err := goat.AccountError(u); err != nil {
+		return zero, err
+	}
+
+	// Upgrade the stored hash if it uses an outdated algorithm or cost. This is best effort:
+	// the credentials are already verified, and a failed rehash is retried on the next login.
+	if r.hasher.NeedsRehash(u.Password) {
+		if hashed, err := r.hasher.Hash(password); err == nil {
+			if _, err := r.db.ExecContext(ctx, "UPDATE users SET password = ? WHERE tenant_id = ? AND id = ?", hashed, r.tenant, u.ID); err == nil {
+				u.Password = hashed
+			}
 		}
-		return nil, err
 	}
 	return user, nil
 }
 
-func (r *MySQLUserRepository) DeleteUser(id uint) error {
+// DeleteUser soft-deletes a user: the row is kept, hidden from everything but GetDeletedUser,
+// RestoreUser and PurgeDeletedUsers.
+func (r *MySQLUserRepository[U]) DeleteUser(id uint) error {
 	ctx := context.Background()
-	_, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	return r.change(ctx, models.UserDeleted, func(db mysqlExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ? AND status <> ? FOR UPDATE", r.tenant, id, models.AccountDeleted))
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		deletedAt := storedNow()
+		_, err = db.ExecContext(ctx, "UPDATE users SET status = ?, deleted_at = ? WHERE tenant_id = ? AND id = ?", models.AccountDeleted, deletedAt, r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		u := user.GetUser()
+		u.Status = models.AccountDeleted
+		u.DeletedAt = &deletedAt
+		return user, nil
+	})
+}
+
+// SetUserStatus makes a user that is not deleted active, suspended or deactivated.
+func (r *MySQLUserRepository[U]) SetUserStatus(id uint, status string) error {
+	ctx := context.Background()
+	typ, err := statusEvent(status)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.change(ctx, typ, func(db mysqlExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ? AND status <> ? FOR UPDATE", r.tenant, id, models.AccountDeleted))
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE users SET status = ? WHERE tenant_id = ? AND id = ?", status, r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		user.GetUser().Status = status
+		return user, nil
+	})
 }
 
-func (r *MySQLUserRepository) ResetPassword(email string, newPassword string) error {
+// GetDeletedUser returns a soft-deleted user.
+func (r *MySQLUserRepository[U]) GetDeletedUser(id uint) (U, error) {
 	ctx := context.Background()
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET password = ? WHERE email = ?", newPassword, email)
+	user, err := scanUser[U](r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ? AND status = ?", r.tenant, id, models.AccountDeleted))
+	if err != nil {
+		var zero U
+		return zero, mysqlUserError(err)
+	}
+	return user, nil
+}
+
+// RestoreUser makes a soft-deleted user active again, if they were deleted within the retention period.
+func (r *MySQLUserRepository[U]) RestoreUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserRestored, func(db mysqlExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ? FOR UPDATE", r.tenant, id))
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		u := user.GetUser()
+		if err := restorable(u, time.Now(), r.retention); err != nil {
+			return user, err
+		}
+		_, err = db.ExecContext(ctx, "UPDATE users SET status = ?, deleted_at = NULL WHERE tenant_id = ? AND id = ?", models.AccountActive, r.tenant, id)
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		u.Status = models.AccountActive
+		u.DeletedAt = nil
+		return user, nil
+	})
+}
+
+// PurgeDeletedUsers removes the users of every tenant that were soft-deleted longer ago than the
+// retention period, and returns how many it removed. Unless purge is nil, it is called for each user
+// first, to remove the records other stores hold about them; the first error it returns stops the
+// purge, leaving that user and the ones after them for the next run.
+func (r *MySQLUserRepository[U]) PurgeDeletedUsers(purge func(tenantID string, id uint) error) (int, error) {
+	ctx := context.Background()
+	cutoff := time.Now().UTC().Add(-r.retention)
+	purged := 0
+	var after uint
+	for {
+		rows, err := r.db.QueryContext(ctx, "SELECT tenant_id, id FROM users WHERE status = ? AND deleted_at < ? AND id > ? ORDER BY id LIMIT ?",
+			models.AccountDeleted, cutoff, after, purgeBatch)
+		if err != nil {
+			return purged, err
+		}
+		var batch []models.User
+		for rows.Next() {
+			var u models.User
+			if err := rows.Scan(&u.TenantID, &u.ID); err != nil {
+				rows.Close()
+				return purged, err
+			}
+			batch = append(batch, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return purged, err
+		}
+
+		for _, u := range batch {
+			after = u.ID
+			if purge != nil {
+				if err := purge(u.TenantID, u.ID); err != nil {
+					return purged, err
+				}
+			}
+			// The user may have been restored meanwhile.
+			res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE tenant_id = ? AND id = ? AND status = ? AND deleted_at < ?", u.TenantID, u.ID, models.AccountDeleted, cutoff)
+			if err != nil {
+				return purged, err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return purged, err
+			}
+			purged += int(n)
+		}
+		if len(batch) < purgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// AnonymizeUser replaces the email, password and custom fields of a user, soft-deleted or not, with
+// placeholders and deactivates them. The row, and the ID other records refer to, are kept.
+func (r *MySQLUserRepository[U]) AnonymizeUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserAnonymized, func(db mysqlExecer) (U, error) {
+		user, err := anonymous[U](r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		u := user.GetUser()
+		res, err := db.ExecContext(ctx, "UPDATE users SET email = ?, password = ?, custom_fields = NULL, status = ?, deleted_at = NULL WHERE tenant_id = ? AND id = ?",
+			u.Email, u.Password, u.Status, r.tenant, id)
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		// The new password is random, so the row always changes if it exists.
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
+}
+
+// EraseUser removes a user, soft-deleted or not, at once.
+func (r *MySQLUserRepository[U]) EraseUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserErased, func(db mysqlExecer) (U, error) {
+		user := erased[U](r.tenant, id)
+		res, err := db.ExecContext(ctx, "DELETE FROM users WHERE tenant_id = ? AND id = ?", r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
+}
+
+func (r *MySQLUserRepository[U]) ResetPassword(email string, newPassword string) error {
+	ctx := context.Background()
+	email = utils.NormalizeEmail(email)
+	hashedPassword, err := r.hasher.Hash(newPassword)
 	if err != nil {
 		return err
 	}
+	// MySQL counts only changed rows, so check that the user exists rather than trust RowsAffected.
+	var exists int
+	err = r.db.QueryRowContext(ctx, "SELECT 1 FROM users WHERE tenant_id = ? AND email = ? AND status <> ?", r.tenant, email, models.AccountDeleted).Scan(&exists)
+	if err != nil {
+		return mysqlUserError(err)
+	}
+	_, err = r.db.ExecContext(ctx, "UPDATE users SET password = ? WHERE tenant_id = ? AND email = ? AND status <> ?", hashedPassword, r.tenant, email, models.AccountDeleted)
+	if err != nil {
+		return mysqlUserError(err)
+	}
 	return nil
 }
 
-func (r *MySQLUserRepository) UpdateUser(user *models.User) error {
+func (r *MySQLUserRepository[U]) UpdateUser(user U) error {
 	ctx := context.Background()
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET email = ?, password = ? WHERE id = ?", user.Email, user.Password, user.ID)
+	u := user.GetUser()
+	u.TenantID = r.tenant // Users cannot be moved to another tenant.
+	u.Email = utils.NormalizeEmail(u.Email)
+	customFields, err := models.MarshalCustomFields(user)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.change(ctx, models.UserUpdated, func(db mysqlExecer) (U, error) {
+		// MySQL counts only changed rows, so check that the user exists rather than trust
+		// RowsAffected.
+		var exists int
+		err := db.QueryRowContext(ctx, "SELECT 1 FROM users WHERE tenant_id = ? AND id = ? AND status <> ? FOR UPDATE", r.tenant, u.ID, models.AccountDeleted).Scan(&exists)
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE users SET email = ?, password = ?, custom_fields = ?, role = ? WHERE tenant_id = ? AND id = ? AND status <> ?",
+			u.Email, u.Password, customFields, u.Role, r.tenant, u.ID, models.AccountDeleted)
+		if err != nil {
+			return user, mysqlUserError(err)
+		}
+		return user, nil
+	})
 }
 
-func (r *MySQLUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *MySQLUserRepository[U]) GetUserByID(id uint) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, "SELECT id, email, password FROM users WHERE id = ?", id).Scan(&user.ID, &user.Email, &user.Password)
+	user, err := scanUser[U](r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND id = ? AND status <> ?", r.tenant, id, models.AccountDeleted))
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, mysqlUserError(err)
 	}
 	return user, nil
 }
 
-func (r *MySQLUserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (r *MySQLUserRepository[U]) GetUserByEmail(email string) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, "SELECT id, email, password FROM users WHERE email = ?", email).Scan(&user.ID, &user.Email, &user.Password)
+	email = utils.NormalizeEmail(email)
+	user, err := scanUser[U](r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND email = ? AND status <> ?", r.tenant, email, models.AccountDeleted))
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, mysqlUserError(err)
 	}
 	return user, nil
 }
 
-func (r *MySQLUserRepository) GetAllUsers() ([]*models.User, error) {
+// ListUsers returns a page of the users that are not deleted matching query. Pages are read with
+// an index range from where the cursor left off, so each costs the same however deep it is.
+func (r *MySQLUserRepository[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	ctx := context.Background()
+	list, err := newUserList(query)
+	if err != nil {
+		return nil, err
+	}
+	stmt, args := userListSQL(r.tenant, list, "email", func(int) string { return "?" })
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []U{}
+	for rows.Next() {
+		user, err := scanUser[U](rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return userPage(list, users), nil
+}
+
+// GetAllUsers returns every user that is not deleted, all at once.
+//
+// Deprecated: use ListUsers, which pages through the users.
+func (r *MySQLUserRepository[U]) GetAllUsers() ([]U, error) {
 	ctx := context.Background()
-	rows, err := r.db.QueryContext(ctx, "SELECT id, email, password FROM users")
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND status <> ?", r.tenant, models.AccountDeleted)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	users := []*models.User{}
+	users := []U{}
 	for rows.Next() {
-		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Email, &user.Password); err != nil {
+		user, err := scanUser[U](rows)
+		if err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -145,18 +548,19 @@ func (r *MySQLUserRepository) GetAllUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-func (r *MySQLUserRepository) GetUsersByEmail(email string) ([]*models.User, error) {
+func (r *MySQLUserRepository[U]) GetUsersByEmail(email string) ([]U, error) {
 	ctx := context.Background()
-	rows, err := r.db.QueryContext(ctx, "SELECT id, email, password FROM users WHERE email = ?", email)
+	email = utils.NormalizeEmail(email)
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = ? AND email = ? AND status <> ?", r.tenant, email, models.AccountDeleted)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	users := []*models.User{}
+	users := []U{}
 	for rows.Next() {
-		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Email, &user.Password); err != nil {
+		user, err := scanUser[U](rows)
+		if err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -164,4 +568,99 @@ func (r *MySQLUserRepository) GetUsersByEmail(email string) ([]*models.User, err
 	return users, nil
 }
 
+// NormalizeStoredEmails normalizes the emails of users stored before emails were normalized, in every
+// tenant, so that lookups, which normalize their input, find them again. A user whose normalized
+// address another user of the tenant already has keeps the stored email and is reported instead.
+// It is safe to run again, and to stop and resume.
+func (r *MySQLUserRepository[U]) NormalizeStoredEmails() ([]models.EmailConflict, error) {
+	ctx := context.Background()
+	conflicts := []models.EmailConflict{}
+	var after uint
+	for {
+		rows, err := r.db.QueryContext(ctx, "SELECT id, tenant_id, email FROM users WHERE id > ? ORDER BY id LIMIT ?", after, emailMigrationBatch)
+		if err != nil {
+			return nil, err
+		}
+		var batch []models.EmailConflict
+		for rows.Next() {
+			var c models.EmailConflict
+			if err := rows.Scan(&c.UserID, &c.TenantID, &c.Email); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, c := range batch {
+			after = c.UserID
+			conflict, err := normalizeStoredEmail(c, func(c models.EmailConflict) error {
+				_, err := r.db.ExecContext(ctx, "UPDATE users SET email = ? WHERE tenant_id = ? AND id = ?", c.Normalized, c.TenantID, c.UserID)
+				return mysqlUserError(err)
+			}, func(c models.EmailConflict) (uint, error) {
+				var id uint
+				err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE tenant_id = ? AND email = ? AND id <> ? AND status <> ?", c.TenantID, c.Normalized, c.UserID, models.AccountDeleted).Scan(&id)
+				return id, err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+		}
+		if len(batch) < emailMigrationBatch {
+			return conflicts, nil
+		}
+	}
+}
 
+// change makes a change to the users table with fn. When the repository has an outbox, fn runs in a
+// transaction that also records an event of type typ on the user fn returns.
+func (r *MySQLUserRepository[U]) change(ctx context.Context, typ models.UserEventType, fn func(db mysqlExecer) (U, error)) error {
+	if !r.outbox {
+		_, err := fn(r.db)
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	user, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	message, err := newOutboxMessage(typ, user)
+	if err != nil {
+		return err
+	}
+	if err := insertMySQLOutboxMessage(ctx, tx, message); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// mysqlUserError translates MySQL driver errors from the users table into goat errors.
+func mysqlUserError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return goat.ErrUserNotFound
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry && mysqlDuplicateKey(mysqlErr, "PRIMARY"):
+		return errUserIDTaken
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry && mysqlDuplicateKey(mysqlErr, "idx_tenant_live_email"):
+		return goat.ErrEmailTaken
+	}
+	return err
+}
+
+// mysqlDuplicateKey reports whether err, an ER_DUP_ENTRY error, is a violation of the unique key
+// named key. MySQL names the key in the message, prefixed with the table name since 8.0.19.
+func mysqlDuplicateKey(err *mysql.MySQLError, key string) bool {
+	return strings.HasSuffix(err.Message, "'"+key+"'") || strings.HasSuffix(err.Message, "."+key+"'")
+}
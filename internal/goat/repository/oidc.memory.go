@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// consentKey identifies a consent in a MemoryOIDCStore.
+type consentKey struct {
+	tenant   string
+	userID   uint
+	clientID string
+}
+
+// memoryOIDC is the state shared by a MemoryOIDCStore and the stores ForTenant returns.
+type memoryOIDC struct {
+	mu       sync.Mutex
+	clients  map[string]models.Client
+	consents map[consentKey]models.Consent
+	grants   map[string]models.Grant
+}
+
+// MemoryOIDCStore keeps OpenID Connect provider state in process memory. It is meant for tests;
+// clients, consents and grants are lost when the process exits.
+type MemoryOIDCStore struct {
+	*memoryOIDC
+	tenant string // Tenant whose consents and grants the store sees; empty for the default tenant.
+}
+
+// NewMemoryOIDCStore creates an empty MemoryOIDCStore.
+func NewMemoryOIDCStore() *MemoryOIDCStore {
+	return &MemoryOIDCStore{memoryOIDC: &memoryOIDC{
+		clients:  map[string]models.Client{},
+		consents: map[consentKey]models.Consent{},
+		grants:   map[string]models.Grant{},
+	}}
+}
+
+// ForTenant implements goat.OIDCStore.
+func (s *MemoryOIDCStore) ForTenant(tenantID string) goat.OIDCStore {
+	return &MemoryOIDCStore{memoryOIDC: s.memoryOIDC, tenant: tenantID}
+}
+
+// SaveClient implements goat.OIDCStore.
+func (s *MemoryOIDCStore) SaveClient(client *models.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[client.ID] = *client
+	return nil
+}
+
+// GetClient implements goat.OIDCStore.
+func (s *MemoryOIDCStore) GetClient(id string) (*models.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, goat.ErrClientNotFound
+	}
+	return &client, nil
+}
+
+// DeleteClient implements goat.OIDCStore.
+func (s *MemoryOIDCStore) DeleteClient(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[id]; !ok {
+		return goat.ErrClientNotFound
+	}
+	delete(s.clients, id)
+	return nil
+}
+
+// SaveConsent implements goat.OIDCStore.
+func (s *MemoryOIDCStore) SaveConsent(consent *models.Consent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	consent.TenantID = s.tenant
+	s.consents[consentKey{s.tenant, consent.UserID, consent.ClientID}] = *consent
+	return nil
+}
+
+// GetConsent implements goat.OIDCStore.
+func (s *MemoryOIDCStore) GetConsent(userID uint, clientID string) (*models.Consent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	consent, ok := s.consents[consentKey{s.tenant, userID, clientID}]
+	if !ok {
+		return nil, goat.ErrConsentNotFound
+	}
+	return &consent, nil
+}
+
+// ListConsents implements goat.OIDCStore.
+func (s *MemoryOIDCStore) ListConsents(userID uint) ([]*models.Consent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	consents := []*models.Consent{}
+	for key, consent := range s.consents {
+		if key.tenant == s.tenant && key.userID == userID {
+			consent := consent
+			consents = append(consents, &consent)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		if !consents[i].GrantedAt.Equal(consents[j].GrantedAt) {
+			return consents[i].GrantedAt.Before(consents[j].GrantedAt)
+		}
+		return consents[i].ClientID < consents[j].ClientID
+	})
+	return consents, nil
+}
+
+// DeleteConsent implements goat.OIDCStore.
+func (s *MemoryOIDCStore) DeleteConsent(userID uint, clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.consents, consentKey{s.tenant, userID, clientID})
+	return nil
+}
+
+// CreateGrant implements goat.OIDCStore.
+func (s *MemoryOIDCStore) CreateGrant(grant *models.Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant.TenantID = s.tenant
+	s.grants[grant.ID] = *grant
+	return nil
+}
+
+// TakeGrant implements goat.OIDCStore.
+func (s *MemoryOIDCStore) TakeGrant(id string) (*models.Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant, ok := s.grants[id]
+	if !ok || grant.TenantID != s.tenant {
+		return nil, goat.ErrGrantNotFound
+	}
+	delete(s.grants, id)
+	return &grant, nil
+}
+
+// DeleteExpiredGrants implements goat.OIDCStore.
+func (s *MemoryOIDCStore) DeleteExpiredGrants(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, grant := range s.grants {
+		if grant.Expired(now) {
+			delete(s.grants, id)
+		}
+	}
+	return nil
+}
+
+// DeleteUserGrants implements goat.OIDCStore.
+func (s *MemoryOIDCStore) DeleteUserGrants(userID uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, grant := range s.grants {
+		if grant.TenantID == s.tenant && grant.UserID == userID {
+			delete(s.grants, id)
+			n++
+		}
+	}
+	return n, nil
+}
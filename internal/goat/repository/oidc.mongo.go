@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBOIDCStore stores OpenID Connect provider state in three MongoDB collections:
+// <prefix>_clients, <prefix>_consents and <prefix>_grants. Consents and grants are limited to the
+// store's tenant; see ForTenant.
+type MongoDBOIDCStore struct {
+	clients  *mongo.Collection // MongoDB collection for registered clients.
+	consents *mongo.Collection // MongoDB collection for user consents.
+	grants   *mongo.Collection // MongoDB collection for authorization codes and refresh tokens.
+	tenant   string            // Tenant whose consents and grants the store sees; empty for the default tenant.
+}
+
+// NewMongoDBOIDCStore initializes a new MongoDBOIDCStore with a given MongoDB client, database name, and collection name prefix.
+// It ensures the unique indexes, an index for finding a user's grants, and a TTL index that lets MongoDB remove
+// expired grants on its own.
+func NewMongoDBOIDCStore(client *mongo.Client, dbName, prefix string) (*MongoDBOIDCStore, error) {
+	ctx := context.Background()
+	db := client.Database(dbName)
+	s := &MongoDBOIDCStore{
+		clients:  db.Collection(prefix + "_clients"),
+		consents: db.Collection(prefix + "_consents"),
+		grants:   db.Collection(prefix + "_grants"),
+	}
+
+	_, err := s.clients.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"client_id": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.grants.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ForTenant implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) ForTenant(tenantID string) goat.OIDCStore {
+	return &MongoDBOIDCStore{clients: s.clients, consents: s.consents, grants: s.grants, tenant: tenantID}
+}
+
+// SaveClient implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) SaveClient(client *models.Client) error {
+	ctx := context.Background()
+	_, err := s.clients.ReplaceOne(ctx, bson.M{"client_id": client.ID}, client, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetClient implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) GetClient(id string) (*models.Client, error) {
+	ctx := context.Background()
+	client := &models.Client{}
+	err := s.clients.FindOne(ctx, bson.M{"client_id": id}).Decode(client)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// DeleteClient implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) DeleteClient(id string) error {
+	ctx := context.Background()
+	res, err := s.clients.DeleteOne(ctx, bson.M{"client_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrClientNotFound
+	}
+	return nil
+}
+
+// SaveConsent implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) SaveConsent(consent *models.Consent) error {
+	ctx := context.Background()
+	consent.TenantID = s.tenant
+	filter := bson.M{"tenant_id": s.tenant, "user_id": consent.UserID, "client_id": consent.ClientID}
+	_, err := s.consents.ReplaceOne(ctx, filter, consent, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConsent implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) GetConsent(userID uint, clientID string) (*models.Consent, error) {
+	ctx := context.Background()
+	consent := &models.Consent{}
+	err := s.consents.FindOne(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID, "client_id": clientID}).Decode(consent)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrConsentNotFound
+		}
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ListConsents implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) ListConsents(userID uint) ([]*models.Consent, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "granted_at", Value: 1}, {Key: "client_id", Value: 1}})
+	cursor, err := s.consents.Find(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	consents := []*models.Consent{}
+	if err := cursor.All(ctx, &consents); err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// DeleteConsent implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) DeleteConsent(userID uint, clientID string) error {
+	ctx := context.Background()
+	_, err := s.consents.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID, "client_id": clientID})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateGrant implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) CreateGrant(grant *models.Grant) error {
+	ctx := context.Background()
+	grant.TenantID = s.tenant
+	_, err := s.grants.InsertOne(ctx, grant)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TakeGrant implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) TakeGrant(id string) (*models.Grant, error) {
+	ctx := context.Background()
+	grant := &models.Grant{}
+	err := s.grants.FindOneAndDelete(ctx, bson.M{"tenant_id": s.tenant, "id": id}).Decode(grant)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrGrantNotFound
+		}
+		return nil, err
+	}
+	return grant, nil
+}
+
+// DeleteExpiredGrants implements goat.OIDCStore. The TTL index already removes expired grants;
+// this removes any the background task has not reached yet.
+func (s *MongoDBOIDCStore) DeleteExpiredGrants(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.grants.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUserGrants implements goat.OIDCStore.
+func (s *MongoDBOIDCStore) DeleteUserGrants(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.grants.DeleteMany(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
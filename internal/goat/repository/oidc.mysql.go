@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MySQLOIDCStore stores OpenID Connect provider state in the oauth_clients, oauth_consents and oauth_grants tables.
+// List fields are stored as JSON arrays. The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Consents and grants are limited to the store's tenant; see ForTenant.
+type MySQLOIDCStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose consents and grants the store sees; empty for the default tenant.
+}
+
+// NewMySQLOIDCStore initializes a new MySQLOIDCStore with a given DSN (Data Source Name) and creates its tables.
+func NewMySQLOIDCStore(dsn string) (*MySQLOIDCStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS oauth_clients (
+		client_id VARCHAR(64) PRIMARY KEY,
+		secret_hash CHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		public BOOLEAN NOT NULL,
+		redirect_uris JSON NOT NULL,
+		grant_types JSON NOT NULL,
+		scopes JSON NOT NULL,
+		skip_consent BOOLEAN NOT NULL,
+		created_at DATETIME(6) NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS oauth_consents (
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		user_id BIGINT UNSIGNED NOT NULL,
+		client_id VARCHAR(64) NOT NULL,
+		scopes JSON NOT NULL,
+		granted_at DATETIME(6) NOT NULL,
+		PRIMARY KEY (tenant_id, user_id, client_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS oauth_grants (
+		id CHAR(64) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		type VARCHAR(32) NOT NULL,
+		client_id VARCHAR(64) NOT NULL,
+		user_id BIGINT UNSIGNED NOT NULL,
+		scopes JSON NOT NULL,
+		redirect_uri VARCHAR(2048) NOT NULL,
+		nonce VARCHAR(255) NOT NULL,
+		code_challenge VARCHAR(128) NOT NULL,
+		auth_time DATETIME(6) NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		expires_at DATETIME(6) NOT NULL,
+		INDEX idx_oauth_grants_expires_at (expires_at),
+		INDEX idx_oauth_grants_user_id (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLOIDCStore{db: db}, nil
+}
+
+// ForTenant implements goat.OIDCStore.
+func (s *MySQLOIDCStore) ForTenant(tenantID string) goat.OIDCStore {
+	return &MySQLOIDCStore{db: s.db, tenant: tenantID}
+}
+
+// SaveClient implements goat.OIDCStore.
+func (s *MySQLOIDCStore) SaveClient(client *models.Client) error {
+	ctx := context.Background()
+	redirectURIs, grantTypes, scopes, err := marshalLists(client.RedirectURIs, client.GrantTypes, client.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO oauth_clients (client_id, secret_hash, name, public, redirect_uris, grant_types, scopes, skip_consent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE secret_hash = VALUES(secret_hash), name = VALUES(name), public = VALUES(public), redirect_uris = VALUES(redirect_uris),
+			grant_types = VALUES(grant_types), scopes = VALUES(scopes), skip_consent = VALUES(skip_consent)`,
+		client.ID, client.SecretHash, client.Name, client.Public, redirectURIs, grantTypes, scopes, client.SkipConsent, client.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetClient implements goat.OIDCStore.
+func (s *MySQLOIDCStore) GetClient(id string) (*models.Client, error) {
+	ctx := context.Background()
+	client := &models.Client{}
+	var redirectURIs, grantTypes, scopes []byte
+	err := s.db.QueryRowContext(ctx, "SELECT client_id, secret_hash, name, public, redirect_uris, grant_types, scopes, skip_consent, created_at FROM oauth_clients WHERE client_id = ?", id).
+		Scan(&client.ID, &client.SecretHash, &client.Name, &client.Public, &redirectURIs, &grantTypes, &scopes, &client.SkipConsent, &client.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrClientNotFound
+		}
+		return nil, err
+	}
+	if err := unmarshalLists([][]byte{redirectURIs, grantTypes, scopes}, &client.RedirectURIs, &client.GrantTypes, &client.Scopes); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// DeleteClient implements goat.OIDCStore.
+func (s *MySQLOIDCStore) DeleteClient(id string) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM oauth_clients WHERE client_id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrClientNotFound
+	}
+	return nil
+}
+
+// SaveConsent implements goat.OIDCStore.
+func (s *MySQLOIDCStore) SaveConsent(consent *models.Consent) error {
+	ctx := context.Background()
+	scopes, err := json.Marshal(consent.Scopes)
+	if err != nil {
+		return err
+	}
+	consent.TenantID = s.tenant
+	_, err = s.db.ExecContext(ctx, `INSERT INTO oauth_consents (tenant_id, user_id, client_id, scopes, granted_at) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE scopes = VALUES(scopes), granted_at = VALUES(granted_at)`,
+		consent.TenantID, consent.UserID, consent.ClientID, scopes, consent.GrantedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConsent implements goat.OIDCStore.
+func (s *MySQLOIDCStore) GetConsent(userID uint, clientID string) (*models.Consent, error) {
+	ctx := context.Background()
+	consent := &models.Consent{TenantID: s.tenant}
+	var scopes []byte
+	err := s.db.QueryRowContext(ctx, "SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE tenant_id = ? AND user_id = ? AND client_id = ?", s.tenant, userID, clientID).
+		Scan(&consent.UserID, &consent.ClientID, &scopes, &consent.GrantedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrConsentNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &consent.Scopes); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ListConsents implements goat.OIDCStore.
+func (s *MySQLOIDCStore) ListConsents(userID uint) ([]*models.Consent, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE tenant_id = ? AND user_id = ? ORDER BY granted_at, client_id", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*models.Consent{}
+	for rows.Next() {
+		consent := &models.Consent{TenantID: s.tenant}
+		var scopes []byte
+		if err := rows.Scan(&consent.UserID, &consent.ClientID, &scopes, &consent.GrantedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(scopes, &consent.Scopes); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+// DeleteConsent implements goat.OIDCStore.
+func (s *MySQLOIDCStore) DeleteConsent(userID uint, clientID string) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM oauth_consents WHERE tenant_id = ? AND user_id = ? AND client_id = ?", s.tenant, userID, clientID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateGrant implements goat.OIDCStore.
+func (s *MySQLOIDCStore) CreateGrant(grant *models.Grant) error {
+	ctx := context.Background()
+	scopes, err := json.Marshal(grant.Scopes)
+	if err != nil {
+		return err
+	}
+	grant.TenantID = s.tenant
+	_, err = s.db.ExecContext(ctx, `INSERT INTO oauth_grants (id, tenant_id, type, client_id, user_id, scopes, redirect_uri, nonce, code_challenge, auth_time, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		grant.ID, grant.TenantID, grant.Type, grant.ClientID, grant.UserID, scopes, grant.RedirectURI, grant.Nonce, grant.CodeChallenge,
+		grant.AuthTime.UTC(), grant.CreatedAt.UTC(), grant.ExpiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TakeGrant implements goat.OIDCStore. Of two concurrent calls for the same grant, only the one
+// whose DELETE removes the row gets it.
+func (s *MySQLOIDCStore) TakeGrant(id string) (*models.Grant, error) {
+	ctx := context.Background()
+	grant := &models.Grant{TenantID: s.tenant}
+	var scopes []byte
+	err := s.db.QueryRowContext(ctx, `SELECT id, type, client_id, user_id, scopes, redirect_uri, nonce, code_challenge, auth_time, created_at, expires_at
+		FROM oauth_grants WHERE tenant_id = ? AND id = ?`, s.tenant, id).
+		Scan(&grant.ID, &grant.Type, &grant.ClientID, &grant.UserID, &scopes, &grant.RedirectURI, &grant.Nonce, &grant.CodeChallenge,
+			&grant.AuthTime, &grant.CreatedAt, &grant.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrGrantNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &grant.Scopes); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM oauth_grants WHERE tenant_id = ? AND id = ?", s.tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, goat.ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+// DeleteExpiredGrants implements goat.OIDCStore.
+func (s *MySQLOIDCStore) DeleteExpiredGrants(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM oauth_grants WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUserGrants implements goat.OIDCStore.
+func (s *MySQLOIDCStore) DeleteUserGrants(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM oauth_grants WHERE tenant_id = ? AND user_id = ?", s.tenant, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// marshalLists encodes string lists as JSON arrays.
+func marshalLists(a, b, c []string) ([]byte, []byte, []byte, error) {
+	var out [3][]byte
+	for i, list := range [][]string{a, b, c} {
+		if list == nil {
+			list = []string{}
+		}
+		data, err := json.Marshal(list)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		out[i] = data
+	}
+	return out[0], out[1], out[2], nil
+}
+
+// unmarshalLists decodes the JSON arrays in data into lists, in order.
+func unmarshalLists(data [][]byte, lists ...*[]string) error {
+	for i, list := range lists {
+		if err := json.Unmarshal(data[i], list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
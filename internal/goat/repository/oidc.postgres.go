@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLOIDCStore stores OpenID Connect provider state in the oauth_clients, oauth_consents and oauth_grants tables.
+// Consents and grants are limited to the store's tenant; see ForTenant.
+type PostgreSQLOIDCStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose consents and grants the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLOIDCStore initializes a new PostgreSQLOIDCStore with a given connection string and creates its tables.
+func NewPostgreSQLOIDCStore(connString string) (*PostgreSQLOIDCStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS oauth_clients (
+		client_id TEXT PRIMARY KEY,
+		secret_hash TEXT NOT NULL,
+		name TEXT NOT NULL,
+		public BOOLEAN NOT NULL,
+		redirect_uris TEXT[] NOT NULL,
+		grant_types TEXT[] NOT NULL,
+		scopes TEXT[] NOT NULL,
+		skip_consent BOOLEAN NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS oauth_consents (
+		tenant_id TEXT NOT NULL DEFAULT '',
+		user_id BIGINT NOT NULL,
+		client_id TEXT NOT NULL,
+		scopes TEXT[] NOT NULL,
+		granted_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (tenant_id, user_id, client_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS oauth_grants (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		type TEXT NOT NULL,
+		client_id TEXT NOT NULL,
+		user_id BIGINT NOT NULL,
+		scopes TEXT[] NOT NULL,
+		redirect_uri TEXT NOT NULL,
+		nonce TEXT NOT NULL,
+		code_challenge TEXT NOT NULL,
+		auth_time TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS oauth_grants_expires_at_idx ON oauth_grants (expires_at)")
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS oauth_grants_tenant_user_id_idx ON oauth_grants (tenant_id, user_id)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLOIDCStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) ForTenant(tenantID string) goat.OIDCStore {
+	return &PostgreSQLOIDCStore{conn: s.conn, tenant: tenantID}
+}
+
+// SaveClient implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) SaveClient(client *models.Client) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, `INSERT INTO oauth_clients (client_id, secret_hash, name, public, redirect_uris, grant_types, scopes, skip_consent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (client_id) DO UPDATE SET secret_hash = EXCLUDED.secret_hash, name = EXCLUDED.name, public = EXCLUDED.public,
+			redirect_uris = EXCLUDED.redirect_uris, grant_types = EXCLUDED.grant_types, scopes = EXCLUDED.scopes, skip_consent = EXCLUDED.skip_consent`,
+		client.ID, client.SecretHash, client.Name, client.Public, nonNil(client.RedirectURIs), nonNil(client.GrantTypes), nonNil(client.Scopes),
+		client.SkipConsent, client.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetClient implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) GetClient(id string) (*models.Client, error) {
+	ctx := context.Background()
+	client := &models.Client{}
+	err := s.conn.QueryRow(ctx, "SELECT client_id, secret_hash, name, public, redirect_uris, grant_types, scopes, skip_consent, created_at FROM oauth_clients WHERE client_id = $1", id).
+		Scan(&client.ID, &client.SecretHash, &client.Name, &client.Public, &client.RedirectURIs, &client.GrantTypes, &client.Scopes, &client.SkipConsent, &client.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// DeleteClient implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) DeleteClient(id string) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM oauth_clients WHERE client_id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrClientNotFound
+	}
+	return nil
+}
+
+// SaveConsent implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) SaveConsent(consent *models.Consent) error {
+	ctx := context.Background()
+	consent.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, `INSERT INTO oauth_consents (tenant_id, user_id, client_id, scopes, granted_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = EXCLUDED.granted_at`,
+		consent.TenantID, consent.UserID, consent.ClientID, nonNil(consent.Scopes), consent.GrantedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConsent implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) GetConsent(userID uint, clientID string) (*models.Consent, error) {
+	ctx := context.Background()
+	consent := &models.Consent{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE tenant_id = $1 AND user_id = $2 AND client_id = $3", s.tenant, userID, clientID).
+		Scan(&consent.UserID, &consent.ClientID, &consent.Scopes, &consent.GrantedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrConsentNotFound
+		}
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ListConsents implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) ListConsents(userID uint) ([]*models.Consent, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE tenant_id = $1 AND user_id = $2 ORDER BY granted_at, client_id", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*models.Consent{}
+	for rows.Next() {
+		consent := &models.Consent{TenantID: s.tenant}
+		if err := rows.Scan(&consent.UserID, &consent.ClientID, &consent.Scopes, &consent.GrantedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+// DeleteConsent implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) DeleteConsent(userID uint, clientID string) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM oauth_consents WHERE tenant_id = $1 AND user_id = $2 AND client_id = $3", s.tenant, userID, clientID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateGrant implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) CreateGrant(grant *models.Grant) error {
+	ctx := context.Background()
+	grant.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, `INSERT INTO oauth_grants (id, tenant_id, type, client_id, user_id, scopes, redirect_uri, nonce, code_challenge, auth_time, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		grant.ID, grant.TenantID, grant.Type, grant.ClientID, grant.UserID, nonNil(grant.Scopes), grant.RedirectURI, grant.Nonce, grant.CodeChallenge,
+		grant.AuthTime, grant.CreatedAt, grant.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TakeGrant implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) TakeGrant(id string) (*models.Grant, error) {
+	ctx := context.Background()
+	grant := &models.Grant{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, `DELETE FROM oauth_grants WHERE tenant_id = $1 AND id = $2
+		RETURNING id, type, client_id, user_id, scopes, redirect_uri, nonce, code_challenge, auth_time, created_at, expires_at`, s.tenant, id).
+		Scan(&grant.ID, &grant.Type, &grant.ClientID, &grant.UserID, &grant.Scopes, &grant.RedirectURI, &grant.Nonce, &grant.CodeChallenge,
+			&grant.AuthTime, &grant.CreatedAt, &grant.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrGrantNotFound
+		}
+		return nil, err
+	}
+	return grant, nil
+}
+
+// DeleteExpiredGrants implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) DeleteExpiredGrants(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM oauth_grants WHERE expires_at <= $1", now)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUserGrants implements goat.OIDCStore.
+func (s *PostgreSQLOIDCStore) DeleteUserGrants(userID uint) (int, error) {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM oauth_grants WHERE tenant_id = $1 AND user_id = $2", s.tenant, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/hasher"
+)
+
+// Option configures a user repository.
+type Option func(*config)
+
+// config holds the settings shared by the user repositories.
+type config struct {
+	hasher    goat.PasswordHasher // Hashes and verifies passwords.
+	outbox    bool                // Records lifecycle events in the outbox.
+	retention time.Duration       // How long soft-deleted users are kept.
+}
+
+// DefaultDeletedRetention is how long soft-deleted users are kept, unless set with WithDeletedRetention.
+const DefaultDeletedRetention = 30 * 24 * time.Hour
+
+// WithPasswordHasher sets the password hasher. It defaults to hasher.Default().
+func WithPasswordHasher(h goat.PasswordHasher) Option {
+	return func(c *config) {
+		c.hasher = h
+	}
+}
+
+// WithOutbox makes the repository record every Register, UpdateUser and DeleteUser, and every change
+// of account state, as a message in the outbox, in the same transaction as the change, for an
+// outbox.Relay to deliver. SQL repositories write to the outbox table; MongoDB ones to the outbox
+// collection of their database, which needs transactions and so a replica set or sharded cluster.
+func WithOutbox() Option {
+	return func(c *config) {
+		c.outbox = true
+	}
+}
+
+// WithDeletedRetention sets how long soft-deleted users are kept: until then they can be restored,
+// and afterwards PurgeDeletedUsers removes them. It defaults to DefaultDeletedRetention.
+func WithDeletedRetention(d time.Duration) Option {
+	return func(c *config) {
+		c.retention = d
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{hasher: hasher.Default(), retention: DefaultDeletedRetention}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// membershipKey identifies a membership in a MemoryOrganizationStore.
+type membershipKey struct {
+	orgID  string
+	userID uint
+}
+
+// memoryOrganizations are the organizations shared by a MemoryOrganizationStore and the stores
+// ForTenant returns.
+type memoryOrganizations struct {
+	mu            sync.RWMutex
+	organizations map[string]models.Organization
+	memberships   map[membershipKey]models.Membership
+	invitations   map[string]models.Invitation
+}
+
+// MemoryOrganizationStore keeps organizations, memberships and invitations in process memory. It is
+// meant for tests; everything is lost when the process exits.
+type MemoryOrganizationStore struct {
+	*memoryOrganizations
+	tenant string // Tenant whose organizations the store sees; empty for the default tenant.
+}
+
+// NewMemoryOrganizationStore creates an empty MemoryOrganizationStore.
+func NewMemoryOrganizationStore() *MemoryOrganizationStore {
+	return &MemoryOrganizationStore{memoryOrganizations: &memoryOrganizations{
+		organizations: map[string]models.Organization{},
+		memberships:   map[membershipKey]models.Membership{},
+		invitations:   map[string]models.Invitation{},
+	}}
+}
+
+// ForTenant implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) ForTenant(tenantID string) goat.OrganizationStore {
+	return &MemoryOrganizationStore{memoryOrganizations: s.memoryOrganizations, tenant: tenantID}
+}
+
+// CreateOrganization implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) CreateOrganization(org *models.Organization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org.TenantID = s.tenant
+	s.organizations[org.ID] = *org
+	return nil
+}
+
+// GetOrganization implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) GetOrganization(id string) (*models.Organization, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	org, ok := s.organizations[id]
+	if !ok || org.TenantID != s.tenant {
+		return nil, goat.ErrOrganizationNotFound
+	}
+	return &org, nil
+}
+
+// UpdateOrganization implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) UpdateOrganization(org *models.Organization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.organizations[org.ID]; !ok || stored.TenantID != s.tenant {
+		return goat.ErrOrganizationNotFound
+	}
+	org.TenantID = s.tenant
+	s.organizations[org.ID] = *org
+	return nil
+}
+
+// DeleteOrganization implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) DeleteOrganization(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if org, ok := s.organizations[id]; !ok || org.TenantID != s.tenant {
+		return goat.ErrOrganizationNotFound
+	}
+	delete(s.organizations, id)
+	for key := range s.memberships {
+		if key.orgID == id {
+			delete(s.memberships, key)
+		}
+	}
+	for key, invitation := range s.invitations {
+		if invitation.OrganizationID == id {
+			delete(s.invitations, key)
+		}
+	}
+	return nil
+}
+
+// TransferOwnership implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) TransferOwnership(orgID string, from, to uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	newOwner, ok := s.memberships[membershipKey{orgID, to}]
+	if !ok || newOwner.TenantID != s.tenant {
+		return goat.ErrMembershipNotFound
+	}
+	org, ok := s.organizations[orgID]
+	if !ok || org.TenantID != s.tenant || org.OwnerID != from {
+		return goat.ErrOrganizationNotFound
+	}
+	org.OwnerID = to
+	s.organizations[orgID] = org
+	newOwner.Role = models.RoleOwner
+	s.memberships[membershipKey{orgID, to}] = newOwner
+	if oldOwner, ok := s.memberships[membershipKey{orgID, from}]; ok {
+		oldOwner.Role = models.RoleAdmin
+		s.memberships[membershipKey{orgID, from}] = oldOwner
+	}
+	return nil
+}
+
+// ListOrganizations implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) ListOrganizations(userID uint) ([]*models.Organization, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	orgs := []*models.Organization{}
+	for key, membership := range s.memberships {
+		if key.userID != userID || membership.TenantID != s.tenant {
+			continue
+		}
+		if org, ok := s.organizations[key.orgID]; ok && org.TenantID == s.tenant {
+			orgs = append(orgs, &org)
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].CreatedAt.Before(orgs[j].CreatedAt)
+	})
+	return orgs, nil
+}
+
+// SaveMembership implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) SaveMembership(membership *models.Membership) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	membership.TenantID = s.tenant
+	s.memberships[membershipKey{membership.OrganizationID, membership.UserID}] = *membership
+	return nil
+}
+
+// GetMembership implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) GetMembership(orgID string, userID uint) (*models.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	membership, ok := s.memberships[membershipKey{orgID, userID}]
+	if !ok || membership.TenantID != s.tenant {
+		return nil, goat.ErrMembershipNotFound
+	}
+	return &membership, nil
+}
+
+// ListMembers implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) ListMembers(orgID string) ([]*models.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	members := []*models.Membership{}
+	for key, membership := range s.memberships {
+		if key.orgID == orgID && membership.TenantID == s.tenant {
+			membership := membership
+			members = append(members, &membership)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+	return members, nil
+}
+
+// DeleteMembership implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) DeleteMembership(orgID string, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := membershipKey{orgID, userID}
+	if membership, ok := s.memberships[key]; !ok || membership.TenantID != s.tenant {
+		return goat.ErrMembershipNotFound
+	}
+	delete(s.memberships, key)
+	return nil
+}
+
+// CreateInvitation implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) CreateInvitation(invitation *models.Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation.TenantID = s.tenant
+	s.invitations[invitation.ID] = *invitation
+	return nil
+}
+
+// GetInvitation implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) GetInvitation(id string) (*models.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invitation, ok := s.invitations[id]
+	if !ok || invitation.TenantID != s.tenant {
+		return nil, goat.ErrInvitationNotFound
+	}
+	return &invitation, nil
+}
+
+// ListInvitations implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) ListInvitations(orgID string) ([]*models.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invitations := []*models.Invitation{}
+	for _, invitation := range s.invitations {
+		if invitation.OrganizationID == orgID && invitation.TenantID == s.tenant {
+			invitation := invitation
+			invitations = append(invitations, &invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+	})
+	return invitations, nil
+}
+
+// DeleteInvitation implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) DeleteInvitation(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if invitation, ok := s.invitations[id]; !ok || invitation.TenantID != s.tenant {
+		return goat.ErrInvitationNotFound
+	}
+	delete(s.invitations, id)
+	return nil
+}
+
+// DeleteInvitationsByEmail implements goat.OrganizationStore.
+func (s *MemoryOrganizationStore) DeleteInvitationsByEmail(email string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, invitation := range s.invitations {
+		if invitation.Email == email && invitation.TenantID == s.tenant {
+			delete(s.invitations, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBOrganizationStore stores organizations in three MongoDB collections:
+// <prefix>_organizations, <prefix>_memberships and <prefix>_invitations.
+// DeleteOrganization and TransferOwnership change several documents in a transaction, which needs a
+// replica set or sharded cluster.
+// Every query is limited to the store's tenant; see ForTenant.
+type MongoDBOrganizationStore struct {
+	client        *mongo.Client     // MongoDB client, for transactions.
+	organizations *mongo.Collection // MongoDB collection for organization documents.
+	memberships   *mongo.Collection // MongoDB collection for membership documents.
+	invitations   *mongo.Collection // MongoDB collection for pending invitations.
+	tenant        string            // Tenant whose organizations the store sees; empty for the default tenant.
+}
+
+// NewMongoDBOrganizationStore initializes a new MongoDBOrganizationStore with a given MongoDB client, database name, and collection name prefix.
+// It ensures the unique indexes, and an index on tenant_id and user_id for listing a user's organizations.
+func NewMongoDBOrganizationStore(client *mongo.Client, dbName, prefix string) (*MongoDBOrganizationStore, error) {
+	ctx := context.Background()
+	db := client.Database(dbName)
+	s := &MongoDBOrganizationStore{
+		client:        client,
+		organizations: db.Collection(prefix + "_organizations"),
+		memberships:   db.Collection(prefix + "_memberships"),
+		invitations:   db.Collection(prefix + "_invitations"),
+	}
+
+	_, err := s.organizations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"id": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.memberships.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.invitations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"organization_id": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ForTenant implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) ForTenant(tenantID string) goat.OrganizationStore {
+	scoped := *s
+	scoped.tenant = tenantID
+	return &scoped
+}
+
+// CreateOrganization implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) CreateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	org.TenantID = s.tenant
+	_, err := s.organizations.InsertOne(ctx, org)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetOrganization implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) GetOrganization(id string) (*models.Organization, error) {
+	ctx := context.Background()
+	org := &models.Organization{}
+	err := s.organizations.FindOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}).Decode(org)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// UpdateOrganization implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) UpdateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	res, err := s.organizations.UpdateOne(ctx, bson.M{"tenant_id": s.tenant, "id": org.ID}, bson.M{"$set": bson.M{"name": org.Name, "owner_id": org.OwnerID}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// DeleteOrganization implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) DeleteOrganization(id string) error {
+	ctx := context.Background()
+	return s.transaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := s.organizations.DeleteOne(sc, bson.M{"tenant_id": s.tenant, "id": id})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return goat.ErrOrganizationNotFound
+		}
+		if _, err := s.memberships.DeleteMany(sc, bson.M{"organization_id": id}); err != nil {
+			return err
+		}
+		if _, err := s.invitations.DeleteMany(sc, bson.M{"organization_id": id}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// TransferOwnership implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) TransferOwnership(orgID string, from, to uint) error {
+	ctx := context.Background()
+	return s.transaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := s.memberships.UpdateOne(sc,
+			bson.M{"tenant_id": s.tenant, "organization_id": orgID, "user_id": to},
+			bson.M{"$set": bson.M{"role": models.RoleOwner}})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return goat.ErrMembershipNotFound
+		}
+		res, err = s.organizations.UpdateOne(sc,
+			bson.M{"tenant_id": s.tenant, "id": orgID, "owner_id": from},
+			bson.M{"$set": bson.M{"owner_id": to}})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return goat.ErrOrganizationNotFound
+		}
+		_, err = s.memberships.UpdateOne(sc,
+			bson.M{"tenant_id": s.tenant, "organization_id": orgID, "user_id": from},
+			bson.M{"$set": bson.M{"role": models.RoleAdmin}})
+		return err
+	})
+}
+
+// transaction runs fn in a transaction. The driver retries the transaction on transient errors, so
+// fn may run more than once.
+func (s *MongoDBOrganizationStore) transaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// ListOrganizations implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) ListOrganizations(userID uint) ([]*models.Organization, error) {
+	ctx := context.Background()
+	ids, err := s.memberships.Distinct(ctx, "organization_id", bson.M{"tenant_id": s.tenant, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	orgs := []*models.Organization{}
+	if len(ids) == 0 {
+		return orgs, nil
+	}
+	cursor, err := s.organizations.Find(ctx, bson.M{"tenant_id": s.tenant, "id": bson.M{"$in": ids}}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// SaveMembership implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) SaveMembership(membership *models.Membership) error {
+	ctx := context.Background()
+	membership.TenantID = s.tenant
+	_, err := s.memberships.ReplaceOne(ctx,
+		bson.M{"organization_id": membership.OrganizationID, "user_id": membership.UserID},
+		membership,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMembership implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) GetMembership(orgID string, userID uint) (*models.Membership, error) {
+	ctx := context.Background()
+	membership := &models.Membership{}
+	err := s.memberships.FindOne(ctx, bson.M{"tenant_id": s.tenant, "organization_id": orgID, "user_id": userID}).Decode(membership)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// ListMembers implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) ListMembers(orgID string) ([]*models.Membership, error) {
+	ctx := context.Background()
+	cursor, err := s.memberships.Find(ctx, bson.M{"tenant_id": s.tenant, "organization_id": orgID}, options.Find().SetSort(bson.M{"joined_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	members := []*models.Membership{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// DeleteMembership implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) DeleteMembership(orgID string, userID uint) error {
+	ctx := context.Background()
+	res, err := s.memberships.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "organization_id": orgID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrMembershipNotFound
+	}
+	return nil
+}
+
+// CreateInvitation implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) CreateInvitation(invitation *models.Invitation) error {
+	ctx := context.Background()
+	invitation.TenantID = s.tenant
+	_, err := s.invitations.InsertOne(ctx, invitation)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetInvitation implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) GetInvitation(id string) (*models.Invitation, error) {
+	ctx := context.Background()
+	invitation := &models.Invitation{}
+	err := s.invitations.FindOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}).Decode(invitation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) ListInvitations(orgID string) ([]*models.Invitation, error) {
+	ctx := context.Background()
+	cursor, err := s.invitations.Find(ctx, bson.M{"tenant_id": s.tenant, "organization_id": orgID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	invitations := []*models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// DeleteInvitation implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) DeleteInvitation(id string) error {
+	ctx := context.Background()
+	res, err := s.invitations.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// DeleteInvitationsByEmail implements goat.OrganizationStore.
+func (s *MongoDBOrganizationStore) DeleteInvitationsByEmail(email string) (int, error) {
+	ctx := context.Background()
+	res, err := s.invitations.DeleteMany(ctx, bson.M{"tenant_id": s.tenant, "email": email})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
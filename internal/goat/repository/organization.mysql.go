@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MySQLOrganizationStore stores organizations in the MySQL tables organizations,
+// organization_members and organization_invitations.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Every query is limited to the store's tenant; see ForTenant.
+type MySQLOrganizationStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose organizations the store sees; empty for the default tenant.
+}
+
+// NewMySQLOrganizationStore initializes a new MySQLOrganizationStore with a given DSN (Data Source Name) and creates its tables.
+func NewMySQLOrganizationStore(dsn string) (*MySQLOrganizationStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS organizations (
+		id VARCHAR(64) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		name VARCHAR(255) NOT NULL,
+		owner_id BIGINT UNSIGNED NOT NULL,
+		created_at DATETIME(6) NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS organization_members (
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		organization_id VARCHAR(64) NOT NULL,
+		user_id BIGINT UNSIGNED NOT NULL,
+		role VARCHAR(32) NOT NULL,
+		joined_at DATETIME(6) NOT NULL,
+		PRIMARY KEY (organization_id, user_id),
+		INDEX idx_organization_members_user_id (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS organization_invitations (
+		id CHAR(64) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		organization_id VARCHAR(64) NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		role VARCHAR(32) NOT NULL,
+		invited_by BIGINT UNSIGNED NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		expires_at DATETIME(6) NOT NULL,
+		INDEX idx_organization_invitations_organization_id (organization_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLOrganizationStore{db: db}, nil
+}
+
+// ForTenant implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) ForTenant(tenantID string) goat.OrganizationStore {
+	return &MySQLOrganizationStore{db: s.db, tenant: tenantID}
+}
+
+// CreateOrganization implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) CreateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	org.TenantID = s.tenant
+	_, err := s.db.ExecContext(ctx, "INSERT INTO organizations (id, tenant_id, name, owner_id, created_at) VALUES (?, ?, ?, ?, ?)",
+		org.ID, org.TenantID, org.Name, org.OwnerID, org.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetOrganization implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) GetOrganization(id string) (*models.Organization, error) {
+	ctx := context.Background()
+	org := &models.Organization{TenantID: s.tenant}
+	err := s.db.QueryRowContext(ctx, "SELECT id, name, owner_id, created_at FROM organizations WHERE tenant_id = ? AND id = ?", s.tenant, id).
+		Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// UpdateOrganization implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) UpdateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	// RowsAffected counts changed rows, which is zero for an update that changes nothing, so check
+	// that the organization exists separately.
+	if _, err := s.GetOrganization(org.ID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "UPDATE organizations SET name = ?, owner_id = ? WHERE tenant_id = ? AND id = ?", org.Name, org.OwnerID, s.tenant, org.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteOrganization implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) DeleteOrganization(id string) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM organizations WHERE tenant_id = ? AND id = ?", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM organization_members WHERE organization_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM organization_invitations WHERE organization_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TransferOwnership implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) TransferOwnership(orgID string, from, to uint) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRowContext(ctx, "SELECT role FROM organization_members WHERE tenant_id = ? AND organization_id = ? AND user_id = ? FOR UPDATE", s.tenant, orgID, to).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return goat.ErrMembershipNotFound
+		}
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE organizations SET owner_id = ? WHERE tenant_id = ? AND id = ? AND owner_id = ?", to, s.tenant, orgID, from)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE organization_members SET role = ? WHERE organization_id = ? AND user_id = ?", models.RoleOwner, orgID, to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE organization_members SET role = ? WHERE organization_id = ? AND user_id = ?", models.RoleAdmin, orgID, from)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListOrganizations implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) ListOrganizations(userID uint) ([]*models.Organization, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, `SELECT o.id, o.name, o.owner_id, o.created_at FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.tenant_id = ? AND m.user_id = ? AND o.tenant_id = ? ORDER BY o.created_at`, s.tenant, userID, s.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*models.Organization{}
+	for rows.Next() {
+		org := &models.Organization{TenantID: s.tenant}
+		if err := rows.Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// SaveMembership implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) SaveMembership(membership *models.Membership) error {
+	ctx := context.Background()
+	membership.TenantID = s.tenant
+	_, err := s.db.ExecContext(ctx, `INSERT INTO organization_members (tenant_id, organization_id, user_id, role, joined_at) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`,
+		membership.TenantID, membership.OrganizationID, membership.UserID, membership.Role, membership.JoinedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMembership implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) GetMembership(orgID string, userID uint) (*models.Membership, error) {
+	ctx := context.Background()
+	membership := &models.Membership{TenantID: s.tenant}
+	err := s.db.QueryRowContext(ctx, "SELECT organization_id, user_id, role, joined_at FROM organization_members WHERE tenant_id = ? AND organization_id = ? AND user_id = ?", s.tenant, orgID, userID).
+		Scan(&membership.OrganizationID, &membership.UserID, &membership.Role, &membership.JoinedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// ListMembers implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) ListMembers(orgID string) ([]*models.Membership, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT organization_id, user_id, role, joined_at FROM organization_members WHERE tenant_id = ? AND organization_id = ? ORDER BY joined_at", s.tenant, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.Membership{}
+	for rows.Next() {
+		membership := &models.Membership{TenantID: s.tenant}
+		if err := rows.Scan(&membership.OrganizationID, &membership.UserID, &membership.Role, &membership.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, membership)
+	}
+	return members, rows.Err()
+}
+
+// DeleteMembership implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) DeleteMembership(orgID string, userID uint) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM organization_members WHERE tenant_id = ? AND organization_id = ? AND user_id = ?", s.tenant, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrMembershipNotFound
+	}
+	return nil
+}
+
+// CreateInvitation implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) CreateInvitation(invitation *models.Invitation) error {
+	ctx := context.Background()
+	invitation.TenantID = s.tenant
+	_, err := s.db.ExecContext(ctx, "INSERT INTO organization_invitations (id, tenant_id, organization_id, email, role, invited_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		invitation.ID, invitation.TenantID, invitation.OrganizationID, invitation.Email, invitation.Role, invitation.InvitedBy, invitation.CreatedAt.UTC(), invitation.ExpiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetInvitation implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) GetInvitation(id string) (*models.Invitation, error) {
+	ctx := context.Background()
+	invitation := &models.Invitation{TenantID: s.tenant}
+	err := s.db.QueryRowContext(ctx, "SELECT id, organization_id, email, role, invited_by, created_at, expires_at FROM organization_invitations WHERE tenant_id = ? AND id = ?", s.tenant, id).
+		Scan(&invitation.ID, &invitation.OrganizationID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) ListInvitations(orgID string) ([]*models.Invitation, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, organization_id, email, role, invited_by, created_at, expires_at FROM organization_invitations WHERE tenant_id = ? AND organization_id = ? ORDER BY created_at", s.tenant, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*models.Invitation{}
+	for rows.Next() {
+		invitation := &models.Invitation{TenantID: s.tenant}
+		if err := rows.Scan(&invitation.ID, &invitation.OrganizationID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) DeleteInvitation(id string) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM organization_invitations WHERE tenant_id = ? AND id = ?", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// DeleteInvitationsByEmail implements goat.OrganizationStore.
+func (s *MySQLOrganizationStore) DeleteInvitationsByEmail(email string) (int, error) {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM organization_invitations WHERE tenant_id = ? AND email = ?", s.tenant, email)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLOrganizationStore stores organizations in the PostgreSQL tables organizations,
+// organization_members and organization_invitations.
+// Every query is limited to the store's tenant; see ForTenant.
+type PostgreSQLOrganizationStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose organizations the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLOrganizationStore initializes a new PostgreSQLOrganizationStore with a given connection string and creates its tables.
+func NewPostgreSQLOrganizationStore(connString string) (*PostgreSQLOrganizationStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS organizations (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		owner_id BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS organization_members (
+		tenant_id TEXT NOT NULL DEFAULT '',
+		organization_id TEXT NOT NULL,
+		user_id BIGINT NOT NULL,
+		role TEXT NOT NULL,
+		joined_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (organization_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS organization_invitations (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		organization_id TEXT NOT NULL,
+		email TEXT NOT NULL,
+		role TEXT NOT NULL,
+		invited_by BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS organization_members_tenant_user_id_idx ON organization_members (tenant_id, user_id)")
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS organization_invitations_organization_id_idx ON organization_invitations (organization_id)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLOrganizationStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) ForTenant(tenantID string) goat.OrganizationStore {
+	return &PostgreSQLOrganizationStore{conn: s.conn, tenant: tenantID}
+}
+
+// CreateOrganization implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) CreateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	org.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, "INSERT INTO organizations (id, tenant_id, name, owner_id, created_at) VALUES ($1, $2, $3, $4, $5)",
+		org.ID, org.TenantID, org.Name, org.OwnerID, org.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetOrganization implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) GetOrganization(id string) (*models.Organization, error) {
+	ctx := context.Background()
+	org := &models.Organization{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT id, name, owner_id, created_at FROM organizations WHERE tenant_id = $1 AND id = $2", s.tenant, id).
+		Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// UpdateOrganization implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) UpdateOrganization(org *models.Organization) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "UPDATE organizations SET name = $1, owner_id = $2 WHERE tenant_id = $3 AND id = $4", org.Name, org.OwnerID, s.tenant, org.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// DeleteOrganization implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) DeleteOrganization(id string) error {
+	ctx := context.Background()
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM organizations WHERE tenant_id = $1 AND id = $2", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM organization_members WHERE organization_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM organization_invitations WHERE organization_id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// TransferOwnership implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) TransferOwnership(orgID string, from, to uint) error {
+	ctx := context.Background()
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, "SELECT role FROM organization_members WHERE tenant_id = $1 AND organization_id = $2 AND user_id = $3 FOR UPDATE", s.tenant, orgID, to).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return goat.ErrMembershipNotFound
+		}
+		return err
+	}
+	tag, err := tx.Exec(ctx, "UPDATE organizations SET owner_id = $1 WHERE tenant_id = $2 AND id = $3 AND owner_id = $4", to, s.tenant, orgID, from)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrOrganizationNotFound
+	}
+	_, err = tx.Exec(ctx, "UPDATE organization_members SET role = $1 WHERE organization_id = $2 AND user_id = $3", models.RoleOwner, orgID, to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE organization_members SET role = $1 WHERE organization_id = $2 AND user_id = $3", models.RoleAdmin, orgID, from)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListOrganizations implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) ListOrganizations(userID uint) ([]*models.Organization, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, `SELECT o.id, o.name, o.owner_id, o.created_at FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.tenant_id = $1 AND m.user_id = $2 AND o.tenant_id = $1 ORDER BY o.created_at`, s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*models.Organization{}
+	for rows.Next() {
+		org := &models.Organization{TenantID: s.tenant}
+		if err := rows.Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// SaveMembership implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) SaveMembership(membership *models.Membership) error {
+	ctx := context.Background()
+	membership.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, `INSERT INTO organization_members (tenant_id, organization_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		membership.TenantID, membership.OrganizationID, membership.UserID, membership.Role, membership.JoinedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMembership implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) GetMembership(orgID string, userID uint) (*models.Membership, error) {
+	ctx := context.Background()
+	membership := &models.Membership{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT organization_id, user_id, role, joined_at FROM organization_members WHERE tenant_id = $1 AND organization_id = $2 AND user_id = $3", s.tenant, orgID, userID).
+		Scan(&membership.OrganizationID, &membership.UserID, &membership.Role, &membership.JoinedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// ListMembers implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) ListMembers(orgID string) ([]*models.Membership, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT organization_id, user_id, role, joined_at FROM organization_members WHERE tenant_id = $1 AND organization_id = $2 ORDER BY joined_at", s.tenant, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.Membership{}
+	for rows.Next() {
+		membership := &models.Membership{TenantID: s.tenant}
+		if err := rows.Scan(&membership.OrganizationID, &membership.UserID, &membership.Role, &membership.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, membership)
+	}
+	return members, rows.Err()
+}
+
+// DeleteMembership implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) DeleteMembership(orgID string, userID uint) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM organization_members WHERE tenant_id = $1 AND organization_id = $2 AND user_id = $3", s.tenant, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrMembershipNotFound
+	}
+	return nil
+}
+
+// CreateInvitation implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) CreateInvitation(invitation *models.Invitation) error {
+	ctx := context.Background()
+	invitation.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, "INSERT INTO organization_invitations (id, tenant_id, organization_id, email, role, invited_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		invitation.ID, invitation.TenantID, invitation.OrganizationID, invitation.Email, invitation.Role, invitation.InvitedBy, invitation.CreatedAt, invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetInvitation implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) GetInvitation(id string) (*models.Invitation, error) {
+	ctx := context.Background()
+	invitation := &models.Invitation{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT id, organization_id, email, role, invited_by, created_at, expires_at FROM organization_invitations WHERE tenant_id = $1 AND id = $2", s.tenant, id).
+		Scan(&invitation.ID, &invitation.OrganizationID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) ListInvitations(orgID string) ([]*models.Invitation, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT id, organization_id, email, role, invited_by, created_at, expires_at FROM organization_invitations WHERE tenant_id = $1 AND organization_id = $2 ORDER BY created_at", s.tenant, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*models.Invitation{}
+	for rows.Next() {
+		invitation := &models.Invitation{TenantID: s.tenant}
+		if err := rows.Scan(&invitation.ID, &invitation.OrganizationID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) DeleteInvitation(id string) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM organization_invitations WHERE tenant_id = $1 AND id = $2", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// DeleteInvitationsByEmail implements goat.OrganizationStore.
+func (s *PostgreSQLOrganizationStore) DeleteInvitationsByEmail(email string) (int, error) {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM organization_invitations WHERE tenant_id = $1 AND email = $2", s.tenant, email)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
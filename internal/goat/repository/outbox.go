@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// newOutboxMessage returns the pending outbox message recording an event of type typ on user.
+func newOutboxMessage[U models.Account](typ models.UserEventType, user U) (*models.OutboxMessage, error) {
+	u := user.GetUser()
+
+	// The password hash never leaves the users table.
+	password := u.Password
+	u.Password = ""
+	payload, err := json.Marshal(user)
+	u.Password = password
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	return &models.OutboxMessage{
+		ID:            fmt.Sprintf("%016x%016x", uint64(now.UnixNano()), binary.BigEndian.Uint64(b[:])),
+		Type:          typ,
+		TenantID:      u.TenantID,
+		UserID:        u.ID,
+		Payload:       payload,
+		CreatedAt:     now,
+		Status:        models.OutboxPending,
+		NextAttemptAt: now,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MemoryOutboxStore keeps outbox messages in process memory. It is meant for tests; messages are
+// lost when the process exits.
+type MemoryOutboxStore struct {
+	mu       sync.Mutex
+	messages map[string]models.OutboxMessage
+}
+
+// NewMemoryOutboxStore creates an empty MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{messages: map[string]models.OutboxMessage{}}
+}
+
+// AppendOutboxMessage implements goat.OutboxStore.
+func (s *MemoryOutboxStore) AppendOutboxMessage(message *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[message.ID] = *message
+	return nil
+}
+
+// ClaimOutboxMessages implements goat.OutboxStore.
+func (s *MemoryOutboxStore) ClaimOutboxMessages(now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := s.list(func(m *models.OutboxMessage) bool {
+		return m.Status == models.OutboxPending && !m.NextAttemptAt.After(now)
+	}, limit)
+	for _, message := range due {
+		message.NextAttemptAt = now.Add(lease)
+		s.messages[message.ID] = *message
+	}
+	return due, nil
+}
+
+// GetOutboxMessage implements goat.OutboxStore.
+func (s *MemoryOutboxStore) GetOutboxMessage(id string) (*models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message, ok := s.messages[id]
+	if !ok {
+		return nil, goat.ErrOutboxMessageNotFound
+	}
+	return &message, nil
+}
+
+// UpdateOutboxMessage implements goat.OutboxStore.
+func (s *MemoryOutboxStore) UpdateOutboxMessage(message *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.messages[message.ID]; !ok {
+		return goat.ErrOutboxMessageNotFound
+	}
+	s.messages[message.ID] = *message
+	return nil
+}
+
+// DeleteOutboxMessage implements goat.OutboxStore.
+func (s *MemoryOutboxStore) DeleteOutboxMessage(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, id)
+	return nil
+}
+
+// ListDeadOutboxMessages implements goat.OutboxStore.
+func (s *MemoryOutboxStore) ListDeadOutboxMessages(limit int) ([]*models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(func(m *models.OutboxMessage) bool { return m.Status == models.OutboxDead }, limit), nil
+}
+
+// list returns copies of up to limit messages matching match, oldest first. The caller holds s.mu.
+func (s *MemoryOutboxStore) list(match func(*models.OutboxMessage) bool, limit int) []*models.OutboxMessage {
+	messages := []*models.OutboxMessage{}
+	for _, message := range s.messages {
+		if match(&message) {
+			message := message
+			messages = append(messages, &message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+// DeleteUserOutboxMessages implements goat.OutboxStore.
+func (s *MemoryOutboxStore) DeleteUserOutboxMessages(userID uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, message := range s.messages {
+		if message.UserID == userID {
+			delete(s.messages, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoOutboxCollection is the collection, in the users' database, that MongoDB user repositories
+// created WithOutbox write to.
+const mongoOutboxCollection = "outbox"
+
+// MongoDBOutboxStore reads the outbox collection written by MongoDB user repositories created
+// WithOutbox. Several relays can share it: each message is claimed with an atomic update.
+type MongoDBOutboxStore struct {
+	collection *mongo.Collection // MongoDB collection for outbox messages.
+}
+
+// NewMongoDBOutboxStore initializes a new MongoDBOutboxStore for the outbox collection of database dbName.
+// It ensures a unique index on the message ID and an index for finding due messages.
+func NewMongoDBOutboxStore(client *mongo.Client, dbName string) (*MongoDBOutboxStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(mongoOutboxCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBOutboxStore{collection: collection}, nil
+}
+
+// AppendOutboxMessage implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) AppendOutboxMessage(message *models.OutboxMessage) error {
+	ctx := context.Background()
+	_, err := s.collection.InsertOne(ctx, message)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClaimOutboxMessages implements goat.OutboxStore. Messages are claimed one at a time, each with
+// an atomic update, so that concurrent relays never take the same one.
+func (s *MongoDBOutboxStore) ClaimOutboxMessages(now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	filter := bson.M{"status": models.OutboxPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	messages := []*models.OutboxMessage{}
+	for len(messages) < limit {
+		message := &models.OutboxMessage{}
+		err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(message)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// GetOutboxMessage implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) GetOutboxMessage(id string) (*models.OutboxMessage, error) {
+	ctx := context.Background()
+	message := &models.OutboxMessage{}
+	err := s.collection.FindOne(ctx, bson.M{"id": id}).Decode(message)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrOutboxMessageNotFound
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+// UpdateOutboxMessage implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) UpdateOutboxMessage(message *models.OutboxMessage) error {
+	ctx := context.Background()
+	res, err := s.collection.UpdateOne(ctx, bson.M{"id": message.ID}, bson.M{"$set": bson.M{
+		"status":          message.Status,
+		"attempts":        message.Attempts,
+		"next_attempt_at": message.NextAttemptAt,
+		"last_error":      message.LastError,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrOutboxMessageNotFound
+	}
+	return nil
+}
+
+// DeleteOutboxMessage implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) DeleteOutboxMessage(id string) error {
+	ctx := context.Background()
+	_, err := s.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListDeadOutboxMessages implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) ListDeadOutboxMessages(limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"id": 1}).SetLimit(int64(limit))
+	cursor, err := s.collection.Find(ctx, bson.M{"status": models.OutboxDead}, opts)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*models.OutboxMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// DeleteUserOutboxMessages implements goat.OutboxStore.
+func (s *MongoDBOutboxStore) DeleteUserOutboxMessages(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// mysqlOutboxTable creates the outbox table, for both MySQLOutboxStore and the user repositories
+// that write to it.
+const mysqlOutboxTable = `CREATE TABLE IF NOT EXISTS outbox (
+	id CHAR(32) PRIMARY KEY,
+	type VARCHAR(64) NOT NULL,
+	tenant_id VARCHAR(64) NOT NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	payload JSON NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt_at DATETIME(6) NOT NULL,
+	last_error TEXT NOT NULL,
+	INDEX idx_outbox_status_next_attempt_at (status, next_attempt_at)
+)`
+
+// mysqlExecer is satisfied by *sql.DB and *sql.Tx.
+type mysqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertMySQLOutboxMessage saves message with db, which is usually the transaction of a user change.
+func insertMySQLOutboxMessage(ctx context.Context, db mysqlExecer, message *models.OutboxMessage) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO outbox ("+outboxColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		message.ID, string(message.Type), message.TenantID, message.UserID, []byte(message.Payload), message.CreatedAt.UTC(),
+		message.Status, message.Attempts, message.NextAttemptAt.UTC(), message.LastError)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MySQLOutboxStore reads the outbox table written by MySQL user repositories created WithOutbox.
+// Several relays can share it: claiming uses SKIP LOCKED, which needs MySQL 8.0 or later.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+type MySQLOutboxStore struct {
+	db *sql.DB // MySQL DB connection.
+}
+
+// NewMySQLOutboxStore initializes a new MySQLOutboxStore with a given DSN (Data Source Name) and creates the outbox table.
+func NewMySQLOutboxStore(dsn string) (*MySQLOutboxStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(mysqlOutboxTable); err != nil {
+		return nil, err
+	}
+	return &MySQLOutboxStore{db: db}, nil
+}
+
+// AppendOutboxMessage implements goat.OutboxStore.
+func (s *MySQLOutboxStore) AppendOutboxMessage(message *models.OutboxMessage) error {
+	return insertMySQLOutboxMessage(context.Background(), s.db, message)
+}
+
+// ClaimOutboxMessages implements goat.OutboxStore.
+func (s *MySQLOutboxStore) ClaimOutboxMessages(now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE SKIP LOCKED",
+		models.OutboxPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*models.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	leaseEnd := now.Add(lease)
+	args := []interface{}{leaseEnd.UTC()}
+	for _, message := range messages {
+		message.NextAttemptAt = leaseEnd
+		args = append(args, message.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(messages)), ", ")
+	if _, err := tx.ExecContext(ctx, "UPDATE outbox SET next_attempt_at = ? WHERE id IN ("+placeholders+")", args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetOutboxMessage implements goat.OutboxStore.
+func (s *MySQLOutboxStore) GetOutboxMessage(id string) (*models.OutboxMessage, error) {
+	ctx := context.Background()
+	message, err := scanOutboxMessage(s.db.QueryRowContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrOutboxMessageNotFound
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+// UpdateOutboxMessage implements goat.OutboxStore.
+func (s *MySQLOutboxStore) UpdateOutboxMessage(message *models.OutboxMessage) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "UPDATE outbox SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		message.Status, message.Attempts, message.NextAttemptAt.UTC(), message.LastError, message.ID)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only changed rows, so look the message up before reporting it missing.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.GetOutboxMessage(message.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteOutboxMessage implements goat.OutboxStore.
+func (s *MySQLOutboxStore) DeleteOutboxMessage(id string) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListDeadOutboxMessages implements goat.OutboxStore.
+func (s *MySQLOutboxStore) ListDeadOutboxMessages(limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE status = ? ORDER BY id LIMIT ?", models.OutboxDead, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// DeleteUserOutboxMessages implements goat.OutboxStore.
+func (s *MySQLOutboxStore) DeleteUserOutboxMessages(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM outbox WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// postgresOutboxTable creates the outbox table and its index, for both PostgreSQLOutboxStore and
+// the user repositories that write to it.
+var postgresOutboxTable = []string{
+	`CREATE TABLE IF NOT EXISTS outbox (
+		id TEXT COLLATE "C" PRIMARY KEY,
+		type TEXT NOT NULL,
+		tenant_id TEXT NOT NULL,
+		user_id BIGINT NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		last_error TEXT NOT NULL
+	)`,
+	"CREATE INDEX IF NOT EXISTS outbox_status_next_attempt_at_idx ON outbox (status, next_attempt_at)",
+}
+
+// pgExecer is satisfied by *pgx.Conn, *pgxpool.Pool and pgx.Tx.
+type pgExecer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// createPostgresOutbox creates the outbox table on conn.
+func createPostgresOutbox(ctx context.Context, conn pgExecer) error {
+	for _, stmt := range postgresOutboxTable {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertPostgresOutboxMessage saves message with db, which is usually the transaction of a user change.
+func insertPostgresOutboxMessage(ctx context.Context, db pgExecer, message *models.OutboxMessage) error {
+	_, err := db.Exec(ctx, "INSERT INTO outbox ("+outboxColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		message.ID, string(message.Type), message.TenantID, message.UserID, []byte(message.Payload), message.CreatedAt,
+		message.Status, message.Attempts, message.NextAttemptAt, message.LastError)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// PostgreSQLOutboxStore reads the outbox table written by PostgreSQL user repositories created
+// WithOutbox. Several relays can share it: claiming uses SKIP LOCKED.
+type PostgreSQLOutboxStore struct {
+	conn *pgx.Conn // PostgreSQL connection for database access.
+}
+
+// NewPostgreSQLOutboxStore initializes a new PostgreSQLOutboxStore with a given connection string and creates the outbox table.
+func NewPostgreSQLOutboxStore(connString string) (*PostgreSQLOutboxStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+	if err := createPostgresOutbox(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &PostgreSQLOutboxStore{conn: conn}, nil
+}
+
+// AppendOutboxMessage implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) AppendOutboxMessage(message *models.OutboxMessage) error {
+	return insertPostgresOutboxMessage(context.Background(), s.conn, message)
+}
+
+// ClaimOutboxMessages implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) ClaimOutboxMessages(now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, `UPDATE outbox SET next_attempt_at = $1 WHERE id IN (
+		SELECT id FROM outbox WHERE status = $2 AND next_attempt_at <= $3 ORDER BY next_attempt_at, id LIMIT $4 FOR UPDATE SKIP LOCKED
+	) RETURNING `+outboxColumns, now.Add(lease), models.OutboxPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING has no order of its own.
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	return messages, nil
+}
+
+// GetOutboxMessage implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) GetOutboxMessage(id string) (*models.OutboxMessage, error) {
+	ctx := context.Background()
+	message, err := scanOutboxMessage(s.conn.QueryRow(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrOutboxMessageNotFound
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+// UpdateOutboxMessage implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) UpdateOutboxMessage(message *models.OutboxMessage) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "UPDATE outbox SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $5",
+		message.Status, message.Attempts, message.NextAttemptAt, message.LastError, message.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrOutboxMessageNotFound
+	}
+	return nil
+}
+
+// DeleteOutboxMessage implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) DeleteOutboxMessage(id string) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM outbox WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListDeadOutboxMessages implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) ListDeadOutboxMessages(limit int) ([]*models.OutboxMessage, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE status = $1 ORDER BY id LIMIT $2", models.OutboxDead, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// DeleteUserOutboxMessages implements goat.OutboxStore.
+func (s *PostgreSQLOutboxStore) DeleteUserOutboxMessages(userID uint) (int, error) {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM outbox WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -2,118 +2,513 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/bontusss/goat/internal/goat"
 	"github.com/bontusss/goat/internal/goat/models"
-	"github.com/google/uuid"
+	"github.com/bontusss/goat/internal/goat/utils"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // PostgreSQLUserRepository is a struct for PostgreSQL operations, encapsulating connection information.
-type PostgreSQLUserRepository struct {
-	conn *pgx.Conn // PostgreSQL connection for database access.
+// It is safe for concurrent use: queries and transactions each take a connection from a pool.
+// Every query is limited to the repository's tenant; see ForTenant.
+type PostgreSQLUserRepository[U models.Account] struct {
+	conn   *pgxpool.Pool // PostgreSQL connection pool for database access.
+	tenant string        // Tenant whose users the repository sees; empty for the default tenant.
+	config               // Password hashing and other shared settings.
 }
 
-// NewPostgreSQLUserRepository initializes a new PostgreSQLUserRepository with a given connection string.
-func NewPostgreSQLUserRepository(connString string) (*PostgreSQLUserRepository, error) {
+// NewPostgreSQLUserRepository initializes a new PostgreSQLUserRepository with a given connection string,
+// which can set the pool's size with pool_max_conns. The repository serves the default tenant; use
+// ForTenant for the others. Databases with users stored before emails were normalized need
+// NormalizeStoredEmails run once.
+func NewPostgreSQLUserRepository[U models.Account](connString string, opts ...Option) (*PostgreSQLUserRepository[U], error) {
 	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, connString)
+	conn, err := pgxpool.Connect(ctx, connString)
 	if err != nil {
 		return nil, err
 	}
 
-	// Ensure that an index on the email field is created to enforce uniqueness.
-	_, err = conn.Exec(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email)")
+	// Create the users table; custom fields are stored as a JSONB document.
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS users (
+		id BIGINT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		email TEXT NOT NULL,
+		password TEXT NOT NULL,
+		custom_fields JSONB
+	)`)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PostgreSQLUserRepository{conn: conn}, nil
+	// Tables created before custom fields existed get the column, NULL for the users already stored.
+	// Emails are unique among the users of a tenant that are not soft-deleted, so the same address
+	// can be registered with several tenants, and again once its user is deleted. Tables created
+	// before tenants existed get the column and lose their email-only index; tables created before
+	// account states existed get the status columns, and their users become active. The role and
+	// created_at columns, and the indexes ListUsers pages with, came later still; users stored before
+	// them are taken to be created at the time of the upgrade.
+	for _, stmt := range []string{
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS custom_fields JSONB",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT ''",
+		"DROP INDEX IF EXISTS users_email_idx",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ",
+		"CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_live_email_idx ON users (tenant_id, email) WHERE status <> 'deleted'",
+		"CREATE INDEX IF NOT EXISTS users_tenant_email_all_idx ON users (tenant_id, email)",
+		"CREATE INDEX IF NOT EXISTS users_status_deleted_at_idx ON users (status, deleted_at)",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now()",
+		"CREATE INDEX IF NOT EXISTS users_tenant_created_at_idx ON users (tenant_id, created_at, id)",
+		`CREATE INDEX IF NOT EXISTS users_tenant_email_c_idx ON users (tenant_id, (email COLLATE "C"), id)`,
+	} {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := newConfig(opts)
+	if cfg.outbox {
+		if err := createPostgresOutbox(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PostgreSQLUserRepository[U]{conn: conn, config: cfg}, nil
+}
+
+// ForTenant returns a repository that sees only the users of tenant. Users registered through it
+// belong to tenant, whatever their TenantID field says.
+func (r *PostgreSQLUserRepository[U]) ForTenant(tenant string) *PostgreSQLUserRepository[U] {
+	scoped := *r
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// Tenant returns the tenant whose users the repository sees; empty for the default tenant.
+func (r *PostgreSQLUserRepository[U]) Tenant() string {
+	return r.tenant
 }
 
 // Register adds a new user to the PostgreSQL database. It hashes the user's password before saving.
-func (r *PostgreSQLUserRepository) Register(user *models.User) error {
+func (r *PostgreSQLUserRepository[U]) Register(user U) error {
 	ctx := context.Background()
-	user.ID = uint(uuid.New().ID()) // Generate a unique ID for the user.
+	u := user.GetUser()
+	u.TenantID = r.tenant
+	u.Email = utils.NormalizeEmail(u.Email)
+	u.Status = models.AccountActive
+	u.CreatedAt = storedNow()
+	u.DeletedAt = nil
 
-	// Hash the user's password for secure storage.
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+	// Hash the user's password for secure storage. An unusable password set by SetUnusablePassword
+	// matches nothing and is stored as is; any other value is hashed, whatever it looks like.
+	if !u.WantsUnusablePassword() {
+		hashedPassword, err := r.hasher.Hash(u.Password)
+		if err != nil {
+			return err
+		}
+		u.Password = hashedPassword
 	}
-	user.Password = string(hashedPassword)
 
-	// Insert the new user into the database.
-	_, err = r.conn.Exec(ctx, "INSERT INTO users (id, email, password) VALUES ($1, $2, $3)", user.ID, user.Email, user.Password)
+	customFields, err := models.MarshalCustomFields(user)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// Insert the new user into the database, under a random ID that no other user has.
+	return withNewID(u, func() error {
+		return r.change(ctx, models.UserRegistered, func(db pgExecer) (U, error) {
+			_, err := db.Exec(ctx, "INSERT INTO users (id, tenant_id, email, password, custom_fields, role, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+				u.ID, u.TenantID, u.Email, u.Password, customFields, u.Role, u.Status, u.CreatedAt)
+			if err != nil {
+				return user, postgresUserError(err)
+			}
+			return user, nil
+		})
+	})
 }
 
 // Login checks a user's credentials against the stored values in the PostgreSQL database.
 // If the credentials are valid, it returns the user object; otherwise, it returns an error.
-func (r *PostgreSQLUserRepository) Login(email, password string) (*models.User, error) {
+func (r *PostgreSQLUserRepository[U]) Login(email, password string) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
+	email = utils.NormalizeEmail(email)
+	var zero U
 
 	// Attempt to find the user by email.
-	err := r.conn.QueryRow(ctx, "SELECT id, email, password FROM users WHERE email = $1", email).Scan(&user.ID, &user.Email, &user.Password)
+	user, err := scanUser[U](r.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 AND email = $2 AND status <> $3", r.tenant, email, models.AccountDeleted))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			// If no row is found, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+			return zero, goat.ErrInvalidCredentials
 		}
-		return nil, err
+		return zero, err
 	}
 
 	// Verify the password against the hashed password stored in the database.
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	u := user.GetUser()
+	if !u.HasUsablePassword() {
+		// The account only signs in through linked identities.
+		return zero, goat.ErrInvalidCredentials
+	}
+	ok, err := r.hasher.Verify(password, u.Password)
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			// If the password does not match, return an invalid credentials error.
-			return nil, goat.ErrInvalidCredentials
+		return zero, err
+	}
+	if !ok {
+		// If the password does not match, return an invalid credentials error.
+		return zero, goat.ErrInvalidCredentials
+	}
+	// Only tell the owner of the password why an inactive account cannot sign in.
+	if err := goat.AccountError(u); err != nil {
+		return zero, err
+	}
+
+	// Upgrade the stored hash if it uses an outdated algorithm or cost. This is best effort:
+	// the credentials are already verified, and a failed rehash is retried on the next login.
+	if r.hasher.NeedsRehash(u.Password) {
+		if hashed, err := r.hasher.Hash(password); err == nil {
+			if _, err := r.conn.Exec(ctx, "UPDATE users SET password = $1 WHERE tenant_id = $2 AND id = $3", hashed, r.tenant, u.ID); err == nil {
+				u.Password = hashed
+			}
 		}
-		return nil, err
 	}
 	return user, nil
 }
 
-func (r *PostgreSQLUserRepository) DeleteUser(id uint) error {
+// DeleteUser soft-deletes a user: the row is kept, hidden from everything but GetDeletedUser,
+// RestoreUser and PurgeDeletedUsers.
+func (r *PostgreSQLUserRepository[U]) DeleteUser(id uint) error {
 	ctx := context.Background()
-	_, err := r.conn.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	return r.change(ctx, models.UserDeleted, func(db pgExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRow(ctx, "UPDATE users SET status = $1, deleted_at = $2 WHERE tenant_id = $3 AND id = $4 AND status <> $1 RETURNING "+userColumns,
+			models.AccountDeleted, storedNow(), r.tenant, id))
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		return user, nil
+	})
+}
+
+// SetUserStatus makes a user that is not deleted active, suspended or deactivated.
+func (r *PostgreSQLUserRepository[U]) SetUserStatus(id uint, status string) error {
+	ctx := context.Background()
+	typ, err := statusEvent(status)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.change(ctx, typ, func(db pgExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRow(ctx, "UPDATE users SET status = $1 WHERE tenant_id = $2 AND id = $3 AND status <> $4 RETURNING "+userColumns,
+			status, r.tenant, id, models.AccountDeleted))
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		return user, nil
+	})
+}
+
+// GetDeletedUser returns a soft-deleted user.
+func (r *PostgreSQLUserRepository[U]) GetDeletedUser(id uint) (U, error) {
+	ctx := context.Background()
+	user, err := scanUser[U](r.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 AND id = $2 AND status = $3", r.tenant, id, models.AccountDeleted))
+	if err != nil {
+		var zero U
+		return zero, postgresUserError(err)
+	}
+	return user, nil
+}
+
+// RestoreUser makes a soft-deleted user active again, if they were deleted within the retention period.
+func (r *PostgreSQLUserRepository[U]) RestoreUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserRestored, func(db pgExecer) (U, error) {
+		user, err := scanUser[U](db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 AND id = $2 FOR UPDATE", r.tenant, id))
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		u := user.GetUser()
+		if err := restorable(u, time.Now(), r.retention); err != nil {
+			return user, err
+		}
+		_, err = db.Exec(ctx, "UPDATE users SET status = $1, deleted_at = NULL WHERE tenant_id = $2 AND id = $3", models.AccountActive, r.tenant, id)
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		u.Status = models.AccountActive
+		u.DeletedAt = nil
+		return user, nil
+	})
+}
+
+// PurgeDeletedUsers removes the users of every tenant that were soft-deleted longer ago than the
+// retention period, and returns how many it removed. Unless purge is nil, it is called for each user
+// first, to remove the records other stores hold about them; the first error it returns stops the
+// purge, leaving that user and the ones after them for the next run.
+func (r *PostgreSQLUserRepository[U]) PurgeDeletedUsers(purge func(tenantID string, id uint) error) (int, error) {
+	ctx := context.Background()
+	cutoff := time.Now().Add(-r.retention)
+	purged := 0
+	var after uint
+	for {
+		rows, err := r.conn.Query(ctx, "SELECT tenant_id, id FROM users WHERE status = $1 AND deleted_at < $2 AND id > $3 ORDER BY id LIMIT $4",
+			models.AccountDeleted, cutoff, after, purgeBatch)
+		if err != nil {
+			return purged, err
+		}
+		var batch []models.User
+		for rows.Next() {
+			var u models.User
+			if err := rows.Scan(&u.TenantID, &u.ID); err != nil {
+				rows.Close()
+				return purged, err
+			}
+			batch = append(batch, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return purged, err
+		}
+
+		for _, u := range batch {
+			after = u.ID
+			if purge != nil {
+				if err := purge(u.TenantID, u.ID); err != nil {
+					return purged, err
+				}
+			}
+			// The user may have been restored meanwhile.
+			tag, err := r.conn.Exec(ctx, "DELETE FROM users WHERE tenant_id = $1 AND id = $2 AND status = $3 AND deleted_at < $4", u.TenantID, u.ID, models.AccountDeleted, cutoff)
+			if err != nil {
+				return purged, err
+			}
+			purged += int(tag.RowsAffected())
+		}
+		if len(batch) < purgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// AnonymizeUser replaces the email, password and custom fields of a user, soft-deleted or not, with
+// placeholders and deactivates them. The row, and the ID other records refer to, are kept.
+func (r *PostgreSQLUserRepository[U]) AnonymizeUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserAnonymized, func(db pgExecer) (U, error) {
+		user, err := anonymous[U](r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		u := user.GetUser()
+		tag, err := db.Exec(ctx, "UPDATE users SET email = $1, password = $2, custom_fields = NULL, status = $3, deleted_at = NULL WHERE tenant_id = $4 AND id = $5",
+			u.Email, u.Password, u.Status, r.tenant, id)
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
+}
+
+// EraseUser removes a user, soft-deleted or not, at once.
+func (r *PostgreSQLUserRepository[U]) EraseUser(id uint) error {
+	ctx := context.Background()
+	return r.change(ctx, models.UserErased, func(db pgExecer) (U, error) {
+		user := erased[U](r.tenant, id)
+		tag, err := db.Exec(ctx, "DELETE FROM users WHERE tenant_id = $1 AND id = $2", r.tenant, id)
+		if err != nil {
+			return user, err
+		}
+		if tag.RowsAffected() == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
 }
 
-func (r *PostgreSQLUserRepository) ResetPassword(email string, newPassword string) error {
+func (r *PostgreSQLUserRepository[U]) ResetPassword(email string, newPassword string) error {
 	ctx := context.Background()
-	_, err := r.conn.Exec(ctx, "UPDATE users SET password = $1 WHERE email = $2", newPassword, email)
+	email = utils.NormalizeEmail(email)
+	hashedPassword, err := r.hasher.Hash(newPassword)
 	if err != nil {
 		return err
 	}
+	tag, err := r.conn.Exec(ctx, "UPDATE users SET password = $1 WHERE tenant_id = $2 AND email = $3 AND status <> $4", hashedPassword, r.tenant, email, models.AccountDeleted)
+	if err != nil {
+		return postgresUserError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrUserNotFound
+	}
 	return nil
 }
 
-func (r *PostgreSQLUserRepository) UpdateUser(user *models.User) error {
+func (r *PostgreSQLUserRepository[U]) UpdateUser(user U) error {
 	ctx := context.Background()
-	_, err := r.conn.Exec(ctx, "UPDATE users SET email = $1, password = $2 WHERE id = $3", user.Email, user.Password, user.ID)
+	u := user.GetUser()
+	u.TenantID = r.tenant // Users cannot be moved to another tenant.
+	u.Email = utils.NormalizeEmail(u.Email)
+	customFields, err := models.MarshalCustomFields(user)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.change(ctx, models.UserUpdated, func(db pgExecer) (U, error) {
+		tag, err := db.Exec(ctx, "UPDATE users SET email = $1, password = $2, custom_fields = $3, role = $4 WHERE tenant_id = $5 AND id = $6 AND status <> $7",
+			u.Email, u.Password, customFields, u.Role, r.tenant, u.ID, models.AccountDeleted)
+		if err != nil {
+			return user, postgresUserError(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return user, goat.ErrUserNotFound
+		}
+		return user, nil
+	})
 }
 
-func (r *PostgreSQLUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *PostgreSQLUserRepository[U]) GetUserByID(id uint) (U, error) {
 	ctx := context.Background()
-	user := &models.User{}
-	err := r.conn.QueryRow(ctx, "SELECT id, email, password FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Password)
+	user, err := scanUser[U](r.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 AND id = $2 AND status <> $3", r.tenant, id, models.AccountDeleted))
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, postgresUserError(err)
 	}
 	return user, nil
 }
 
+func (r *PostgreSQLUserRepository[U]) GetUserByEmail(email string) (U, error) {
+	ctx := context.Background()
+	email = utils.NormalizeEmail(email)
+	user, err := scanUser[U](r.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 AND email = $2 AND status <> $3", r.tenant, email, models.AccountDeleted))
+	if err != nil {
+		var zero U
+		return zero, postgresUserError(err)
+	}
+	return user, nil
+}
+
+// ListUsers returns a page of the users that are not deleted matching query. Pages are read with
+// an index range from where the cursor left off, so each costs the same however deep it is. Emails
+// are matched and sorted bytewise, in the C collation, which the email index is built with.
+func (r *PostgreSQLUserRepository[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	ctx := context.Background()
+	list, err := newUserList(query)
+	if err != nil {
+		return nil, err
+	}
+	stmt, args := userListSQL(r.tenant, list, `(email COLLATE "C")`, func(n int) string { return "$" + strconv.Itoa(n) })
+	rows, err := r.conn.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []U{}
+	for rows.Next() {
+		user, err := scanUser[U](rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return userPage(list, users), nil
+}
+
+// NormalizeStoredEmails normalizes the emails of users stored before emails were normalized, in every
+// tenant, so that lookups, which normalize their input, find them again. A user whose normalized
+// address another user of the tenant already has keeps the stored email and is reported instead.
+// It is safe to run again, and to stop and resume.
+func (r *PostgreSQLUserRepository[U]) NormalizeStoredEmails() ([]models.EmailConflict, error) {
+	ctx := context.Background()
+	conflicts := []models.EmailConflict{}
+	var after uint
+	for {
+		rows, err := r.conn.Query(ctx, "SELECT id, tenant_id, email FROM users WHERE id > $1 ORDER BY id LIMIT $2", after, emailMigrationBatch)
+		if err != nil {
+			return nil, err
+		}
+		var batch []models.EmailConflict
+		for rows.Next() {
+			var c models.EmailConflict
+			if err := rows.Scan(&c.UserID, &c.TenantID, &c.Email); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, c := range batch {
+			after = c.UserID
+			conflict, err := normalizeStoredEmail(c, func(c models.EmailConflict) error {
+				_, err := r.conn.Exec(ctx, "UPDATE users SET email = $1 WHERE tenant_id = $2 AND id = $3", c.Normalized, c.TenantID, c.UserID)
+				return postgresUserError(err)
+			}, func(c models.EmailConflict) (uint, error) {
+				var id uint
+				err := r.conn.QueryRow(ctx, "SELECT id FROM users WHERE tenant_id = $1 AND email = $2 AND id <> $3 AND status <> $4", c.TenantID, c.Normalized, c.UserID, models.AccountDeleted).Scan(&id)
+				return id, err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+		}
+		if len(batch) < emailMigrationBatch {
+			return conflicts, nil
+		}
+	}
+}
+
+// change makes a change to the users table with fn. When the repository has an outbox, fn runs in a
+// transaction that also records an event of type typ on the user fn returns.
+func (r *PostgreSQLUserRepository[U]) change(ctx context.Context, typ models.UserEventType, fn func(db pgExecer) (U, error)) error {
+	if !r.outbox {
+		_, err := fn(r.conn)
+		return err
+	}
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	user, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	message, err := newOutboxMessage(typ, user)
+	if err != nil {
+		return err
+	}
+	if err := insertPostgresOutboxMessage(ctx, tx, message); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// postgresUserError translates PostgreSQL driver errors from the users table into goat errors.
+func postgresUserError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return goat.ErrUserNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation && pgErr.ConstraintName == "users_pkey":
+		return errUserIDTaken
+	case errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation && pgErr.ConstraintName == "users_tenant_live_email_idx":
+		return goat.ErrEmailTaken
+	}
+	return err
+}
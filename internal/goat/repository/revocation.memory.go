@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// revokedToken identifies a revoked token in a MemoryRevocationStore.
+type revokedToken struct {
+	tenant, jti string
+}
+
+// userWatermark identifies a user's watermark in a MemoryRevocationStore.
+type userWatermark struct {
+	tenant string
+	userID uint
+}
+
+// memoryRevocations are the revocations shared by a MemoryRevocationStore and the stores ForTenant returns.
+type memoryRevocations struct {
+	mu         sync.RWMutex
+	tokens     map[revokedToken]time.Time  // Revoked token IDs and when the tokens expire.
+	watermarks map[userWatermark]time.Time // Per-user time before which tokens are invalid.
+}
+
+// MemoryRevocationStore keeps revoked tokens in process memory. It is meant for tests and single-instance
+// deployments; revocations are lost when the process exits.
+type MemoryRevocationStore struct {
+	*memoryRevocations
+	tenant string // Tenant whose revocations the store sees; empty for the default tenant.
+}
+
+// NewMemoryRevocationStore creates an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{memoryRevocations: &memoryRevocations{
+		tokens:     map[revokedToken]time.Time{},
+		watermarks: map[userWatermark]time.Time{},
+	}}
+}
+
+// ForTenant implements goat.RevocationStore.
+func (s *MemoryRevocationStore) ForTenant(tenantID string) goat.RevocationStore {
+	return &MemoryRevocationStore{memoryRevocations: s.memoryRevocations, tenant: tenantID}
+}
+
+// RevokeToken implements goat.RevocationStore.
+func (s *MemoryRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[revokedToken{s.tenant, jti}] = expiresAt
+	return nil
+}
+
+// IsRevoked implements goat.RevocationStore.
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tokens[revokedToken{s.tenant, jti}]
+	return ok, nil
+}
+
+// RevokeUserTokens implements goat.RevocationStore.
+func (s *MemoryRevocationStore) RevokeUserTokens(userID uint, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := userWatermark{s.tenant, userID}
+	if before.After(s.watermarks[key]) {
+		s.watermarks[key] = before
+	}
+	return nil
+}
+
+// TokensValidAfter implements goat.RevocationStore.
+func (s *MemoryRevocationStore) TokensValidAfter(userID uint) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.watermarks[userWatermark{s.tenant, userID}], nil
+}
+
+// DeleteExpired implements goat.RevocationStore.
+func (s *MemoryRevocationStore) DeleteExpired(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expiresAt := range s.tokens {
+		if !now.Before(expiresAt) {
+			delete(s.tokens, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBRevocationStore stores revoked tokens and per-user watermarks in two MongoDB collections.
+// Every query but DeleteExpired is limited to the store's tenant; see ForTenant.
+type MongoDBRevocationStore struct {
+	tokens     *mongo.Collection // Revoked token IDs, removed by a TTL index once the tokens expire.
+	watermarks *mongo.Collection // Per-user time before which tokens are invalid.
+	tenant     string            // Tenant whose revocations the store sees; empty for the default tenant.
+}
+
+// NewMongoDBRevocationStore initializes a new MongoDBRevocationStore with a given MongoDB client and database name.
+// It uses the revoked_tokens and token_watermarks collections.
+func NewMongoDBRevocationStore(client *mongo.Client, dbName string) (*MongoDBRevocationStore, error) {
+	ctx := context.Background()
+	db := client.Database(dbName)
+	tokens := db.Collection("revoked_tokens")
+	watermarks := db.Collection("token_watermarks")
+
+	_, err := tokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = watermarks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBRevocationStore{tokens: tokens, watermarks: watermarks}, nil
+}
+
+// ForTenant implements goat.RevocationStore.
+func (s *MongoDBRevocationStore) ForTenant(tenantID string) goat.RevocationStore {
+	return &MongoDBRevocationStore{tokens: s.tokens, watermarks: s.watermarks, tenant: tenantID}
+}
+
+// RevokeToken implements goat.RevocationStore.
+func (s *MongoDBRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.tokens.UpdateOne(ctx, bson.M{"tenant_id": s.tenant, "jti": jti}, bson.M{"$set": bson.M{"expires_at": expiresAt}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsRevoked implements goat.RevocationStore.
+func (s *MongoDBRevocationStore) IsRevoked(jti string) (bool, error) {
+	ctx := context.Background()
+	n, err := s.tokens.CountDocuments(ctx, bson.M{"tenant_id": s.tenant, "jti": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeUserTokens implements goat.RevocationStore.
+func (s *MongoDBRevocationStore) RevokeUserTokens(userID uint, before time.Time) error {
+	ctx := context.Background()
+	_, err := s.watermarks.UpdateOne(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}, bson.M{"$max": bson.M{"not_before": before}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TokensValidAfter implements goat.RevocationStore.
+func (s *MongoDBRevocationStore) TokensValidAfter(userID uint) (time.Time, error) {
+	ctx := context.Background()
+	var doc struct {
+		Before time.Time `bson:"not_before"`
+	}
+	err := s.watermarks.FindOne(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return doc.Before, nil
+}
+
+// DeleteExpired implements goat.RevocationStore. The TTL index already removes expired entries,
+// so this only purges those MongoDB has not got to yet.
+func (s *MongoDBRevocationStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.tokens.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// MySQLRevocationStore stores revoked tokens and per-user watermarks in MySQL tables.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Every query but DeleteExpired is limited to the store's tenant; see ForTenant.
+type MySQLRevocationStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose revocations the store sees; empty for the default tenant.
+}
+
+// NewMySQLRevocationStore initializes a new MySQLRevocationStore with a given DSN (Data Source Name)
+// and creates the revoked_tokens and token_watermarks tables.
+func NewMySQLRevocationStore(dsn string) (*MySQLRevocationStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS revoked_tokens (
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		jti VARCHAR(64) NOT NULL,
+		expires_at DATETIME(6) NOT NULL,
+		PRIMARY KEY (tenant_id, jti),
+		INDEX idx_revoked_tokens_expires_at (expires_at)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS token_watermarks (
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		user_id BIGINT UNSIGNED NOT NULL,
+		not_before DATETIME(6) NOT NULL,
+		PRIMARY KEY (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLRevocationStore{db: db}, nil
+}
+
+// ForTenant implements goat.RevocationStore.
+func (s *MySQLRevocationStore) ForTenant(tenantID string) goat.RevocationStore {
+	return &MySQLRevocationStore{db: s.db, tenant: tenantID}
+}
+
+// RevokeToken implements goat.RevocationStore.
+func (s *MySQLRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO revoked_tokens (tenant_id, jti, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)", s.tenant, jti, expiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsRevoked implements goat.RevocationStore.
+func (s *MySQLRevocationStore) IsRevoked(jti string) (bool, error) {
+	ctx := context.Background()
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM revoked_tokens WHERE tenant_id = ? AND jti = ?", s.tenant, jti).Scan(&one)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeUserTokens implements goat.RevocationStore.
+func (s *MySQLRevocationStore) RevokeUserTokens(userID uint, before time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO token_watermarks (tenant_id, user_id, not_before) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE not_before = GREATEST(not_before, VALUES(not_before))", s.tenant, userID, before.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TokensValidAfter implements goat.RevocationStore.
+func (s *MySQLRevocationStore) TokensValidAfter(userID uint) (time.Time, error) {
+	ctx := context.Background()
+	var before time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT not_before FROM token_watermarks WHERE tenant_id = ? AND user_id = ?", s.tenant, userID).Scan(&before)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return before, nil
+}
+
+// DeleteExpired implements goat.RevocationStore.
+func (s *MySQLRevocationStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLRevocationStore stores revoked tokens and per-user watermarks in PostgreSQL tables.
+// Every query but DeleteExpired is limited to the store's tenant; see ForTenant.
+type PostgreSQLRevocationStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose revocations the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLRevocationStore initializes a new PostgreSQLRevocationStore with a given connection string
+// and creates the revoked_tokens and token_watermarks tables.
+func NewPostgreSQLRevocationStore(connString string) (*PostgreSQLRevocationStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS revoked_tokens (
+		tenant_id TEXT NOT NULL DEFAULT '',
+		jti TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (tenant_id, jti)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS token_watermarks (
+		tenant_id TEXT NOT NULL DEFAULT '',
+		user_id BIGINT NOT NULL,
+		not_before TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (tenant_id, user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLRevocationStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) ForTenant(tenantID string) goat.RevocationStore {
+	return &PostgreSQLRevocationStore{conn: s.conn, tenant: tenantID}
+}
+
+// RevokeToken implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "INSERT INTO revoked_tokens (tenant_id, jti, expires_at) VALUES ($1, $2, $3) ON CONFLICT (tenant_id, jti) DO UPDATE SET expires_at = EXCLUDED.expires_at", s.tenant, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsRevoked implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) IsRevoked(jti string) (bool, error) {
+	ctx := context.Background()
+	var one int
+	err := s.conn.QueryRow(ctx, "SELECT 1 FROM revoked_tokens WHERE tenant_id = $1 AND jti = $2", s.tenant, jti).Scan(&one)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeUserTokens implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) RevokeUserTokens(userID uint, before time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "INSERT INTO token_watermarks (tenant_id, user_id, not_before) VALUES ($1, $2, $3) ON CONFLICT (tenant_id, user_id) DO UPDATE SET not_before = GREATEST(token_watermarks.not_before, EXCLUDED.not_before)", s.tenant, userID, before)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TokensValidAfter implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) TokensValidAfter(userID uint) (time.Time, error) {
+	ctx := context.Background()
+	var before time.Time
+	err := s.conn.QueryRow(ctx, "SELECT not_before FROM token_watermarks WHERE tenant_id = $1 AND user_id = $2", s.tenant, userID).Scan(&before)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return before, nil
+}
+
+// DeleteExpired implements goat.RevocationStore.
+func (s *PostgreSQLRevocationStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= $1", now)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// rowScanner is satisfied by *sql.Row, *sql.Rows, pgx.Row and pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// userColumns are the columns of the users table, in the order scanUser reads them.
+const userColumns = "id, tenant_id, email, password, custom_fields, role, status, created_at, deleted_at"
+
+// scanUser reads the userColumns of a users row into a new U.
+func scanUser[U models.Account](row rowScanner) (U, error) {
+	account := models.New[U]()
+	user := account.GetUser()
+	var customFields []byte
+	if err := row.Scan(&user.ID, &user.TenantID, &user.Email, &user.Password, &customFields, &user.Role, &user.Status, &user.CreatedAt, &user.DeletedAt); err != nil {
+		var zero U
+		return zero, err
+	}
+
+	if err := models.UnmarshalCustomFields(customFields, account); err != nil {
+		var zero U
+		return zero, err
+	}
+	return account, nil
+}
+
+// Driver error codes for unique constraint violations.
+const (
+	mysqlErrDuplicateEntry = 1062    // ER_DUP_ENTRY
+	pgErrUniqueViolation   = "23505" // unique_violation
+)
+
+// nonNil returns list, or an empty list if it is nil, so that it is stored as an empty
+// array rather than NULL.
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// auditColumns are the columns of the audit_events table, in the order scanAuditEvent reads them.
+const auditColumns = "id, type, actor_id, target_id, tenant_id, ip, user_agent, metadata, created_at, seq, prev_hash, hash, redacted"
+
+// scanAuditEvent reads the auditColumns of an audit_events row.
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{}
+	var metadata, redacted []byte
+	err := row.Scan(&event.ID, &event.Type, &event.ActorID, &event.TargetID, &event.TenantID, &event.IP, &event.UserAgent, &metadata, &event.CreatedAt,
+		&event.Seq, &event.PrevHash, &event.Hash, &redacted)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if len(redacted) > 0 {
+		if err := json.Unmarshal(redacted, &event.Redacted); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// auditCheckpointColumns are the columns of the audit_checkpoints table, in the order
+// scanAuditCheckpoint reads them.
+const auditCheckpointColumns = "seq, hash, signature, created_at"
+
+// scanAuditCheckpoint reads the auditCheckpointColumns of an audit_checkpoints row.
+func scanAuditCheckpoint(row rowScanner) (*models.AuditCheckpoint, error) {
+	checkpoint := &models.AuditCheckpoint{}
+	if err := row.Scan(&checkpoint.Seq, &checkpoint.Hash, &checkpoint.Signature, &checkpoint.CreatedAt); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// auditQuerySQL builds the SELECT statement for query. placeholder returns the driver's
+// placeholder for the nth argument, counting from 1, and email is the driver's expression for
+// the email in an event's metadata.
+func auditQuerySQL(query *models.AuditQuery, placeholder func(n int) string, email string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	cond := func(format string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(format, placeholder(len(args))))
+	}
+
+	if len(query.Types) > 0 {
+		in := make([]string, len(query.Types))
+		for i, t := range query.Types {
+			args = append(args, t)
+			in[i] = placeholder(len(args))
+		}
+		conds = append(conds, "type IN ("+strings.Join(in, ", ")+")")
+	}
+	if query.ActorID != 0 {
+		cond("actor_id = %s", query.ActorID)
+	}
+	if query.TargetID != 0 {
+		cond("target_id = %s", query.TargetID)
+	}
+	if query.TenantID != "" {
+		cond("tenant_id = %s", query.TenantID)
+	}
+	if query.Email != "" {
+		cond(email+" = %s", query.Email)
+	}
+	if !query.Since.IsZero() {
+		cond("created_at >= %s", query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		cond("created_at < %s", query.Until.UTC())
+	}
+	if query.Before != "" {
+		cond("id < %s", query.Before)
+	}
+
+	stmt := "SELECT " + auditColumns + " FROM audit_events"
+	if len(conds) > 0 {
+		stmt += " WHERE " + strings.Join(conds, " AND ")
+	}
+	stmt += " ORDER BY id DESC"
+	if query.Limit > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", query.Limit)
+	}
+	return stmt, args
+}
+
+// outboxColumns are the columns of the outbox table, in the order scanOutboxMessage reads them.
+const outboxColumns = "id, type, tenant_id, user_id, payload, created_at, status, attempts, next_attempt_at, last_error"
+
+// scanOutboxMessage reads the outboxColumns of an outbox row.
+func scanOutboxMessage(row rowScanner) (*models.OutboxMessage, error) {
+	message := &models.OutboxMessage{}
+	var typ string
+	var payload []byte
+	err := row.Scan(&message.ID, &typ, &message.TenantID, &message.UserID, &payload, &message.CreatedAt,
+		&message.Status, &message.Attempts, &message.NextAttemptAt, &message.LastError)
+	if err != nil {
+		return nil, err
+	}
+	message.Type = models.UserEventType(typ)
+	message.Payload = payload
+	return message, nil
+}
+
+// webhookDeliveryColumns are the columns of the webhook_deliveries table, in the order scanWebhookDelivery reads them.
+const webhookDeliveryColumns = "id, endpoint_id, event_id, event_type, user_id, payload, status, attempts, next_attempt_at, response_status, response_body, last_error, created_at, updated_at"
+
+// scanWebhookDelivery reads the webhookDeliveryColumns of a webhook_deliveries row.
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(&delivery.ID, &delivery.EndpointID, &delivery.EventID, &delivery.EventType, &delivery.UserID, &payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.ResponseBody, &delivery.LastError, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// memorySessions are the sessions shared by a MemorySessionStore and the stores ForTenant returns.
+type memorySessions struct {
+	mu       sync.RWMutex
+	sessions map[string]models.Session
+}
+
+// MemorySessionStore keeps sessions in process memory. It is meant for tests and single-instance deployments;
+// sessions are lost when the process exits.
+type MemorySessionStore struct {
+	*memorySessions
+	tenant string // Tenant whose sessions the store sees; empty for the default tenant.
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{memorySessions: &memorySessions{sessions: map[string]models.Session{}}}
+}
+
+// ForTenant implements goat.SessionStore.
+func (s *MemorySessionStore) ForTenant(tenantID string) goat.SessionStore {
+	return &MemorySessionStore{memorySessions: s.memorySessions, tenant: tenantID}
+}
+
+// Create implements goat.SessionStore.
+func (s *MemorySessionStore) Create(session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.TenantID = s.tenant
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+// Get implements goat.SessionStore.
+func (s *MemorySessionStore) Get(id string) (*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	if !ok || session.TenantID != s.tenant {
+		return nil, goat.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// Touch implements goat.SessionStore.
+func (s *MemorySessionStore) Touch(id string, lastSeenAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || session.TenantID != s.tenant {
+		return goat.ErrSessionNotFound
+	}
+	session.LastSeenAt = lastSeenAt
+	s.sessions[id] = session
+	return nil
+}
+
+// Delete implements goat.SessionStore.
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[id]; ok && session.TenantID == s.tenant {
+		delete(s.sessions, id)
+	}
+	return nil
+}
+
+// DeleteExpired implements goat.SessionStore.
+func (s *MemorySessionStore) DeleteExpired(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.Expired(now) {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+// ListByUser implements goat.SessionStore.
+func (s *MemorySessionStore) ListByUser(userID uint) ([]*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := []*models.Session{}
+	for _, session := range s.sessions {
+		if session.TenantID == s.tenant && session.UserID == userID {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// DeleteByUser implements goat.SessionStore.
+func (s *MemorySessionStore) DeleteByUser(userID uint, except string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.TenantID == s.tenant && session.UserID == userID && id != except {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBSessionStore stores sessions in a MongoDB collection.
+// Every query is limited to the store's tenant; see ForTenant.
+type MongoDBSessionStore struct {
+	collection *mongo.Collection // MongoDB collection for session documents.
+	tenant     string            // Tenant whose sessions the store sees; empty for the default tenant.
+}
+
+// NewMongoDBSessionStore initializes a new MongoDBSessionStore with a given MongoDB client, database name, and collection name.
+// It ensures a unique index on the session ID and a TTL index that lets MongoDB remove expired sessions on its own.
+func NewMongoDBSessionStore(client *mongo.Client, dbName, collectionName string) (*MongoDBSessionStore, error) {
+	ctx := context.Background()
+	collection := client.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}}},
+		{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDBSessionStore{collection: collection}, nil
+}
+
+// ForTenant implements goat.SessionStore.
+func (s *MongoDBSessionStore) ForTenant(tenantID string) goat.SessionStore {
+	return &MongoDBSessionStore{collection: s.collection, tenant: tenantID}
+}
+
+// Create implements goat.SessionStore.
+func (s *MongoDBSessionStore) Create(session *models.Session) error {
+	ctx := context.Background()
+	session.TenantID = s.tenant
+	_, err := s.collection.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get implements goat.SessionStore.
+func (s *MongoDBSessionStore) Get(id string) (*models.Session, error) {
+	ctx := context.Background()
+	session := &models.Session{}
+	err := s.collection.FindOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}).Decode(session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// Touch implements goat.SessionStore.
+func (s *MongoDBSessionStore) Touch(id string, lastSeenAt time.Time) error {
+	ctx := context.Background()
+	res, err := s.collection.UpdateOne(ctx, bson.M{"tenant_id": s.tenant, "id": id}, bson.M{"$set": bson.M{"last_seen_at": lastSeenAt}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Delete implements goat.SessionStore.
+func (s *MongoDBSessionStore) Delete(id string) error {
+	ctx := context.Background()
+	_, err := s.collection.DeleteOne(ctx, bson.M{"tenant_id": s.tenant, "id": id})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired implements goat.SessionStore. The TTL index already removes expired sessions,
+// but only about once a minute, so this can be used to purge them immediately.
+func (s *MongoDBSessionStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListByUser implements goat.SessionStore.
+func (s *MongoDBSessionStore) ListByUser(userID uint) ([]*models.Session, error) {
+	ctx := context.Background()
+	cursor, err := s.collection.Find(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID}, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []*models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteByUser implements goat.SessionStore.
+func (s *MongoDBSessionStore) DeleteByUser(userID uint, except string) error {
+	ctx := context.Background()
+	_, err := s.collection.DeleteMany(ctx, bson.M{"tenant_id": s.tenant, "user_id": userID, "id": bson.M{"$ne": except}})
+	if err != nil {
+		return err
+	}
+	return nil
+}
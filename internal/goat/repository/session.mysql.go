@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MySQLSessionStore stores sessions in a MySQL table.
+// The DSN must set parseTime=true so that timestamps scan into time.Time.
+// Every query is limited to the store's tenant; see ForTenant.
+type MySQLSessionStore struct {
+	db     *sql.DB // MySQL DB connection.
+	tenant string  // Tenant whose sessions the store sees; empty for the default tenant.
+}
+
+// NewMySQLSessionStore initializes a new MySQLSessionStore with a given DSN (Data Source Name) and creates the sessions table.
+func NewMySQLSessionStore(dsn string) (*MySQLSessionStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+		id CHAR(64) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		user_id BIGINT UNSIGNED NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		last_seen_at DATETIME(6) NOT NULL,
+		expires_at DATETIME(6) NOT NULL,
+		user_agent VARCHAR(512) NOT NULL,
+		ip VARCHAR(45) NOT NULL,
+		INDEX idx_sessions_user_id (tenant_id, user_id),
+		INDEX idx_sessions_expires_at (expires_at)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLSessionStore{db: db}, nil
+}
+
+// ForTenant implements goat.SessionStore.
+func (s *MySQLSessionStore) ForTenant(tenantID string) goat.SessionStore {
+	return &MySQLSessionStore{db: s.db, tenant: tenantID}
+}
+
+// Create implements goat.SessionStore.
+func (s *MySQLSessionStore) Create(session *models.Session) error {
+	ctx := context.Background()
+	session.TenantID = s.tenant
+	_, err := s.db.ExecContext(ctx, "INSERT INTO sessions (id, tenant_id, user_id, created_at, last_seen_at, expires_at, user_agent, ip) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		session.ID, session.TenantID, session.UserID, session.CreatedAt.UTC(), session.LastSeenAt.UTC(), session.ExpiresAt.UTC(), session.UserAgent, session.IP)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get implements goat.SessionStore.
+func (s *MySQLSessionStore) Get(id string) (*models.Session, error) {
+	ctx := context.Background()
+	session := &models.Session{TenantID: s.tenant}
+	err := s.db.QueryRowContext(ctx, "SELECT id, user_id, created_at, last_seen_at, expires_at, user_agent, ip FROM sessions WHERE tenant_id = ? AND id = ?", s.tenant, id).
+		Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.UserAgent, &session.IP)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// Touch implements goat.SessionStore.
+func (s *MySQLSessionStore) Touch(id string, lastSeenAt time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "UPDATE sessions SET last_seen_at = ? WHERE tenant_id = ? AND id = ?", lastSeenAt.UTC(), s.tenant, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete implements goat.SessionStore.
+func (s *MySQLSessionStore) Delete(id string) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE tenant_id = ? AND id = ?", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired implements goat.SessionStore.
+func (s *MySQLSessionStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListByUser implements goat.SessionStore.
+func (s *MySQLSessionStore) ListByUser(userID uint) ([]*models.Session, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, user_id, created_at, last_seen_at, expires_at, user_agent, ip FROM sessions WHERE tenant_id = ? AND user_id = ? ORDER BY last_seen_at DESC", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session := &models.Session{TenantID: s.tenant}
+		if err := rows.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.UserAgent, &session.IP); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteByUser implements goat.SessionStore.
+func (s *MySQLSessionStore) DeleteByUser(userID uint, except string) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE tenant_id = ? AND user_id = ? AND id <> ?", s.tenant, userID, except)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLSessionStore stores sessions in a PostgreSQL table.
+// Every query is limited to the store's tenant; see ForTenant.
+type PostgreSQLSessionStore struct {
+	conn   *pgx.Conn // PostgreSQL connection for database access.
+	tenant string    // Tenant whose sessions the store sees; empty for the default tenant.
+}
+
+// NewPostgreSQLSessionStore initializes a new PostgreSQLSessionStore with a given connection string and creates the sessions table.
+func NewPostgreSQLSessionStore(connString string) (*PostgreSQLSessionStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		user_id BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		last_seen_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		user_agent TEXT NOT NULL,
+		ip TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE INDEX IF NOT EXISTS sessions_tenant_user_id_idx ON sessions (tenant_id, user_id)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgreSQLSessionStore{conn: conn}, nil
+}
+
+// ForTenant implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) ForTenant(tenantID string) goat.SessionStore {
+	return &PostgreSQLSessionStore{conn: s.conn, tenant: tenantID}
+}
+
+// Create implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) Create(session *models.Session) error {
+	ctx := context.Background()
+	session.TenantID = s.tenant
+	_, err := s.conn.Exec(ctx, "INSERT INTO sessions (id, tenant_id, user_id, created_at, last_seen_at, expires_at, user_agent, ip) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		session.ID, session.TenantID, session.UserID, session.CreatedAt, session.LastSeenAt, session.ExpiresAt, session.UserAgent, session.IP)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) Get(id string) (*models.Session, error) {
+	ctx := context.Background()
+	session := &models.Session{TenantID: s.tenant}
+	err := s.conn.QueryRow(ctx, "SELECT id, user_id, created_at, last_seen_at, expires_at, user_agent, ip FROM sessions WHERE tenant_id = $1 AND id = $2", s.tenant, id).
+		Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.UserAgent, &session.IP)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// Touch implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) Touch(id string, lastSeenAt time.Time) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "UPDATE sessions SET last_seen_at = $1 WHERE tenant_id = $2 AND id = $3", lastSeenAt, s.tenant, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Delete implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) Delete(id string) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM sessions WHERE tenant_id = $1 AND id = $2", s.tenant, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM sessions WHERE expires_at <= $1", now)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListByUser implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) ListByUser(userID uint) ([]*models.Session, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT id, user_id, created_at, last_seen_at, expires_at, user_agent, ip FROM sessions WHERE tenant_id = $1 AND user_id = $2 ORDER BY last_seen_at DESC", s.tenant, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session := &models.Session{TenantID: s.tenant}
+		if err := rows.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.UserAgent, &session.IP); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteByUser implements goat.SessionStore.
+func (s *PostgreSQLSessionStore) DeleteByUser(userID uint, except string) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "DELETE FROM sessions WHERE tenant_id = $1 AND user_id = $2 AND id <> $3", s.tenant, userID, except)
+	if err != nil {
+		return err
+	}
+	return nil
+}
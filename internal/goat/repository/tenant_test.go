@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// TestTenantIsolation checks that a record saved for one tenant cannot be read, changed or deleted
+// through a store of another tenant, the default one included.
+func TestTenantIsolation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// want fails the test unless err is target.
+	want := func(t *testing.T, what string, err, target error) {
+		t.Helper()
+		if !errors.Is(err, target) {
+			t.Errorf("%s: error = %v, want %v", what, err, target)
+		}
+	}
+	// wantNone fails the test unless a list is empty.
+	wantNone := func(t *testing.T, what string, n int, err error) {
+		t.Helper()
+		if err != nil || n != 0 {
+			t.Errorf("%s: %d records, error %v, want none", what, n, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		check func(t *testing.T, tenant string)
+	}{
+		{"sessions", func(t *testing.T, tenant string) {
+			store := NewMemorySessionStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			if err := acme.Create(&models.Session{ID: "s1", UserID: 1, ExpiresAt: now.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := other.Get("s1")
+			want(t, "Get", err, goat.ErrSessionNotFound)
+			want(t, "Touch", other.Touch("s1", now), goat.ErrSessionNotFound)
+			sessions, err := other.ListByUser(1)
+			wantNone(t, "ListByUser", len(sessions), err)
+			if err := other.Delete("s1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := other.DeleteByUser(1, ""); err != nil {
+				t.Fatal(err)
+			}
+			if s, err := acme.Get("s1"); err != nil || s.TenantID != "acme" {
+				t.Errorf("session after changes in another tenant = %+v, %v", s, err)
+			}
+		}},
+		{"API keys", func(t *testing.T, tenant string) {
+			store := NewMemoryAPIKeyStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			if err := acme.CreateAPIKey(&models.APIKey{ID: "goat_1", UserID: 1}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := other.GetAPIKey("goat_1")
+			want(t, "GetAPIKey", err, goat.ErrAPIKeyNotFound)
+			want(t, "DeleteAPIKey", other.DeleteAPIKey("goat_1"), goat.ErrAPIKeyNotFound)
+			keys, err := other.ListAPIKeys(1)
+			wantNone(t, "ListAPIKeys", len(keys), err)
+			if err := other.TouchAPIKey("goat_1", now); err != nil && !errors.Is(err, goat.ErrAPIKeyNotFound) {
+				t.Fatal(err)
+			}
+			if k, err := acme.GetAPIKey("goat_1"); err != nil || k.LastUsedAt != nil {
+				t.Errorf("key after changes in another tenant = %+v, %v", k, err)
+			}
+		}},
+		{"identities", func(t *testing.T, tenant string) {
+			store := NewMemoryIdentityStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			if err := acme.CreateIdentity(&models.Identity{UserID: 1, Provider: "google", Subject: "123"}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := other.GetIdentity("google", "123")
+			want(t, "GetIdentity", err, goat.ErrIdentityNotFound)
+			want(t, "DeleteIdentity", other.DeleteIdentity("google", "123"), goat.ErrIdentityNotFound)
+			identities, err := other.ListIdentities(1)
+			wantNone(t, "ListIdentities", len(identities), err)
+
+			// The same account can be linked to a user of every tenant.
+			if err := other.CreateIdentity(&models.Identity{UserID: 2, Provider: "google", Subject: "123"}); err != nil {
+				t.Errorf("CreateIdentity of an identity linked in another tenant: %v", err)
+			}
+			if i, err := acme.GetIdentity("google", "123"); err != nil || i.UserID != 1 {
+				t.Errorf("identity after changes in another tenant = %+v, %v", i, err)
+			}
+		}},
+		{"OIDC consents and grants", func(t *testing.T, tenant string) {
+			store := NewMemoryOIDCStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			if err := acme.SaveConsent(&models.Consent{UserID: 1, ClientID: "app", Scopes: []string{"openid"}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := acme.CreateGrant(&models.Grant{ID: "g1", UserID: 1, ClientID: "app", ExpiresAt: now.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := other.GetConsent(1, "app")
+			want(t, "GetConsent", err, goat.ErrConsentNotFound)
+			consents, err := other.ListConsents(1)
+			wantNone(t, "ListConsents", len(consents), err)
+			_, err = other.TakeGrant("g1")
+			want(t, "TakeGrant", err, goat.ErrGrantNotFound)
+			n, err := other.DeleteUserGrants(1)
+			wantNone(t, "DeleteUserGrants", n, err)
+			if err := other.DeleteConsent(1, "app"); err != nil && !errors.Is(err, goat.ErrConsentNotFound) {
+				t.Fatal(err)
+			}
+
+			if _, err := acme.GetConsent(1, "app"); err != nil {
+				t.Errorf("consent after changes in another tenant: %v", err)
+			}
+			if _, err := acme.TakeGrant("g1"); err != nil {
+				t.Errorf("grant after changes in another tenant: %v", err)
+			}
+		}},
+		{"organizations", func(t *testing.T, tenant string) {
+			store := NewMemoryOrganizationStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			org := &models.Organization{ID: "o1", Name: "Acme", OwnerID: 1}
+			if err := acme.CreateOrganization(org); err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range []*models.Membership{{OrganizationID: "o1", UserID: 1, Role: models.RoleOwner}, {OrganizationID: "o1", UserID: 2, Role: models.RoleMember}} {
+				if err := acme.SaveMembership(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := acme.CreateInvitation(&models.Invitation{ID: "i1", OrganizationID: "o1", Email: "bob@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := other.GetOrganization("o1")
+			want(t, "GetOrganization", err, goat.ErrOrganizationNotFound)
+			want(t, "UpdateOrganization", other.UpdateOrganization(&models.Organization{ID: "o1", Name: "Taken", OwnerID: 9}), goat.ErrOrganizationNotFound)
+			want(t, "DeleteOrganization", other.DeleteOrganization("o1"), goat.ErrOrganizationNotFound)
+			want(t, "TransferOwnership", other.TransferOwnership("o1", 1, 2), goat.ErrMembershipNotFound)
+			orgs, err := other.ListOrganizations(1)
+			wantNone(t, "ListOrganizations", len(orgs), err)
+			_, err = other.GetMembership("o1", 2)
+			want(t, "GetMembership", err, goat.ErrMembershipNotFound)
+			members, err := other.ListMembers("o1")
+			wantNone(t, "ListMembers", len(members), err)
+			want(t, "DeleteMembership", other.DeleteMembership("o1", 2), goat.ErrMembershipNotFound)
+			_, err = other.GetInvitation("i1")
+			want(t, "GetInvitation", err, goat.ErrInvitationNotFound)
+			invitations, err := other.ListInvitations("o1")
+			wantNone(t, "ListInvitations", len(invitations), err)
+			want(t, "DeleteInvitation", other.DeleteInvitation("i1"), goat.ErrInvitationNotFound)
+			n, err := other.DeleteInvitationsByEmail("bob@example.com")
+			wantNone(t, "DeleteInvitationsByEmail", n, err)
+
+			if o, err := acme.GetOrganization("o1"); err != nil || o.Name != "Acme" || o.OwnerID != 1 {
+				t.Errorf("organization after changes in another tenant = %+v, %v", o, err)
+			}
+			if members, err := acme.ListMembers("o1"); err != nil || len(members) != 2 {
+				t.Errorf("members after changes in another tenant = %v, %v, want 2", members, err)
+			}
+			if _, err := acme.GetInvitation("i1"); err != nil {
+				t.Errorf("invitation after changes in another tenant: %v", err)
+			}
+		}},
+		{"revocations", func(t *testing.T, tenant string) {
+			store := NewMemoryRevocationStore()
+			acme, other := store.ForTenant("acme"), store.ForTenant(tenant)
+			if err := acme.RevokeToken("jti", now.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if err := acme.RevokeUserTokens(1, now); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := other.IsRevoked("jti"); err != nil || revoked {
+				t.Errorf("IsRevoked = %t, %v, want a token of another tenant not revoked", revoked, err)
+			}
+			if after, err := other.TokensValidAfter(1); err != nil || !after.IsZero() {
+				t.Errorf("TokensValidAfter = %v, %v, want no watermark", after, err)
+			}
+			if revoked, err := acme.IsRevoked("jti"); err != nil || !revoked {
+				t.Errorf("IsRevoked in the token's tenant = %t, %v, want revoked", revoked, err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		for _, tenant := range []string{"", "globex"} {
+			name := tt.name + "/default tenant"
+			if tenant != "" {
+				name = tt.name + "/" + tenant
+			}
+			t.Run(name, func(t *testing.T) { tt.check(t, tenant) })
+		}
+	}
+}
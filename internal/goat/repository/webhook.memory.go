@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// MemoryWebhookStore keeps webhook endpoints and deliveries in process memory. It is meant for tests;
+// everything is lost when the process exits.
+type MemoryWebhookStore struct {
+	mu         sync.Mutex
+	endpoints  map[string]models.WebhookEndpoint
+	deliveries map[string]models.WebhookDelivery
+}
+
+// NewMemoryWebhookStore creates an empty MemoryWebhookStore.
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		endpoints:  map[string]models.WebhookEndpoint{},
+		deliveries: map[string]models.WebhookDelivery{},
+	}
+}
+
+// CreateWebhookEndpoint implements goat.WebhookStore.
+func (s *MemoryWebhookStore) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints[endpoint.ID] = copyEndpoint(endpoint)
+	return nil
+}
+
+// GetWebhookEndpoint implements goat.WebhookStore.
+func (s *MemoryWebhookStore) GetWebhookEndpoint(id string) (*models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint, ok := s.endpoints[id]
+	if !ok {
+		return nil, goat.ErrWebhookNotFound
+	}
+	endpoint = copyEndpoint(&endpoint)
+	return &endpoint, nil
+}
+
+// ListWebhookEndpoints implements goat.WebhookStore.
+func (s *MemoryWebhookStore) ListWebhookEndpoints(tenantID string) ([]*models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoints := []*models.WebhookEndpoint{}
+	for _, endpoint := range s.endpoints {
+		if endpoint.TenantID != tenantID {
+			continue
+		}
+		endpoint = copyEndpoint(&endpoint)
+		endpoints = append(endpoints, &endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if !endpoints[i].CreatedAt.Equal(endpoints[j].CreatedAt) {
+			return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt)
+		}
+		return endpoints[i].ID < endpoints[j].ID
+	})
+	return endpoints, nil
+}
+
+// UpdateWebhookEndpoint implements goat.WebhookStore.
+func (s *MemoryWebhookStore) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.endpoints[endpoint.ID]
+	if !ok {
+		return goat.ErrWebhookNotFound
+	}
+	updated := copyEndpoint(endpoint)
+	updated.TenantID = stored.TenantID
+	s.endpoints[endpoint.ID] = updated
+	return nil
+}
+
+// DeleteWebhookEndpoint implements goat.WebhookStore.
+func (s *MemoryWebhookStore) DeleteWebhookEndpoint(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.endpoints[id]; !ok {
+		return goat.ErrWebhookNotFound
+	}
+	delete(s.endpoints, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.EndpointID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// CreateWebhookDelivery implements goat.WebhookStore.
+func (s *MemoryWebhookStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		return goat.ErrWebhookDeliveryExists
+	}
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// GetWebhookDelivery implements goat.WebhookStore.
+func (s *MemoryWebhookStore) GetWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, goat.ErrWebhookDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// UpdateWebhookDelivery implements goat.WebhookStore.
+func (s *MemoryWebhookStore) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return goat.ErrWebhookDeliveryNotFound
+	}
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// ListWebhookDeliveries implements goat.WebhookStore.
+func (s *MemoryWebhookStore) ListWebhookDeliveries(endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []*models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.EndpointID == endpointID {
+			delivery := delivery
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDeliveries implements goat.WebhookStore.
+func (s *MemoryWebhookStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []*models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.WebhookPending && !delivery.NextAttemptAt.After(now) {
+			delivery := delivery
+			due = append(due, &delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for _, delivery := range due {
+		delivery.NextAttemptAt = now.Add(lease)
+		s.deliveries[delivery.ID] = *delivery
+	}
+	return due, nil
+}
+
+// copyEndpoint returns a copy of endpoint that shares no memory with it.
+func copyEndpoint(endpoint *models.WebhookEndpoint) models.WebhookEndpoint {
+	c := *endpoint
+	c.Events = append([]string(nil), endpoint.Events...)
+	return c
+}
+
+// DeleteUserWebhookDeliveries implements goat.WebhookStore.
+func (s *MemoryWebhookStore) DeleteUserWebhookDeliveries(userID uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, delivery := range s.deliveries {
+		if delivery.UserID == userID {
+			delete(s.deliveries, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDBWebhookStore stores webhook endpoints and deliveries in two MongoDB collections:
+// <prefix>_endpoints and <prefix>_deliveries. Several dispatchers can share it: each delivery is
+// claimed with an atomic update.
+type MongoDBWebhookStore struct {
+	endpoints  *mongo.Collection // MongoDB collection for webhook endpoints.
+	deliveries *mongo.Collection // MongoDB collection for webhook deliveries.
+}
+
+// NewMongoDBWebhookStore initializes a new MongoDBWebhookStore with a given MongoDB client, database name, and collection name prefix.
+// It ensures unique indexes on the IDs and indexes for listing a tenant's endpoints and an endpoint's deliveries, finding due ones and finding a user's.
+func NewMongoDBWebhookStore(client *mongo.Client, dbName, prefix string) (*MongoDBWebhookStore, error) {
+	ctx := context.Background()
+	db := client.Database(dbName)
+	s := &MongoDBWebhookStore{
+		endpoints:  db.Collection(prefix + "_endpoints"),
+		deliveries: db.Collection(prefix + "_deliveries"),
+	}
+
+	_, err := s.endpoints.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "endpoint_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.M{"user_id": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateWebhookEndpoint implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	stored := *endpoint
+	stored.Events = nonNil(endpoint.Events)
+	_, err := s.endpoints.InsertOne(ctx, &stored)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetWebhookEndpoint implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) GetWebhookEndpoint(id string) (*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	endpoint := &models.WebhookEndpoint{}
+	err := s.endpoints.FindOne(ctx, bson.M{"id": id}).Decode(endpoint)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// ListWebhookEndpoints implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) ListWebhookEndpoints(tenantID string) ([]*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := s.endpoints.Find(ctx, bson.M{"tenant_id": tenantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []*models.WebhookEndpoint{}
+	if err := cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// UpdateWebhookEndpoint implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	res, err := s.endpoints.UpdateOne(ctx, bson.M{"id": endpoint.ID}, bson.M{"$set": bson.M{
+		"url":    endpoint.URL,
+		"secret": endpoint.Secret,
+		"events": nonNil(endpoint.Events),
+		"active": endpoint.Active,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint implements goat.WebhookStore. The endpoint is removed first, so that
+// deliveries left behind by a failure part-way are never sent, and are removed by a retry.
+func (s *MongoDBWebhookStore) DeleteWebhookEndpoint(id string) error {
+	ctx := context.Background()
+	res, err := s.endpoints.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return goat.ErrWebhookNotFound
+	}
+	_, err = s.deliveries.DeleteMany(ctx, bson.M{"endpoint_id": id})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateWebhookDelivery implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	_, err := s.deliveries.InsertOne(ctx, delivery)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return goat.ErrWebhookDeliveryExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDelivery implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) GetWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	delivery := &models.WebhookDelivery{}
+	err := s.deliveries.FindOne(ctx, bson.M{"id": id}).Decode(delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, goat.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// UpdateWebhookDelivery implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	res, err := s.deliveries.UpdateOne(ctx, bson.M{"id": delivery.ID}, bson.M{"$set": bson.M{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"last_error":      delivery.LastError,
+		"updated_at":      delivery.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return goat.ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeliveries implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) ListWebhookDeliveries(endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := s.deliveries.Find(ctx, bson.M{"endpoint_id": endpointID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDeliveries implements goat.WebhookStore. Deliveries are claimed one at a time, each
+// with an atomic update, so that concurrent dispatchers never take the same one.
+func (s *MongoDBWebhookStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	filter := bson.M{"status": models.WebhookPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	deliveries := []*models.WebhookDelivery{}
+	for len(deliveries) < limit {
+		delivery := &models.WebhookDelivery{}
+		err := s.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(delivery)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// DeleteUserWebhookDeliveries implements goat.WebhookStore.
+func (s *MongoDBWebhookStore) DeleteUserWebhookDeliveries(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.deliveries.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQLWebhookStore stores webhook endpoints and deliveries in MySQL tables; subscribed event types
+// are stored as a JSON array. Several dispatchers can share it: claiming uses SKIP LOCKED, which
+// needs MySQL 8.0 or later. The DSN must set parseTime=true so that timestamps scan into time.Time.
+type MySQLWebhookStore struct {
+	db *sql.DB // MySQL DB connection.
+}
+
+// NewMySQLWebhookStore initializes a new MySQLWebhookStore with a given DSN (Data Source Name) and creates the
+// webhook_endpoints and webhook_deliveries tables.
+func NewMySQLWebhookStore(dsn string) (*MySQLWebhookStore, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+		id CHAR(32) PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		url VARCHAR(2048) NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		events JSON NOT NULL,
+		active BOOLEAN NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		INDEX idx_webhook_endpoints_tenant_id (tenant_id, created_at)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id CHAR(32) PRIMARY KEY,
+		endpoint_id CHAR(32) NOT NULL,
+		event_id VARCHAR(64) NOT NULL,
+		event_type VARCHAR(64) NOT NULL,
+		user_id BIGINT UNSIGNED NOT NULL,
+		payload JSON NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		attempts INT NOT NULL,
+		next_attempt_at DATETIME(6) NOT NULL,
+		response_status INT NOT NULL,
+		response_body TEXT NOT NULL,
+		last_error TEXT NOT NULL,
+		created_at DATETIME(6) NOT NULL,
+		updated_at DATETIME(6) NOT NULL,
+		INDEX idx_webhook_deliveries_endpoint_id (endpoint_id, created_at),
+		INDEX idx_webhook_deliveries_status_next_attempt_at (status, next_attempt_at),
+		INDEX idx_webhook_deliveries_user_id (user_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLWebhookStore{db: db}, nil
+}
+
+// CreateWebhookEndpoint implements goat.WebhookStore.
+func (s *MySQLWebhookStore) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	events, err := json.Marshal(nonNil(endpoint.Events))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO webhook_endpoints (id, tenant_id, url, secret, events, active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		endpoint.ID, endpoint.TenantID, endpoint.URL, endpoint.Secret, events, endpoint.Active, endpoint.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetWebhookEndpoint implements goat.WebhookStore.
+func (s *MySQLWebhookStore) GetWebhookEndpoint(id string) (*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	endpoint, err := scanMySQLWebhookEndpoint(s.db.QueryRowContext(ctx, "SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_endpoints WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// ListWebhookEndpoints implements goat.WebhookStore.
+func (s *MySQLWebhookStore) ListWebhookEndpoints(tenantID string) ([]*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_endpoints WHERE tenant_id = ? ORDER BY created_at, id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanMySQLWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// UpdateWebhookEndpoint implements goat.WebhookStore.
+func (s *MySQLWebhookStore) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	events, err := json.Marshal(nonNil(endpoint.Events))
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE webhook_endpoints SET url = ?, secret = ?, events = ?, active = ? WHERE id = ?",
+		endpoint.URL, endpoint.Secret, events, endpoint.Active, endpoint.ID)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only changed rows, so look the endpoint up before reporting it missing.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.GetWebhookEndpoint(endpoint.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint implements goat.WebhookStore.
+func (s *MySQLWebhookStore) DeleteWebhookEndpoint(id string) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhook_endpoints WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return goat.ErrWebhookNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE endpoint_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateWebhookDelivery implements goat.WebhookStore.
+func (s *MySQLWebhookStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "INSERT INTO webhook_deliveries ("+webhookDeliveryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		delivery.ID, delivery.EndpointID, delivery.EventID, delivery.EventType, delivery.UserID, []byte(delivery.Payload), delivery.Status, delivery.Attempts,
+		delivery.NextAttemptAt.UTC(), delivery.ResponseStatus, delivery.ResponseBody, delivery.LastError, delivery.CreatedAt.UTC(), delivery.UpdatedAt.UTC())
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return goat.ErrWebhookDeliveryExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDelivery implements goat.WebhookStore.
+func (s *MySQLWebhookStore) GetWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	delivery, err := scanWebhookDelivery(s.db.QueryRowContext(ctx, "SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, goat.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// UpdateWebhookDelivery implements goat.WebhookStore.
+func (s *MySQLWebhookStore) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?,
+		response_body = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), delivery.ResponseStatus,
+		delivery.ResponseBody, delivery.LastError, delivery.UpdatedAt.UTC(), delivery.ID)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only changed rows, so look the delivery up before reporting it missing.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.GetWebhookDelivery(delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListWebhookDeliveries implements goat.WebhookStore.
+func (s *MySQLWebhookStore) ListWebhookDeliveries(endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE endpoint_id = ? ORDER BY created_at DESC, id DESC LIMIT ?",
+		endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries implements goat.WebhookStore.
+func (s *MySQLWebhookStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE SKIP LOCKED",
+		models.WebhookPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	leaseEnd := now.Add(lease)
+	args := []interface{}{leaseEnd.UTC()}
+	for _, delivery := range deliveries {
+		delivery.NextAttemptAt = leaseEnd
+		args = append(args, delivery.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(deliveries)), ", ")
+	if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN ("+placeholders+")", args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// scanMySQLWebhookEndpoint reads a webhook_endpoints row, decoding its JSON list of events.
+func scanMySQLWebhookEndpoint(row rowScanner) (*models.WebhookEndpoint, error) {
+	endpoint := &models.WebhookEndpoint{}
+	var events []byte
+	if err := row.Scan(&endpoint.ID, &endpoint.TenantID, &endpoint.URL, &endpoint.Secret, &events, &endpoint.Active, &endpoint.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(events, &endpoint.Events); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteUserWebhookDeliveries implements goat.WebhookStore.
+func (s *MySQLWebhookStore) DeleteUserWebhookDeliveries(userID uint) (int, error) {
+	ctx := context.Background()
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
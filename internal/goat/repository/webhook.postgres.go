@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgreSQLWebhookStore stores webhook endpoints and deliveries in PostgreSQL tables; subscribed event types
+// are stored as a TEXT[] column. Several dispatchers can share it: claiming uses SKIP LOCKED.
+type PostgreSQLWebhookStore struct {
+	conn *pgx.Conn // PostgreSQL connection for database access.
+}
+
+// NewPostgreSQLWebhookStore initializes a new PostgreSQLWebhookStore with a given connection string and creates the
+// webhook_endpoints and webhook_deliveries tables.
+func NewPostgreSQLWebhookStore(connString string) (*PostgreSQLWebhookStore, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT[] NOT NULL,
+			active BOOLEAN NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id TEXT COLLATE "C" PRIMARY KEY,
+			endpoint_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			user_id BIGINT NOT NULL,
+			payload JSONB NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt_at TIMESTAMPTZ NOT NULL,
+			response_status INTEGER NOT NULL,
+			response_body TEXT NOT NULL,
+			last_error TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		"CREATE INDEX IF NOT EXISTS webhook_endpoints_tenant_id_idx ON webhook_endpoints (tenant_id, created_at)",
+		"CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at)",
+		"CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at)",
+		"CREATE INDEX IF NOT EXISTS webhook_deliveries_user_id_idx ON webhook_deliveries (user_id)",
+	} {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PostgreSQLWebhookStore{conn: conn}, nil
+}
+
+// CreateWebhookEndpoint implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "INSERT INTO webhook_endpoints (id, tenant_id, url, secret, events, active, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		endpoint.ID, endpoint.TenantID, endpoint.URL, endpoint.Secret, nonNil(endpoint.Events), endpoint.Active, endpoint.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetWebhookEndpoint implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) GetWebhookEndpoint(id string) (*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	endpoint := &models.WebhookEndpoint{}
+	err := s.conn.QueryRow(ctx, "SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_endpoints WHERE id = $1", id).
+		Scan(&endpoint.ID, &endpoint.TenantID, &endpoint.URL, &endpoint.Secret, &endpoint.Events, &endpoint.Active, &endpoint.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// ListWebhookEndpoints implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) ListWebhookEndpoints(tenantID string) ([]*models.WebhookEndpoint, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_endpoints WHERE tenant_id = $1 ORDER BY created_at, id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint := &models.WebhookEndpoint{}
+		if err := rows.Scan(&endpoint.ID, &endpoint.TenantID, &endpoint.URL, &endpoint.Secret, &endpoint.Events, &endpoint.Active, &endpoint.CreatedAt); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// UpdateWebhookEndpoint implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "UPDATE webhook_endpoints SET url = $1, secret = $2, events = $3, active = $4 WHERE id = $5",
+		endpoint.URL, endpoint.Secret, nonNil(endpoint.Events), endpoint.Active, endpoint.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) DeleteWebhookEndpoint(id string) error {
+	ctx := context.Background()
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM webhook_endpoints WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrWebhookNotFound
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM webhook_deliveries WHERE endpoint_id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CreateWebhookDelivery implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	_, err := s.conn.Exec(ctx, "INSERT INTO webhook_deliveries ("+webhookDeliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		delivery.ID, delivery.EndpointID, delivery.EventID, delivery.EventType, delivery.UserID, []byte(delivery.Payload), delivery.Status, delivery.Attempts,
+		delivery.NextAttemptAt, delivery.ResponseStatus, delivery.ResponseBody, delivery.LastError, delivery.CreatedAt, delivery.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation {
+			return goat.ErrWebhookDeliveryExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDelivery implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) GetWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	delivery, err := scanWebhookDelivery(s.conn.QueryRow(ctx, "SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, goat.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// UpdateWebhookDelivery implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, response_status = $4,
+		response_body = $5, last_error = $6, updated_at = $7 WHERE id = $8`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.ResponseStatus,
+		delivery.ResponseBody, delivery.LastError, delivery.UpdatedAt, delivery.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return goat.ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeliveries implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) ListWebhookDeliveries(endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, "SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE endpoint_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ctx := context.Background()
+	rows, err := s.conn.Query(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN (
+		SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= $3 ORDER BY next_attempt_at, id LIMIT $4 FOR UPDATE SKIP LOCKED
+	) RETURNING `+webhookDeliveryColumns, now.Add(lease), models.WebhookPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING has no order of its own.
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+// DeleteUserWebhookDeliveries implements goat.WebhookStore.
+func (s *PostgreSQLWebhookStore) DeleteUserWebhookDeliveries(userID uint) (int, error) {
+	ctx := context.Background()
+	tag, err := s.conn.Exec(ctx, "DELETE FROM webhook_deliveries WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
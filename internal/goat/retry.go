@@ -0,0 +1,19 @@
+package goat
+
+import "time"
+
+// minRetryDelay is how long a background task waits after its first failure.
+const minRetryDelay = time.Second
+
+// RetryDelay returns how long a background task that has failed failures times in a row waits before
+// trying again: a second after the first failure, doubled for each further one, up to max.
+func RetryDelay(failures int, max time.Duration) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+var (
+	_ goat.AccountService[*models.User] = (*MysqlServiceImpl[*models.User])(nil)
+	_ goat.AccountService[*models.User] = (*MongoServiceImpl[*models.User])(nil)
+	_ goat.AccountService[*models.User] = (*PostgresServiceImpl[*models.User])(nil)
+)
+
+// accountRepository is the part of a user repository that manages account states.
+type accountRepository[U models.Account] interface {
+	Tenant() string
+	GetUserByID(id uint) (U, error)
+	GetDeletedUser(id uint) (U, error)
+	SetUserStatus(id uint, status string) error
+	RestoreUser(id uint) error
+	AnonymizeUser(id uint) error
+	EraseUser(id uint) error
+}
+
+// setStatus gives the user with id status through repo, between the hooks of typ.
+func (h hooks[U]) setStatus(repo accountRepository[U], typ models.UserEventType, id uint, status string) error {
+	return h.transition(repo.Tenant(), typ, id, repo.GetUserByID, func(id uint) error {
+		return repo.SetUserStatus(id, status)
+	})
+}
+
+// restore makes the soft-deleted user with id active again through repo, between the UserRestored hooks.
+func (h hooks[U]) restore(repo accountRepository[U], id uint) error {
+	return h.transition(repo.Tenant(), models.UserRestored, id, repo.GetDeletedUser, repo.RestoreUser)
+}
+
+// anonymize replaces the personal data of the user with id through repo, between the
+// UserAnonymized hooks.
+func (h hooks[U]) anonymize(repo accountRepository[U], id uint) error {
+	return h.transition(repo.Tenant(), models.UserAnonymized, id, anyUser(repo), repo.AnonymizeUser)
+}
+
+// erase removes the user with id through repo, between the UserErased hooks.
+func (h hooks[U]) erase(repo accountRepository[U], id uint) error {
+	return h.transition(repo.Tenant(), models.UserErased, id, anyUser(repo), repo.EraseUser)
+}
+
+// anyUser returns a function that loads a user through repo whether they are soft-deleted or not.
+func anyUser[U models.Account](repo accountRepository[U]) func(uint) (U, error) {
+	return func(id uint) (U, error) {
+		user, err := repo.GetUserByID(id)
+		if errors.Is(err, goat.ErrUserNotFound) {
+			return repo.GetDeletedUser(id)
+		}
+		return user, err
+	}
+}
+
+// UserRecords are the stores holding records about users that PurgeDeletedUsers removes with them.
+// Any of them can be nil, for features the application does not use.
+type UserRecords struct {
+	Identities    goat.IdentityStore
+	Sessions      goat.SessionStore
+	APIKeys       goat.APIKeyStore
+	Organizations goat.OrganizationStore
+}
+
+// purger holds the stores PurgeDeletedUsers removes user records from. It is embedded in the user
+// services, which share it with the services their ForTenant returns.
+type purger struct {
+	records *UserRecords
+}
+
+// newPurger creates a purger without stores.
+func newPurger() purger {
+	return purger{records: &UserRecords{}}
+}
+
+// SetUserRecords makes PurgeDeletedUsers remove the records of records along with each user.
+func (p purger) SetUserRecords(records UserRecords) {
+	*p.records = records
+}
+
+// purge removes the sessions, API keys, identities and organization memberships of the user with
+// id of tenantID. Organizations the user owned alone are deleted; the others pass to their
+// longest-standing admin, or member if there is none.
+func (p purger) purge(tenantID string, id uint) error {
+	records := *p.records
+	if s := records.Sessions; s != nil {
+		if err := s.ForTenant(tenantID).DeleteByUser(id, ""); err != nil {
+			return err
+		}
+	}
+	if s := records.APIKeys; s != nil {
+		s = s.ForTenant(tenantID)
+		keys, err := s.ListAPIKeys(id)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := s.DeleteAPIKey(key.ID); err != nil && !errors.Is(err, goat.ErrAPIKeyNotFound) {
+				return err
+			}
+		}
+	}
+	if s := records.Identities; s != nil {
+		s = s.ForTenant(tenantID)
+		identities, err := s.ListIdentities(id)
+		if err != nil {
+			return err
+		}
+		for _, identity := range identities {
+			if err := s.DeleteIdentity(identity.Provider, identity.Subject); err != nil && !errors.Is(err, goat.ErrIdentityNotFound) {
+				return err
+			}
+		}
+	}
+	if s := records.Organizations; s != nil {
+		s = s.ForTenant(tenantID)
+		orgs, err := s.ListOrganizations(id)
+		if err != nil {
+			return err
+		}
+		for _, org := range orgs {
+			if org.OwnerID == id {
+				successor, err := successor(s, org.ID, id)
+				if err != nil {
+					return err
+				}
+				if successor == 0 {
+					if err := s.DeleteOrganization(org.ID); err != nil && !errors.Is(err, goat.ErrOrganizationNotFound) {
+						return err
+					}
+					continue
+				}
+				if err := s.TransferOwnership(org.ID, id, successor); err != nil {
+					return err
+				}
+			}
+			if err := s.DeleteMembership(org.ID, id); err != nil && !errors.Is(err, goat.ErrMembershipNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// successor returns the member of the organization with orgID that takes it over from its owner:
+// the longest-standing admin, or member if there is none. It returns 0 if the owner is alone.
+func successor(store goat.OrganizationStore, orgID string, ownerID uint) (uint, error) {
+	members, err := store.ListMembers(orgID)
+	if err != nil {
+		return 0, err
+	}
+	var next uint
+	for _, m := range members {
+		switch {
+		case m.UserID == ownerID:
+		case m.Role == models.RoleAdmin:
+			return m.UserID, nil
+		case next == 0:
+			next = m.UserID
+		}
+	}
+	return next, nil
+}
+
+// RunPurge removes the users whose retention period is over with accounts.PurgeDeletedUsers, every
+// interval, until ctx is cancelled. Failures are reported to onError, if not nil, and the purge is
+// retried sooner, backing off up to interval.
+func RunPurge[U models.Account](ctx context.Context, accounts goat.AccountService[U], interval time.Duration, onError func(error)) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := accounts.PurgeDeletedUsers(); err != nil {
+				failures++
+				if onError != nil {
+					onError(err)
+				}
+				timer.Reset(goat.RetryDelay(failures, interval))
+				continue
+			}
+			failures = 0
+			timer.Reset(interval)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+func TestPurge(t *testing.T) {
+	const purged, other uint = 1, 2
+	joined := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		members   []models.Membership // Members of the organization, besides the purged user who owns it.
+		wantOwner uint                // Owner of the organization after the purge; 0 if it is deleted.
+	}{
+		{"owner alone", nil, 0},
+		{"to the only member", []models.Membership{{UserID: 3, Role: models.RoleMember}}, 3},
+		{"to the longest-standing member", []models.Membership{{UserID: 4, Role: models.RoleMember}, {UserID: 3, Role: models.RoleMember}}, 4},
+		{"to an admin before members", []models.Membership{{UserID: 3, Role: models.RoleMember}, {UserID: 5, Role: models.RoleAdmin}}, 5},
+		{"to the longest-standing admin", []models.Membership{{UserID: 5, Role: models.RoleAdmin}, {UserID: 6, Role: models.RoleAdmin}}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := repository.NewMemorySessionStore()
+			keys := repository.NewMemoryAPIKeyStore()
+			identities := repository.NewMemoryIdentityStore()
+			orgs := repository.NewMemoryOrganizationStore()
+			p := newPurger()
+			p.SetUserRecords(UserRecords{Identities: identities, Sessions: sessions, APIKeys: keys, Organizations: orgs})
+
+			acme := orgs.ForTenant("acme")
+			for _, user := range []uint{purged, other} {
+				if err := sessions.ForTenant("acme").Create(&models.Session{ID: fmt.Sprint("s", user), UserID: user, ExpiresAt: joined.Add(time.Hour)}); err != nil {
+					t.Fatal(err)
+				}
+				if err := keys.ForTenant("acme").CreateAPIKey(&models.APIKey{ID: fmt.Sprint("goat_", user), UserID: user}); err != nil {
+					t.Fatal(err)
+				}
+				if err := identities.ForTenant("acme").CreateIdentity(&models.Identity{UserID: user, Provider: "google", Subject: fmt.Sprint(user)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The purged user is a member of an organization of another user, and owns one.
+			if err := acme.CreateOrganization(&models.Organization{ID: "joined", OwnerID: other}); err != nil {
+				t.Fatal(err)
+			}
+			if err := acme.CreateOrganization(&models.Organization{ID: "owned", OwnerID: purged}); err != nil {
+				t.Fatal(err)
+			}
+			memberships := []*models.Membership{
+				{OrganizationID: "joined", UserID: other, Role: models.RoleOwner, JoinedAt: joined},
+				{OrganizationID: "joined", UserID: purged, Role: models.RoleAdmin, JoinedAt: joined.Add(time.Minute)},
+				{OrganizationID: "owned", UserID: purged, Role: models.RoleOwner, JoinedAt: joined},
+			}
+			for i, m := range tt.members {
+				m := m
+				m.OrganizationID = "owned"
+				m.JoinedAt = joined.Add(time.Duration(i+1) * time.Minute)
+				memberships = append(memberships, &m)
+			}
+			for _, m := range memberships {
+				if err := acme.SaveMembership(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// A user of the same ID in another tenant is left alone.
+			if err := sessions.Create(&models.Session{ID: "s0", UserID: purged, ExpiresAt: joined.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.purge("acme", purged); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range []struct {
+				user uint
+				want int
+			}{{purged, 0}, {other, 1}} {
+				s, err := sessions.ForTenant("acme").ListByUser(c.user)
+				if err != nil || len(s) != c.want {
+					t.Errorf("sessions of user %d = %d, %v, want %d", c.user, len(s), err, c.want)
+				}
+				k, err := keys.ForTenant("acme").ListAPIKeys(c.user)
+				if err != nil || len(k) != c.want {
+					t.Errorf("API keys of user %d = %d, %v, want %d", c.user, len(k), err, c.want)
+				}
+				i, err := identities.ForTenant("acme").ListIdentities(c.user)
+				if err != nil || len(i) != c.want {
+					t.Errorf("identities of user %d = %d, %v, want %d", c.user, len(i), err, c.want)
+				}
+			}
+			if s, err := sessions.ListByUser(purged); err != nil || len(s) != 1 {
+				t.Errorf("sessions of the user of the default tenant = %d, %v, want 1", len(s), err)
+			}
+			if _, err := acme.GetMembership("joined", purged); !errors.Is(err, goat.ErrMembershipNotFound) {
+				t.Errorf("membership of another user's organization: error = %v, want %v", err, goat.ErrMembershipNotFound)
+			}
+
+			org, err := acme.GetOrganization("owned")
+			if tt.wantOwner == 0 {
+				if !errors.Is(err, goat.ErrOrganizationNotFound) {
+					t.Errorf("organization owned alone = %+v, %v, want it deleted", org, err)
+				}
+				return
+			}
+			if err != nil || org.OwnerID != tt.wantOwner {
+				t.Fatalf("organization = %+v, %v, want owner %d", org, err, tt.wantOwner)
+			}
+			if m, err := acme.GetMembership("owned", tt.wantOwner); err != nil || m.Role != models.RoleOwner {
+				t.Errorf("new owner's membership = %+v, %v, want %q", m, err, models.RoleOwner)
+			}
+			if _, err := acme.GetMembership("owned", purged); !errors.Is(err, goat.ErrMembershipNotFound) {
+				t.Errorf("purged owner's membership: error = %v, want %v", err, goat.ErrMembershipNotFound)
+			}
+		})
+	}
+
+	// Without stores, there is nothing to remove.
+	if err := newPurger().purge("acme", purged); err != nil {
+		t.Errorf("purge without stores error = %v", err)
+	}
+}
+
+// purgeCounter is a goat.AccountService whose PurgeDeletedUsers fails the first failures times and
+// reports every call on ran.
+type purgeCounter struct {
+	goat.AccountService[*models.User]
+	failures int
+	calls    int
+	ran      chan struct{}
+}
+
+func (p *purgeCounter) PurgeDeletedUsers() (int, error) {
+	p.calls++
+	p.ran <- struct{}{}
+	if p.calls <= p.failures {
+		return 0, errors.New("store unavailable")
+	}
+	return 0, nil
+}
+
+func TestRunPurge(t *testing.T) {
+	accounts := &purgeCounter{failures: 2, ran: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	reported := 0
+	done := make(chan error)
+	go func() {
+		done <- RunPurge[*models.User](ctx, accounts, 10*time.Millisecond, func(error) { reported++ })
+	}()
+
+	// The purge runs again after failing, and keeps running once it succeeds.
+	for i := 1; i <= 4; i++ {
+		select {
+		case <-accounts.ran:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("purge %d did not run", i)
+		}
+	}
+	cancel()
+	for {
+		select {
+		case <-accounts.ran:
+			continue
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("RunPurge error = %v, want %v", err, context.Canceled)
+			}
+		}
+		break
+	}
+	if reported != accounts.failures {
+		t.Errorf("%d failures reported, want %d", reported, accounts.failures)
+	}
+}
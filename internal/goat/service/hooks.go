@@ -0,0 +1,124 @@
+package service
+
+import (
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/events"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// hooks emits the lifecycle events of a user service. It is embedded in the user services, which
+// share it with the services their ForTenant returns, and implements goat.UserHooks for them.
+type hooks[U models.Account] struct {
+	bus    *events.Bus[U]
+	tokens *tokenRevocation
+}
+
+// tokenRevocation holds the store in which a password reset revokes the user's access tokens.
+type tokenRevocation struct {
+	revocations goat.RevocationStore
+}
+
+var (
+	_ goat.UserHooks[*models.User] = (*MysqlServiceImpl[*models.User])(nil)
+	_ goat.UserHooks[*models.User] = (*MongoServiceImpl[*models.User])(nil)
+	_ goat.UserHooks[*models.User] = (*PostgresServiceImpl[*models.User])(nil)
+)
+
+// newHooks creates hooks with an empty bus.
+func newHooks[U models.Account]() hooks[U] {
+	return hooks[U]{bus: events.NewBus[U](), tokens: &tokenRevocation{}}
+}
+
+// SetRevocations makes ResetPassword revoke, in revocations, every access token issued to the user
+// before their password was reset, as jwt.Authenticator.RevokeAllTokens does. Without it, tokens
+// issued before a reset stay valid until they expire.
+func (h hooks[U]) SetRevocations(revocations goat.RevocationStore) {
+	h.tokens.revocations = revocations
+}
+
+// BeforeUserEvent implements goat.UserHooks.
+func (h hooks[U]) BeforeUserEvent(typ models.UserEventType, hook models.UserHook[U]) {
+	h.bus.Before(typ, hook)
+}
+
+// AfterUserEvent implements goat.UserHooks.
+func (h hooks[U]) AfterUserEvent(typ models.UserEventType, hook models.UserHook[U]) {
+	h.bus.After(typ, hook)
+}
+
+// Events returns the service's event bus, to report failing after-hooks with OnError or to Wait for
+// running ones.
+func (h hooks[U]) Events() *events.Bus[U] {
+	return h.bus
+}
+
+// register saves a new user with save, between the UserRegistered hooks.
+func (h hooks[U]) register(tenantID string, user U, save func(U) error) error {
+	event := &models.UserEvent[U]{Type: models.UserRegistered, User: user, TenantID: tenantID, OccurredAt: time.Now()}
+	return h.emit(event, func() error { return save(user) })
+}
+
+// update saves user with save, between the UserUpdated hooks. The stored user is loaded first, as
+// the event's Previous, when any hook is listening.
+func (h hooks[U]) update(tenantID string, user U, load func(uint) (U, error), save func(U) error) error {
+	event := &models.UserEvent[U]{Type: models.UserUpdated, User: user, TenantID: tenantID, OccurredAt: time.Now()}
+	if h.bus.Handles(event.Type) {
+		previous, err := load(user.GetUser().ID)
+		if err != nil {
+			return err
+		}
+		event.Previous = previous
+	}
+	return h.emit(event, func() error { return save(user) })
+}
+
+// transition changes the state of the user with id with save, between the hooks of typ: deletes
+// them for UserDeleted, or suspends, deactivates, reactivates, restores, anonymizes or erases them.
+// The user is loaded first, for the event, when any hook is listening.
+func (h hooks[U]) transition(tenantID string, typ models.UserEventType, id uint, load func(uint) (U, error), save func(uint) error) error {
+	event := &models.UserEvent[U]{Type: typ, TenantID: tenantID, OccurredAt: time.Now()}
+	if h.bus.Handles(event.Type) {
+		user, err := load(id)
+		if err != nil {
+			return err
+		}
+		event.User = user
+	}
+	return h.emit(event, func() error { return save(id) })
+}
+
+// resetPassword sets the password of the user with email with save, between the UserPasswordReset
+// hooks, then revokes the user's tokens if SetRevocations was called. The user is loaded first, for
+// the event and the revocation, when any hook is listening or tokens are revoked.
+func (h hooks[U]) resetPassword(tenantID, email, password string, load func(string) (U, error), save func(string, string) error) error {
+	event := &models.UserEvent[U]{Type: models.UserPasswordReset, TenantID: tenantID, OccurredAt: time.Now()}
+	revocations := h.tokens.revocations
+	if h.bus.Handles(event.Type) || revocations != nil {
+		user, err := load(email)
+		if err != nil {
+			return err
+		}
+		event.User = user
+	}
+	if err := h.emit(event, func() error { return save(email, password) }); err != nil {
+		return err
+	}
+	if revocations != nil {
+		return revocations.ForTenant(tenantID).RevokeUserTokens(event.User.GetUser().ID, time.Now())
+	}
+	return nil
+}
+
+// emit runs the before-hooks of event, then save, and once save succeeds, the after-hooks.
+func (h hooks[U]) emit(event *models.UserEvent[U], save func() error) error {
+	if err := h.bus.Check(event); err != nil {
+		return err
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	h.bus.Publish(event)
+	return nil
+}
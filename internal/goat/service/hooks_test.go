@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+func TestResetPasswordRevokesTokens(t *testing.T) {
+	user := &models.User{ID: 3, Email: "ada@example.com"}
+	load := func(email string) (*models.User, error) {
+		if email != user.Email {
+			return nil, goat.ErrUserNotFound
+		}
+		return user, nil
+	}
+	saveErr := errors.New("store unavailable")
+
+	tests := []struct {
+		name      string
+		tenantID  string
+		email     string
+		revoke    bool
+		save      error
+		wantErr   error
+		wantMoved bool
+	}{
+		{"revokes after the reset", "", user.Email, true, nil, nil, true},
+		{"revokes in the user's tenant", "acme", user.Email, true, nil, nil, true},
+		{"keeps tokens without a revocation store", "", user.Email, false, nil, nil, false},
+		{"keeps tokens when the reset fails", "", user.Email, true, saveErr, saveErr, false},
+		{"reports a missing user", "", "bob@example.com", true, nil, goat.ErrUserNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations := repository.NewMemoryRevocationStore()
+			h := newHooks[*models.User]()
+			if tt.revoke {
+				h.SetRevocations(revocations)
+			}
+			start := time.Now()
+			err := h.resetPassword(tt.tenantID, tt.email, "new password", load, func(string, string) error { return tt.save })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resetPassword error = %v, want %v", err, tt.wantErr)
+			}
+
+			validAfter, err := revocations.ForTenant(tt.tenantID).TokensValidAfter(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved := !validAfter.Before(start); moved != tt.wantMoved {
+				t.Errorf("watermark = %v, moved = %t, want %t", validAfter, moved, tt.wantMoved)
+			}
+		})
+	}
+}
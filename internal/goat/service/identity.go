@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+// IdentityServiceImpl manages the external identities linked to the users of a goat.UserService.
+// Users must keep at least one way to sign in: a usable password or a linked identity.
+type IdentityServiceImpl[U models.Account] struct {
+	users      goat.UserService[U]
+	identities goat.IdentityStore
+}
+
+// NewIdentityService creates an IdentityServiceImpl for the users of users, keeping links in identities.
+func NewIdentityService[U models.Account](users goat.UserService[U], identities goat.IdentityStore) goat.IdentityService[U] {
+	return &IdentityServiceImpl[U]{users: users, identities: identities}
+}
+
+// ForTenant implements goat.IdentityService. Both the users and the links are scoped, so the same
+// external account can be linked to a user in each tenant.
+func (s *IdentityServiceImpl[U]) ForTenant(tenantID string) goat.IdentityService[U] {
+	return &IdentityServiceImpl[U]{users: s.users.ForTenant(tenantID), identities: s.identities.ForTenant(tenantID)}
+}
+
+// LinkIdentity implements goat.IdentityService. Linking an identity the user already has is a no-op.
+// An identity linked to a user who no longer exists, even as a soft-deleted user, is linked anew.
+func (s *IdentityServiceImpl[U]) LinkIdentity(userID uint, identity *models.Identity) error {
+	if _, err := s.users.GetUserByID(userID); err != nil {
+		return err
+	}
+
+	existing, err := s.identities.GetIdentity(identity.Provider, identity.Subject)
+	switch {
+	case err == nil && existing.UserID == userID:
+		return nil
+	case err == nil:
+		// An identity left behind by a user who is gone is replaced.
+		dangling, err := s.dangling(existing)
+		if err != nil {
+			return err
+		}
+		if !dangling {
+			return goat.ErrIdentityTaken
+		}
+		if err := s.identities.DeleteIdentity(existing.Provider, existing.Subject); err != nil && !errors.Is(err, goat.ErrIdentityNotFound) {
+			return err
+		}
+	case !errors.Is(err, goat.ErrIdentityNotFound):
+		return err
+	}
+
+	identity.UserID = userID
+	if identity.LinkedAt.IsZero() {
+		identity.LinkedAt = time.Now()
+	}
+	if err := s.identities.CreateIdentity(identity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnlinkIdentity implements goat.IdentityService. It refuses with goat.ErrLastLoginMethod when the
+// identity is the user's only way to sign in.
+func (s *IdentityServiceImpl[U]) UnlinkIdentity(userID uint, provider, subject string) error {
+	identity, err := s.identities.GetIdentity(provider, subject)
+	if err != nil {
+		return err
+	}
+	if identity.UserID != userID {
+		return goat.ErrIdentityNotFound
+	}
+
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.GetUser().HasUsablePassword() {
+		identities, err := s.identities.ListIdentities(userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return goat.ErrLastLoginMethod
+		}
+	}
+
+	if err := s.identities.DeleteIdentity(provider, subject); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListIdentities implements goat.IdentityService.
+func (s *IdentityServiceImpl[U]) ListIdentities(userID uint) ([]*models.Identity, error) {
+	identities, err := s.identities.ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// GetUserByIdentity implements goat.IdentityService. An identity whose user is deleted is reported
+// as goat.ErrIdentityNotFound.
+func (s *IdentityServiceImpl[U]) GetUserByIdentity(provider, subject string) (U, error) {
+	identity, err := s.identities.GetIdentity(provider, subject)
+	if err != nil {
+		var zero U
+		return zero, err
+	}
+
+	user, err := s.users.GetUserByID(identity.UserID)
+	if err != nil {
+		var zero U
+		if errors.Is(err, goat.ErrUserNotFound) {
+			// The user was deleted; their identities no longer sign anyone in.
+			return zero, goat.ErrIdentityNotFound
+		}
+		return zero, err
+	}
+
+	return user, nil
+}
+
+// dangling reports whether the user identity is linked to is gone for good: not found, and not
+// soft-deleted either, when the user service can tell.
+func (s *IdentityServiceImpl[U]) dangling(identity *models.Identity) (bool, error) {
+	_, err := s.users.GetUserByID(identity.UserID)
+	if !errors.Is(err, goat.ErrUserNotFound) {
+		return false, err
+	}
+	if accounts, ok := s.users.(goat.AccountService[U]); ok {
+		_, err := accounts.GetDeletedUser(identity.UserID)
+		if !errors.Is(err, goat.ErrUserNotFound) {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/utils"
+	"github.com/google/uuid"
+)
+
+// DefaultInvitationTTL is how long invitations can be accepted when NewOrganizationService is given no TTL.
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// OrganizationServiceImpl manages organizations kept in a goat.OrganizationStore. Owners and admins
+// manage members and invitations; only the owner can delete the organization or hand it over.
+// Organizations and their members belong to one tenant; see ForTenant.
+type OrganizationServiceImpl[U models.Account] struct {
+	store         goat.OrganizationStore
+	users         goat.UserService[U]
+	invitationTTL time.Duration
+	now           func() time.Time
+}
+
+// NewOrganizationService creates an OrganizationServiceImpl keeping organizations in store, whose
+// members must be users in users. Invitations expire after invitationTTL, or DefaultInvitationTTL if
+// it is zero.
+func NewOrganizationService[U models.Account](store goat.OrganizationStore, users goat.UserService[U], invitationTTL time.Duration) goat.OrganizationService[U] {
+	if invitationTTL <= 0 {
+		invitationTTL = DefaultInvitationTTL
+	}
+	return &OrganizationServiceImpl[U]{store: store, users: users, invitationTTL: invitationTTL, now: time.Now}
+}
+
+// ForTenant implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) ForTenant(tenantID string) goat.OrganizationService[U] {
+	scoped := *s
+	scoped.store = s.store.ForTenant(tenantID)
+	scoped.users = s.users.ForTenant(tenantID)
+	return &scoped
+}
+
+// CreateOrganization implements goat.OrganizationService. The creator becomes the owner.
+func (s *OrganizationServiceImpl[U]) CreateOrganization(ownerID uint, name string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		errs := &goat.ValidationError{}
+		errs.Add("name", goat.CodeRequired, "name is required", nil)
+		return nil, errs.Err()
+	}
+	if _, err := s.users.GetUserByID(ownerID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	org := &models.Organization{ID: uuid.NewString(), Name: name, OwnerID: ownerID, CreatedAt: now}
+	if err := s.store.CreateOrganization(org); err != nil {
+		return nil, err
+	}
+	err := s.store.SaveMembership(&models.Membership{OrganizationID: org.ID, UserID: ownerID, Role: models.RoleOwner, JoinedAt: now})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// GetOrganization implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) GetOrganization(userID uint, orgID string) (*models.Organization, error) {
+	if _, err := s.member(orgID, userID); err != nil {
+		return nil, err
+	}
+	org, err := s.store.GetOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// ListOrganizations implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) ListOrganizations(userID uint) ([]*models.Organization, error) {
+	orgs, err := s.store.ListOrganizations(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// DeleteOrganization implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) DeleteOrganization(actorID uint, orgID string) error {
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != models.RoleOwner {
+		return goat.ErrPermissionDenied
+	}
+	if err := s.store.DeleteOrganization(orgID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListMembers implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) ListMembers(userID uint, orgID string) ([]*models.Membership, error) {
+	if _, err := s.member(orgID, userID); err != nil {
+		return nil, err
+	}
+	members, err := s.store.ListMembers(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// UpdateMemberRole implements goat.OrganizationService. The owner role changes hands only through
+// TransferOwnership.
+func (s *OrganizationServiceImpl[U]) UpdateMemberRole(actorID uint, orgID string, userID uint, role string) error {
+	if !models.ValidRole(role) {
+		return goat.ErrInvalidRole
+	}
+	if role == models.RoleOwner {
+		return goat.ErrOwnerRequired
+	}
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return err
+	}
+	membership, err := s.store.GetMembership(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.RoleOwner {
+		return goat.ErrOwnerRequired
+	}
+	if !actor.Manages(membership.Role) || !actor.Manages(role) {
+		return goat.ErrPermissionDenied
+	}
+
+	membership.Role = role
+	if err := s.store.SaveMembership(membership); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveMember implements goat.OrganizationService. The owner cannot be removed, nor leave, until
+// they have transferred ownership.
+func (s *OrganizationServiceImpl[U]) RemoveMember(actorID uint, orgID string, userID uint) error {
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return err
+	}
+	membership, err := s.store.GetMembership(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.RoleOwner {
+		return goat.ErrOwnerRequired
+	}
+	if actorID != userID && !actor.Manages(membership.Role) {
+		return goat.ErrPermissionDenied
+	}
+
+	if err := s.store.DeleteMembership(orgID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TransferOwnership implements goat.OrganizationService. The new owner must already be a member;
+// the previous owner stays on as an admin. The store makes both changes at once, so the
+// organization never has two owners or none.
+func (s *OrganizationServiceImpl[U]) TransferOwnership(actorID uint, orgID string, newOwnerID uint) error {
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != models.RoleOwner {
+		return goat.ErrPermissionDenied
+	}
+	if newOwnerID == actorID {
+		return nil
+	}
+	if _, err := s.users.GetUserByID(newOwnerID); err != nil {
+		return err
+	}
+	if err := s.store.TransferOwnership(orgID, actorID, newOwnerID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InviteMember implements goat.OrganizationService. The token is sent to email out of band, usually
+// as part of a link, and only a hash of it is stored.
+func (s *OrganizationServiceImpl[U]) InviteMember(actorID uint, orgID, email, role string) (*models.Invitation, string, error) {
+	if !models.ValidRole(role) {
+		return nil, "", goat.ErrInvalidRole
+	}
+	if role == models.RoleOwner {
+		return nil, "", goat.ErrOwnerRequired
+	}
+	email = utils.DefaultEmailPolicy.NormalizeEmail(email)
+	if err := utils.DefaultEmailPolicy.ValidateEmail(email); err != nil {
+		return nil, "", err
+	}
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !actor.Manages(role) {
+		return nil, "", goat.ErrPermissionDenied
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := s.now()
+	invitation := &models.Invitation{
+		ID:             hashInvitationToken(token),
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      actorID,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.invitationTTL),
+	}
+	if err := s.store.CreateInvitation(invitation); err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// ListInvitations implements goat.OrganizationService. Only owners and admins can see invitations.
+func (s *OrganizationServiceImpl[U]) ListInvitations(actorID uint, orgID string) ([]*models.Invitation, error) {
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Manages(models.RoleMember) {
+		return nil, goat.ErrPermissionDenied
+	}
+	invitations, err := s.store.ListInvitations(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) RevokeInvitation(actorID uint, orgID, invitationID string) error {
+	invitation, err := s.store.GetInvitation(invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation.OrganizationID != orgID {
+		return goat.ErrInvitationNotFound
+	}
+	actor, err := s.member(orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.Manages(invitation.Role) {
+		return goat.ErrPermissionDenied
+	}
+	if err := s.store.DeleteInvitation(invitationID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AcceptInvitation implements goat.OrganizationService. The invitation is used up, whether or not the
+// user was already a member.
+func (s *OrganizationServiceImpl[U]) AcceptInvitation(user U, token string) (*models.Membership, error) {
+	userID := user.GetUser().ID
+	if _, err := s.users.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+	invitation, err := s.invitation(user, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.DeleteInvitation(invitation.ID); err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.GetMembership(invitation.OrganizationID, userID)
+	switch {
+	case err == nil:
+		return nil, goat.ErrAlreadyMember
+	case !errors.Is(err, goat.ErrMembershipNotFound):
+		return nil, err
+	}
+
+	membership := &models.Membership{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         userID,
+		Role:           invitation.Role,
+		JoinedAt:       s.now(),
+	}
+	if err := s.store.SaveMembership(membership); err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+// DeclineInvitation implements goat.OrganizationService.
+func (s *OrganizationServiceImpl[U]) DeclineInvitation(user U, token string) error {
+	invitation, err := s.invitation(user, token)
+	if err != nil && !errors.Is(err, goat.ErrInvitationExpired) {
+		return err
+	}
+	if err := s.store.DeleteInvitation(invitation.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// member returns userID's membership of orgID. Non-members are told the organization does not exist,
+// so they cannot probe for organization IDs.
+func (s *OrganizationServiceImpl[U]) member(orgID string, userID uint) (*models.Membership, error) {
+	membership, err := s.store.GetMembership(orgID, userID)
+	if err != nil {
+		if errors.Is(err, goat.ErrMembershipNotFound) {
+			return nil, goat.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// invitation looks up the invitation for token and checks that it was sent to user's email. An
+// expired invitation is returned along with goat.ErrInvitationExpired.
+func (s *OrganizationServiceImpl[U]) invitation(user U, token string) (*models.Invitation, error) {
+	invitation, err := s.store.GetInvitation(hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Email != utils.DefaultEmailPolicy.NormalizeEmail(user.GetUser().Email) {
+		return nil, goat.ErrInvitationNotFound
+	}
+	if invitation.Expired(s.now()) {
+		return invitation, goat.ErrInvitationExpired
+	}
+	return invitation, nil
+}
+
+// hashInvitationToken returns the hex-encoded SHA-256 of token, which is the ID of its invitation.
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+// Users of the organization newTestOrganization creates.
+const (
+	ownerID    uint = 1
+	adminID    uint = 2
+	memberID   uint = 3
+	outsiderID uint = 4 // A user who is not a member.
+	admin2ID   uint = 5
+)
+
+// orgUsers is a goat.UserService holding users 1 to 5.
+type orgUsers struct {
+	goat.UserService[*models.User]
+}
+
+func (u orgUsers) ForTenant(string) goat.UserService[*models.User] { return u }
+
+func (u orgUsers) GetUserByID(id uint) (*models.User, error) {
+	if id < ownerID || id > admin2ID {
+		return nil, goat.ErrUserNotFound
+	}
+	return &models.User{ID: id}, nil
+}
+
+// newTestOrganization returns a service and an organization owned by ownerID, with adminID and
+// admin2ID as admins and memberID as a member.
+func newTestOrganization(t *testing.T) (goat.OrganizationService[*models.User], goat.OrganizationStore, *models.Organization) {
+	t.Helper()
+	store := repository.NewMemoryOrganizationStore()
+	svc := NewOrganizationService[*models.User](store, orgUsers{}, 0)
+	org, err := svc.CreateOrganization(ownerID, "Acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, role := range map[uint]string{adminID: models.RoleAdmin, admin2ID: models.RoleAdmin, memberID: models.RoleMember} {
+		if err := store.SaveMembership(&models.Membership{OrganizationID: org.ID, UserID: id, Role: role, JoinedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc, store, org
+}
+
+// role returns the role of userID in org, or "" if they are not a member.
+func role(t *testing.T, store goat.OrganizationStore, orgID string, userID uint) string {
+	t.Helper()
+	m, err := store.GetMembership(orgID, userID)
+	if errors.Is(err, goat.ErrMembershipNotFound) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Role
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   uint
+		user    uint
+		role    string
+		wantErr error
+	}{
+		{"owner promotes a member", ownerID, memberID, models.RoleAdmin, nil},
+		{"owner demotes an admin", ownerID, adminID, models.RoleMember, nil},
+		{"admin promotes a member", adminID, memberID, models.RoleAdmin, nil},
+		{"admin demotes another admin", adminID, admin2ID, models.RoleMember, nil},
+		{"member promotes themselves", memberID, memberID, models.RoleAdmin, goat.ErrPermissionDenied},
+		{"member demotes an admin", memberID, adminID, models.RoleMember, goat.ErrPermissionDenied},
+		{"admin makes a member the owner", adminID, memberID, models.RoleOwner, goat.ErrOwnerRequired},
+		{"owner makes a member the owner", ownerID, memberID, models.RoleOwner, goat.ErrOwnerRequired},
+		{"admin demotes the owner", adminID, ownerID, models.RoleMember, goat.ErrOwnerRequired},
+		{"unknown role", ownerID, memberID, "superuser", goat.ErrInvalidRole},
+		{"outsider", outsiderID, memberID, models.RoleAdmin, goat.ErrOrganizationNotFound},
+		{"user who is not a member", ownerID, outsiderID, models.RoleAdmin, goat.ErrMembershipNotFound},
+	}
+	for _, tt := range tests {
+		svc, store, org := newTestOrganization(t)
+		before := role(t, store, org.ID, tt.user)
+		err := svc.UpdateMemberRole(tt.actor, org.ID, tt.user, tt.role)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: UpdateMemberRole error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		want := before
+		if tt.wantErr == nil {
+			want = tt.role
+		}
+		if got := role(t, store, org.ID, tt.user); got != want {
+			t.Errorf("%s: role = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   uint
+		user    uint
+		wantErr error
+	}{
+		{"admin removes a member", adminID, memberID, nil},
+		{"admin removes another admin", adminID, admin2ID, nil},
+		{"owner removes an admin", ownerID, adminID, nil},
+		{"member leaves", memberID, memberID, nil},
+		{"admin leaves", adminID, adminID, nil},
+		{"member removes an admin", memberID, adminID, goat.ErrPermissionDenied},
+		{"admin removes the owner", adminID, ownerID, goat.ErrOwnerRequired},
+		{"owner leaves", ownerID, ownerID, goat.ErrOwnerRequired},
+		{"outsider removes a member", outsiderID, memberID, goat.ErrOrganizationNotFound},
+	}
+	for _, tt := range tests {
+		svc, store, org := newTestOrganization(t)
+		err := svc.RemoveMember(tt.actor, org.ID, tt.user)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: RemoveMember error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if removed := role(t, store, org.ID, tt.user) == ""; removed != (tt.wantErr == nil) {
+			t.Errorf("%s: removed = %t, want %t", tt.name, removed, tt.wantErr == nil)
+		}
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	tests := []struct {
+		name      string
+		actor     uint
+		newOwner  uint
+		wantErr   error
+		wantOwner uint
+	}{
+		{"to an admin", ownerID, adminID, nil, adminID},
+		{"to a member", ownerID, memberID, nil, memberID},
+		{"to themselves", ownerID, ownerID, nil, ownerID},
+		{"by an admin", adminID, adminID, goat.ErrPermissionDenied, ownerID},
+		{"by a member", memberID, memberID, goat.ErrPermissionDenied, ownerID},
+		{"by an outsider", outsiderID, outsiderID, goat.ErrOrganizationNotFound, ownerID},
+		{"to a user who is not a member", ownerID, outsiderID, goat.ErrMembershipNotFound, ownerID},
+		{"to an unknown user", ownerID, 9, goat.ErrUserNotFound, ownerID},
+	}
+	for _, tt := range tests {
+		svc, store, org := newTestOrganization(t)
+		err := svc.TransferOwnership(tt.actor, org.ID, tt.newOwner)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: TransferOwnership error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		// The organization has exactly one owner, who is the one it records.
+		stored, err := store.GetOrganization(org.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.OwnerID != tt.wantOwner {
+			t.Errorf("%s: OwnerID = %d, want %d", tt.name, stored.OwnerID, tt.wantOwner)
+		}
+		members, err := store.ListMembers(org.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range members {
+			if isOwner := m.Role == models.RoleOwner; isOwner != (m.UserID == tt.wantOwner) {
+				t.Errorf("%s: user %d has role %q, want the owner to be %d only", tt.name, m.UserID, m.Role, tt.wantOwner)
+			}
+		}
+		// The previous owner stays on as an admin.
+		if tt.wantOwner != ownerID {
+			if got := role(t, store, org.ID, ownerID); got != models.RoleAdmin {
+				t.Errorf("%s: previous owner's role = %q, want %q", tt.name, got, models.RoleAdmin)
+			}
+		}
+	}
+
+	// Only the new owner can delete the organization.
+	svc, _, org := newTestOrganization(t)
+	if err := svc.TransferOwnership(ownerID, org.ID, adminID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteOrganization(ownerID, org.ID); !errors.Is(err, goat.ErrPermissionDenied) {
+		t.Errorf("DeleteOrganization by the previous owner error = %v, want %v", err, goat.ErrPermissionDenied)
+	}
+	if err := svc.DeleteOrganization(adminID, org.ID); err != nil {
+		t.Errorf("DeleteOrganization by the new owner error = %v", err)
+	}
+}
+
+func TestInviteMemberRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   uint
+		role    string
+		wantErr error
+	}{
+		{"owner invites an admin", ownerID, models.RoleAdmin, nil},
+		{"admin invites an admin", adminID, models.RoleAdmin, nil},
+		{"admin invites a member", adminID, models.RoleMember, nil},
+		{"member invites a member", memberID, models.RoleMember, goat.ErrPermissionDenied},
+		{"owner invites an owner", ownerID, models.RoleOwner, goat.ErrOwnerRequired},
+		{"outsider invites a member", outsiderID, models.RoleMember, goat.ErrOrganizationNotFound},
+	}
+	for _, tt := range tests {
+		svc, _, org := newTestOrganization(t)
+		_, token, err := svc.InviteMember(tt.actor, org.ID, "Bob@Example.com", tt.role)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: InviteMember error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+
+		// Only the invited address can accept, and it joins with the invited role.
+		if _, err := svc.AcceptInvitation(&models.User{ID: outsiderID, Email: "eve@example.com"}, token); !errors.Is(err, goat.ErrInvitationNotFound) {
+			t.Errorf("%s: AcceptInvitation by another address error = %v, want %v", tt.name, err, goat.ErrInvitationNotFound)
+		}
+		m, err := svc.AcceptInvitation(&models.User{ID: outsiderID, Email: "bob@example.com"}, token)
+		if err != nil || m.Role != tt.role {
+			t.Errorf("%s: AcceptInvitation = %+v, %v, want role %q", tt.name, m, err, tt.role)
+		}
+	}
+}
@@ -7,20 +7,22 @@ import (
 	"github.com/bontusss/goat/internal/goat/utils" // Add the correct import path for utils
 )
 
-type MongoServiceImpl struct {
-	mongoRepository repository.MongoDBUserRepository
+type MongoServiceImpl[U models.Account] struct {
+	mongoRepository repository.MongoDBUserRepository[U]
+	hooks[U]
+	purger
 }
 
-func NewMongoService(repo repository.MongoDBUserRepository) goat.UserService {
-	return &MongoServiceImpl{repo}
+func NewMongoService[U models.Account](repo repository.MongoDBUserRepository[U]) goat.UserService[U] {
+	return &MongoServiceImpl[U]{mongoRepository: repo, hooks: newHooks[U](), purger: newPurger()}
 }
 
-func (s *MongoServiceImpl) Register(user *models.User) error {
+func (s *MongoServiceImpl[U]) Register(user U) error {
 	// validate user data
-	if err := utils.ValidateUser(user); err != nil {
+	if err := utils.ValidateUser(user.GetUser()); err != nil {
 		return err
 	}
-	if err := s.mongoRepository.Register(user); err != nil {
+	if err := s.register(s.mongoRepository.Tenant(), user, s.mongoRepository.Register); err != nil {
 		return err
 	}
 
@@ -28,8 +30,8 @@ func (s *MongoServiceImpl) Register(user *models.User) error {
 }
 
 // DeleteUser implements goat.UserService.
-func (s *MongoServiceImpl) DeleteUser(id uint) error {
-	if err := s.mongoRepository.DeleteUser(id); err != nil {
+func (s *MongoServiceImpl[U]) DeleteUser(id uint) error {
+	if err := s.transition(s.mongoRepository.Tenant(), models.UserDeleted, id, s.mongoRepository.GetUserByID, s.mongoRepository.DeleteUser); err != nil {
 		return err
 	}
 
@@ -37,39 +39,113 @@ func (s *MongoServiceImpl) DeleteUser(id uint) error {
 }
 
 // GetUserByID implements goat.UserService.
-func (s *MongoServiceImpl) GetUserByID(id uint) (*models.User, error) {
+func (s *MongoServiceImpl[U]) GetUserByID(id uint) (U, error) {
 	user, err := s.mongoRepository.GetUserByID(id)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail implements goat.UserService.
+func (s *MongoServiceImpl[U]) GetUserByEmail(email string) (U, error) {
+	user, err := s.mongoRepository.GetUserByEmail(email)
+	if err != nil {
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // Login implements goat.UserService.
-func (s *MongoServiceImpl) Login(email string, password string) (*models.User, error) {
+func (s *MongoServiceImpl[U]) Login(email string, password string) (U, error) {
 	user, err := s.mongoRepository.Login(email, password)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // ResetPassword implements goat.UserService.
-func (s *MongoServiceImpl) ResetPassword(email string, newPassword string) error {
-	if err := s.mongoRepository.ResetPassword(email, newPassword); err != nil {
+func (s *MongoServiceImpl[U]) ResetPassword(email string, newPassword string) error {
+	if err := utils.ValidatePassword(email, newPassword); err != nil {
+		return err
+	}
+
+	if err := s.resetPassword(s.mongoRepository.Tenant(), email, newPassword, s.mongoRepository.GetUserByEmail, s.mongoRepository.ResetPassword); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// ListUsers implements goat.UserService.
+func (s *MongoServiceImpl[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	page, err := s.mongoRepository.ListUsers(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
 // UpdateUser implements goat.UserService.
-func (s *MongoServiceImpl) UpdateUser(user *models.User) error {
-	if err := s.mongoRepository.UpdateUser(user); err != nil {
+func (s *MongoServiceImpl[U]) UpdateUser(user U) error {
+	if err := s.update(s.mongoRepository.Tenant(), user, s.mongoRepository.GetUserByID, s.mongoRepository.UpdateUser); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ForTenant implements goat.UserService. The returned service cannot see or change the users
+// of any other tenant.
+func (m *MongoServiceImpl[U]) ForTenant(tenantID string) goat.UserService[U] {
+	return &MongoServiceImpl[U]{mongoRepository: *m.mongoRepository.ForTenant(tenantID), hooks: m.hooks, purger: m.purger}
+}
+
+// SuspendUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) SuspendUser(id uint) error {
+	return s.setStatus(&s.mongoRepository, models.UserSuspended, id, models.AccountSuspended)
+}
+
+// DeactivateUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) DeactivateUser(id uint) error {
+	return s.setStatus(&s.mongoRepository, models.UserDeactivated, id, models.AccountDeactivated)
+}
+
+// ReactivateUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) ReactivateUser(id uint) error {
+	return s.setStatus(&s.mongoRepository, models.UserReactivated, id, models.AccountActive)
+}
+
+// GetDeletedUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) GetDeletedUser(id uint) (U, error) {
+	return s.mongoRepository.GetDeletedUser(id)
+}
+
+// RestoreUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) RestoreUser(id uint) error {
+	return s.restore(&s.mongoRepository, id)
+}
+
+// AnonymizeUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) AnonymizeUser(id uint) error {
+	return s.anonymize(&s.mongoRepository, id)
+}
+
+// EraseUser implements goat.AccountService.
+func (s *MongoServiceImpl[U]) EraseUser(id uint) error {
+	return s.erase(&s.mongoRepository, id)
+}
+
+// PurgeDeletedUsers implements goat.AccountService. It purges the users of every tenant, whichever
+// tenant the service is for, with the records of the stores set by SetUserRecords.
+func (s *MongoServiceImpl[U]) PurgeDeletedUsers() (int, error) {
+	return s.mongoRepository.PurgeDeletedUsers(s.purge)
+}
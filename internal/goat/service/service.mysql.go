@@ -7,17 +7,19 @@ import (
 	"github.com/bontusss/goat/internal/goat/utils"
 )
 
-type MysqlServiceImpl struct {
-	MysqlRepository repository.MySQLUserRepository
+type MysqlServiceImpl[U models.Account] struct {
+	MysqlRepository repository.MySQLUserRepository[U]
+	hooks[U]
+	purger
 }
 
-func NewMysqlService(repo repository.MySQLUserRepository) goat.UserService {
-	return &MysqlServiceImpl{repo}
+func NewMysqlService[U models.Account](repo repository.MySQLUserRepository[U]) goat.UserService[U] {
+	return &MysqlServiceImpl[U]{MysqlRepository: repo, hooks: newHooks[U](), purger: newPurger()}
 }
 
 // DeleteUser implements goat.UserService.
-func (m *MysqlServiceImpl) DeleteUser(id uint) error {
-	if err := m.MysqlRepository.DeleteUser(id); err != nil {
+func (m *MysqlServiceImpl[U]) DeleteUser(id uint) error {
+	if err := m.transition(m.MysqlRepository.Tenant(), models.UserDeleted, id, m.MysqlRepository.GetUserByID, m.MysqlRepository.DeleteUser); err != nil {
 		return err
 	}
 
@@ -25,32 +27,45 @@ func (m *MysqlServiceImpl) DeleteUser(id uint) error {
 }
 
 // GetUserByID implements goat.UserService.
-func (m *MysqlServiceImpl) GetUserByID(id uint) (*models.User, error) {
+func (m *MysqlServiceImpl[U]) GetUserByID(id uint) (U, error) {
 	user, err := m.MysqlRepository.GetUserByID(id)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail implements goat.UserService.
+func (m *MysqlServiceImpl[U]) GetUserByEmail(email string) (U, error) {
+	user, err := m.MysqlRepository.GetUserByEmail(email)
+	if err != nil {
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // Login implements goat.UserService.
-func (m *MysqlServiceImpl) Login(email string, password string) (*models.User, error) {
+func (m *MysqlServiceImpl[U]) Login(email string, password string) (U, error) {
 	user, err := m.MysqlRepository.Login(email, password)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // Register implements goat.UserService.
-func (m *MysqlServiceImpl) Register(user *models.User) error {
-	if err := utils.ValidateUser(user); err != nil {
+func (m *MysqlServiceImpl[U]) Register(user U) error {
+	if err := utils.ValidateUser(user.GetUser()); err != nil {
 		return err
 	}
 
-	if err := m.MysqlRepository.Register(user); err != nil {
+	if err := m.register(m.MysqlRepository.Tenant(), user, m.MysqlRepository.Register); err != nil {
 		return err
 	}
 
@@ -58,19 +73,80 @@ func (m *MysqlServiceImpl) Register(user *models.User) error {
 }
 
 // ResetPassword implements goat.UserService.
-func (m *MysqlServiceImpl) ResetPassword(email string, newPassword string) error {
-	if err := m.MysqlRepository.ResetPassword(email, newPassword); err != nil {
+func (m *MysqlServiceImpl[U]) ResetPassword(email string, newPassword string) error {
+	if err := utils.ValidatePassword(email, newPassword); err != nil {
+		return err
+	}
+
+	if err := m.resetPassword(m.MysqlRepository.Tenant(), email, newPassword, m.MysqlRepository.GetUserByEmail, m.MysqlRepository.ResetPassword); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// ListUsers implements goat.UserService.
+func (m *MysqlServiceImpl[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	page, err := m.MysqlRepository.ListUsers(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
 // UpdateUser implements goat.UserService.
-func (m *MysqlServiceImpl) UpdateUser(user *models.User) error {
-	if err := m.MysqlRepository.UpdateUser(user); err != nil {
+func (m *MysqlServiceImpl[U]) UpdateUser(user U) error {
+	if err := m.update(m.MysqlRepository.Tenant(), user, m.MysqlRepository.GetUserByID, m.MysqlRepository.UpdateUser); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ForTenant implements goat.UserService. The returned service cannot see or change the users
+// of any other tenant.
+func (m *MysqlServiceImpl[U]) ForTenant(tenantID string) goat.UserService[U] {
+	return &MysqlServiceImpl[U]{MysqlRepository: *m.MysqlRepository.ForTenant(tenantID), hooks: m.hooks, purger: m.purger}
+}
+
+// SuspendUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) SuspendUser(id uint) error {
+	return m.setStatus(&m.MysqlRepository, models.UserSuspended, id, models.AccountSuspended)
+}
+
+// DeactivateUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) DeactivateUser(id uint) error {
+	return m.setStatus(&m.MysqlRepository, models.UserDeactivated, id, models.AccountDeactivated)
+}
+
+// ReactivateUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) ReactivateUser(id uint) error {
+	return m.setStatus(&m.MysqlRepository, models.UserReactivated, id, models.AccountActive)
+}
+
+// GetDeletedUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) GetDeletedUser(id uint) (U, error) {
+	return m.MysqlRepository.GetDeletedUser(id)
+}
+
+// RestoreUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) RestoreUser(id uint) error {
+	return m.restore(&m.MysqlRepository, id)
+}
+
+// AnonymizeUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) AnonymizeUser(id uint) error {
+	return m.anonymize(&m.MysqlRepository, id)
+}
+
+// EraseUser implements goat.AccountService.
+func (m *MysqlServiceImpl[U]) EraseUser(id uint) error {
+	return m.erase(&m.MysqlRepository, id)
+}
+
+// PurgeDeletedUsers implements goat.AccountService. It purges the users of every tenant, whichever
+// tenant the service is for, with the records of the stores set by SetUserRecords.
+func (m *MysqlServiceImpl[U]) PurgeDeletedUsers() (int, error) {
+	return m.MysqlRepository.PurgeDeletedUsers(m.purge)
+}
@@ -7,17 +7,19 @@ import (
 	"github.com/bontusss/goat/internal/goat/utils"
 )
 
-type PostgresServiceImpl struct {
-	postgresRepository repository.PostgreSQLUserRepository
+type PostgresServiceImpl[U models.Account] struct {
+	postgresRepository repository.PostgreSQLUserRepository[U]
+	hooks[U]
+	purger
 }
 
-func NewPostgreSQLUserRepository(repo repository.PostgreSQLUserRepository) goat.UserService {
-	return &PostgresServiceImpl{postgresRepository: repo}
+func NewPostgreSQLUserRepository[U models.Account](repo repository.PostgreSQLUserRepository[U]) goat.UserService[U] {
+	return &PostgresServiceImpl[U]{postgresRepository: repo, hooks: newHooks[U](), purger: newPurger()}
 }
 
 // DeleteUser implements goat.UserService.
-func (p *PostgresServiceImpl) DeleteUser(id uint) error {
-	if err := p.postgresRepository.DeleteUser(id); err != nil {
+func (p *PostgresServiceImpl[U]) DeleteUser(id uint) error {
+	if err := p.transition(p.postgresRepository.Tenant(), models.UserDeleted, id, p.postgresRepository.GetUserByID, p.postgresRepository.DeleteUser); err != nil {
 		return err
 	}
 
@@ -25,32 +27,45 @@ func (p *PostgresServiceImpl) DeleteUser(id uint) error {
 }
 
 // GetUserByID implements goat.UserService.
-func (p *PostgresServiceImpl) GetUserByID(id uint) (*models.User, error) {
+func (p *PostgresServiceImpl[U]) GetUserByID(id uint) (U, error) {
 	user, err := p.postgresRepository.GetUserByID(id)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail implements goat.UserService.
+func (p *PostgresServiceImpl[U]) GetUserByEmail(email string) (U, error) {
+	user, err := p.postgresRepository.GetUserByEmail(email)
+	if err != nil {
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // Login implements goat.UserService.
-func (p *PostgresServiceImpl) Login(email string, password string) (*models.User, error) {
+func (p *PostgresServiceImpl[U]) Login(email string, password string) (U, error) {
 	user, err := p.postgresRepository.Login(email, password)
 	if err != nil {
-		return nil, err
+		var zero U
+		return zero, err
 	}
 
 	return user, nil
 }
 
 // Register implements goat.UserService.
-func (p *PostgresServiceImpl) Register(user *models.User) error {
-	if err := utils.ValidateUser(user); err != nil {
+func (p *PostgresServiceImpl[U]) Register(user U) error {
+	if err := utils.ValidateUser(user.GetUser()); err != nil {
 		return err
 	}
 
-	if err := p.postgresRepository.Register(user); err != nil {
+	if err := p.register(p.postgresRepository.Tenant(), user, p.postgresRepository.Register); err != nil {
 		return err
 	}
 
@@ -58,19 +73,80 @@ func (p *PostgresServiceImpl) Register(user *models.User) error {
 }
 
 // ResetPassword implements goat.UserService.
-func (p *PostgresServiceImpl) ResetPassword(email string, newPassword string) error {
-	if err := p.postgresRepository.ResetPassword(email, newPassword); err != nil {
+func (p *PostgresServiceImpl[U]) ResetPassword(email string, newPassword string) error {
+	if err := utils.ValidatePassword(email, newPassword); err != nil {
+		return err
+	}
+
+	if err := p.resetPassword(p.postgresRepository.Tenant(), email, newPassword, p.postgresRepository.GetUserByEmail, p.postgresRepository.ResetPassword); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// ListUsers implements goat.UserService.
+func (p *PostgresServiceImpl[U]) ListUsers(query *models.UserQuery) (*models.UserPage[U], error) {
+	page, err := p.postgresRepository.ListUsers(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
 // UpdateUser implements goat.UserService.
-func (p *PostgresServiceImpl) UpdateUser(user *models.User) error {
-	if err := p.postgresRepository.UpdateUser(user); err != nil {
+func (p *PostgresServiceImpl[U]) UpdateUser(user U) error {
+	if err := p.update(p.postgresRepository.Tenant(), user, p.postgresRepository.GetUserByID, p.postgresRepository.UpdateUser); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ForTenant implements goat.UserService. The returned service cannot see or change the users
+// of any other tenant.
+func (m *PostgresServiceImpl[U]) ForTenant(tenantID string) goat.UserService[U] {
+	return &PostgresServiceImpl[U]{postgresRepository: *m.postgresRepository.ForTenant(tenantID), hooks: m.hooks, purger: m.purger}
+}
+
+// SuspendUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) SuspendUser(id uint) error {
+	return p.setStatus(&p.postgresRepository, models.UserSuspended, id, models.AccountSuspended)
+}
+
+// DeactivateUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) DeactivateUser(id uint) error {
+	return p.setStatus(&p.postgresRepository, models.UserDeactivated, id, models.AccountDeactivated)
+}
+
+// ReactivateUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) ReactivateUser(id uint) error {
+	return p.setStatus(&p.postgresRepository, models.UserReactivated, id, models.AccountActive)
+}
+
+// GetDeletedUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) GetDeletedUser(id uint) (U, error) {
+	return p.postgresRepository.GetDeletedUser(id)
+}
+
+// RestoreUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) RestoreUser(id uint) error {
+	return p.restore(&p.postgresRepository, id)
+}
+
+// AnonymizeUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) AnonymizeUser(id uint) error {
+	return p.anonymize(&p.postgresRepository, id)
+}
+
+// EraseUser implements goat.AccountService.
+func (p *PostgresServiceImpl[U]) EraseUser(id uint) error {
+	return p.erase(&p.postgresRepository, id)
+}
+
+// PurgeDeletedUsers implements goat.AccountService. It purges the users of every tenant, whichever
+// tenant the service is for, with the records of the stores set by SetUserRecords.
+func (p *PostgresServiceImpl[U]) PurgeDeletedUsers() (int, error) {
+	return p.postgresRepository.PurgeDeletedUsers(p.purge)
+}
@@ -0,0 +1,343 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// Config controls session lifetimes and the session cookie.
+type Config struct {
+	CookieName      string        // Name of the session cookie.
+	CookiePath      string        // Path attribute of the cookie.
+	CookieDomain    string        // Domain attribute of the cookie; empty means host-only.
+	Secure          bool          // Only send the cookie over HTTPS.
+	SameSite        http.SameSite // SameSite attribute of the cookie.
+	IdleTimeout     time.Duration // End the session after this long without requests; 0 disables the idle timeout.
+	AbsoluteTimeout time.Duration // End the session this long after login, regardless of activity.
+	TouchInterval   time.Duration // Minimum time between last-seen updates, to limit writes to the store.
+}
+
+// DefaultConfig returns a Config with a secure, HttpOnly, SameSite=Lax cookie, a 30 minute idle timeout
+// and a 12 hour absolute timeout.
+func DefaultConfig() Config {
+	return Config{
+		CookieName:      "goat_session",
+		CookiePath:      "/",
+		Secure:          true,
+		SameSite:        http.SameSiteLaxMode,
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 12 * time.Hour,
+		TouchInterval:   time.Minute,
+	}
+}
+
+// Gin context keys under which Middleware stores the authenticated user and their session.
+const (
+	userKey    = "goat.user"
+	sessionKey = "goat.session"
+)
+
+// maxUserAgentLength bounds the user agent recorded for a session.
+const maxUserAgentLength = 512
+
+// Manager issues and validates server-side sessions. It implements goat.Authenticator for
+// applications that prefer cookies to JWTs. Requests are served from the sessions and users of
+// their tenant, as resolved by tenant.Middleware.
+type Manager[U models.Account] struct {
+	store  goat.SessionStore
+	users  goat.UserService[U]
+	config Config
+	social goat.SocialLogin[U]
+	now    func() time.Time
+}
+
+var (
+	_ goat.Authenticator[*models.User] = (*Manager[*models.User])(nil)
+	_ goat.SessionService              = (*Manager[*models.User])(nil)
+)
+
+// NewManager creates a Manager that keeps sessions in store and loads users from users.
+func NewManager[U models.Account](store goat.SessionStore, users goat.UserService[U], config Config) *Manager[U] {
+	return &Manager[U]{store: store, users: users, config: config, now: time.Now}
+}
+
+// ForTenant implements goat.SessionService.
+func (m *Manager[U]) ForTenant(tenantID string) goat.SessionService {
+	return m.scoped(tenantID)
+}
+
+// scoped returns a copy of m that only sees the sessions and users of tenantID.
+func (m *Manager[U]) scoped(tenantID string) *Manager[U] {
+	scoped := *m
+	scoped.store = m.store.ForTenant(tenantID)
+	scoped.users = m.users.ForTenant(tenantID)
+	return &scoped
+}
+
+// SetSocialLogin enables SocialLogin, completing provider sign-ins with social.
+func (m *Manager[U]) SetSocialLogin(social goat.SocialLogin[U]) {
+	m.social = social
+}
+
+// Login checks the credentials, starts a new session and sets the session cookie.
+// Any session the client already presented is destroyed first, so a session ID planted
+// before login can never become authenticated (session fixation).
+func (m *Manager[U]) Login(c *gin.Context, email, password string) (U, error) {
+	m = m.scoped(tenant.ID(c))
+	user, err := m.users.Login(email, password)
+	if err != nil {
+		var zero U
+		return zero, err
+	}
+	return m.login(c, user)
+}
+
+// login starts a new session for user, destroying any session the client already presented.
+func (m *Manager[U]) login(c *gin.Context, user U) (U, error) {
+	if err := m.destroy(c); err != nil {
+		var zero U
+		return zero, err
+	}
+
+	now := m.now()
+	if err := m.start(c, &models.Session{
+		UserID:     user.GetUser().ID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(m.config.AbsoluteTimeout),
+		UserAgent:  truncate(c.Request.UserAgent(), maxUserAgentLength),
+		IP:         c.ClientIP(),
+	}); err != nil {
+		var zero U
+		return zero, err
+	}
+	return user, nil
+}
+
+// Logout destroys the current session and clears the cookie.
+func (m *Manager[U]) Logout(c *gin.Context) error {
+	return m.scoped(tenant.ID(c)).destroy(c)
+}
+
+// Authenticate implements goat.Authenticator. It validates the session cookie, enforcing the idle
+// and absolute timeouts, records activity and returns the session's user.
+func (m *Manager[U]) Authenticate(c *gin.Context) (U, error) {
+	var zero U
+	m = m.scoped(tenant.ID(c))
+	session, err := m.current(c)
+	if err != nil {
+		return zero, err
+	}
+
+	now := m.now()
+	if now.Sub(session.LastSeenAt) >= m.config.TouchInterval {
+		if err := m.store.Touch(session.ID, now); err != nil {
+			return zero, err
+		}
+		session.LastSeenAt = now
+	}
+	c.Set(sessionKey, session)
+
+	user, err := m.users.GetUserByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, goat.ErrUserNotFound) {
+			// The user was deleted; the session must not outlive them.
+			_ = m.store.Delete(session.ID)
+			return zero, goat.ErrUnauthorized
+		}
+		return zero, err
+	}
+	// Suspended and deactivated users keep their sessions, which work again once they are reactivated.
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		return zero, err
+	}
+	return user, nil
+}
+
+// RefreshAuthToken implements goat.Authenticator. It rotates the session ID while keeping the
+// session's original absolute expiry, and returns the session's user.
+func (m *Manager[U]) RefreshAuthToken(c *gin.Context) (U, error) {
+	var zero U
+	m = m.scoped(tenant.ID(c))
+	session, err := m.current(c)
+	if err != nil {
+		return zero, err
+	}
+	user, err := m.users.GetUserByID(session.UserID)
+	if err != nil {
+		return zero, err
+	}
+	if err := goat.AccountError(user.GetUser()); err != nil {
+		return zero, err
+	}
+
+	if err := m.store.Delete(session.ID); err != nil {
+		return zero, err
+	}
+	session.LastSeenAt = m.now()
+	if err := m.start(c, session); err != nil {
+		return zero, err
+	}
+	return user, nil
+}
+
+// SocialLogin implements goat.Authenticator. It completes a provider sign-in started with the
+// SocialLogin set by SetSocialLogin, and starts a session for the user as Login does.
+func (m *Manager[U]) SocialLogin(c *gin.Context, provider string) (U, error) {
+	var zero U
+	if m.social == nil {
+		return zero, goat.ErrUnsupportedProvider
+	}
+	user, err := m.social.Complete(c, provider)
+	if err != nil {
+		return zero, err
+	}
+	return m.scoped(tenant.ID(c)).login(c, user)
+}
+
+// Middleware authenticates every request with Authenticate, aborting with 401 when there is no
+// valid session. The user is available to later handlers through User.
+func (m *Manager[U]) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := m.Authenticate(c)
+		if err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+// ListSessions implements goat.SessionService.
+func (m *Manager[U]) ListSessions(userID uint) ([]*models.Session, error) {
+	return m.store.ListByUser(userID)
+}
+
+// RevokeSession implements goat.SessionService. The client holding the session is logged out
+// on its next request.
+func (m *Manager[U]) RevokeSession(id string) error {
+	return m.store.Delete(id)
+}
+
+// RevokeAllSessions implements goat.SessionService.
+func (m *Manager[U]) RevokeAllSessions(userID uint, exceptCurrent string) error {
+	return m.store.DeleteByUser(userID, exceptCurrent)
+}
+
+// Current returns the session of the request, as loaded by Authenticate.
+func Current(c *gin.Context) (*models.Session, bool) {
+	session, ok := c.Get(sessionKey)
+	if !ok {
+		return nil, false
+	}
+	s, ok := session.(*models.Session)
+	return s, ok
+}
+
+// User returns the user stored in c by Middleware.
+func User[U models.Account](c *gin.Context) (U, bool) {
+	user, ok := c.Get(userKey)
+	if !ok {
+		var zero U
+		return zero, false
+	}
+	u, ok := user.(U)
+	return u, ok
+}
+
+// current loads the session named by the request's cookie and checks that it has not timed out.
+// A missing or unknown session is reported as goat.ErrUnauthorized.
+func (m *Manager[U]) current(c *gin.Context) (*models.Session, error) {
+	token, err := c.Cookie(m.config.CookieName)
+	if err != nil || token == "" {
+		return nil, goat.ErrUnauthorized
+	}
+	session, err := m.store.Get(hashToken(token))
+	if err != nil {
+		if errors.Is(err, goat.ErrSessionNotFound) {
+			return nil, goat.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := m.now()
+	if session.Expired(now) || session.IdleExpired(now, m.config.IdleTimeout) {
+		_ = m.store.Delete(session.ID)
+		m.clearCookie(c)
+		return nil, goat.ErrSessionExpired
+	}
+	return session, nil
+}
+
+// start assigns session a fresh random ID, saves it and sets the cookie.
+func (m *Manager[U]) start(c *gin.Context, session *models.Session) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	session.ID = hashToken(token)
+	if err := m.store.Create(session); err != nil {
+		return err
+	}
+
+	maxAge := int(session.ExpiresAt.Sub(m.now()).Seconds())
+	c.SetSameSite(m.config.SameSite)
+	c.SetCookie(m.config.CookieName, token, maxAge, m.config.CookiePath, m.config.CookieDomain, m.config.Secure, true)
+	return nil
+}
+
+// destroy deletes the session named by the request's cookie, if any, and clears the cookie.
+func (m *Manager[U]) destroy(c *gin.Context) error {
+	token, err := c.Cookie(m.config.CookieName)
+	if err != nil || token == "" {
+		return nil
+	}
+	if err := m.store.Delete(hashToken(token)); err != nil {
+		return err
+	}
+	m.clearCookie(c)
+	return nil
+}
+
+func (m *Manager[U]) clearCookie(c *gin.Context) {
+	c.SetSameSite(m.config.SameSite)
+	c.SetCookie(m.config.CookieName, "", -1, m.config.CookiePath, m.config.CookieDomain, m.config.Secure, true)
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// newToken returns 32 bytes from crypto/rand, base64url encoded.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the session ID stored for a cookie token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
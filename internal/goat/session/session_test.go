@@ -0,0 +1,240 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+	"github.com/bontusss/goat/internal/goat/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// testUsers is a goat.UserService holding the users of every tenant, whose passwords are "secret".
+type testUsers struct {
+	goat.UserService[*models.User]
+	users map[uint]*models.User
+}
+
+func (u testUsers) ForTenant(string) goat.UserService[*models.User] { return u }
+
+func (u testUsers) Login(email, password string) (*models.User, error) {
+	for _, user := range u.users {
+		if user.Email == email && password == "secret" {
+			return user, nil
+		}
+	}
+	return nil, goat.ErrInvalidCredentials
+}
+
+func (u testUsers) GetUserByID(id uint) (*models.User, error) {
+	user, ok := u.users[id]
+	if !ok {
+		return nil, goat.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// newTestManager returns a manager for the users whose clock is at *now.
+func newTestManager(now *time.Time, users ...*models.User) *Manager[*models.User] {
+	byID := map[uint]*models.User{}
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	m := NewManager[*models.User](repository.NewMemorySessionStore(), testUsers{users: byID}, DefaultConfig())
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+// request runs fn with a context for a request carrying the session cookie token, if set, and
+// returns the session cookie set in the response, or "" if none was set or it was cleared.
+func request(t *testing.T, m *Manager[*models.User], token string, fn func(c *gin.Context)) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/session", nil)
+	c.Request.Header.Set("User-Agent", "laptop")
+	if token != "" {
+		c.Request.AddCookie(&http.Cookie{Name: m.config.CookieName, Value: token})
+	}
+	fn(c)
+	// Login clears the cookie it was given before setting the new one, so the last one counts.
+	set := ""
+	for _, cookie := range (&http.Response{Header: w.Header()}).Cookies() {
+		if cookie.Name == m.config.CookieName {
+			set = cookie.Value
+		}
+	}
+	return set
+}
+
+// login signs ada in with the session cookie token, if set, and returns the new session cookie.
+func login(t *testing.T, m *Manager[*models.User], token string) string {
+	t.Helper()
+	return request(t, m, token, func(c *gin.Context) {
+		if _, err := m.Login(c, "ada@example.com", "secret"); err != nil {
+			t.Fatalf("Login error = %v", err)
+		}
+	})
+}
+
+// authenticate returns the error of authenticating a request with the session cookie token.
+func authenticate(t *testing.T, m *Manager[*models.User], token string) error {
+	t.Helper()
+	var err error
+	request(t, m, token, func(c *gin.Context) { _, err = m.Authenticate(c) })
+	return err
+}
+
+func TestLoginPreventsFixation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ada := &models.User{ID: 1, Email: "ada@example.com"}
+
+	tests := []struct {
+		name    string
+		planted func(m *Manager[*models.User]) string // The cookie the client presents when logging in.
+	}{
+		{"no cookie", func(*Manager[*models.User]) string { return "" }},
+		{"unknown cookie", func(*Manager[*models.User]) string { return "planted-by-an-attacker" }},
+		{"cookie of a live session", func(m *Manager[*models.User]) string { return login(t, m, "") }},
+	}
+	for _, tt := range tests {
+		m := newTestManager(&now, ada)
+		planted := tt.planted(m)
+
+		token := login(t, m, planted)
+		if token == "" || token == planted {
+			t.Fatalf("%s: session cookie = %q, want a new one", tt.name, token)
+		}
+		if err := authenticate(t, m, token); err != nil {
+			t.Errorf("%s: new session: Authenticate error = %v", tt.name, err)
+		}
+		if planted != "" {
+			if err := authenticate(t, m, planted); !errors.Is(err, goat.ErrUnauthorized) {
+				t.Errorf("%s: planted session: Authenticate error = %v, want %v", tt.name, err, goat.ErrUnauthorized)
+			}
+		}
+
+		// The store holds the digest of the cookie, never the cookie itself.
+		sessions, err := m.ListSessions(ada.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 || sessions[0].ID != hashToken(token) || sessions[0].UserAgent != "laptop" {
+			t.Errorf("%s: sessions = %+v, want one named by the digest of the cookie", tt.name, sessions)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	config := DefaultConfig()
+
+	tests := []struct {
+		name    string
+		change  func(m *Manager[*models.User], current, other string, now *time.Time)
+		wantErr error
+	}{
+		{"active session", func(m *Manager[*models.User], _, _ string, now *time.Time) {
+			*now = now.Add(config.IdleTimeout / 2)
+		}, nil},
+		{"idle timeout", func(m *Manager[*models.User], _, _ string, now *time.Time) {
+			*now = now.Add(config.IdleTimeout + time.Second)
+		}, goat.ErrSessionExpired},
+		{"absolute timeout", func(m *Manager[*models.User], current, _ string, now *time.Time) {
+			// Activity keeps the session from idling out, but not past its absolute expiry.
+			for *now = now.Add(config.IdleTimeout / 2); now.Before(start.Add(config.AbsoluteTimeout)); *now = now.Add(config.IdleTimeout / 2) {
+				if err := authenticate(t, m, current); err != nil {
+					t.Fatalf("Authenticate at %v error = %v", *now, err)
+				}
+			}
+		}, goat.ErrSessionExpired},
+		{"revoked", func(m *Manager[*models.User], current, _ string, _ *time.Time) {
+			if err := m.RevokeSession(hashToken(current)); err != nil {
+				t.Fatal(err)
+			}
+		}, goat.ErrUnauthorized},
+		{"other sessions revoked", func(m *Manager[*models.User], current, _ string, _ *time.Time) {
+			if err := m.RevokeAllSessions(1, hashToken(current)); err != nil {
+				t.Fatal(err)
+			}
+		}, nil},
+		{"all sessions revoked", func(m *Manager[*models.User], _, other string, _ *time.Time) {
+			if err := m.RevokeAllSessions(1, hashToken(other)); err != nil {
+				t.Fatal(err)
+			}
+		}, goat.ErrUnauthorized},
+		{"revoked in another tenant", func(m *Manager[*models.User], current, _ string, _ *time.Time) {
+			if err := m.ForTenant("acme").RevokeSession(hashToken(current)); err != nil {
+				t.Fatal(err)
+			}
+		}, nil},
+		{"user deleted", func(m *Manager[*models.User], _, _ string, _ *time.Time) {
+			delete(m.users.(testUsers).users, 1)
+		}, goat.ErrUnauthorized},
+		{"user suspended", func(m *Manager[*models.User], _, _ string, _ *time.Time) {
+			m.users.(testUsers).users[1].Status = models.AccountSuspended
+		}, goat.ErrAccountSuspended},
+	}
+	for _, tt := range tests {
+		now := start
+		m := newTestManager(&now, &models.User{ID: 1, Email: "ada@example.com"})
+		current := login(t, m, "")
+		other := login(t, m, "")
+
+		tt.change(m, current, other, &now)
+		if err := authenticate(t, m, current); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Authenticate error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// A session is only valid in the tenant it was started in.
+	now := start
+	m := newTestManager(&now, &models.User{ID: 1, Email: "ada@example.com"})
+	token := login(t, m, "")
+	var err error
+	request(t, m, token, func(c *gin.Context) {
+		tenant.Middleware(tenant.Fixed("acme"))(c)
+		_, err = m.Authenticate(c)
+	})
+	if !errors.Is(err, goat.ErrUnauthorized) {
+		t.Errorf("session of another tenant: Authenticate error = %v, want %v", err, goat.ErrUnauthorized)
+	}
+}
+
+func TestRefreshRotatesSession(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(&now, &models.User{ID: 1, Email: "ada@example.com"})
+	token := login(t, m, "")
+
+	now = now.Add(20 * time.Minute)
+	refreshed := request(t, m, token, func(c *gin.Context) {
+		if _, err := m.RefreshAuthToken(c); err != nil {
+			t.Fatalf("RefreshAuthToken error = %v", err)
+		}
+	})
+	if refreshed == "" || refreshed == token {
+		t.Fatalf("session cookie after refresh = %q, want a new one", refreshed)
+	}
+	if err := authenticate(t, m, token); !errors.Is(err, goat.ErrUnauthorized) {
+		t.Errorf("old cookie: Authenticate error = %v, want %v", err, goat.ErrUnauthorized)
+	}
+	if err := authenticate(t, m, refreshed); err != nil {
+		t.Errorf("new cookie: Authenticate error = %v", err)
+	}
+
+	// The refreshed session keeps the absolute expiry of the login.
+	sessions, err := m.ListSessions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := now.Add(-20 * time.Minute).Add(DefaultConfig().AbsoluteTimeout)
+	if len(sessions) != 1 || !sessions[0].ExpiresAt.Equal(want) {
+		t.Errorf("sessions after refresh = %+v, want one expiring at %v", sessions, want)
+	}
+}
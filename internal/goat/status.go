@@ -0,0 +1,205 @@
+package goat
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kind classifies goat errors independently of the transport used to report them.
+type Kind uint8
+
+const (
+	KindInternal         Kind = iota // Unexpected failure; details must not reach clients.
+	KindInvalid                      // The request is malformed or fails validation.
+	KindUnauthenticated              // Credentials or token are missing or wrong.
+	KindPermissionDenied             // The caller is authenticated but not allowed.
+	KindNotFound                     // The requested resource does not exist.
+	KindConflict                     // The request conflicts with existing state, e.g. a duplicate email.
+)
+
+// GRPCCode is a gRPC status code. The values are those of google.golang.org/grpc/codes,
+// so a GRPCCode converts directly with codes.Code(c).
+type GRPCCode uint32
+
+const (
+	GRPCCodeOK               GRPCCode = 0
+	GRPCCodeInvalidArgument  GRPCCode = 3
+	GRPCCodeNotFound         GRPCCode = 5
+	GRPCCodeAlreadyExists    GRPCCode = 6
+	GRPCCodePermissionDenied GRPCCode = 7
+	GRPCCodeInternal         GRPCCode = 13
+	GRPCCodeUnauthenticated  GRPCCode = 16
+)
+
+// errorClass describes how a sentinel error is reported.
+type errorClass struct {
+	err  error
+	kind Kind
+	code string // Machine-readable error code, also used in the problem type URI.
+}
+
+// errorClasses lists the sentinel errors in the order they are matched.
+var errorClasses = []errorClass{
+	{ErrValidation, KindInvalid, "validation_failed"},
+	{ErrMalformedRequest, KindInvalid, "malformed_request"},
+	{ErrEmailNotProvided, KindInvalid, "email_required"},
+	{ErrPasswordNotProvided, KindInvalid, "password_required"},
+	{ErrInvalidEmail, KindInvalid, "invalid_email"},
+	{ErrDisposableEmail, KindInvalid, "disposable_email"},
+	{ErrWeakPassword, KindInvalid, "weak_password"},
+	{ErrInvalidCredentials, KindUnauthenticated, "invalid_credentials"},
+	{ErrUnauthorized, KindUnauthenticated, "unauthorized"},
+	{ErrInvalidToken, KindUnauthenticated, "invalid_token"},
+	{ErrExpiredToken, KindUnauthenticated, "expired_token"},
+	{ErrMissingToken, KindUnauthenticated, "missing_token"},
+	{ErrSessionExpired, KindUnauthenticated, "session_expired"},
+	{ErrAPIKeyExpired, KindUnauthenticated, "api_key_expired"},
+	{ErrTenantMismatch, KindUnauthenticated, "tenant_mismatch"},
+	{ErrUnsupportedProvider, KindInvalid, "unsupported_provider"},
+	{ErrInvalidOAuthState, KindInvalid, "invalid_oauth_state"},
+	{ErrInvalidScope, KindInvalid, "invalid_scope"},
+	{ErrTenantRequired, KindInvalid, "tenant_required"},
+	{ErrInvalidRole, KindInvalid, "invalid_role"},
+	{ErrInvitationExpired, KindInvalid, "invitation_expired"},
+	{ErrInvalidAccountStatus, KindInvalid, "invalid_account_status"},
+	{ErrInvalidWebhookURL, KindInvalid, "invalid_webhook_url"},
+	{ErrInvalidWebhookEvent, KindInvalid, "invalid_webhook_event"},
+	{ErrInvalidSignature, KindUnauthenticated, "invalid_signature"},
+	{ErrInvalidErasureMode, KindInvalid, "invalid_erasure_mode"},
+	{ErrInvalidErasureReport, KindInvalid, "invalid_erasure_report"},
+	{ErrInvalidUserSort, KindInvalid, "invalid_user_sort"},
+	{ErrInvalidCursor, KindInvalid, "invalid_cursor"},
+	{ErrEmailNotVerified, KindPermissionDenied, "email_not_verified"},
+	{ErrPermissionDenied, KindPermissionDenied, "permission_denied"},
+	{ErrVetoed, KindPermissionDenied, "vetoed"},
+	{ErrAccountSuspended, KindPermissionDenied, "account_suspended"},
+	{ErrAccountDeactivated, KindPermissionDenied, "account_deactivated"},
+	{ErrUserNotFound, KindNotFound, "user_not_found"},
+	{ErrSessionNotFound, KindNotFound, "session_not_found"},
+	{ErrIdentityNotFound, KindNotFound, "identity_not_found"},
+	{ErrClientNotFound, KindNotFound, "client_not_found"},
+	{ErrConsentNotFound, KindNotFound, "consent_not_found"},
+	{ErrGrantNotFound, KindNotFound, "grant_not_found"},
+	{ErrAPIKeyNotFound, KindNotFound, "api_key_not_found"},
+	{ErrOrganizationNotFound, KindNotFound, "organization_not_found"},
+	{ErrMembershipNotFound, KindNotFound, "membership_not_found"},
+	{ErrInvitationNotFound, KindNotFound, "invitation_not_found"},
+	{ErrAuditEventNotFound, KindNotFound, "audit_event_not_found"},
+	{ErrAuditCheckpointNotFound, KindNotFound, "audit_checkpoint_not_found"},
+	{ErrOutboxMessageNotFound, KindNotFound, "outbox_message_not_found"},
+	{ErrWebhookNotFound, KindNotFound, "webhook_not_found"},
+	{ErrWebhookDeliveryNotFound, KindNotFound, "webhook_delivery_not_found"},
+	{ErrEmailTaken, KindConflict, "email_taken"},
+	{ErrIdentityTaken, KindConflict, "identity_taken"},
+	{ErrAccountExists, KindConflict, "account_exists"},
+	{ErrLastLoginMethod, KindConflict, "last_login_method"},
+	{ErrAlreadyMember, KindConflict, "already_member"},
+	{ErrOwnerRequired, KindConflict, "owner_required"},
+	{ErrAuditSeqTaken, KindConflict, "audit_seq_taken"},
+	{ErrWebhookDeliveryExists, KindConflict, "webhook_delivery_exists"},
+	{ErrUserNotDeleted, KindConflict, "user_not_deleted"},
+	{ErrRestoreWindowExpired, KindConflict, "restore_window_expired"},
+	{ErrErasureIncomplete, KindConflict, "erasure_incomplete"},
+}
+
+var internalClass = errorClass{ErrInternalServerError, KindInternal, "internal_error"}
+
+func classify(err error) errorClass {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c
+		}
+	}
+	return internalClass
+}
+
+// KindOf returns the Kind of err. Errors that are not goat errors are KindInternal.
+func KindOf(err error) Kind {
+	return classify(err).kind
+}
+
+// CodeOf returns the machine-readable code of err, e.g. "email_taken".
+func CodeOf(err error) string {
+	return classify(err).code
+}
+
+// HTTPStatus returns the HTTP status code for err. A request that could not be read at all is a
+// 400 Bad Request; other invalid requests are 422 Unprocessable Entity.
+func HTTPStatus(err error) int {
+	if errors.Is(err, ErrMalformedRequest) {
+		return http.StatusBadRequest
+	}
+	switch KindOf(err) {
+	case KindInvalid:
+		return http.StatusUnprocessableEntity
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindPermissionDenied:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus returns the gRPC status code for err.
+func GRPCStatus(err error) GRPCCode {
+	switch KindOf(err) {
+	case KindInvalid:
+		return GRPCCodeInvalidArgument
+	case KindUnauthenticated:
+		return GRPCCodeUnauthenticated
+	case KindPermissionDenied:
+		return GRPCCodePermissionDenied
+	case KindNotFound:
+		return GRPCCodeNotFound
+	case KindConflict:
+		return GRPCCodeAlreadyExists
+	}
+	return GRPCCodeInternal
+}
+
+// ProblemDetails is an RFC 7807 problem details body.
+type ProblemDetails struct {
+	Type   string        `json:"type"`
+	Title  string        `json:"title"`
+	Status int           `json:"status"`
+	Detail string        `json:"detail,omitempty"`
+	Code   string        `json:"code"`             // Extension member: machine-readable error code.
+	Fields []*FieldError `json:"fields,omitempty"` // Extension member: per-field validation errors.
+}
+
+// ProblemContentType is the media type of a ProblemDetails body.
+const ProblemContentType = "application/problem+json"
+
+// Problem builds the problem details body for err. Internal errors are reported without detail,
+// so that driver messages never reach clients.
+func Problem(err error) ProblemDetails {
+	c := classify(err)
+	p := ProblemDetails{
+		Type:   "urn:goat:error:" + c.code,
+		Title:  c.err.Error(),
+		Status: HTTPStatus(err),
+		Code:   c.code,
+	}
+	if c.kind != KindInternal && err.Error() != p.Title {
+		p.Detail = err.Error()
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		p.Fields = verr.Fields
+	}
+	return p
+}
+
+// AbortWithProblem writes err as an RFC 7807 problem details response and aborts the request.
+// Validation errors are rendered as 422 Unprocessable Entity with per-field details.
+func AbortWithProblem(c *gin.Context, err error) {
+	problem := Problem(err)
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
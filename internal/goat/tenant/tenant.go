@@ -0,0 +1,116 @@
+// Package tenant resolves the tenant a request is for and scopes user services to it, so that a
+// handler can only ever see the users of the request's tenant.
+package tenant
+
+import (
+	"net"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/gin-gonic/gin"
+)
+
+// tenantKey is the gin context key under which Middleware stores the tenant ID.
+const tenantKey = "goat.tenant"
+
+// maxIDLength is the longest tenant ID the SQL backends can store.
+const maxIDLength = 64
+
+// Resolver determines the tenant of a request. It returns an empty ID when the request does not
+// name a tenant, and an error only when it names one in a way that cannot be accepted.
+type Resolver func(c *gin.Context) (string, error)
+
+// FromHeader resolves the tenant from the request header name, e.g. "X-Tenant-ID".
+func FromHeader(name string) Resolver {
+	return func(c *gin.Context) (string, error) {
+		return strings.TrimSpace(c.GetHeader(name)), nil
+	}
+}
+
+// FromSubdomain resolves the tenant from the label in front of baseDomain in the request's host,
+// so "acme.example.com" is tenant "acme" for a baseDomain of "example.com". The base domain itself
+// and hosts outside it name no tenant.
+func FromSubdomain(baseDomain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c *gin.Context) (string, error) {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		label, ok := strings.CutSuffix(host, suffix)
+		if !ok || label == "" || strings.Contains(label, ".") {
+			return "", nil
+		}
+		return label, nil
+	}
+}
+
+// Fixed resolves every request to id. It is useful as the last resolver given to First, to serve
+// requests that name no tenant from a default one.
+func Fixed(id string) Resolver {
+	return func(*gin.Context) (string, error) {
+		return id, nil
+	}
+}
+
+// First tries each resolver in turn and returns the first tenant found.
+func First(resolvers ...Resolver) Resolver {
+	return func(c *gin.Context) (string, error) {
+		for _, resolve := range resolvers {
+			id, err := resolve(c)
+			if err != nil || id != "" {
+				return id, err
+			}
+		}
+		return "", nil
+	}
+}
+
+// Middleware resolves the tenant of every request with resolve and stores it for ID and Users,
+// aborting with ErrTenantRequired when there is none.
+func Middleware(resolve Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := resolve(c)
+		if err == nil && (id == "" || len(id) > maxIDLength) {
+			err = goat.ErrTenantRequired
+		}
+		if err != nil {
+			goat.AbortWithProblem(c, err)
+			return
+		}
+		c.Set(tenantKey, id)
+		c.Next()
+	}
+}
+
+// ID returns the tenant stored in c by Middleware, or the default tenant, "", without it.
+func ID(c *gin.Context) string {
+	return c.GetString(tenantKey)
+}
+
+// Users returns users scoped to the request's tenant.
+func Users[U models.Account](c *gin.Context, users goat.UserService[U]) goat.UserService[U] {
+	return users.ForTenant(ID(c))
+}
+
+// Identities returns identities scoped to the request's tenant, like Users.
+func Identities[U models.Account](c *gin.Context, identities goat.IdentityService[U]) goat.IdentityService[U] {
+	return identities.ForTenant(ID(c))
+}
+
+// APIKeys returns keys scoped to the request's tenant, like Users.
+func APIKeys(c *gin.Context, keys goat.APIKeyService) goat.APIKeyService {
+	return keys.ForTenant(ID(c))
+}
+
+// Sessions returns sessions scoped to the request's tenant, like Users.
+func Sessions(c *gin.Context, sessions goat.SessionService) goat.SessionService {
+	return sessions.ForTenant(ID(c))
+}
+
+// Organizations returns organizations scoped to the request's tenant, like Users.
+func Organizations[U models.Account](c *gin.Context, orgs goat.OrganizationService[U]) goat.OrganizationService[U] {
+	return orgs.ForTenant(ID(c))
+}
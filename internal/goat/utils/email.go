@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/bontusss/goat/internal/goat"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// EmailPolicy controls how email addresses are validated and normalized.
+type EmailPolicy struct {
+	Canonicalize      bool                // Apply provider-specific rules, e.g. drop dots and +tags for Gmail.
+	BlockDisposable   bool                // Reject addresses at disposable email domains.
+	DisposableDomains map[string]struct{} // Domains treated as disposable; defaults to a small built-in list.
+}
+
+// DefaultEmailPolicy is the policy applied by ValidateUser and by the repositories when looking users up by email.
+// Applications can replace it at startup.
+var DefaultEmailPolicy = EmailPolicy{
+	BlockDisposable: true,
+}
+
+// defaultDisposableDomains is a short list of well-known throwaway email providers.
+var defaultDisposableDomains = map[string]struct{}{
+	"mailinator.com": {}, "guerrillamail.com": {}, "10minutemail.com": {}, "tempmail.com": {},
+	"temp-mail.org": {}, "throwawaymail.com": {}, "yopmail.com": {}, "trashmail.com": {},
+	"getnada.com": {}, "dispostable.com": {}, "sharklasers.com": {}, "maildrop.cc": {},
+}
+
+// provider describes how a mail provider treats the local part of its addresses.
+type provider struct {
+	ignoreDots bool   // Dots in the local part are not significant.
+	tagSep     string // Everything after this separator is a subaddress tag.
+	domain     string // Canonical domain for aliases, e.g. googlemail.com -> gmail.com.
+}
+
+var providers = map[string]provider{
+	"gmail.com":      {ignoreDots: true, tagSep: "+", domain: "gmail.com"},
+	"googlemail.com": {ignoreDots: true, tagSep: "+", domain: "gmail.com"},
+	"outlook.com":    {tagSep: "+"},
+	"hotmail.com":    {tagSep: "+"},
+	"live.com":       {tagSep: "+"},
+	"icloud.com":     {tagSep: "+"},
+	"me.com":         {tagSep: "+"},
+	"fastmail.com":   {tagSep: "+"},
+	"protonmail.com": {tagSep: "+"},
+	"proton.me":      {tagSep: "+"},
+	"yahoo.com":      {tagSep: "-"},
+}
+
+// Email validation error codes, reported as the code of email field errors.
+const (
+	CodeDisposableEmail = "disposable"
+)
+
+// ValidateEmail checks that email is a bare RFC 5322 addr-spec within the RFC 5321 length limits,
+// and that its domain is not disposable when the policy blocks them. It expects a normalized address.
+func (p EmailPolicy) ValidateEmail(email string) error {
+	if email == "" {
+		return goat.ErrEmailNotProvided
+	}
+	if len(email) > 254 {
+		return goat.ErrInvalidEmail
+	}
+
+	// ParseAddress also accepts display names and angle brackets, so require the result to be the input itself.
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return goat.ErrInvalidEmail
+	}
+
+	local, domain, _ := cutLast(email, "@")
+	if len(local) > 64 || !validDomain(domain) {
+		return goat.ErrInvalidEmail
+	}
+
+	if p.BlockDisposable && p.isDisposable(domain) {
+		return goat.ErrDisposableEmail
+	}
+	return nil
+}
+
+// NormalizeEmail trims surrounding whitespace, applies Unicode NFC normalization and lowercases the address,
+// converting an internationalized domain to its ASCII form. With Canonicalize set it also applies
+// provider-specific rules so that aliases of the same mailbox map to a single address.
+func (p EmailPolicy) NormalizeEmail(email string) string {
+	email = strings.ToLower(norm.NFC.String(strings.TrimSpace(email)))
+	local, domain, ok := cutLast(email, "@")
+	if !ok {
+		return email
+	}
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+
+	if pr, ok := providers[domain]; ok && p.Canonicalize {
+		if pr.tagSep != "" {
+			local, _, _ = strings.Cut(local, pr.tagSep)
+		}
+		if pr.ignoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+		if pr.domain != "" {
+			domain = pr.domain
+		}
+	}
+	return local + "@" + domain
+}
+
+func (p EmailPolicy) isDisposable(domain string) bool {
+	domains := p.DisposableDomains
+	if domains == nil {
+		domains = defaultDisposableDomains
+	}
+	// Match subdomains of listed domains as well.
+	for d := domain; d != ""; {
+		if _, ok := domains[d]; ok {
+			return true
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+	return false
+}
+
+// LoadDisposableDomains reads a list of disposable email domains, one per line; lines starting with # are ignored.
+func LoadDisposableDomains(r io.Reader) (map[string]struct{}, error) {
+	domains := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// NormalizeEmail normalizes email with DefaultEmailPolicy.
+func NormalizeEmail(email string) string {
+	return DefaultEmailPolicy.NormalizeEmail(email)
+}
+
+// validDomain checks that domain is a dotted hostname of valid DNS labels.
+func validDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(domain) > 253 || len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
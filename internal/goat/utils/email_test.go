@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	canonical := EmailPolicy{Canonicalize: true}
+	tests := []struct {
+		name   string
+		policy EmailPolicy
+		email  string
+		want   string
+	}{
+		{"trims and lowercases", DefaultEmailPolicy, "  Ada@Example.COM ", "ada@example.com"},
+		{"composes to NFC", DefaultEmailPolicy, "josé@example.com", "josé@example.com"},
+		{"converts the domain to ASCII", DefaultEmailPolicy, "ada@BÜCHER.example", "ada@xn--bcher-kva.example"},
+		{"keeps provider aliases by default", DefaultEmailPolicy, "A.da+news@gmail.com", "a.da+news@gmail.com"},
+		{"gmail drops dots and tags", canonical, "A.da+news@GoogleMail.com", "ada@gmail.com"},
+		{"outlook drops tags only", canonical, "a.da+news@outlook.com", "a.da@outlook.com"},
+		{"yahoo tags use a dash", canonical, "ada-news@yahoo.com", "ada@yahoo.com"},
+		{"other domains are kept", canonical, "a.da+news@example.com", "a.da+news@example.com"},
+		{"the last @ separates the domain", canonical, `"a@b"@gmail.com`, `"a@b"@gmail.com`},
+		{"no domain", canonical, " Ada ", "ada"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.NormalizeEmail(tt.email); got != tt.want {
+			t.Errorf("%s: NormalizeEmail(%q) = %q, want %q", tt.name, tt.email, got, tt.want)
+		}
+		// Normalizing is idempotent, so stored emails compare equal to normalized input.
+		if got := tt.policy.NormalizeEmail(tt.want); got != tt.want {
+			t.Errorf("%s: NormalizeEmail(%q) = %q, want it unchanged", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  EmailPolicy
+		email   string
+		wantErr error
+	}{
+		{"valid", DefaultEmailPolicy, "ada@example.com", nil},
+		{"empty", DefaultEmailPolicy, "", goat.ErrEmailNotProvided},
+		{"no domain", DefaultEmailPolicy, "ada", goat.ErrInvalidEmail},
+		{"single label domain", DefaultEmailPolicy, "ada@localhost", goat.ErrInvalidEmail},
+		{"display name", DefaultEmailPolicy, "Ada <ada@example.com>", goat.ErrInvalidEmail},
+		{"label starting with a dash", DefaultEmailPolicy, "ada@-example.com", goat.ErrInvalidEmail},
+		{"local part too long", DefaultEmailPolicy, strings.Repeat("a", 65) + "@example.com", goat.ErrInvalidEmail},
+		{"address too long", DefaultEmailPolicy, strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 60)+".", 4) + "com", goat.ErrInvalidEmail},
+		{"disposable", DefaultEmailPolicy, "ada@mailinator.com", goat.ErrDisposableEmail},
+		{"disposable subdomain", DefaultEmailPolicy, "ada@eu.mailinator.com", goat.ErrDisposableEmail},
+		{"disposable allowed", EmailPolicy{}, "ada@mailinator.com", nil},
+		{"custom disposable list", EmailPolicy{BlockDisposable: true, DisposableDomains: map[string]struct{}{"example.org": {}}}, "ada@mailinator.com", nil},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.ValidateEmail(tt.email); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: ValidateEmail(%q) = %v, want %v", tt.name, tt.email, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadDisposableDomains(t *testing.T) {
+	domains, err := LoadDisposableDomains(strings.NewReader("# throwaway providers\n\n  Example.ORG \nmailinator.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 2 {
+		t.Errorf("loaded %d domains, want 2", len(domains))
+	}
+	policy := EmailPolicy{BlockDisposable: true, DisposableDomains: domains}
+	if err := policy.ValidateEmail("ada@example.org"); !errors.Is(err, goat.ErrDisposableEmail) {
+		t.Errorf("ValidateEmail with the loaded list = %v, want %v", err, goat.ErrDisposableEmail)
+	}
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// Password rule identifiers, reported as the code of password field errors.
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleUppercase     = "uppercase"
+	RuleLowercase     = "lowercase"
+	RuleDigit         = "digit"
+	RuleSymbol        = "symbol"
+	RuleContainsEmail = "contains_email"
+	RuleStrength      = "strength"
+	RuleBreached      = "breached"
+)
+
+// PasswordPolicy describes the rules a password must satisfy.
+type PasswordPolicy struct {
+	MinLength     int               // Minimum number of characters.
+	MaxLength     int               // Maximum number of bytes; bcrypt ignores everything past 72.
+	RequireUpper  bool              // At least one uppercase letter.
+	RequireLower  bool              // At least one lowercase letter.
+	RequireDigit  bool              // At least one digit.
+	RequireSymbol bool              // At least one character that is not a letter or digit.
+	DisallowEmail bool              // Reject passwords containing the email or its local part.
+	MinScore      int               // Minimum strength score from 0 (weakest) to 4, see PasswordStrength.
+	Breached      BreachedPasswords // Optional list of known breached passwords.
+}
+
+// DefaultPasswordPolicy is the policy applied by ValidateUser.
+// Applications can replace it at startup to tighten or relax the rules.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     8,
+	MaxLength:     72,
+	DisallowEmail: true,
+	MinScore:      2,
+}
+
+// Validate checks password against the policy. It returns nil or a *goat.ValidationError with a
+// "password" field error for every rule that failed; the rule name is used as the error code.
+func (p PasswordPolicy) Validate(email, password string) error {
+	errs := &goat.ValidationError{}
+	fail := func(rule, format string, args ...interface{}) {
+		errs.Add("password", rule, fmt.Sprintf(format, args...), goat.ErrWeakPassword)
+	}
+
+	if n := len([]rune(password)); p.MinLength > 0 && n < p.MinLength {
+		fail(RuleMinLength, "password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		fail(RuleMaxLength, "password must be at most %d bytes long", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		fail(RuleUppercase, "password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		fail(RuleLowercase, "password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail(RuleDigit, "password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		fail(RuleSymbol, "password must contain a symbol")
+	}
+
+	if p.DisallowEmail && containsEmail(password, email) {
+		fail(RuleContainsEmail, "password must not contain the email address")
+	}
+	if score, _ := PasswordStrength(password); score < p.MinScore {
+		fail(RuleStrength, "password is too easy to guess")
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		fail(RuleBreached, "password has appeared in a data breach")
+	}
+
+	return errs.Err()
+}
+
+// containsEmail reports whether password contains the email address or its local part.
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+	local, _, _ := strings.Cut(email, "@")
+	return strings.Contains(password, email) || (len(local) >= 3 && strings.Contains(password, local))
+}
+
+// commonPasswords are rejected outright by the strength estimator.
+var commonPasswords = map[string]struct{}{
+	"password": {}, "password1": {}, "password123": {}, "passw0rd": {}, "123456": {}, "12345678": {},
+	"123456789": {}, "1234567890": {}, "qwerty": {}, "qwerty123": {}, "qwertyuiop": {}, "abc123": {},
+	"111111": {}, "123123": {}, "iloveyou": {}, "admin": {}, "welcome": {}, "letmein": {}, "monkey": {},
+	"dragon": {}, "football": {}, "baseball": {}, "sunshine": {}, "princess": {}, "trustno1": {},
+	"master": {}, "shadow": {}, "superman": {}, "michael": {}, "login": {}, "starwars": {}, "whatever": {},
+}
+
+// sequences are runs of characters users commonly type in order.
+var sequences = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"01234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+}
+
+// PasswordStrength estimates how hard password is to guess, in the spirit of zxcvbn.
+// It returns the estimated entropy in bits and a score from 0 (trivial) to 4 (very strong).
+// Repeated characters, keyboard and alphabet sequences and common passwords reduce the estimate.
+func PasswordStrength(password string) (score int, entropy float64) {
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok || password == "" {
+		return 0, 0
+	}
+
+	var pool float64
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+	for _, c := range []struct {
+		present bool
+		size    float64
+	}{{hasLower, 26}, {hasUpper, 26}, {hasDigit, 10}, {hasSymbol, 33}, {hasOther, 100}} {
+		if c.present {
+			pool += c.size
+		}
+	}
+	perChar := math.Log2(pool)
+
+	// Characters that repeat the previous one or continue a sequence add almost nothing.
+	runes := []rune(lower)
+	for i, r := range runes {
+		if i > 0 && (r == runes[i-1] || inSequence(runes[i-1], r)) {
+			entropy += 1
+			continue
+		}
+		entropy += perChar
+	}
+
+	switch {
+	case entropy < 28:
+		score = 0
+	case entropy < 36:
+		score = 1
+	case entropy < 60:
+		score = 2
+	case entropy < 128:
+		score = 3
+	default:
+		score = 4
+	}
+	return score, entropy
+}
+
+// inSequence reports whether b follows a, forwards or backwards, in one of the known sequences.
+func inSequence(a, b rune) bool {
+	for _, seq := range sequences {
+		i := strings.IndexRune(seq, a)
+		if i < 0 {
+			continue
+		}
+		if (i+1 < len(seq) && rune(seq[i+1]) == b) || (i > 0 && rune(seq[i-1]) == b) {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachedPasswords is a set of passwords known from data breaches, stored as uppercase SHA-1 hex digests
+// so that plaintext lists never need to be kept in memory.
+type BreachedPasswords map[string]struct{}
+
+// LoadBreachedPasswords reads a breached-password list, one entry per line. Lines may hold a plaintext
+// password, a SHA-1 hex digest, or a digest followed by ":count" as in the Have I Been Pwned downloads.
+func LoadBreachedPasswords(r io.Reader) (BreachedPasswords, error) {
+	list := BreachedPasswords{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if digest, _, _ := strings.Cut(line, ":"); isSHA1Hex(digest) {
+			list[strings.ToUpper(digest)] = struct{}{}
+			continue
+		}
+		list[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains reports whether password is in the list.
+func (b BreachedPasswords) Contains(password string) bool {
+	_, ok := b[sha1Hex(password)]
+	return ok
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// failedRules returns the codes of the password field errors in err.
+func failedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *goat.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a *goat.ValidationError", err)
+	}
+	var rules []string
+	for _, f := range verr.Fields {
+		if f.Field != "password" || !errors.Is(f, goat.ErrWeakPassword) {
+			t.Errorf("field error %+v, want a password error wrapping %v", f, goat.ErrWeakPassword)
+		}
+		rules = append(rules, f.Code)
+	}
+	return rules
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	breached, err := LoadBreachedPasswords(strings.NewReader("hunter2hunter2\n" + sha1Hex("Xk9#vLp2!qZr") + ":3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict := PasswordPolicy{
+		MinLength:     10,
+		MaxLength:     72,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		DisallowEmail: true,
+		MinScore:      3,
+		Breached:      breached,
+	}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		email    string
+		password string
+		want     []string
+	}{
+		{"strong", strict, "ada@example.com", "Tr0ub4dor&3x", nil},
+		{"too short", strict, "ada@example.com", "Tr0u&3", []string{RuleMinLength, RuleStrength}},
+		{"too long", strict, "ada@example.com", "Tr0ub4dor&3x" + strings.Repeat("é", 40), []string{RuleMaxLength}},
+		{"no uppercase", strict, "ada@example.com", "tr0ub4dor&3x", []string{RuleUppercase}},
+		{"no lowercase", strict, "ada@example.com", "TR0UB4DOR&3X", []string{RuleLowercase}},
+		{"no digit", strict, "ada@example.com", "Troubador&xyq", []string{RuleDigit}},
+		{"no symbol", strict, "ada@example.com", "Tr0ub4dor3xq", []string{RuleSymbol}},
+		{"contains the email", strict, "ada@example.com", "Ada@Example.com1", []string{RuleContainsEmail}},
+		{"contains the local part", strict, "lovelace@example.com", "Lovelace&3xQ9", []string{RuleContainsEmail}},
+		{"short local part is allowed", strict, "al@example.com", "Tr0ub4dor&3xal", nil},
+		{"breached", strict, "ada@example.com", "Xk9#vLp2!qZr", []string{RuleBreached}},
+		{"every rule", strict, "ada@example.com", "", []string{RuleMinLength, RuleUppercase, RuleLowercase, RuleDigit, RuleSymbol, RuleStrength}},
+		{"default policy, common password", DefaultPasswordPolicy, "ada@example.com", "password123", []string{RuleStrength}},
+		{"default policy, passphrase", DefaultPasswordPolicy, "ada@example.com", "correct horse battery staple", nil},
+		{"no policy", PasswordPolicy{}, "ada@example.com", "a", nil},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Validate(tt.email, tt.password)
+		if got := failedRules(t, err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: failed rules = %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.want != nil && !errors.Is(err, goat.ErrValidation) {
+			t.Errorf("%s: errors.Is(err, ErrValidation) = false", tt.name)
+		}
+	}
+
+	// The plaintext entry is matched against the policy's list.
+	withList := DefaultPasswordPolicy
+	withList.Breached = breached
+	if got := failedRules(t, withList.Validate("ada@example.com", "hunter2hunter2")); !reflect.DeepEqual(got, []string{RuleBreached}) {
+		t.Errorf("breached plaintext entry: failed rules = %v, want %v", got, []string{RuleBreached})
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+		{"password", 0},
+		{"Password123", 0}, // Common passwords are matched case-insensitively.
+		{"aaaaaaaaaaaa", 0},
+		{"abcdefghijkl", 0},
+		{"qwertyuiopas", 0},
+		{"kx7mq2", 1},
+		{"kx7mq2wz", 2},
+		{"Tr0ub4dor&3x", 3},
+		{"correct horse battery staple", 4},
+	}
+	for _, tt := range tests {
+		if score, entropy := PasswordStrength(tt.password); score != tt.want {
+			t.Errorf("PasswordStrength(%q) = %d (%.1f bits), want %d", tt.password, score, entropy, tt.want)
+		}
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	list, err := LoadBreachedPasswords(strings.NewReader(strings.Join([]string{
+		"letmein",
+		"",
+		"  " + strings.ToLower(sha1Hex("s3cret!")) + "  ",
+		sha1Hex("hunter2") + ":1234",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		password string
+		want     bool
+	}{
+		{"letmein", true},
+		{"s3cret!", true},
+		{"hunter2", true},
+		{"Letmein", false},
+		{"", false},
+	} {
+		if got := list.Contains(tt.password); got != tt.want {
+			t.Errorf("Contains(%q) = %t, want %t", tt.password, got, tt.want)
+		}
+	}
+}
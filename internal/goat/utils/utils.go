@@ -5,14 +5,44 @@ import (
 	"github.com/bontusss/goat/internal/goat/models"
 )
 
+// emailErrorCodes maps the errors returned by EmailPolicy.ValidateEmail to field error codes.
+var emailErrorCodes = map[error]string{
+	goat.ErrEmailNotProvided: goat.CodeRequired,
+	goat.ErrInvalidEmail:     goat.CodeInvalid,
+	goat.ErrDisposableEmail:  CodeDisposableEmail,
+}
+
+// ValidateUser checks the fields of a new user. It normalizes the email in place and validates it with
+// DefaultEmailPolicy, then applies DefaultPasswordPolicy to the password unless SetUnusablePassword set it.
+// All problems are reported together in a *goat.ValidationError.
 func ValidateUser(user *models.User) error {
-	if user.Email == "" {
-		return goat.ErrEmailNotProvided
+	errs := &goat.ValidationError{}
+
+	user.Email = DefaultEmailPolicy.NormalizeEmail(user.Email)
+	if err := DefaultEmailPolicy.ValidateEmail(user.Email); err != nil {
+		errs.Add("email", emailErrorCodes[err], err.Error(), err)
 	}
 
-	if user.Password == "" {
-		return goat.ErrPasswordNotProvided
+	switch {
+	case user.WantsUnusablePassword():
+		// Set by SetUnusablePassword for accounts that sign in through linked identities only.
+	case user.Password == "":
+		errs.Add("password", goat.CodeRequired, goat.ErrPasswordNotProvided.Error(), goat.ErrPasswordNotProvided)
+	default:
+		errs.Merge("password", DefaultPasswordPolicy.Validate(user.Email, user.Password))
 	}
 
-	return nil
-}
\ No newline at end of file
+	return errs.Err()
+}
+
+// ValidatePassword checks a new password for the user with email against DefaultPasswordPolicy, as
+// ValidateUser does on registration. Problems are reported in a *goat.ValidationError.
+func ValidatePassword(email, password string) error {
+	errs := &goat.ValidationError{}
+	if password == "" {
+		errs.Add("password", goat.CodeRequired, goat.ErrPasswordNotProvided.Error(), goat.ErrPasswordNotProvided)
+		return errs.Err()
+	}
+	errs.Merge("password", DefaultPasswordPolicy.Validate(DefaultEmailPolicy.NormalizeEmail(email), password))
+	return errs.Err()
+}
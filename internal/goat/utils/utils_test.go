@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+)
+
+func TestValidateUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		unusable  bool
+		wantEmail string
+		want      []string // Field and code of every field error.
+	}{
+		{"valid", " Ada@Example.com ", "Tr0ub4dor&3x", false, "ada@example.com", nil},
+		{"nothing provided", "", "", false, "", []string{"email/required", "password/required"}},
+		{"invalid email and weak password", "ada", "password", false, "ada", []string{"email/invalid", "password/strength"}},
+		{"disposable email", "ada@mailinator.com", "Tr0ub4dor&3x", false, "ada@mailinator.com", []string{"email/" + CodeDisposableEmail}},
+		{"password contains the email", "lovelace@example.com", "lovelace1984!", false, "lovelace@example.com", []string{"password/" + RuleContainsEmail}},
+		{"unusable password", "ada@example.com", "", true, "ada@example.com", nil},
+	}
+	for _, tt := range tests {
+		user := &models.User{Email: tt.email, Password: tt.password}
+		if tt.unusable {
+			if err := user.SetUnusablePassword(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := ValidateUser(user)
+		if user.Email != tt.wantEmail {
+			t.Errorf("%s: email = %q, want %q", tt.name, user.Email, tt.wantEmail)
+		}
+
+		var fields []string
+		var verr *goat.ValidationError
+		if errors.As(err, &verr) {
+			for _, f := range verr.Fields {
+				fields = append(fields, f.Field+"/"+f.Code)
+			}
+		} else if err != nil {
+			t.Errorf("%s: error %v is not a *goat.ValidationError", tt.name, err)
+		}
+		if !reflect.DeepEqual(fields, tt.want) {
+			t.Errorf("%s: field errors = %v, want %v", tt.name, fields, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package goat
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrValidation is matched by every *ValidationError, so callers can check errors.Is(err, ErrValidation).
+var ErrValidation = errors.New("validation failed")
+
+// Validation error codes reported in FieldError.Code.
+const (
+	CodeRequired = "required"
+	CodeInvalid  = "invalid"
+)
+
+// FieldError describes a single problem with one input field.
+type FieldError struct {
+	Field   string `json:"field"`   // Name of the offending field, as it appears in JSON.
+	Code    string `json:"code"`    // Machine-readable reason, e.g. "required" or "min_length".
+	Message string `json:"message"` // Human-readable description.
+	Err     error  `json:"-"`       // Sentinel error for the problem, e.g. ErrEmailNotProvided.
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Unwrap returns the sentinel error for the problem.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError aggregates every field error found while validating input, so clients learn about
+// all problems at once. errors.Is matches ErrValidation as well as the sentinel of any field error.
+type ValidationError struct {
+	Fields []*FieldError `json:"fields"`
+}
+
+// Add records a problem with field. err is the sentinel for the problem and may be nil.
+func (e *ValidationError) Add(field, code, message string, err error) {
+	e.Fields = append(e.Fields, &FieldError{Field: field, Code: code, Message: message, Err: err})
+}
+
+// Merge appends the field errors of other, if err is a *ValidationError; any other non-nil error is
+// recorded against field with the "invalid" code.
+func (e *ValidationError) Merge(field string, err error) {
+	if err == nil {
+		return
+	}
+	var other *ValidationError
+	if errors.As(err, &other) {
+		e.Fields = append(e.Fields, other.Fields...)
+		return
+	}
+	e.Add(field, CodeInvalid, err.Error(), err)
+}
+
+// Err returns e, or nil when no field errors were recorded.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return ErrValidation.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap returns the field errors, so errors.Is and errors.As can inspect each of them.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
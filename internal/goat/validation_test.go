@@ -0,0 +1,119 @@
+package goat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	weak := &ValidationError{}
+	weak.Add("password", "min_length", "password must be at least 8 characters long", ErrWeakPassword)
+	weak.Add("password", "strength", "password is too easy to guess", ErrWeakPassword)
+
+	tests := []struct {
+		name       string
+		build      func(e *ValidationError)
+		wantFields []string // Field and code of every field error.
+		wantIs     []error
+		wantIsNot  []error
+	}{
+		{
+			"no problems",
+			func(e *ValidationError) {},
+			nil, nil, []error{ErrValidation},
+		},
+		{
+			"one problem",
+			func(e *ValidationError) { e.Add("email", CodeRequired, "email is required", ErrEmailNotProvided) },
+			[]string{"email/required"}, []error{ErrValidation, ErrEmailNotProvided}, []error{ErrWeakPassword},
+		},
+		{
+			"every problem is kept",
+			func(e *ValidationError) {
+				e.Add("email", CodeInvalid, "invalid email address", ErrInvalidEmail)
+				e.Merge("password", weak)
+			},
+			[]string{"email/invalid", "password/min_length", "password/strength"},
+			[]error{ErrValidation, ErrInvalidEmail, ErrWeakPassword}, []error{ErrEmailNotProvided},
+		},
+		{
+			"wrapped validation errors are merged",
+			func(e *ValidationError) { e.Merge("password", fmt.Errorf("reset: %w", weak)) },
+			[]string{"password/min_length", "password/strength"}, []error{ErrValidation, ErrWeakPassword}, nil,
+		},
+		{
+			"other errors are merged as invalid",
+			func(e *ValidationError) { e.Merge("name", ErrMalformedRequest) },
+			[]string{"name/invalid"}, []error{ErrValidation, ErrMalformedRequest}, nil,
+		},
+		{
+			"nil is not merged",
+			func(e *ValidationError) { e.Merge("name", nil) },
+			nil, nil, []error{ErrValidation},
+		},
+		{
+			"problem without a sentinel",
+			func(e *ValidationError) { e.Add("name", CodeRequired, "name is required", nil) },
+			[]string{"name/required"}, []error{ErrValidation}, []error{ErrMalformedRequest},
+		},
+	}
+	for _, tt := range tests {
+		e := &ValidationError{}
+		tt.build(e)
+		err := e.Err()
+		if tt.wantFields == nil {
+			if err != nil {
+				t.Errorf("%s: Err() = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+
+		// Wrapped, as services return it, the error still matches and exposes its fields.
+		err = fmt.Errorf("register: %w", err)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("%s: errors.As(*ValidationError) = false", tt.name)
+		}
+		var fields []string
+		for _, f := range verr.Fields {
+			fields = append(fields, f.Field+"/"+f.Code)
+		}
+		if !reflect.DeepEqual(fields, tt.wantFields) {
+			t.Errorf("%s: fields = %v, want %v", tt.name, fields, tt.wantFields)
+		}
+		var field *FieldError
+		if !errors.As(err, &field) || field != verr.Fields[0] {
+			t.Errorf("%s: errors.As(*FieldError) = %v, want the first field error", tt.name, field)
+		}
+		for _, target := range tt.wantIs {
+			if !errors.Is(err, target) {
+				t.Errorf("%s: errors.Is(err, %v) = false", tt.name, target)
+			}
+		}
+		for _, target := range tt.wantIsNot {
+			if errors.Is(err, target) {
+				t.Errorf("%s: errors.Is(err, %v) = true", tt.name, target)
+			}
+		}
+	}
+}
+
+func TestValidationErrorProblem(t *testing.T) {
+	e := &ValidationError{}
+	e.Add("email", CodeRequired, "email is required", ErrEmailNotProvided)
+	e.Add("password", "min_length", "password must be at least 8 characters long", ErrWeakPassword)
+
+	want := "validation failed: email: email is required; password: password must be at least 8 characters long"
+	if got := e.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	// Validation errors are reported as such, not as the first field's sentinel.
+	p := Problem(e)
+	if p.Status != http.StatusUnprocessableEntity || p.Code != "validation_failed" || !reflect.DeepEqual(p.Fields, e.Fields) {
+		t.Errorf("Problem() = %+v, want status 422, code validation_failed and the field errors", p)
+	}
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+)
+
+// Headers set on every webhook request.
+const (
+	SignatureHeader = "X-Goat-Signature" // "t=<unix seconds>,v1=<hex HMAC-SHA256>"; see Sign.
+	EventHeader     = "X-Goat-Event"     // The event type, e.g. "user.registered".
+	DeliveryHeader  = "X-Goat-Delivery"  // The delivery ID; a replay has a new one.
+)
+
+// Sign returns the SignatureHeader value for body sent at t: the Unix time, and the hex HMAC-SHA256
+// of "<unix time>.<body>" keyed with the endpoint's secret. Signing the time with the body lets
+// receivers reject old requests replayed by someone who captured them.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + signature(secret, timestamp, body)
+}
+
+// Verify checks the SignatureHeader value header of a request with body, as received at now. It
+// returns goat.ErrInvalidSignature if no v1 signature matches, or if the signed time is more than
+// tolerance away from now; a zero tolerance skips the time check. Receivers written in Go can use it
+// as is; others can follow Sign.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return goat.ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return goat.ErrInvalidSignature
+		}
+	}
+
+	expected := signature(secret, timestamp, body)
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return goat.ErrInvalidSignature
+}
+
+// signature returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
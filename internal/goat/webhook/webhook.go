@@ -0,0 +1,542 @@
+// Package webhook sends user lifecycle events to HTTP endpoints registered by partner systems.
+// Each event is stored as one delivery per subscribed endpoint, then POSTed with an HMAC-SHA256
+// signature and retried with exponential backoff until the endpoint answers with a 2xx status.
+// Every delivery keeps the outcome of its last attempt, and can be replayed.
+//
+// Events come from the transactional outbox: register OutboxHandler on an outbox.Relay for the
+// event types partners may subscribe to. Endpoints belong to a tenant and are sent only its events.
+//
+// Endpoint URLs are chosen by whoever registers them, so by default requests are never sent to
+// loopback, private, link-local or other non-public addresses, which would let them reach the
+// deployment's internal services or a cloud metadata service, and redirects are not followed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/outbox"
+)
+
+// Lengths of the response body and error kept with a delivery; longer ones are truncated.
+const (
+	maxResponseLength = 1024
+	maxErrorLength    = 1024
+)
+
+// secretPrefix starts every endpoint secret, which makes leaked secrets easy to spot and scan for.
+const secretPrefix = "whsec_"
+
+// maxRetryDelay is the longest Run waits before looking again after the store failed.
+const maxRetryDelay = time.Minute
+
+// ErrForbiddenAddress is the error of an attempt to send a request to an address that is not public.
+var ErrForbiddenAddress = errors.New("webhook: endpoint address is not public")
+
+// Config controls how the Dispatcher sends, retries and gives up.
+type Config struct {
+	Client       *http.Client  // Sends the requests, without following redirects; nil for a client that only connects to public addresses.
+	Timeout      time.Duration // Longest time an endpoint has to answer.
+	EventTypes   []string      // Event types endpoints may subscribe to.
+	BatchSize    int           // Deliveries claimed at once.
+	PollInterval time.Duration // Time between polls once no delivery is due.
+	Lease        time.Duration // How long a claimed delivery is hidden from other dispatchers; longer than Timeout.
+	MaxAttempts  int           // Attempts made before a delivery is marked failed.
+	MinBackoff   time.Duration // Delay before the first retry; doubled for each further one.
+	MaxBackoff   time.Duration // Longest delay between retries.
+}
+
+// DefaultConfig returns a Config for user lifecycle events that gives endpoints ten seconds to answer
+// and retries for about a day before giving up.
+func DefaultConfig() Config {
+	return Config{
+		Timeout: 10 * time.Second,
+		EventTypes: []string{
+			string(models.UserRegistered), string(models.UserUpdated), string(models.UserDeleted),
+			string(models.UserSuspended), string(models.UserDeactivated), string(models.UserReactivated), string(models.UserRestored),
+			string(models.UserAnonymized), string(models.UserErased),
+		},
+		BatchSize:    50,
+		PollInterval: time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  15,
+		MinBackoff:   10 * time.Second,
+		MaxBackoff:   4 * time.Hour,
+	}
+}
+
+// Event is the JSON body of every webhook request.
+type Event struct {
+	ID        string          `json:"id"` // The same in every delivery of the event, so receivers can drop duplicates.
+	Type      string          `json:"type"`
+	TenantID  string          `json:"tenant_id,omitempty"`
+	UserID    uint            `json:"user_id,omitempty"` // For user events, the user the event is about.
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"` // For user events, the user without their password.
+}
+
+// Dispatcher manages webhook endpoints and delivers events to them. Any number of dispatchers can
+// share a store. Endpoints are managed for one tenant; see ForTenant. Deliveries are sent for every
+// tenant, whichever tenant the dispatcher is for.
+type Dispatcher struct {
+	store  goat.WebhookStore
+	config Config
+	client *http.Client
+	tenant string // Tenant whose endpoints the dispatcher manages; empty for the default tenant.
+	now    func() time.Time
+}
+
+// NewDispatcher creates a Dispatcher that keeps endpoints and deliveries in store.
+func NewDispatcher(store goat.WebhookStore, config Config) *Dispatcher {
+	var client http.Client
+	if config.Client != nil {
+		client = *config.Client
+	} else {
+		client = http.Client{Transport: publicTransport()}
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		// A redirect could point anywhere, including at an internal address; the endpoint
+		// answering with one counts as a failed attempt.
+		return http.ErrUseLastResponse
+	}
+	return &Dispatcher{store: store, config: config, client: &client, now: time.Now}
+}
+
+// ForTenant returns a copy of d that manages the endpoints of tenantID.
+func (d *Dispatcher) ForTenant(tenantID string) *Dispatcher {
+	scoped := *d
+	scoped.tenant = tenantID
+	return &scoped
+}
+
+// CreateEndpoint registers url to be sent events of the given types, or of every type if events is
+// empty. The returned endpoint carries its signing secret, which is shown only this once.
+func (d *Dispatcher) CreateEndpoint(url string, events []string) (*models.WebhookEndpoint, error) {
+	if err := d.validate(url, events); err != nil {
+		return nil, err
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &models.WebhookEndpoint{
+		ID:        id,
+		TenantID:  d.tenant,
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedAt: d.now().UTC().Truncate(time.Millisecond),
+	}
+	if err := d.store.CreateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// Endpoint returns the endpoint id. Endpoints of other tenants are reported as not found.
+func (d *Dispatcher) Endpoint(id string) (*models.WebhookEndpoint, error) {
+	endpoint, err := d.store.GetWebhookEndpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.TenantID != d.tenant {
+		return nil, goat.ErrWebhookNotFound
+	}
+	return endpoint, nil
+}
+
+// Endpoints returns every endpoint, oldest first.
+func (d *Dispatcher) Endpoints() ([]*models.WebhookEndpoint, error) {
+	return d.store.ListWebhookEndpoints(d.tenant)
+}
+
+// UpdateEndpoint saves changes to an endpoint's URL, events and active flag. Deliveries already
+// queued are sent to the new URL; an inactive endpoint is sent no new events, but queued deliveries
+// and replays still go out.
+func (d *Dispatcher) UpdateEndpoint(endpoint *models.WebhookEndpoint) error {
+	if err := d.validate(endpoint.URL, endpoint.Events); err != nil {
+		return err
+	}
+	if _, err := d.Endpoint(endpoint.ID); err != nil {
+		return err
+	}
+	return d.store.UpdateWebhookEndpoint(endpoint)
+}
+
+// RotateSecret gives endpoint id a new signing secret and returns the endpoint with it. Requests are
+// signed with the new secret from then on, including retries of earlier deliveries.
+func (d *Dispatcher) RotateSecret(id string) (*models.WebhookEndpoint, error) {
+	endpoint, err := d.Endpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Secret, err = newSecret(); err != nil {
+		return nil, err
+	}
+	if err := d.store.UpdateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes endpoint id and its deliveries.
+func (d *Dispatcher) DeleteEndpoint(id string) error {
+	if _, err := d.Endpoint(id); err != nil {
+		return err
+	}
+	return d.store.DeleteWebhookEndpoint(id)
+}
+
+// Deliveries returns up to limit of the deliveries to endpoint id, newest first.
+func (d *Dispatcher) Deliveries(id string, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := d.Endpoint(id); err != nil {
+		return nil, err
+	}
+	return d.store.ListWebhookDeliveries(id, limit)
+}
+
+// Delivery returns the delivery id. Deliveries to the endpoints of other tenants are reported as
+// not found.
+func (d *Dispatcher) Delivery(id string) (*models.WebhookDelivery, error) {
+	delivery, err := d.store.GetWebhookDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.Endpoint(delivery.EndpointID); err != nil {
+		if errors.Is(err, goat.ErrWebhookNotFound) {
+			return nil, goat.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Replay queues the event of delivery id to be sent to its endpoint again, as a new delivery with a
+// fresh set of attempts. The event keeps its ID, so receivers that drop duplicates will ignore it.
+func (d *Dispatcher) Replay(id string) (*models.WebhookDelivery, error) {
+	original, err := d.Delivery(id)
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	delivery := d.newDelivery(deliveryID, original.EndpointID, original.EventID, original.EventType, original.UserID, original.Payload)
+	if err := d.store.CreateWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Enqueue queues event for every active endpoint of its tenant subscribed to its type. Enqueueing an
+// event again creates no duplicate deliveries, so it is safe to retry after an error.
+func (d *Dispatcher) Enqueue(event *Event) error {
+	endpoints, err := d.store.ListWebhookEndpoints(event.TenantID)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribed(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		delivery := d.newDelivery(deliveryID(endpoint.ID, event.ID), endpoint.ID, event.ID, event.Type, event.UserID, payload)
+		if err := d.store.CreateWebhookDelivery(delivery); err != nil && !errors.Is(err, goat.ErrWebhookDeliveryExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutboxHandler returns an outbox.Handler that enqueues each message as an event with the message's
+// ID, so that a message the relay delivers twice is still sent to each endpoint once.
+func (d *Dispatcher) OutboxHandler() outbox.Handler {
+	return func(ctx context.Context, message *models.OutboxMessage) error {
+		return d.Enqueue(&Event{
+			ID:        message.ID,
+			Type:      string(message.Type),
+			TenantID:  message.TenantID,
+			UserID:    message.UserID,
+			CreatedAt: message.CreatedAt,
+			Data:      message.Payload,
+		})
+	}
+}
+
+// DeliverOnce claims one batch of due deliveries and sends them. It returns the number of deliveries
+// claimed; an error means the store failed, and the deliveries left undone are sent again once
+// their lease runs out.
+func (d *Dispatcher) DeliverOnce(ctx context.Context) (int, error) {
+	deliveries, err := d.store.ClaimWebhookDeliveries(d.now(), d.config.Lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Run sends deliveries until ctx is cancelled. A full batch is followed by the next one straight
+// away; otherwise Run waits PollInterval before looking again. Store failures are reported to
+// onError, if not nil, and Run looks again after a delay that backs off up to maxRetryDelay.
+func (d *Dispatcher) Run(ctx context.Context, onError func(error)) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			n, err := d.DeliverOnce(ctx)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				failures++
+				if onError != nil {
+					onError(err)
+				}
+				timer.Reset(goat.RetryDelay(failures, maxRetryDelay))
+			case n == d.config.BatchSize:
+				failures = 0
+				timer.Reset(0)
+			default:
+				failures = 0
+				timer.Reset(d.config.PollInterval)
+			}
+		}
+	}
+}
+
+// deliver makes one attempt at delivery and saves its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	endpoint, err := d.store.GetWebhookEndpoint(delivery.EndpointID)
+	if err != nil {
+		if errors.Is(err, goat.ErrWebhookNotFound) {
+			// The endpoint is being deleted along with its deliveries.
+			return nil
+		}
+		return err
+	}
+
+	status, body, err := d.send(ctx, endpoint, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down is not the endpoint's fault; the lease runs out and the attempt is made again.
+		return ctx.Err()
+	}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("webhook: endpoint answered with status %d", status)
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = truncate(body, maxResponseLength)
+	delivery.UpdatedAt = now
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookSucceeded
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = models.WebhookFailed
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+	default:
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+	}
+	return d.store.UpdateWebhookDelivery(delivery)
+}
+
+// send POSTs the payload of delivery to endpoint and returns the response status and the start of
+// the response body.
+func (d *Dispatcher) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, error) {
+	if d.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "goat-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseLength))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// publicTransport returns a transport that refuses to connect to addresses that are not public. The
+// check is made on the address being dialled, after DNS resolution, so a name that resolves to an
+// internal address, or is rebound to one after the endpoint was registered, is refused too.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseNonPublic,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through a proxy, the address dialled would be the proxy's, not the endpoint's.
+	transport.Proxy = nil
+	return transport
+}
+
+// refuseNonPublic is a net.Dialer Control function that fails with ErrForbiddenAddress unless
+// address is a public unicast address.
+func refuseNonPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !public(addr.Unmap()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which netip does not count as
+// private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// public reports whether addr is a global unicast address outside the private, shared and
+// loopback ranges. Link-local addresses, which include the metadata service of most clouds at
+// 169.254.169.254, are not global unicast.
+func public(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// newDelivery returns a pending delivery of an event to endpointID, due now.
+func (d *Dispatcher) newDelivery(id, endpointID, eventID, eventType string, userID uint, payload []byte) *models.WebhookDelivery {
+	now := d.now().UTC().Truncate(time.Millisecond)
+	return &models.WebhookDelivery{
+		ID:            id,
+		EndpointID:    endpointID,
+		EventID:       eventID,
+		EventType:     eventType,
+		UserID:        userID,
+		Payload:       payload,
+		Status:        models.WebhookPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// validate checks that rawURL is an absolute http or https URL and that events are known types.
+func (d *Dispatcher) validate(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return goat.ErrInvalidWebhookURL
+	}
+	for _, event := range events {
+		if !contains(d.config.EventTypes, event) {
+			return fmt.Errorf("%w: %q", goat.ErrInvalidWebhookEvent, event)
+		}
+	}
+	return nil
+}
+
+// backoff returns the delay before retrying a delivery that has failed attempts times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.MinBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+	return delay
+}
+
+// deliveryID returns the ID of the first delivery of event eventID to endpointID. Deriving it from
+// both lets the store reject a second delivery of the same event.
+func deliveryID(endpointID, eventID string) string {
+	sum := sha256.Sum256([]byte(endpointID + "\x00" + eventID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// randomID returns a 32 hex character ID that starts with the nanosecond time, followed by random bits.
+func randomID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%016x", uint64(time.Now().UnixNano()), binary.BigEndian.Uint64(b[:])), nil
+}
+
+// newSecret returns a new endpoint signing secret.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
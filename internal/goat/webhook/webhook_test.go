@@ -0,0 +1,439 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bontusss/goat/internal/goat"
+	"github.com/bontusss/goat/internal/goat/models"
+	"github.com/bontusss/goat/internal/goat/repository"
+)
+
+// receiver is a webhook endpoint that records the requests it is sent and answers each with the
+// next of its statuses, then 200 once they run out.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*receivedRequest
+}
+
+// receivedRequest is a request sent to a receiver.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, &receivedRequest{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, "answered")
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// received returns the requests sent to r so far.
+func (r *receiver) received() []*receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*receivedRequest(nil), r.requests...)
+}
+
+// testClock is a settable time source for a Dispatcher.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+// newTestDispatcher returns a Dispatcher on a memory store that sends requests with client and
+// reads the time from the returned clock.
+func newTestDispatcher(client *http.Client) (*Dispatcher, *testClock) {
+	config := DefaultConfig()
+	config.Client = client
+	config.MaxAttempts = 3
+	d := NewDispatcher(repository.NewMemoryWebhookStore(), config)
+	clock := &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	d.now = clock.Now
+	return d, clock
+}
+
+// enqueue queues a user.registered event about user 7 of tenant.
+func enqueue(t *testing.T, d *Dispatcher, tenant string) *Event {
+	t.Helper()
+	event := &Event{
+		ID:        "event-1",
+		Type:      string(models.UserRegistered),
+		TenantID:  tenant,
+		UserID:    7,
+		CreatedAt: d.now(),
+		Data:      []byte(`{"id":7}`),
+	}
+	if err := d.Enqueue(event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// deliverOnce runs one DeliverOnce and checks that it claimed want deliveries.
+func deliverOnce(t *testing.T, d *Dispatcher, want int) {
+	t.Helper()
+	n, err := d.DeliverOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != want {
+		t.Fatalf("DeliverOnce claimed %d deliveries, want %d", n, want)
+	}
+}
+
+// onlyDelivery returns the one delivery to endpoint.
+func onlyDelivery(t *testing.T, d *Dispatcher, endpoint *models.WebhookEndpoint) *models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := d.Deliveries(endpoint.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestDeliverSignsRequests(t *testing.T) {
+	r := newReceiver(t)
+	d, clock := newTestDispatcher(r.Client())
+	endpoint, err := d.CreateEndpoint(r.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+	deliverOnce(t, d, 1)
+
+	requests := r.received()
+	if len(requests) != 1 {
+		t.Fatalf("endpoint got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if err := Verify(endpoint.Secret, req.header.Get(SignatureHeader), req.body, time.Minute, clock.now); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if err := Verify("whsec_other", req.header.Get(SignatureHeader), req.body, time.Minute, clock.now); !errors.Is(err, goat.ErrInvalidSignature) {
+		t.Errorf("signature verifies with another secret: %v", err)
+	}
+	if got := req.header.Get(EventHeader); got != string(models.UserRegistered) {
+		t.Errorf("%s = %q, want %q", EventHeader, got, models.UserRegistered)
+	}
+
+	delivery := onlyDelivery(t, d, endpoint)
+	if got := req.header.Get(DeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %q, want %q", DeliveryHeader, got, delivery.ID)
+	}
+	if delivery.Status != models.WebhookSucceeded || delivery.Attempts != 1 {
+		t.Errorf("delivery is %s after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+	}
+	if delivery.ResponseStatus != http.StatusOK || delivery.ResponseBody != "answered" {
+		t.Errorf("delivery log has response %d %q, want 200 %q", delivery.ResponseStatus, delivery.ResponseBody, "answered")
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	d, clock := newTestDispatcher(r.Client())
+	endpoint, err := d.CreateEndpoint(r.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+
+	deliverOnce(t, d, 1)
+	delivery := onlyDelivery(t, d, endpoint)
+	if delivery.Status != models.WebhookPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after a 500, delivery is %s after %d attempts with status %d", delivery.Status, delivery.Attempts, delivery.ResponseStatus)
+	}
+	if delivery.LastError == "" {
+		t.Error("failed attempt left no error in the delivery log")
+	}
+	if want := clock.now.Add(d.config.MinBackoff); !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %v, want %v", delivery.NextAttemptAt, want)
+	}
+
+	// Nothing is due until the backoff has passed.
+	deliverOnce(t, d, 0)
+	clock.now = clock.now.Add(d.config.MinBackoff)
+	deliverOnce(t, d, 1)
+	delivery = onlyDelivery(t, d, endpoint)
+	if want := clock.now.Add(2 * d.config.MinBackoff); !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("second retry at %v, want the doubled backoff %v", delivery.NextAttemptAt, want)
+	}
+
+	clock.now = delivery.NextAttemptAt
+	deliverOnce(t, d, 1)
+	delivery = onlyDelivery(t, d, endpoint)
+	if delivery.Status != models.WebhookSucceeded || delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Errorf("delivery is %s after %d attempts with error %q, want succeeded after 3", delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	d, clock := newTestDispatcher(r.Client())
+	endpoint, err := d.CreateEndpoint(r.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+
+	for i := 0; i < d.config.MaxAttempts; i++ {
+		deliverOnce(t, d, 1)
+		clock.now = clock.now.Add(d.config.MaxBackoff)
+	}
+	delivery := onlyDelivery(t, d, endpoint)
+	if delivery.Status != models.WebhookFailed || delivery.Attempts != d.config.MaxAttempts {
+		t.Errorf("delivery is %s after %d attempts, want failed after %d", delivery.Status, delivery.Attempts, d.config.MaxAttempts)
+	}
+	deliverOnce(t, d, 0)
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	d, _ := newTestDispatcher(nil)
+	for attempts, want := range map[int]time.Duration{
+		1:  d.config.MinBackoff,
+		2:  2 * d.config.MinBackoff,
+		3:  4 * d.config.MinBackoff,
+		50: d.config.MaxBackoff,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	r := newReceiver(t)
+	d, _ := newTestDispatcher(r.Client())
+	endpoint, err := d.CreateEndpoint(r.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := enqueue(t, d, "")
+	deliverOnce(t, d, 1)
+	original := onlyDelivery(t, d, endpoint)
+
+	// Enqueueing the same event again sends nothing new.
+	if err := d.Enqueue(event); err != nil {
+		t.Fatal(err)
+	}
+	deliverOnce(t, d, 0)
+
+	replay, err := d.Replay(original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ID == original.ID || replay.EventID != original.EventID || replay.Status != models.WebhookPending {
+		t.Errorf("replay %+v of %+v is not a new pending delivery of the same event", replay, original)
+	}
+	deliverOnce(t, d, 1)
+
+	requests := r.received()
+	if len(requests) != 2 {
+		t.Fatalf("endpoint got %d requests, want 2", len(requests))
+	}
+	if got := requests[1].header.Get(DeliveryHeader); got != replay.ID {
+		t.Errorf("replay sent with %s %q, want %q", DeliveryHeader, got, replay.ID)
+	}
+	if string(requests[1].body) != string(requests[0].body) {
+		t.Errorf("replay body %s differs from the original %s", requests[1].body, requests[0].body)
+	}
+}
+
+func TestEndpointsBelongToTheirTenant(t *testing.T) {
+	r := newReceiver(t)
+	d, _ := newTestDispatcher(r.Client())
+	acme := d.ForTenant("acme")
+	endpoint, err := acme.CreateEndpoint(r.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.TenantID != "acme" {
+		t.Fatalf("endpoint tenant = %q, want acme", endpoint.TenantID)
+	}
+	if _, err := d.Endpoint(endpoint.ID); !errors.Is(err, goat.ErrWebhookNotFound) {
+		t.Errorf("another tenant got the endpoint: %v", err)
+	}
+	if err := d.DeleteEndpoint(endpoint.ID); !errors.Is(err, goat.ErrWebhookNotFound) {
+		t.Errorf("another tenant deleted the endpoint: %v", err)
+	}
+
+	// Events of other tenants are not sent to the endpoint.
+	enqueue(t, d, "")
+	deliverOnce(t, d, 0)
+	enqueue(t, d, "acme")
+	deliverOnce(t, d, 1)
+	if n := len(r.received()); n != 1 {
+		t.Errorf("endpoint got %d requests, want 1", n)
+	}
+
+	delivery := onlyDelivery(t, acme, endpoint)
+	if _, err := d.Replay(delivery.ID); !errors.Is(err, goat.ErrWebhookDeliveryNotFound) {
+		t.Errorf("another tenant replayed the delivery: %v", err)
+	}
+}
+
+func TestDeliverDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirector.Close()
+
+	d, _ := newTestDispatcher(redirector.Client())
+	endpoint, err := d.CreateEndpoint(redirector.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+	deliverOnce(t, d, 1)
+
+	if followed {
+		t.Error("the redirect was followed")
+	}
+	delivery := onlyDelivery(t, d, endpoint)
+	if delivery.Status != models.WebhookPending || delivery.ResponseStatus != http.StatusFound {
+		t.Errorf("redirected delivery is %s with status %d, want a pending retry after 302", delivery.Status, delivery.ResponseStatus)
+	}
+}
+
+func TestDefaultClientRefusesNonPublicAddresses(t *testing.T) {
+	r := newReceiver(t)
+	d, _ := newTestDispatcher(nil)
+	endpoint, err := d.CreateEndpoint(r.URL, nil) // Listens on a loopback address.
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+	deliverOnce(t, d, 1)
+
+	if n := len(r.received()); n != 0 {
+		t.Errorf("endpoint on a loopback address got %d requests", n)
+	}
+	delivery := onlyDelivery(t, d, endpoint)
+	if !strings.Contains(delivery.LastError, ErrForbiddenAddress.Error()) {
+		t.Errorf("delivery error = %q, want it to mention %q", delivery.LastError, ErrForbiddenAddress)
+	}
+}
+
+func TestRefuseNonPublic(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"93.184.216.34:443":            true,
+		"[2606:2800:220:1::1]:443":     true,
+		"127.0.0.1:80":                 false,
+		"[::1]:80":                     false,
+		"[::ffff:127.0.0.1]:80":        false,
+		"10.1.2.3:80":                  false,
+		"172.16.0.1:80":                false,
+		"192.168.1.1:80":               false,
+		"100.64.0.1:80":                false,
+		"169.254.169.254:80":           false,
+		"[fe80::1]:80":                 false,
+		"[fd00:ec2::254]:80":           false,
+		"0.0.0.0:80":                   false,
+		"224.0.0.1:80":                 false,
+		"[ff02::1]:80":                 false,
+		"255.255.255.255:80":           false,
+		"[::ffff:169.254.169.254]:443": false,
+	} {
+		err := refuseNonPublic("tcp", address, nil)
+		if allowed && err != nil {
+			t.Errorf("%s refused: %v", address, err)
+		}
+		if !allowed && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s allowed", address)
+		}
+	}
+	if public(netip.MustParseAddr("100.128.0.1")) != true {
+		t.Error("address just past the shared address space refused")
+	}
+}
+
+// flakyStore fails the first claim of deliveries.
+type flakyStore struct {
+	goat.WebhookStore
+	mu     sync.Mutex
+	failed bool
+}
+
+var errStoreDown = errors.New("store is down")
+
+func (s *flakyStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	failed := s.failed
+	s.failed = true
+	s.mu.Unlock()
+	if !failed {
+		return nil, errStoreDown
+	}
+	return s.WebhookStore.ClaimWebhookDeliveries(now, lease, limit)
+}
+
+func TestRunSurvivesStoreFailures(t *testing.T) {
+	r := newReceiver(t)
+	config := DefaultConfig()
+	config.Client = r.Client()
+	d := NewDispatcher(&flakyStore{WebhookStore: repository.NewMemoryWebhookStore()}, config)
+	if _, err := d.CreateEndpoint(r.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, d, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reported := make(chan error, 1)
+	done := make(chan error)
+	go func() {
+		done <- d.Run(ctx, func(err error) {
+			select {
+			case reported <- err:
+			default:
+			}
+		})
+	}()
+
+	for len(r.received()) == 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if n := len(r.received()); n != 1 {
+		t.Errorf("endpoint got %d requests after the store recovered, want 1", n)
+	}
+	select {
+	case err := <-reported:
+		if !errors.Is(err, errStoreDown) {
+			t.Errorf("reported %v, want %v", err, errStoreDown)
+		}
+	default:
+		t.Error("the store failure was not reported")
+	}
+}